@@ -5,25 +5,65 @@
 // Package animation
 package animation
 
+import (
+	"github.com/wangzun/gogame/engine/core"
+	"github.com/wangzun/gogame/engine/math32"
+)
+
+// Animation events dispatched by an animation with an *Event parameter,
+// when played by itself or by an action of a mixer or controller.
+const (
+	OnStart    = "animation.OnStart"    // Playback started from the beginning
+	OnLoop     = "animation.OnLoop"     // Playback looped
+	OnFinished = "animation.OnFinished" // Playback reached the end without looping
+	OnMarker   = "animation.OnMarker"   // Playback passed a marker
+)
+
+// maxLoopEvents is the maximum number of loops for which the markers and the loop event
+// are dispatched in a single update, when the delta spans many loops.
+const maxLoopEvents = 16
+
 // Animation is a keyframe animation, containing channels.
 // Each channel animates a specific property of an object.
 // Animations can span multiple objects and properties.
+// An animation dispatches events when its playback starts, loops and finishes
+// and when it passes the times of its markers.
 type Animation struct {
-	name     string     // Animation name
-	loop     bool       // Whether the animation loops
-	paused   bool       // Whether the animation is paused
-	start    float32    // Initial time offset value
-	time     float32    // Total running time
-	minTime  float32    // Minimum time value across all channels
-	maxTime  float32    // Maximum time value across all channels
-	speed    float32    // Animation speed multiplier
-	channels []IChannel // List of channels
+	core.Dispatcher            // Embedded event dispatcher
+	name            string     // Animation name
+	loop            bool       // Whether the animation loops
+	paused          bool       // Whether the animation is paused
+	started         bool       // Whether the start event was dispatched
+	start           float32    // Initial time offset value
+	time            float32    // Total running time
+	minTime         float32    // Minimum time value across all channels
+	maxTime         float32    // Maximum time value across all channels
+	speed           float32    // Animation speed multiplier
+	channels        []IChannel // List of channels
+	markers         []Marker   // Markers sorted by time
+}
+
+// Marker is a named time of an animation, such as the time of a footstep or of a hit.
+type Marker struct {
+	Name string  // Marker name
+	Time float32 // Marker time
+}
+
+// Event is the parameter of the events dispatched by an animation.
+type Event struct {
+	Animation *Animation // Animation which dispatched the event
+	Action    *Action    // Action playing the animation or nil if played by itself
+	Weight    float32    // Weight of the action or 1 if played by itself
+	Name      string     // Marker name for OnMarker events
+	Time      float32    // Marker time for OnMarker events or animation time otherwise
+	Loops     int        // Number of loops completed in the update for OnLoop events
 }
 
 // NewAnimation creates and returns a pointer to a new Animation object.
 func NewAnimation() *Animation {
 
 	anim := new(Animation)
+	anim.Dispatcher.Initialize()
 	anim.speed = 1
 	return anim
 }
@@ -56,6 +96,7 @@ func (anim *Animation) Speed() float32 {
 func (anim *Animation) Reset() {
 
 	anim.time = anim.start
+	anim.started = false
 
 	// Update all channels
 	for i := range anim.channels {
@@ -94,9 +135,9 @@ func (anim *Animation) SetStart(v float32) {
 	anim.start = v
 }
 
-// Update interpolates and updates the target values for each channel.
-// If the animation is paused, returns false. If the animation is not paused,
-// returns true if the input value is inside the key frames ranges or false otherwise.
+// Update advances the animation time by the specified delta multiplied by the speed,
+// dispatching the animation events, and updates the target values for each channel.
+// If the animation is paused it does nothing. A non-looping animation is paused at its end.
 func (anim *Animation) Update(delta float32) {
 
 	// Check if paused
@@ -104,20 +145,31 @@ func (anim *Animation) Update(delta float32) {
 		return
 	}
 
-	// Check if input is less than minimum
-	anim.time = anim.time + delta * anim.speed
-	if anim.time < anim.minTime {
-		return
+	// Dispatches the start event and includes the markers at the start time
+	ev := Event{Animation: anim, Weight: 1}
+	delta *= anim.speed
+	inclusive := false
+	if !anim.started {
+		anim.started = true
+		inclusive = true
+		anim.dispatch(OnStart, ev, anim.time)
 	}
 
-	// Check if input is greater than maximum
-	if anim.time > anim.maxTime {
-		if anim.loop {
-			anim.time = anim.time - anim.maxTime
-		} else {
-			anim.time = anim.maxTime - 0.000001
-			anim.SetPaused(true)
+	// Waits for the time to reach the minimum
+	if anim.time < anim.minTime {
+		anim.time += delta
+		if anim.time < anim.minTime {
+			return
 		}
+		delta = anim.time - anim.minTime
+		anim.time = anim.minTime
+		inclusive = true
+	}
+
+	var finished bool
+	anim.time, finished = anim.advance(ev, anim.time, delta, anim.loop, inclusive)
+	if finished {
+		anim.SetPaused(true)
 	}
 
 	// Update all channels
@@ -127,6 +179,141 @@ func (anim *Animation) Update(delta float32) {
 	}
 }
 
+// AddMarker adds a marker with the specified name at the specified time to the animation.
+// Several markers may have the same name or time.
+func (anim *Animation) AddMarker(name string, time float32) {
+
+	i := len(anim.markers)
+	for i > 0 && anim.markers[i-1].Time > time {
+		i--
+	}
+	anim.markers = append(anim.markers, Marker{})
+	copy(anim.markers[i+1:], anim.markers[i:])
+	anim.markers[i] = Marker{name, time}
+}
+
+// RemoveMarkers removes all the markers with the specified name from the animation.
+func (anim *Animation) RemoveMarkers(name string) {
+
+	markers := anim.markers[:0]
+	for _, m := range anim.markers {
+		if m.Name != name {
+			markers = append(markers, m)
+		}
+	}
+	anim.markers = markers
+}
+
+// Markers returns the markers of the animation sorted by time.
+func (anim *Animation) Markers() []Marker {
+
+	return anim.markers
+}
+
+// advance returns the specified time advanced by the specified delta, wrapped if looping or
+// clamped otherwise, and whether a non-looping playback reached the end, dispatching the
+// events of the markers passed, including the ones at the initial time if inclusive is true,
+// and the loop and finish events. When the delta spans several loops the markers and the
+// loop event are dispatched once per loop, up to maxLoopEvents loops, and the loop events
+// carry the number of loops completed. The markers are dispatched in reverse order when
+// playing backwards.
+func (anim *Animation) advance(ev Event, time, delta float32, loop, inclusive bool) (float32, bool) {
+
+	first := anim.minTime
+	last := anim.maxTime
+	length := last - first
+	t := time + delta
+
+	// Plays forward
+	if delta >= 0 {
+		if loop && length > 0 {
+			if t > last {
+				// Dispatches the events of the partial loop to the end and of
+				// each full loop skipped, up to the maximum
+				lev := ev
+				lev.Loops = int(math32.Max(math32.Floor((t-first)/length), 1))
+				anim.dispatchMarkers(ev, time, last, inclusive)
+				anim.dispatch(OnLoop, lev, first)
+				for i := 1; i < lev.Loops && i < maxLoopEvents; i++ {
+					anim.dispatchMarkers(ev, first, last, true)
+					anim.dispatch(OnLoop, lev, first)
+				}
+				time = first
+				inclusive = true
+				t = first + math32.Mod(t-first, length)
+			}
+			anim.dispatchMarkers(ev, time, t, inclusive)
+			return t, false
+		}
+		if t >= last {
+			anim.dispatchMarkers(ev, time, last, inclusive)
+			if time < last {
+				anim.dispatch(OnFinished, ev, last)
+			}
+			return last, true
+		}
+		anim.dispatchMarkers(ev, time, t, inclusive)
+		return t, false
+	}
+
+	// Plays backwards
+	if loop && length > 0 {
+		if t < first {
+			lev := ev
+			lev.Loops = int(math32.Max(math32.Floor((last-t)/length), 1))
+			anim.dispatchMarkers(ev, time, first, inclusive)
+			anim.dispatch(OnLoop, lev, last)
+			for i := 1; i < lev.Loops && i < maxLoopEvents; i++ {
+				anim.dispatchMarkers(ev, last, first, true)
+				anim.dispatch(OnLoop, lev, last)
+			}
+			time = last
+			inclusive = true
+			t = last - math32.Mod(last-t, length)
+		}
+		anim.dispatchMarkers(ev, time, t, inclusive)
+		return t, false
+	}
+	if t <= first {
+		anim.dispatchMarkers(ev, time, first, inclusive)
+		if time > first {
+			anim.dispatch(OnFinished, ev, first)
+		}
+		return first, true
+	}
+	anim.dispatchMarkers(ev, time, t, inclusive)
+	return t, false
+}
+
+// dispatchMarkers dispatches the events of the markers passed playing from the specified time,
+// which is included if inclusive is true, to the specified time, forward or backwards.
+func (anim *Animation) dispatchMarkers(ev Event, from, to float32, inclusive bool) {
+
+	if from <= to {
+		for _, m := range anim.markers {
+			if (m.Time > from || inclusive && m.Time == from) && m.Time <= to {
+				ev.Name = m.Name
+				anim.dispatch(OnMarker, ev, m.Time)
+			}
+		}
+		return
+	}
+	for i := len(anim.markers) - 1; i >= 0; i-- {
+		m := anim.markers[i]
+		if (m.Time < from || inclusive && m.Time == from) && m.Time >= to {
+			ev.Name = m.Name
+			anim.dispatch(OnMarker, ev, m.Time)
+		}
+	}
+}
+
+// dispatch dispatches the specified event with a copy of the specified event parameter with the specified time.
+func (anim *Animation) dispatch(evname string, ev Event, time float32) {
+
+	ev.Time = time
+	anim.Dispatch(evname, &ev)
+}
+
 // AddChannel adds a channel to the animation.
 func (anim *Animation) AddChannel(ch IChannel) {
 
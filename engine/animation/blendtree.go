@@ -0,0 +1,168 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package animation
+
+import (
+	"github.com/wangzun/gogame/engine/math32"
+)
+
+// BlendTree blends animations by the values of one or two float parameters of a controller,
+// such as the speed and direction of a character. Each animation has a position in the space
+// of the parameters, which for one parameter is a threshold, and the animations nearest to the
+// values of the parameters get the largest weights, so an animation whose position is at the
+// values gets the full weight. The animations are synchronized by their normalized time.
+type BlendTree struct {
+	params  []string      // Names of the parameters
+	motions []blendMotion // Animations with their positions
+}
+
+// blendMotion is an animation of a blend tree.
+type blendMotion struct {
+	clip   string         // Name of the animation
+	pos    math32.Vector2 // Position in the parameter space
+	weight float32        // Current weight
+}
+
+// NewBlendTree1D creates and returns a pointer to a new blend tree which blends
+// the two animations whose thresholds enclose the value of the specified parameter.
+func NewBlendTree1D(param string) *BlendTree {
+
+	bt := new(BlendTree)
+	bt.params = []string{param}
+	return bt
+}
+
+// NewBlendTree2D creates and returns a pointer to a new blend tree which blends the animations
+// by the distance of their positions to the values of the specified parameters, using
+// gradient band interpolation, which works for any arrangement of the positions.
+func NewBlendTree2D(paramX, paramY string) *BlendTree {
+
+	bt := new(BlendTree)
+	bt.params = []string{paramX, paramY}
+	return bt
+}
+
+// AddMotion adds the animation with the specified clip name at the specified
+// threshold of the parameter of a 1D blend tree and returns the blend tree.
+func (bt *BlendTree) AddMotion(clip string, threshold float32) *BlendTree {
+
+	// Keeps the motions sorted by threshold
+	i := len(bt.motions)
+	for i > 0 && bt.motions[i-1].pos.X > threshold {
+		i--
+	}
+	bt.motions = append(bt.motions, blendMotion{})
+	copy(bt.motions[i+1:], bt.motions[i:])
+	bt.motions[i] = blendMotion{clip: clip, pos: math32.Vector2{threshold, 0}}
+	return bt
+}
+
+// AddMotion2D adds the animation with the specified clip name at the specified
+// position in the parameters space of a 2D blend tree and returns the blend tree.
+func (bt *BlendTree) AddMotion2D(clip string, x, y float32) *BlendTree {
+
+	bt.motions = append(bt.motions, blendMotion{clip: clip, pos: math32.Vector2{x, y}})
+	return bt
+}
+
+// Parameters returns the names of the parameters of the blend tree.
+func (bt *BlendTree) Parameters() []string {
+
+	return bt.params
+}
+
+// MotionCount returns the number of animations of the blend tree.
+func (bt *BlendTree) MotionCount() int {
+
+	return len(bt.motions)
+}
+
+// Weight returns the weight of the animation with the specified index from the last update.
+func (bt *BlendTree) Weight(idx int) float32 {
+
+	return bt.motions[idx].weight
+}
+
+// updateWeights updates the weights of the animations from the parameters of the specified controller.
+func (bt *BlendTree) updateWeights(c *Controller) {
+
+	if len(bt.motions) == 0 {
+		return
+	}
+	for i := range bt.motions {
+		bt.motions[i].weight = 0
+	}
+	if len(bt.params) == 1 {
+		bt.updateWeights1D(c.Float(bt.params[0]))
+	} else {
+		bt.updateWeights2D(math32.Vector2{c.Float(bt.params[0]), c.Float(bt.params[1])})
+	}
+}
+
+// updateWeights1D interpolates linearly the two motions whose thresholds enclose the specified value.
+func (bt *BlendTree) updateWeights1D(v float32) {
+
+	last := len(bt.motions) - 1
+	if v <= bt.motions[0].pos.X {
+		bt.motions[0].weight = 1
+		return
+	}
+	if v >= bt.motions[last].pos.X {
+		bt.motions[last].weight = 1
+		return
+	}
+	for i := 0; i < last; i++ {
+		t0 := bt.motions[i].pos.X
+		t1 := bt.motions[i+1].pos.X
+		if v >= t0 && v < t1 {
+			k := (v - t0) / (t1 - t0)
+			bt.motions[i].weight = 1 - k
+			bt.motions[i+1].weight = k
+			return
+		}
+	}
+}
+
+// updateWeights2D computes the weight of each motion as the minimum over the other
+// motions of how near the specified point is to it along the direction to the other
+// motion, and normalizes the weights.
+func (bt *BlendTree) updateWeights2D(p math32.Vector2) {
+
+	var total float32
+	for i := range bt.motions {
+		pi := bt.motions[i].pos
+		w := float32(1)
+		for j := range bt.motions {
+			if j == i {
+				continue
+			}
+			var dij, dip math32.Vector2
+			dij.SubVectors(&bt.motions[j].pos, &pi)
+			dip.SubVectors(&p, &pi)
+			lenSq := dij.LengthSq()
+			if lenSq == 0 {
+				continue
+			}
+			w = math32.Min(w, math32.Clamp(1-dip.Dot(&dij)/lenSq, 0, 1))
+		}
+		bt.motions[i].weight = w
+		total += w
+	}
+	if total > 0 {
+		for i := range bt.motions {
+			bt.motions[i].weight /= total
+		}
+		return
+	}
+
+	// Uses the nearest motion when outside all the bands
+	nearest := 0
+	for i := range bt.motions {
+		if p.DistanceToSquared(&bt.motions[i].pos) < p.DistanceToSquared(&bt.motions[nearest].pos) {
+			nearest = i
+		}
+	}
+	bt.motions[nearest].weight = 1
+}
@@ -83,19 +83,41 @@ func (c *Channel) Update(time float32) {
 		return
 	}
 
-	// Find keyframe interval
-	var idx int
-	for idx = 0; idx < len(c.keyframes)-1; idx++ {
-		if time >= c.keyframes[idx] && time < c.keyframes[idx+1] {
-			break
-		}
+	// Find keyframe interval, which at the last keyframe is the last interval
+	idx, relativeDelta := c.interval(time)
+	if idx == len(c.keyframes)-1 {
+		idx--
+		relativeDelta = 1
 	}
 
 	// Interpolate and update
-	relativeDelta := (time - c.keyframes[idx]) / (c.keyframes[idx+1] - c.keyframes[idx])
 	c.interpAction(idx, relativeDelta)
 }
 
+// interval returns the index of the keyframe preceding the specified time, which is clamped
+// to the keyframes, and the relative position of the time between it and the next keyframe.
+func (c *Channel) interval(time float32) (int, float32) {
+
+	last := len(c.keyframes) - 1
+	if last < 1 || time <= c.keyframes[0] {
+		return 0, 0
+	}
+	if time >= c.keyframes[last] {
+		return last, 0
+	}
+	idx := 0
+	for idx < last-1 && time >= c.keyframes[idx+1] {
+		idx++
+	}
+	return idx, (time - c.keyframes[idx]) / (c.keyframes[idx+1] - c.keyframes[idx])
+}
+
+// lerp returns whether the values of the keyframes returned by interval must be interpolated.
+func (c *Channel) lerp(k float32) bool {
+
+	return k > 0 && c.interpType != STEP
+}
+
 // IChannel is the interface for all channel types.
 type IChannel interface {
 	Update(time float32)
@@ -151,6 +173,25 @@ func NewPositionChannel(node core.INode) *PositionChannel {
 	return pc
 }
 
+// Target returns the node animated by the channel.
+func (pc *PositionChannel) Target() core.INode {
+
+	return pc.target
+}
+
+// Value sets the specified vector to the position at the specified time without updating the node.
+// Times outside the keyframes get the values of the first or last keyframe.
+func (pc *PositionChannel) Value(time float32, v *math32.Vector3) {
+
+	idx, k := pc.interval(time)
+	pc.values.GetVector3(idx*3, v)
+	if pc.lerp(k) {
+		var v2 math32.Vector3
+		pc.values.GetVector3((idx+1)*3, &v2)
+		v.Lerp(&v2, k)
+	}
+}
+
 // RotationChannel is the animation channel for a node's rotation.
 type RotationChannel NodeChannel
 
@@ -195,6 +236,25 @@ func NewRotationChannel(node core.INode) *RotationChannel {
 	return rc
 }
 
+// Target returns the node animated by the channel.
+func (rc *RotationChannel) Target() core.INode {
+
+	return rc.target
+}
+
+// Value sets the specified quaternion to the rotation at the specified time without updating the node.
+// Times outside the keyframes get the values of the first or last keyframe.
+func (rc *RotationChannel) Value(time float32, q *math32.Quaternion) {
+
+	idx, k := rc.interval(time)
+	q.FromArray(rc.values, idx*4)
+	if rc.lerp(k) {
+		var q2 math32.Quaternion
+		q2.FromArray(rc.values, (idx+1)*4)
+		q.Slerp(&q2, k)
+	}
+}
+
 // ScaleChannel is the animation channel for a node's scale.
 type ScaleChannel NodeChannel
 
@@ -235,6 +295,25 @@ func NewScaleChannel(node core.INode) *ScaleChannel {
 	return sc
 }
 
+// Target returns the node animated by the channel.
+func (sc *ScaleChannel) Target() core.INode {
+
+	return sc.target
+}
+
+// Value sets the specified vector to the scale at the specified time without updating the node.
+// Times outside the keyframes get the values of the first or last keyframe.
+func (sc *ScaleChannel) Value(time float32, v *math32.Vector3) {
+
+	idx, k := sc.interval(time)
+	sc.values.GetVector3(idx*3, v)
+	if sc.lerp(k) {
+		var v2 math32.Vector3
+		sc.values.GetVector3((idx+1)*3, &v2)
+		v.Lerp(&v2, k)
+	}
+}
+
 // MorphChannel is the IChannel for morph geometries.
 type MorphChannel struct {
 	Channel
@@ -285,6 +364,28 @@ func NewMorphChannel(mg *geometry.MorphGeometry) *MorphChannel {
 	return mc
 }
 
+// Target returns the morph geometry animated by the channel.
+func (mc *MorphChannel) Target() *geometry.MorphGeometry {
+
+	return mc.target
+}
+
+// Value sets the specified slice, whose length must be the number of morph targets, to the
+// weights at the specified time without updating the morph geometry.
+// Times outside the keyframes get the values of the first or last keyframe.
+func (mc *MorphChannel) Value(time float32, weights []float32) {
+
+	n := len(weights)
+	idx, k := mc.interval(time)
+	copy(weights, mc.values[idx*n:(idx+1)*n])
+	if mc.lerp(k) {
+		next := mc.values[(idx+1)*n : (idx+2)*n]
+		for i := range weights {
+			weights[i] += (next[i] - weights[i]) * k
+		}
+	}
+}
+
 // InterpolationType specifies the interpolation type.
 type InterpolationType string
 
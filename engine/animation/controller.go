@@ -0,0 +1,677 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package animation
+
+import (
+	"github.com/wangzun/gogame/engine/math32"
+)
+
+// Controller is an animation state machine which plays the animations of its current state
+// through the actions of a mixer. Each state plays one animation, or a blend tree which blends
+// several animations by the values of parameters, and has transitions to other states which
+// are taken when their conditions on the parameters are met, after an optional exit time,
+// cross-fading the states over the duration of the transition. The animations of the states
+// dispatch their events with the weights they are played with.
+// The controller sets the time and weight of its actions, which must not be changed directly,
+// and the mixer must be updated after the controller.
+type Controller struct {
+	mixer      *Mixer                // Mixer which blends the actions
+	layer      int                   // Index of the mixer layer of the actions
+	clips      map[string]*Animation // Animations by name
+	params     map[string]*param     // Parameters by name
+	states     map[string]*State     // States by name
+	anyTrans   []*Transition         // Transitions from any state
+	current    *State                // Current state
+	previous   *State                // State fading out during a transition
+	transition *Transition           // Current transition or nil
+	transTime  float32               // Elapsed transition time
+	weights    map[*Action]float32   // Weights of the driven actions in this update
+	times      map[*Action]float32   // Times of the driven actions in this update
+	driven     map[*Action]bool      // Actions driven by the controller
+}
+
+// ParamType is the type of a controller parameter.
+type ParamType int
+
+// The parameter types.
+const (
+	ParamFloat   ParamType = iota // Float value
+	ParamBool                     // Boolean value
+	ParamTrigger                  // Boolean value reset when consumed by a transition
+)
+
+// param is a controller parameter.
+type param struct {
+	ptype ParamType
+	value float32 // Value with booleans as 0 or 1
+}
+
+// State is a state of a controller which plays an animation or a blend tree.
+type State struct {
+	ctrl        *Controller
+	name        string        // State name
+	clip        string        // Name of the animation if not a blend tree
+	tree        *BlendTree    // Blend tree or nil
+	speed       float32       // Playback speed multiplier
+	loop        bool          // Whether the state loops
+	time        float32       // Normalized time, which counts the loops
+	prevTime    float32       // Normalized time before the last update
+	started     bool          // Whether the animations were applied since the state was entered
+	transitions []*Transition // Transitions from this state
+}
+
+// CondOp is the operator of a transition condition.
+type CondOp int
+
+// The condition operators.
+const (
+	CondGreater   CondOp = iota // Float parameter greater than the value
+	CondLess                    // Float parameter less than the value
+	CondEquals                  // Parameter equal to the value
+	CondNotEquals               // Parameter not equal to the value
+	CondTrue                    // Boolean or trigger parameter set
+	CondFalse                   // Boolean parameter not set
+)
+
+// Condition is a condition of a transition on a controller parameter.
+type Condition struct {
+	Param string  // Parameter name
+	Op    CondOp  // Operator
+	Value float32 // Value compared with the parameter
+}
+
+// Transition is a transition between two states of a controller.
+type Transition struct {
+	to         string      // Name of the destination state
+	duration   float32     // Cross-fade duration in seconds
+	exitTime   float32     // Normalized time of the source state after which the transition may be taken
+	hasExit    bool        // Whether the transition has an exit time
+	conditions []Condition // Conditions which must all be met
+}
+
+// NewController creates and returns a pointer to a new controller which plays
+// its animations through the actions of the specified mixer.
+func NewController(mixer *Mixer) *Controller {
+
+	c := new(Controller)
+	c.mixer = mixer
+	c.clips = make(map[string]*Animation)
+	c.params = make(map[string]*param)
+	c.states = make(map[string]*State)
+	c.weights = make(map[*Action]float32)
+	c.times = make(map[*Action]float32)
+	c.driven = make(map[*Action]bool)
+	return c
+}
+
+// Mixer returns the mixer of the controller.
+func (c *Controller) Mixer() *Mixer {
+
+	return c.mixer
+}
+
+// SetLayer sets the index of the mixer layer of the actions of the controller (default = 0),
+// so for example a controller for the upper body may play over another for the whole body.
+func (c *Controller) SetLayer(idx int) {
+
+	c.layer = idx
+	for a := range c.driven {
+		a.SetLayer(idx)
+	}
+}
+
+// Layer returns the index of the mixer layer of the actions of the controller.
+func (c *Controller) Layer() int {
+
+	return c.layer
+}
+
+// AddClips adds the specified animations, which are referenced by the states by their names.
+func (c *Controller) AddClips(anims ...*Animation) {
+
+	for _, anim := range anims {
+		c.clips[anim.Name()] = anim
+	}
+}
+
+// Clip returns the animation with the specified name or nil if not found.
+func (c *Controller) Clip(name string) *Animation {
+
+	return c.clips[name]
+}
+
+// AddFloat adds a float parameter with the specified name and initial value.
+func (c *Controller) AddFloat(name string, value float32) {
+
+	c.params[name] = &param{ParamFloat, value}
+}
+
+// AddBool adds a boolean parameter with the specified name and initial value.
+func (c *Controller) AddBool(name string, value bool) {
+
+	c.params[name] = &param{ParamBool, boolValue(value)}
+}
+
+// AddTrigger adds a trigger parameter with the specified name, which is a boolean parameter
+// that is set by SetTrigger and reset when a transition with a condition on it is taken.
+func (c *Controller) AddTrigger(name string) {
+
+	c.params[name] = &param{ParamTrigger, 0}
+}
+
+// SetFloat sets the value of the specified float parameter.
+func (c *Controller) SetFloat(name string, value float32) {
+
+	if p := c.params[name]; p != nil {
+		p.value = value
+	}
+}
+
+// Float returns the value of the specified float parameter or zero if not found.
+func (c *Controller) Float(name string) float32 {
+
+	if p := c.params[name]; p != nil {
+		return p.value
+	}
+	return 0
+}
+
+// SetBool sets the value of the specified boolean parameter.
+func (c *Controller) SetBool(name string, value bool) {
+
+	if p := c.params[name]; p != nil {
+		p.value = boolValue(value)
+	}
+}
+
+// Bool returns the value of the specified boolean or trigger parameter or false if not found.
+func (c *Controller) Bool(name string) bool {
+
+	if p := c.params[name]; p != nil {
+		return p.value != 0
+	}
+	return false
+}
+
+// SetTrigger sets the specified trigger parameter.
+func (c *Controller) SetTrigger(name string) {
+
+	c.SetBool(name, true)
+}
+
+// ResetTrigger resets the specified trigger parameter.
+func (c *Controller) ResetTrigger(name string) {
+
+	c.SetBool(name, false)
+}
+
+// AddState adds a state with the specified name which plays the animation with the specified
+// clip name, and returns a pointer to it. The state loops by default. The first state added
+// is the initial state.
+func (c *Controller) AddState(name, clip string) *State {
+
+	s := &State{ctrl: c, name: name, clip: clip, speed: 1, loop: true}
+	c.states[name] = s
+	if c.current == nil {
+		c.current = s
+	}
+	return s
+}
+
+// AddBlendState adds a state with the specified name which plays the
+// specified blend tree, and returns a pointer to it. The state loops by default.
+func (c *Controller) AddBlendState(name string, tree *BlendTree) *State {
+
+	s := c.AddState(name, "")
+	s.tree = tree
+	return s
+}
+
+// State returns the state with the specified name or nil if not found.
+func (c *Controller) State(name string) *State {
+
+	return c.states[name]
+}
+
+// AddTransition adds a transition from the state with the specified name, or from any state
+// if the name is empty, to the state with the specified name with the specified cross-fade
+// duration in seconds and returns a pointer to it. Transitions are checked in the order
+// they are added, after the transitions from any state, and the first one whose conditions
+// are met is taken. A transition without conditions and exit time is taken immediately.
+func (c *Controller) AddTransition(from, to string, duration float32) *Transition {
+
+	t := &Transition{to: to, duration: duration}
+	if from == "" {
+		c.anyTrans = append(c.anyTrans, t)
+	} else if s := c.states[from]; s != nil {
+		s.transitions = append(s.transitions, t)
+	} else {
+		log.Error("Controller.AddTransition: state %q not found", from)
+	}
+	return t
+}
+
+// SetState makes the state with the specified name the current state,
+// starting it from the beginning without transition.
+func (c *Controller) SetState(name string) {
+
+	s := c.states[name]
+	if s == nil {
+		log.Error("Controller.SetState: state %q not found", name)
+		return
+	}
+	c.current = s
+	c.previous = nil
+	c.transition = nil
+	s.enter()
+}
+
+// CrossFade starts a transition from the current state to the state with
+// the specified name with the specified duration in seconds.
+func (c *Controller) CrossFade(name string, duration float32) {
+
+	c.startTransition(&Transition{to: name, duration: duration})
+}
+
+// CurrentState returns the current state, which is the destination state during a transition.
+func (c *Controller) CurrentState() *State {
+
+	return c.current
+}
+
+// InTransition returns whether the controller is cross-fading two states.
+func (c *Controller) InTransition() bool {
+
+	return c.transition != nil
+}
+
+// Update advances the states by the specified time in seconds, takes the transitions whose
+// conditions are met and sets the time and weight of the actions of the states.
+// Transitions are not checked during another transition.
+func (c *Controller) Update(delta float32) {
+
+	if c.current == nil {
+		return
+	}
+
+	// Advances the states and the current transition
+	prevTime := c.current.time
+	c.current.advance(delta)
+	if c.transition != nil {
+		c.previous.advance(delta)
+		c.transTime += delta
+		if c.transTime >= c.transition.duration {
+			c.transition = nil
+			c.previous = nil
+		}
+	}
+
+	// Takes the first transition whose conditions are met
+	if c.transition == nil {
+		if t := c.findTransition(prevTime); t != nil {
+			c.startTransition(t)
+		}
+	}
+
+	// Sets the actions of the states weighted by the transition progress
+	for a := range c.weights {
+		delete(c.weights, a)
+		delete(c.times, a)
+	}
+	if c.transition != nil {
+		k := c.transTime / c.transition.duration
+		c.previous.apply(1 - k)
+		c.current.apply(k)
+	} else {
+		c.current.apply(1)
+	}
+	for a := range c.driven {
+		w, ok := c.weights[a]
+		if !ok || w <= 0 {
+			a.Stop()
+			delete(c.driven, a)
+			continue
+		}
+		// The time is set directly as it follows the animation, so the root motion is kept
+		a.SetWeight(w)
+		a.time = c.times[a]
+	}
+}
+
+// findTransition returns the first transition from any state to another state or from the
+// current state which may be taken when the normalized time of the current state advanced
+// from the specified previous time, or nil if none.
+func (c *Controller) findTransition(prevTime float32) *Transition {
+
+	for _, t := range c.anyTrans {
+		if t.to != c.current.name && t.check(c, prevTime) {
+			return t
+		}
+	}
+	for _, t := range c.current.transitions {
+		if t.check(c, prevTime) {
+			return t
+		}
+	}
+	return nil
+}
+
+// startTransition starts the specified transition from the current state.
+func (c *Controller) startTransition(t *Transition) {
+
+	to := c.states[t.to]
+	if to == nil {
+		log.Error("Controller: transition to state %q not found", t.to)
+		return
+	}
+	for _, cond := range t.conditions {
+		if p := c.params[cond.Param]; p != nil && p.ptype == ParamTrigger {
+			p.value = 0
+		}
+	}
+	if t.duration <= 0 || to == c.current {
+		c.SetState(t.to)
+		return
+	}
+	c.previous = c.current
+	c.current = to
+	c.transition = t
+	c.transTime = 0
+	to.enter()
+}
+
+// drive adds the specified weight and time to the specified animation, which is played
+// by the controller with the specified loop setting. The time of the animation with the
+// largest weight is used.
+func (c *Controller) drive(name string, weight, time float32, loop bool) {
+
+	anim := c.clips[name]
+	if anim == nil || weight <= 0 {
+		return
+	}
+	a := c.mixer.Action(anim)
+	if !c.driven[a] {
+		c.driven[a] = true
+		a.Stop()
+		a.SetLayer(c.layer)
+		a.Play()
+		a.SetPaused(true)
+	}
+	// The loop setting lets the mixer follow the root motion across the end of the animation
+	a.SetLoop(loop)
+	prev, ok := c.weights[a]
+	if !ok || weight > prev {
+		c.times[a] = time
+	}
+	c.weights[a] = prev + weight
+}
+
+// Name returns the name of the state.
+func (s *State) Name() string {
+
+	return s.name
+}
+
+// Clip returns the name of the animation played by the state or an empty string for blend trees.
+func (s *State) Clip() string {
+
+	return s.clip
+}
+
+// BlendTree returns the blend tree played by the state or nil if not a blend tree.
+func (s *State) BlendTree() *BlendTree {
+
+	return s.tree
+}
+
+// SetSpeed sets the playback speed multiplier of the state (default = 1).
+func (s *State) SetSpeed(speed float32) *State {
+
+	s.speed = speed
+	return s
+}
+
+// Speed returns the playback speed multiplier of the state.
+func (s *State) Speed() float32 {
+
+	return s.speed
+}
+
+// SetLoop sets whether the state loops (default = true). A state which doesn't
+// loop keeps the last keyframes of its animations when it reaches the end.
+func (s *State) SetLoop(state bool) *State {
+
+	s.loop = state
+	return s
+}
+
+// Loop returns whether the state loops.
+func (s *State) Loop() bool {
+
+	return s.loop
+}
+
+// NormalizedTime returns the time of the state as a fraction of its duration
+// whose integer part is the number of completed loops.
+func (s *State) NormalizedTime() float32 {
+
+	return s.time
+}
+
+// duration returns the duration of the state in seconds, which for blend
+// trees is the average of the durations of the animations by their weights.
+func (s *State) duration() float32 {
+
+	if s.tree == nil {
+		return clipLength(s.ctrl.clips[s.clip])
+	}
+	s.tree.updateWeights(s.ctrl)
+	var d float32
+	for _, m := range s.tree.motions {
+		d += m.weight * clipLength(s.ctrl.clips[m.clip])
+	}
+	return d
+}
+
+// enter restarts the state.
+func (s *State) enter() {
+
+	s.time = 0
+	s.prevTime = 0
+	s.started = false
+
+	// The actions which were already driven, as when the current state is entered
+	// again, jump to the start, which is not root motion
+	clips := []string{s.clip}
+	if s.tree != nil {
+		clips = clips[:0]
+		for _, m := range s.tree.motions {
+			clips = append(clips, m.clip)
+		}
+	}
+	for a := range s.ctrl.driven {
+		for _, clip := range clips {
+			if a.anim == s.ctrl.clips[clip] {
+				a.rootValid = false
+			}
+		}
+	}
+}
+
+// advance advances the normalized time of the state by the specified time in seconds.
+func (s *State) advance(delta float32) {
+
+	s.prevTime = s.time
+	d := s.duration()
+	if d <= 0 {
+		return
+	}
+	s.time += delta * s.speed / d
+	if s.time < 0 {
+		s.time = 0
+	}
+	if !s.loop && s.time > 1 {
+		s.time = 1
+	}
+}
+
+// apply drives the animations of the state with the specified weight at the current time
+// and dispatches their events. The animations of a blend tree are synchronized by their
+// normalized time and all the ones with non-zero weights dispatch events.
+func (s *State) apply(weight float32) {
+
+	frac := s.time
+	if s.loop {
+		frac = s.time - math32.Floor(s.time)
+	}
+	inclusive := !s.started
+	s.started = true
+	if s.tree == nil {
+		s.ctrl.drive(s.clip, weight, clipTime(s.ctrl.clips[s.clip], frac), s.loop)
+		s.dispatchEvents(s.clip, weight, inclusive)
+		return
+	}
+	s.tree.updateWeights(s.ctrl)
+	for _, m := range s.tree.motions {
+		s.ctrl.drive(m.clip, weight*m.weight, clipTime(s.ctrl.clips[m.clip], frac), s.loop)
+		s.dispatchEvents(m.clip, weight*m.weight, inclusive)
+	}
+}
+
+// dispatchEvents dispatches the events of the specified animation played by the state with
+// the specified weight since the last update, which include the start event and the markers
+// at the start time if inclusive is true.
+func (s *State) dispatchEvents(clip string, weight float32, inclusive bool) {
+
+	anim := s.ctrl.clips[clip]
+	if anim == nil || weight <= 0 {
+		return
+	}
+	ev := Event{Animation: anim, Action: s.ctrl.mixer.Action(anim), Weight: weight}
+	prevFrac := s.prevTime
+	if s.loop {
+		prevFrac = s.prevTime - math32.Floor(s.prevTime)
+	}
+	from := clipTime(anim, prevFrac)
+	if inclusive {
+		anim.dispatch(OnStart, ev, from)
+	}
+	anim.advance(ev, from, (s.time-s.prevTime)*clipLength(anim), s.loop, inclusive)
+}
+
+// clipLength returns the duration of the specified animation or zero if nil.
+func clipLength(anim *Animation) float32 {
+
+	if anim == nil {
+		return 0
+	}
+	return anim.maxTime - anim.minTime
+}
+
+// clipTime returns the time of the specified animation at the specified fraction of its duration.
+func clipTime(anim *Animation, frac float32) float32 {
+
+	if anim == nil {
+		return 0
+	}
+	return anim.minTime + frac*(anim.maxTime-anim.minTime)
+}
+
+// AddCondition adds a condition on the specified parameter to the transition
+// and returns the transition. The value is ignored by CondTrue and CondFalse.
+func (t *Transition) AddCondition(param string, op CondOp, value float32) *Transition {
+
+	t.conditions = append(t.conditions, Condition{param, op, value})
+	return t
+}
+
+// Conditions returns the conditions of the transition.
+func (t *Transition) Conditions() []Condition {
+
+	return t.conditions
+}
+
+// SetExitTime sets the normalized time of the source state after which the transition may be
+// taken. Exit times less than 1 are reached on every loop of looping states.
+func (t *Transition) SetExitTime(exitTime float32) *Transition {
+
+	t.exitTime = exitTime
+	t.hasExit = true
+	return t
+}
+
+// ExitTime returns the exit time of the transition and whether it has one.
+func (t *Transition) ExitTime() (float32, bool) {
+
+	return t.exitTime, t.hasExit
+}
+
+// Destination returns the name of the destination state of the transition.
+func (t *Transition) Destination() string {
+
+	return t.to
+}
+
+// Duration returns the cross-fade duration of the transition in seconds.
+func (t *Transition) Duration() float32 {
+
+	return t.duration
+}
+
+// check returns whether the transition may be taken when the normalized
+// time of the current state advanced from the specified previous time.
+func (t *Transition) check(c *Controller, prevTime float32) bool {
+
+	if t.hasExit {
+		time := c.current.time
+		if t.exitTime < 1 && c.current.loop {
+			// The exit time is reached in every loop
+			if math32.Floor(time-t.exitTime) <= math32.Floor(prevTime-t.exitTime) {
+				return false
+			}
+		} else if time < t.exitTime {
+			return false
+		}
+	}
+	for _, cond := range t.conditions {
+		if !cond.met(c) {
+			return false
+		}
+	}
+	return true
+}
+
+// met returns whether the condition is met by the current parameter values.
+func (cond *Condition) met(c *Controller) bool {
+
+	p := c.params[cond.Param]
+	if p == nil {
+		return false
+	}
+	switch cond.Op {
+	case CondGreater:
+		return p.value > cond.Value
+	case CondLess:
+		return p.value < cond.Value
+	case CondEquals:
+		return p.value == cond.Value
+	case CondNotEquals:
+		return p.value != cond.Value
+	case CondTrue:
+		return p.value != 0
+	case CondFalse:
+		return p.value == 0
+	}
+	return false
+}
+
+// boolValue returns 1 for true and 0 for false.
+func boolValue(b bool) float32 {
+
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,207 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package animation
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+)
+
+// ctrlFile is a controller JSON description
+type ctrlFile struct {
+	Layer       int              `json:"layer"`
+	Initial     string           `json:"initial"`
+	Parameters  []ctrlParam      `json:"parameters"`
+	States      []ctrlState      `json:"states"`
+	Transitions []ctrlTransition `json:"transitions"`
+}
+
+// ctrlParam is a controller parameter JSON description
+type ctrlParam struct {
+	Name  string      `json:"name"`
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+// ctrlState is a controller state JSON description
+type ctrlState struct {
+	Name  string     `json:"name"`
+	Clip  string     `json:"clip"`
+	Speed *float32   `json:"speed"`
+	Loop  *bool      `json:"loop"`
+	Blend *ctrlBlend `json:"blend"`
+}
+
+// ctrlBlend is a blend tree JSON description
+type ctrlBlend struct {
+	Parameters []string     `json:"parameters"`
+	Motions    []ctrlMotion `json:"motions"`
+}
+
+// ctrlMotion is a blend tree animation JSON description
+type ctrlMotion struct {
+	Clip      string     `json:"clip"`
+	Threshold float32    `json:"threshold"`
+	Position  [2]float32 `json:"position"`
+}
+
+// ctrlTransition is a controller transition JSON description
+type ctrlTransition struct {
+	From       string          `json:"from"`
+	To         string          `json:"to"`
+	Duration   float32         `json:"duration"`
+	ExitTime   *float32        `json:"exitTime"`
+	Conditions []ctrlCondition `json:"conditions"`
+}
+
+// ctrlCondition is a transition condition JSON description
+type ctrlCondition struct {
+	Parameter string  `json:"parameter"`
+	Op        string  `json:"op"`
+	Value     float32 `json:"value"`
+}
+
+// condOps maps the JSON condition operators to the condition operators
+var condOps = map[string]CondOp{
+	">":     CondGreater,
+	"<":     CondLess,
+	"==":    CondEquals,
+	"!=":    CondNotEquals,
+	"true":  CondTrue,
+	"false": CondFalse,
+	"":      CondTrue,
+}
+
+// LoadController creates and returns a pointer to a new controller for the specified mixer
+// from the specified JSON file. See ParseController for the format of the file.
+func LoadController(filename string, mixer *Mixer) (*Controller, error) {
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	c, err := ParseController(data, mixer)
+	if err != nil {
+		return nil, fmt.Errorf("controller file:%s: %v", filename, err)
+	}
+	return c, nil
+}
+
+// ParseController creates and returns a pointer to a new controller for the specified mixer
+// from the specified JSON description, whose animations must then be added by name with
+// AddClips, for example from the animations of a glTF file:
+//
+//	{
+//	  "layer": 0,
+//	  "initial": "idle",
+//	  "parameters": [
+//	    {"name": "speed", "type": "float", "value": 0},
+//	    {"name": "grounded", "type": "bool", "value": true},
+//	    {"name": "jump", "type": "trigger"}
+//	  ],
+//	  "states": [
+//	    {"name": "idle", "clip": "Idle"},
+//	    {"name": "move", "blend": {"parameters": ["speed"], "motions": [
+//	      {"clip": "Walk", "threshold": 1}, {"clip": "Run", "threshold": 4}]}},
+//	    {"name": "strafe", "blend": {"parameters": ["x", "y"], "motions": [
+//	      {"clip": "Left", "position": [-1, 0]}, {"clip": "Forward", "position": [0, 1]}]}},
+//	    {"name": "jump", "clip": "Jump", "loop": false, "speed": 1.2}
+//	  ],
+//	  "transitions": [
+//	    {"from": "idle", "to": "move", "duration": 0.25,
+//	      "conditions": [{"parameter": "speed", "op": ">", "value": 0.1}]},
+//	    {"from": "", "to": "jump", "duration": 0.1, "conditions": [{"parameter": "jump"}]},
+//	    {"from": "jump", "to": "idle", "duration": 0.2, "exitTime": 0.9}
+//	  ]
+//	}
+//
+// The parameter types are "float", "bool" and "trigger". Blend trees with one parameter use the
+// thresholds of the motions and blend trees with two parameters use their positions.
+// Transitions from an empty state are from any state. The condition operators are ">", "<",
+// "==", "!=", "true" and "false", which is the default for boolean and trigger parameters.
+func ParseController(data []byte, mixer *Mixer) (*Controller, error) {
+
+	var f ctrlFile
+	err := json.Unmarshal(data, &f)
+	if err != nil {
+		return nil, err
+	}
+
+	c := NewController(mixer)
+	c.SetLayer(f.Layer)
+	for _, p := range f.Parameters {
+		switch p.Type {
+		case "float":
+			v, _ := p.Value.(float64)
+			c.AddFloat(p.Name, float32(v))
+		case "bool":
+			v, _ := p.Value.(bool)
+			c.AddBool(p.Name, v)
+		case "trigger":
+			c.AddTrigger(p.Name)
+		default:
+			return nil, fmt.Errorf("parameter %q: invalid type %q", p.Name, p.Type)
+		}
+	}
+
+	for _, sd := range f.States {
+		var s *State
+		if sd.Blend != nil {
+			var bt *BlendTree
+			switch len(sd.Blend.Parameters) {
+			case 1:
+				bt = NewBlendTree1D(sd.Blend.Parameters[0])
+				for _, m := range sd.Blend.Motions {
+					bt.AddMotion(m.Clip, m.Threshold)
+				}
+			case 2:
+				bt = NewBlendTree2D(sd.Blend.Parameters[0], sd.Blend.Parameters[1])
+				for _, m := range sd.Blend.Motions {
+					bt.AddMotion2D(m.Clip, m.Position[0], m.Position[1])
+				}
+			default:
+				return nil, fmt.Errorf("state %q: blend trees must have 1 or 2 parameters", sd.Name)
+			}
+			s = c.AddBlendState(sd.Name, bt)
+		} else {
+			s = c.AddState(sd.Name, sd.Clip)
+		}
+		if sd.Speed != nil {
+			s.SetSpeed(*sd.Speed)
+		}
+		if sd.Loop != nil {
+			s.SetLoop(*sd.Loop)
+		}
+	}
+
+	for _, td := range f.Transitions {
+		if td.From != "" && c.State(td.From) == nil {
+			return nil, fmt.Errorf("transition from invalid state %q", td.From)
+		}
+		if c.State(td.To) == nil {
+			return nil, fmt.Errorf("transition to invalid state %q", td.To)
+		}
+		t := c.AddTransition(td.From, td.To, td.Duration)
+		if td.ExitTime != nil {
+			t.SetExitTime(*td.ExitTime)
+		}
+		for _, cd := range td.Conditions {
+			op, ok := condOps[cd.Op]
+			if !ok {
+				return nil, fmt.Errorf("transition to %q: invalid condition operator %q", td.To, cd.Op)
+			}
+			t.AddCondition(cd.Parameter, op, cd.Value)
+		}
+	}
+
+	if f.Initial != "" {
+		if c.State(f.Initial) == nil {
+			return nil, fmt.Errorf("invalid initial state %q", f.Initial)
+		}
+		c.SetState(f.Initial)
+	}
+	return c, nil
+}
@@ -0,0 +1,739 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package animation
+
+import (
+	"github.com/wangzun/gogame/engine/core"
+	"github.com/wangzun/gogame/engine/geometry"
+	"github.com/wangzun/gogame/engine/math32"
+)
+
+// Mixer plays several animations on the same objects at the same time. Each animation is
+// played by an action of the mixer with its own time, weight, time scale and blend mode.
+// Every update, the values of the channels of all the playing actions are blended per node
+// in the order of the layers of the actions, starting from the pose of the nodes when they
+// were first animated by the mixer, and the position, rotation and scale of each node are
+// then set once. The morph target weights are blended the same way.
+// The animations played by a mixer must not be updated by themselves.
+// The motion of a root bone can be extracted from the animations, see SetRootBone.
+type Mixer struct {
+	actions  []*Action                               // Actions in order of creation
+	layers   []*Layer                                // Layers in blending order
+	nodes    []*mixerNode                            // States of the animated nodes
+	nodeMap  map[*core.Node]*mixerNode               // States of the animated nodes by node
+	morphs   []*mixerMorph                           // States of the animated morph geometries
+	morphMap map[*geometry.MorphGeometry]*mixerMorph // States of the animated morph geometries by geometry
+	root     rootMotion                              // Root motion state
+}
+
+// BlendMode specifies how an action is combined with the pose of the lower layers and actions.
+type BlendMode int
+
+// The blend modes.
+const (
+	BlendOverride BlendMode = iota // Moves the pose towards the values of the action in proportion to its weight
+	BlendAdditive                  // Adds the difference between the values of the action and its first keyframe
+)
+
+// Action is an instance of an animation played by a mixer.
+type Action struct {
+	mixer        *Mixer     // Mixer which owns the action
+	anim         *Animation // Animation played
+	time         float32    // Current animation time
+	timeScale    float32    // Time scale multiplied by the speed of the animation
+	weight       float32    // Weight without fading
+	blend        BlendMode  // Blend mode
+	layer        int        // Index of the mixer layer
+	loop         bool       // Whether the action loops
+	playing      bool       // Whether the action is playing
+	paused       bool       // Whether the action time is paused
+	started      bool       // Whether the start event was dispatched
+	fade         float32    // Current fade factor
+	fadeFrom     float32    // Fade factor at the start of the fade
+	fadeTo       float32    // Fade factor at the end of the fade
+	fadeTime     float32    // Elapsed fade time
+	fadeDuration float32    // Duration of the fade or zero when not fading
+	rootTime     float32    // Animation time of the last root motion update
+	rootValid    bool       // Whether the root motion time is valid
+}
+
+// Layer is a layer of a mixer. The actions of a layer are blended over the pose of the lower
+// layers scaled by the weight of the layer and, when the layer has a bone mask, by the weight
+// of each node in the mask, so for example a layer whose mask has the upper body bones can
+// play an animation on them while a lower layer plays another animation on the legs.
+type Layer struct {
+	weight float32                // Weight of the layer
+	mask   map[*core.Node]float32 // Weight of each node or nil for all the nodes
+}
+
+// mixerNode is the blending state of an animated node.
+type mixerNode struct {
+	node      *core.Node
+	restPos   math32.Vector3    // Position before being animated
+	restRot   math32.Quaternion // Rotation before being animated
+	restScale math32.Vector3    // Scale before being animated
+	pos       math32.Vector3    // Blended position
+	rot       math32.Quaternion // Blended rotation
+	scale     math32.Vector3    // Blended scale
+	posSum    math32.Vector3    // Weighted sum of the override positions of the layer
+	rotSum    math32.Quaternion // Weighted sum of the override rotations of the layer
+	scaleSum  math32.Vector3    // Weighted sum of the override scales of the layer
+	posW      float32           // Sum of the weights of the override positions of the layer
+	rotW      float32           // Sum of the weights of the override rotations of the layer
+	scaleW    float32           // Sum of the weights of the override scales of the layer
+	addPos    math32.Vector3    // Additive translation of the layer
+	addRot    math32.Quaternion // Additive rotation of the layer
+	addScale  math32.Vector3    // Additive scale factors of the layer
+}
+
+// mixerMorph is the blending state of an animated morph geometry.
+type mixerMorph struct {
+	mg      *geometry.MorphGeometry
+	rest    []float32 // Weights before being animated
+	weights []float32 // Blended weights
+	sum     []float32 // Weighted sum of the override weights of the layer
+	total   float32   // Sum of the action weights of the override weights of the layer
+	add     []float32 // Additive weights of the layer
+	value   []float32 // Sampled weights
+	ref     []float32 // Sampled reference weights of additive actions
+}
+
+// NewMixer creates and returns a pointer to a new mixer with one layer without bone mask.
+func NewMixer() *Mixer {
+
+	m := new(Mixer)
+	m.nodeMap = make(map[*core.Node]*mixerNode)
+	m.morphMap = make(map[*geometry.MorphGeometry]*mixerMorph)
+	m.root.yaw = true
+	m.AddLayer()
+	return m
+}
+
+// Action returns the action of the mixer which plays the specified animation, creating it if
+// necessary. A new action is stopped, has weight and time scale 1, override blend mode, the
+// loop setting of the animation and is in the first layer. The current pose of the objects
+// animated by the animation, if not already animated by the mixer, is used as their rest pose.
+func (m *Mixer) Action(anim *Animation) *Action {
+
+	for _, a := range m.actions {
+		if a.anim == anim {
+			return a
+		}
+	}
+
+	a := new(Action)
+	a.mixer = m
+	a.anim = anim
+	a.time = anim.start
+	a.timeScale = 1
+	a.weight = 1
+	a.fade = 1
+	a.loop = anim.loop
+	m.actions = append(m.actions, a)
+
+	// Saves the rest pose of the new animated objects
+	for _, ch := range anim.channels {
+		switch ch := ch.(type) {
+		case *PositionChannel:
+			m.bindNode(ch.target)
+		case *RotationChannel:
+			m.bindNode(ch.target)
+		case *ScaleChannel:
+			m.bindNode(ch.target)
+		case *MorphChannel:
+			if m.morphMap[ch.target] == nil {
+				mm := new(mixerMorph)
+				mm.mg = ch.target
+				mm.rest = append([]float32(nil), ch.target.Weights()...)
+				n := len(mm.rest)
+				mm.weights = make([]float32, n)
+				mm.sum = make([]float32, n)
+				mm.add = make([]float32, n)
+				mm.value = make([]float32, n)
+				mm.ref = make([]float32, n)
+				m.morphs = append(m.morphs, mm)
+				m.morphMap[ch.target] = mm
+			}
+		}
+	}
+	return a
+}
+
+// bindNode saves the rest pose of the specified node if not already animated by the mixer.
+func (m *Mixer) bindNode(inode core.INode) {
+
+	node := inode.GetNode()
+	if m.nodeMap[node] != nil {
+		return
+	}
+	ns := new(mixerNode)
+	ns.node = node
+	ns.restPos = node.Position()
+	ns.restRot = node.Quaternion()
+	ns.restScale = node.Scale()
+	m.nodes = append(m.nodes, ns)
+	m.nodeMap[node] = ns
+}
+
+// Actions returns the actions of the mixer.
+func (m *Mixer) Actions() []*Action {
+
+	return m.actions
+}
+
+// RemoveAction removes the specified action from the mixer.
+// The objects it animated keep being set by the mixer.
+func (m *Mixer) RemoveAction(a *Action) {
+
+	for i, ma := range m.actions {
+		if ma == a {
+			copy(m.actions[i:], m.actions[i+1:])
+			m.actions[len(m.actions)-1] = nil
+			m.actions = m.actions[:len(m.actions)-1]
+			return
+		}
+	}
+}
+
+// StopAll stops all the actions of the mixer, so the animated objects return to their rest pose.
+func (m *Mixer) StopAll() {
+
+	for _, a := range m.actions {
+		a.Stop()
+	}
+}
+
+// AddLayer adds a layer with weight 1 and without bone mask above
+// the existing layers of the mixer and returns its index.
+func (m *Mixer) AddLayer() int {
+
+	m.layers = append(m.layers, &Layer{weight: 1})
+	return len(m.layers) - 1
+}
+
+// Layer returns the layer of the mixer with the specified index.
+func (m *Mixer) Layer(idx int) *Layer {
+
+	return m.layers[idx]
+}
+
+// LayerCount returns the number of layers of the mixer.
+func (m *Mixer) LayerCount() int {
+
+	return len(m.layers)
+}
+
+// Update advances the playing actions by the specified time in seconds,
+// blends their values and sets the animated objects.
+func (m *Mixer) Update(delta float32) {
+
+	for _, a := range m.actions {
+		a.advance(delta)
+	}
+	if m.root.bone != nil {
+		m.updateRootMotion()
+	}
+
+	// Starts from the rest pose
+	for _, ns := range m.nodes {
+		ns.pos = ns.restPos
+		ns.rot = ns.restRot
+		ns.scale = ns.restScale
+	}
+	for _, mm := range m.morphs {
+		copy(mm.weights, mm.rest)
+	}
+
+	// Blends the actions of each layer over the lower layers
+	for li, layer := range m.layers {
+		for _, ns := range m.nodes {
+			ns.clear()
+		}
+		for _, mm := range m.morphs {
+			mm.clear()
+		}
+		active := false
+		for _, a := range m.actions {
+			w := a.EffectiveWeight()
+			if a.layer != li || w <= 0 {
+				continue
+			}
+			a.accumulate(w)
+			active = true
+		}
+		if !active || layer.weight <= 0 {
+			continue
+		}
+		for _, ns := range m.nodes {
+			ns.apply(layer.weight * layer.BoneWeight(ns.node))
+		}
+		for _, mm := range m.morphs {
+			mm.apply(layer.weight)
+		}
+	}
+
+	// Sets the blended values once without the root motion
+	if m.root.bone != nil {
+		m.stripRootMotion()
+	}
+	for _, ns := range m.nodes {
+		ns.node.SetPositionVec(&ns.pos)
+		ns.node.SetQuaternionQuat(&ns.rot)
+		ns.node.SetScaleVec(&ns.scale)
+	}
+	for _, mm := range m.morphs {
+		mm.mg.SetWeights(mm.weights)
+	}
+}
+
+// clear clears the accumulated values of the layer.
+func (ns *mixerNode) clear() {
+
+	ns.posSum.Zero()
+	ns.rotSum.Set(0, 0, 0, 0)
+	ns.scaleSum.Zero()
+	ns.posW = 0
+	ns.rotW = 0
+	ns.scaleW = 0
+	ns.addPos.Zero()
+	ns.addRot.SetIdentity()
+	ns.addScale.Set(1, 1, 1)
+}
+
+// apply blends the accumulated values of the layer over the current pose with the specified factor.
+func (ns *mixerNode) apply(f float32) {
+
+	if f <= 0 {
+		return
+	}
+
+	// The override values replace the pose in proportion to the sum of their weights
+	if ns.posW > 0 {
+		ns.posSum.DivideScalar(ns.posW)
+		ns.pos.Lerp(&ns.posSum, f*math32.Min(ns.posW, 1))
+	}
+	if ns.rotW > 0 && ns.rotSum.Length() > 0 {
+		ns.rotSum.Normalize()
+		ns.rot.Slerp(&ns.rotSum, f*math32.Min(ns.rotW, 1))
+	}
+	if ns.scaleW > 0 {
+		ns.scaleSum.DivideScalar(ns.scaleW)
+		ns.scale.Lerp(&ns.scaleSum, f*math32.Min(ns.scaleW, 1))
+	}
+
+	// The additive values are added to the pose
+	ns.addPos.MultiplyScalar(f)
+	ns.pos.Add(&ns.addPos)
+	var add math32.Quaternion
+	add.SetIdentity()
+	add.Slerp(&ns.addRot, f)
+	ns.rot.MultiplyQuaternions(&add, &ns.rot)
+	ns.scale.X *= 1 + (ns.addScale.X-1)*f
+	ns.scale.Y *= 1 + (ns.addScale.Y-1)*f
+	ns.scale.Z *= 1 + (ns.addScale.Z-1)*f
+}
+
+// clear clears the accumulated weights of the layer.
+func (mm *mixerMorph) clear() {
+
+	for i := range mm.sum {
+		mm.sum[i] = 0
+		mm.add[i] = 0
+	}
+	mm.total = 0
+}
+
+// apply blends the accumulated weights of the layer over the current weights with the specified factor.
+func (mm *mixerMorph) apply(f float32) {
+
+	k := f * math32.Min(mm.total, 1)
+	for i := range mm.weights {
+		if mm.total > 0 {
+			mm.weights[i] += (mm.sum[i]/mm.total - mm.weights[i]) * k
+		}
+		mm.weights[i] += mm.add[i] * f
+	}
+}
+
+// Animation returns the animation played by the action.
+func (a *Action) Animation() *Animation {
+
+	return a.anim
+}
+
+// Play starts or resumes playing the action from its current time, or from the start if it
+// finished without looping. The animation dispatches OnStart at the next update when the
+// action starts from the start.
+func (a *Action) Play() {
+
+	if a.Finished() {
+		a.time = a.anim.start
+		a.started = false
+		a.rootValid = false
+	}
+	if !a.playing || !a.rootValid {
+		a.rootTime = a.time
+		a.rootValid = true
+	}
+	a.playing = true
+	a.paused = false
+}
+
+// Stop stops the action, cancels its fading and resets its time to the start of the animation.
+func (a *Action) Stop() {
+
+	a.playing = false
+	a.paused = false
+	a.started = false
+	a.time = a.anim.start
+	a.fade = 1
+	a.fadeDuration = 0
+}
+
+// Playing returns whether the action is playing, even if paused.
+func (a *Action) Playing() bool {
+
+	return a.playing
+}
+
+// Finished returns whether the action reached the end of the animation without looping.
+// A finished action keeps the values of the last keyframes while playing.
+func (a *Action) Finished() bool {
+
+	if a.loop {
+		return false
+	}
+	if a.timeScale*a.anim.speed < 0 {
+		return a.time <= a.anim.minTime
+	}
+	return a.time >= a.anim.maxTime
+}
+
+// SetPaused sets whether the time of the action is paused. A paused action keeps blending
+// its values at the current time and fading.
+func (a *Action) SetPaused(state bool) {
+
+	a.paused = state
+}
+
+// Paused returns whether the time of the action is paused.
+func (a *Action) Paused() bool {
+
+	return a.paused
+}
+
+// SetTime sets the current animation time of the action.
+// The jump to the new time is not extracted as root motion.
+func (a *Action) SetTime(time float32) {
+
+	a.time = time
+	a.rootValid = false
+}
+
+// Time returns the current animation time of the action.
+func (a *Action) Time() float32 {
+
+	return a.time
+}
+
+// SetTimeScale sets the time scale of the action, which is multiplied by the speed of the
+// animation (default = 1). Negative time scales play the animation backwards.
+func (a *Action) SetTimeScale(scale float32) {
+
+	a.timeScale = scale
+}
+
+// TimeScale returns the time scale of the action.
+func (a *Action) TimeScale() float32 {
+
+	return a.timeScale
+}
+
+// SetWeight sets the weight of the action (default = 1).
+func (a *Action) SetWeight(weight float32) {
+
+	a.weight = weight
+}
+
+// Weight returns the weight of the action without fading.
+func (a *Action) Weight() float32 {
+
+	return a.weight
+}
+
+// EffectiveWeight returns the weight of the action multiplied by
+// its current fade factor or zero if it is not playing.
+func (a *Action) EffectiveWeight() float32 {
+
+	if !a.playing {
+		return 0
+	}
+	return a.weight * a.fade
+}
+
+// SetLoop sets whether the action loops.
+func (a *Action) SetLoop(state bool) {
+
+	a.loop = state
+}
+
+// Loop returns whether the action loops.
+func (a *Action) Loop() bool {
+
+	return a.loop
+}
+
+// SetBlendMode sets the blend mode of the action (default = BlendOverride).
+func (a *Action) SetBlendMode(mode BlendMode) {
+
+	a.blend = mode
+}
+
+// BlendMode returns the blend mode of the action.
+func (a *Action) BlendMode() BlendMode {
+
+	return a.blend
+}
+
+// SetLayer sets the index of the mixer layer of the action (default = 0).
+func (a *Action) SetLayer(idx int) {
+
+	a.layer = idx
+}
+
+// Layer returns the index of the mixer layer of the action.
+func (a *Action) Layer() int {
+
+	return a.layer
+}
+
+// FadeIn starts playing the action if not playing and fades its weight
+// in from its current fade factor over the specified duration in seconds.
+func (a *Action) FadeIn(duration float32) {
+
+	if !a.playing {
+		a.fade = 0
+	}
+	a.Play()
+	a.startFade(1, duration)
+}
+
+// FadeOut fades the weight of the action out from its current fade factor over
+// the specified duration in seconds and stops the action at the end of the fade.
+func (a *Action) FadeOut(duration float32) {
+
+	if !a.playing {
+		return
+	}
+	a.startFade(0, duration)
+}
+
+// CrossFadeTo fades the action out and the specified action in over
+// the specified duration in seconds, starting it if not playing.
+func (a *Action) CrossFadeTo(other *Action, duration float32) {
+
+	a.FadeOut(duration)
+	other.FadeIn(duration)
+}
+
+// Fading returns whether the weight of the action is fading.
+func (a *Action) Fading() bool {
+
+	return a.fadeDuration > 0
+}
+
+// startFade starts fading the weight to the specified fade factor over the specified duration.
+func (a *Action) startFade(to, duration float32) {
+
+	a.fadeFrom = a.fade
+	a.fadeTo = to
+	a.fadeTime = 0
+	a.fadeDuration = duration
+	if duration <= 0 {
+		a.endFade()
+	}
+}
+
+// endFade ends the fade, stopping the action if faded out.
+func (a *Action) endFade() {
+
+	a.fade = a.fadeTo
+	a.fadeDuration = 0
+	if a.fade <= 0 {
+		a.Stop()
+	}
+}
+
+// advance advances the fade and the time of the action by the specified time in seconds.
+func (a *Action) advance(delta float32) {
+
+	if !a.playing {
+		return
+	}
+
+	// The fading is not affected by the time scale
+	if a.fadeDuration > 0 {
+		a.fadeTime += delta
+		if a.fadeTime >= a.fadeDuration {
+			a.endFade()
+			if !a.playing {
+				return
+			}
+		} else {
+			a.fade = a.fadeFrom + (a.fadeTo-a.fadeFrom)*a.fadeTime/a.fadeDuration
+		}
+	}
+	if a.paused {
+		return
+	}
+
+	// Advances the time dispatching the animation events
+	ev := Event{Animation: a.anim, Action: a, Weight: a.EffectiveWeight()}
+	inclusive := !a.started
+	if !a.started {
+		a.started = true
+		a.anim.dispatch(OnStart, ev, a.time)
+	}
+	a.time, _ = a.anim.advance(ev, a.time, delta*a.timeScale*a.anim.speed, a.loop, inclusive)
+}
+
+// accumulate adds the values of the action at its current time with the specified
+// weight to the accumulated values of the animated objects.
+func (a *Action) accumulate(w float32) {
+
+	m := a.mixer
+	additive := a.blend == BlendAdditive
+	for _, ch := range a.anim.channels {
+		switch ch := ch.(type) {
+		case *PositionChannel:
+			ns := m.nodeMap[ch.target.GetNode()]
+			var v math32.Vector3
+			ch.Value(a.time, &v)
+			if additive {
+				var ref math32.Vector3
+				ch.Value(ch.keyframes[0], &ref)
+				v.Sub(&ref)
+				ns.addPos.Add(v.MultiplyScalar(w))
+			} else {
+				ns.posSum.Add(v.MultiplyScalar(w))
+				ns.posW += w
+			}
+		case *RotationChannel:
+			ns := m.nodeMap[ch.target.GetNode()]
+			var q math32.Quaternion
+			ch.Value(a.time, &q)
+			if additive {
+				// Rotation from the reference to the current rotation
+				var ref, add math32.Quaternion
+				ch.Value(ch.keyframes[0], &ref)
+				q.Multiply(ref.Conjugate())
+				add.SetIdentity()
+				add.Slerp(&q, w)
+				ns.addRot.MultiplyQuaternions(&add, &ns.addRot)
+			} else {
+				// Sums the rotations in the hemisphere of the current pose
+				sw := w
+				if q.Dot(&ns.rot) < 0 {
+					sw = -w
+				}
+				ns.rotSum.Set(ns.rotSum.X+q.X*sw, ns.rotSum.Y+q.Y*sw, ns.rotSum.Z+q.Z*sw, ns.rotSum.W+q.W*sw)
+				ns.rotW += w
+			}
+		case *ScaleChannel:
+			ns := m.nodeMap[ch.target.GetNode()]
+			var v math32.Vector3
+			ch.Value(a.time, &v)
+			if additive {
+				var ref math32.Vector3
+				ch.Value(ch.keyframes[0], &ref)
+				ns.addScale.X *= 1 + (scaleRatio(v.X, ref.X)-1)*w
+				ns.addScale.Y *= 1 + (scaleRatio(v.Y, ref.Y)-1)*w
+				ns.addScale.Z *= 1 + (scaleRatio(v.Z, ref.Z)-1)*w
+			} else {
+				ns.scaleSum.Add(v.MultiplyScalar(w))
+				ns.scaleW += w
+			}
+		case *MorphChannel:
+			mm := m.morphMap[ch.target]
+			ch.Value(a.time, mm.value)
+			if additive {
+				ch.Value(ch.keyframes[0], mm.ref)
+				for i := range mm.add {
+					mm.add[i] += (mm.value[i] - mm.ref[i]) * w
+				}
+			} else {
+				for i := range mm.sum {
+					mm.sum[i] += mm.value[i] * w
+				}
+				mm.total += w
+			}
+		}
+	}
+}
+
+// scaleRatio returns the ratio between the specified scale and reference scale or 1 if the reference is zero.
+func scaleRatio(scale, ref float32) float32 {
+
+	if ref == 0 {
+		return 1
+	}
+	return scale / ref
+}
+
+// SetWeight sets the weight of the layer (default = 1).
+func (l *Layer) SetWeight(weight float32) {
+
+	l.weight = weight
+}
+
+// Weight returns the weight of the layer.
+func (l *Layer) Weight() float32 {
+
+	return l.weight
+}
+
+// AddBone adds the specified node to the bone mask of the layer with the specified weight
+// from 0 to 1 and, if recursive is true, all its descendants. A layer without bone mask
+// affects all the nodes, while a layer with a bone mask only affects the nodes in the mask.
+func (l *Layer) AddBone(node core.INode, weight float32, recursive bool) {
+
+	if l.mask == nil {
+		l.mask = make(map[*core.Node]float32)
+	}
+	l.mask[node.GetNode()] = weight
+	if recursive {
+		for _, child := range node.GetNode().Children() {
+			l.AddBone(child, weight, true)
+		}
+	}
+}
+
+// RemoveBone removes the specified node and, if recursive is true,
+// all its descendants from the bone mask of the layer.
+func (l *Layer) RemoveBone(node core.INode, recursive bool) {
+
+	delete(l.mask, node.GetNode())
+	if recursive {
+		for _, child := range node.GetNode().Children() {
+			l.RemoveBone(child, true)
+		}
+	}
+}
+
+// ClearMask removes the bone mask of the layer, so it affects all the nodes.
+func (l *Layer) ClearMask() {
+
+	l.mask = nil
+}
+
+// BoneWeight returns the weight of the specified node in the bone mask of the layer,
+// which is 1 for all the nodes when the layer has no bone mask.
+func (l *Layer) BoneWeight(node core.INode) float32 {
+
+	if l.mask == nil {
+		return 1
+	}
+	return l.mask[node.GetNode()]
+}
@@ -0,0 +1,554 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package animation
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/wangzun/gogame/engine/core"
+	"github.com/wangzun/gogame/engine/graphic"
+	"github.com/wangzun/gogame/engine/math32"
+)
+
+// Retargeter converts animations of the bones of a source skeleton into animations of the
+// bones of a target skeleton with a different rig, such as animation packs authored for
+// another character. The bones are mapped by name or by a table and each target bone takes
+// the rotation of its source bone relative to the rest pose, in world space, so differences
+// between the rest poses and the bone axes of the rigs are compensated. The translation of
+// the root bone, usually the hips, is scaled by the proportions of the skeletons.
+// The other translations and the scales of the source animations are not retargeted.
+type Retargeter struct {
+	source     retargetRig    // Source skeleton
+	target     retargetRig    // Target skeleton
+	pairs      []retargetPair // Mapped bones
+	root       *core.Node     // Source root bone or nil to use the topmost mapped bone
+	scale      float32        // Translation scale or 0 to compute it from the proportions
+	sampleRate float32        // Samples per second or 0 to sample at the keyframes
+}
+
+// retargetPair is a source bone mapped to a target bone.
+type retargetPair struct {
+	src int // Index of the source bone
+	dst int // Index of the target bone
+}
+
+// retargetRig contains the rest pose of a skeleton.
+type retargetRig struct {
+	bones     []*core.Node        // Bones of the skeleton
+	index     map[*core.Node]int  // Index of each bone
+	parent    []int               // Index of the parent bone of each bone or -1
+	base      []math32.Quaternion // World rotation of the parent of each bone without parent bone
+	baseMat   []math32.Matrix4    // World matrix of the parent of each bone
+	restPos   []math32.Vector3    // Rest local position of each bone
+	restRot   []math32.Quaternion // Rest local rotation of each bone
+	restWorld []math32.Quaternion // Rest world rotation of each bone
+	world     []math32.Quaternion // Sampled world rotation of each bone
+	done      []bool              // Whether the world rotation of each bone was sampled
+}
+
+// NewRetargeter creates and returns a pointer to a new retargeter from the specified source
+// skeleton to the specified target skeleton, whose current poses are used as their rest poses,
+// so they should be in their rest pose, such as just after being loaded. No bones are mapped.
+func NewRetargeter(source, target *graphic.Skeleton) *Retargeter {
+
+	r := new(Retargeter)
+	r.source.init(source)
+	r.target.init(target)
+	return r
+}
+
+// MapByName maps the bones of the target skeleton to the bones of the source skeleton
+// with the same name, ignoring the case, any namespace prefix ending with a colon, such as
+// "mixamorig:", and any characters other than letters and digits, and returns the number
+// of mapped bones.
+func (r *Retargeter) MapByName() int {
+
+	names := make(map[string]int)
+	for i, b := range r.source.bones {
+		names[normalizeBoneName(b.Name())] = i
+	}
+	count := 0
+	for i, b := range r.target.bones {
+		if src, ok := names[normalizeBoneName(b.Name())]; ok {
+			r.mapBones(src, i)
+			count++
+		}
+	}
+	return count
+}
+
+// Map maps the target bone with the specified name to the source bone with the specified name.
+func (r *Retargeter) Map(sourceBone, targetBone string) error {
+
+	src := r.source.find(sourceBone)
+	if src < 0 {
+		return fmt.Errorf("source bone %q not found", sourceBone)
+	}
+	dst := r.target.find(targetBone)
+	if dst < 0 {
+		return fmt.Errorf("target bone %q not found", targetBone)
+	}
+	r.mapBones(src, dst)
+	return nil
+}
+
+// SetMapping maps the bones from the specified table of source bone names to target bone names.
+func (r *Retargeter) SetMapping(table map[string]string) error {
+
+	for src, dst := range table {
+		err := r.Map(src, dst)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ClearMapping removes the mapping of all the bones.
+func (r *Retargeter) ClearMapping() {
+
+	r.pairs = nil
+}
+
+// Mapping returns the names of the source bones mapped to the names of the target bones.
+func (r *Retargeter) Mapping() map[string]string {
+
+	table := make(map[string]string)
+	for _, p := range r.pairs {
+		table[r.source.bones[p.src].Name()] = r.target.bones[p.dst].Name()
+	}
+	return table
+}
+
+// SetRootBone sets the source bone whose translation is retargeted, which is by default
+// the mapped source bone without mapped ancestors, usually the hips.
+func (r *Retargeter) SetRootBone(sourceBone string) error {
+
+	src := r.source.find(sourceBone)
+	if src < 0 {
+		return fmt.Errorf("source bone %q not found", sourceBone)
+	}
+	r.root = r.source.bones[src]
+	return nil
+}
+
+// SetScale sets the scale of the retargeted root translation, or 0 to compute it from the
+// proportions of the skeletons (default), as the ratio of the total length of the mapped
+// target bones to the total length of the mapped source bones.
+func (r *Retargeter) SetScale(scale float32) {
+
+	r.scale = scale
+}
+
+// Scale returns the scale of the retargeted root translation.
+func (r *Retargeter) Scale() float32 {
+
+	if r.scale > 0 {
+		return r.scale
+	}
+
+	// Sums the world lengths of the bones from each mapped bone to its nearest mapped ancestor
+	mapped := make(map[int]int)
+	for _, p := range r.pairs {
+		mapped[p.src] = p.dst
+	}
+	var srcLen, dstLen float32
+	for _, p := range r.pairs {
+		anc := r.source.parent[p.src]
+		for anc >= 0 {
+			if _, ok := mapped[anc]; ok {
+				break
+			}
+			anc = r.source.parent[anc]
+		}
+		if anc < 0 {
+			continue
+		}
+		srcLen += r.source.distance(p.src, anc)
+		dstLen += r.target.distance(p.dst, mapped[anc])
+	}
+	if srcLen == 0 || dstLen == 0 {
+		return 1
+	}
+	return dstLen / srcLen
+}
+
+// SetSampleRate sets the number of samples per second of the retargeted animations,
+// or 0 to sample them at the keyframe times of the source animations (default).
+func (r *Retargeter) SetSampleRate(rate float32) {
+
+	r.sampleRate = rate
+}
+
+// SampleRate returns the number of samples per second of the retargeted animations.
+func (r *Retargeter) SampleRate() float32 {
+
+	return r.sampleRate
+}
+
+// Retarget creates and returns a new animation of the mapped bones of the target skeleton
+// from the specified animation of the bones of the source skeleton, with linear interpolation,
+// the same name, loop setting, speed and markers.
+func (r *Retargeter) Retarget(anim *Animation) *Animation {
+
+	out := NewAnimation()
+	out.SetName(anim.name)
+	out.SetLoop(anim.loop)
+	out.SetSpeed(anim.speed)
+	out.markers = append(out.markers, anim.markers...)
+	if len(r.pairs) == 0 {
+		return out
+	}
+
+	// Finds the channels of the source bones
+	rotChannels := make(map[int]*RotationChannel)
+	posChannels := make(map[int]*PositionChannel)
+	for _, ch := range anim.channels {
+		switch ch := ch.(type) {
+		case *RotationChannel:
+			if idx, ok := r.source.index[ch.target.GetNode()]; ok {
+				rotChannels[idx] = ch
+			}
+		case *PositionChannel:
+			if idx, ok := r.source.index[ch.target.GetNode()]; ok {
+				posChannels[idx] = ch
+			}
+		}
+	}
+	times := r.sampleTimes(anim, rotChannels, posChannels)
+	if len(times) == 0 {
+		return out
+	}
+
+	// Samples the rotations of the target bones
+	srcOf := make(map[int]int)
+	for _, p := range r.pairs {
+		srcOf[p.dst] = p.src
+	}
+	rotValues := make([]math32.ArrayF32, len(r.pairs))
+	for i := range rotValues {
+		rotValues[i] = math32.NewArrayF32(0, 4*len(times))
+	}
+	root := r.rootPair()
+	posValues := math32.NewArrayF32(0, 3*len(times))
+	scale := r.Scale()
+	for _, t := range times {
+		r.source.sample(t, rotChannels)
+		r.sampleTarget(srcOf)
+		for i, p := range r.pairs {
+			local := r.target.local(p.dst)
+			// Keeps consecutive rotations in the same hemisphere for interpolation
+			if n := len(rotValues[i]); n >= 4 {
+				prev := math32.Quaternion{X: rotValues[i][n-4], Y: rotValues[i][n-3], Z: rotValues[i][n-2], W: rotValues[i][n-1]}
+				if prev.Dot(&local) < 0 {
+					local.Set(-local.X, -local.Y, -local.Z, -local.W)
+				}
+			}
+			rotValues[i].Append(local.X, local.Y, local.Z, local.W)
+		}
+		if root >= 0 {
+			pos := r.rootPosition(r.pairs[root], posChannels[r.pairs[root].src], t, scale)
+			posValues.Append(pos.X, pos.Y, pos.Z)
+		}
+	}
+
+	keyframes := math32.NewArrayF32(0, len(times))
+	keyframes.Append(times...)
+	for i, p := range r.pairs {
+		ch := NewRotationChannel(r.target.bones[p.dst])
+		ch.SetBuffers(keyframes, rotValues[i])
+		out.AddChannel(ch)
+	}
+	if root >= 0 && posChannels[r.pairs[root].src] != nil {
+		ch := NewPositionChannel(r.target.bones[r.pairs[root].dst])
+		ch.SetBuffers(keyframes, posValues)
+		out.AddChannel(ch)
+	}
+	return out
+}
+
+// mapBones maps the specified target bone to the specified source bone.
+func (r *Retargeter) mapBones(src, dst int) {
+
+	for i := range r.pairs {
+		if r.pairs[i].dst == dst {
+			r.pairs[i].src = src
+			return
+		}
+	}
+	r.pairs = append(r.pairs, retargetPair{src: src, dst: dst})
+}
+
+// rootPair returns the index of the pair of the root bone or -1 if not mapped.
+func (r *Retargeter) rootPair() int {
+
+	if r.root != nil {
+		for i, p := range r.pairs {
+			if r.source.bones[p.src] == r.root {
+				return i
+			}
+		}
+		return -1
+	}
+	best, bestDepth := -1, 0
+	for i, p := range r.pairs {
+		depth := r.source.depth(p.src)
+		if best < 0 || depth < bestDepth {
+			best, bestDepth = i, depth
+		}
+	}
+	return best
+}
+
+// sampleTimes returns the sorted times at which the specified animation is sampled.
+func (r *Retargeter) sampleTimes(anim *Animation, rotChannels map[int]*RotationChannel, posChannels map[int]*PositionChannel) []float32 {
+
+	if r.sampleRate > 0 {
+		var times []float32
+		n := int(math32.Ceil((anim.maxTime - anim.minTime) * r.sampleRate))
+		for i := 0; i <= n; i++ {
+			times = append(times, math32.Min(anim.minTime+float32(i)/r.sampleRate, anim.maxTime))
+		}
+		return times
+	}
+	set := make(map[float32]bool)
+	for _, ch := range rotChannels {
+		for _, t := range ch.keyframes {
+			set[t] = true
+		}
+	}
+	for _, ch := range posChannels {
+		for _, t := range ch.keyframes {
+			set[t] = true
+		}
+	}
+	times := make([]float32, 0, len(set))
+	for t := range set {
+		times = append(times, t)
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+	return times
+}
+
+// sampleTarget computes the world rotations of the bones of the target skeleton, where each
+// mapped bone takes the world rotation of its source bone relative to the rest pose and the
+// other bones keep their rest local rotations.
+func (r *Retargeter) sampleTarget(srcOf map[int]int) {
+
+	rig := &r.target
+	rig.clear()
+	var eval func(idx int) math32.Quaternion
+	eval = func(idx int) math32.Quaternion {
+		if rig.done[idx] {
+			return rig.world[idx]
+		}
+		q := rig.base[idx]
+		if p := rig.parent[idx]; p >= 0 {
+			q = eval(p)
+		}
+		if src, ok := srcOf[idx]; ok {
+			delta := r.source.restWorld[src]
+			delta.Conjugate()
+			delta.MultiplyQuaternions(&r.source.world[src], &delta)
+			q.MultiplyQuaternions(&delta, &rig.restWorld[idx])
+		} else {
+			q.Multiply(&rig.restRot[idx])
+		}
+		rig.world[idx] = q
+		rig.done[idx] = true
+		return q
+	}
+	for i := range rig.bones {
+		eval(i)
+	}
+}
+
+// rootPosition returns the local position of the target root bone for the specified time.
+func (r *Retargeter) rootPosition(p retargetPair, ch *PositionChannel, t, scale float32) math32.Vector3 {
+
+	pos := r.target.restPos[p.dst]
+	if ch == nil {
+		return pos
+	}
+
+	// Displacement from the rest position converted from the source to the target parent space
+	var disp math32.Vector3
+	ch.Value(t, &disp)
+	disp.Sub(&r.source.restPos[p.src])
+	transformVector(&disp, &r.source.baseMat[p.src])
+	disp.MultiplyScalar(scale)
+	var inv math32.Matrix4
+	if inv.GetInverse(&r.target.baseMat[p.dst]) == nil {
+		transformVector(&disp, &inv)
+	}
+	pos.Add(&disp)
+	return pos
+}
+
+// init saves the rest pose of the specified skeleton.
+func (rig *retargetRig) init(sk *graphic.Skeleton) {
+
+	rig.bones = sk.Bones()
+	n := len(rig.bones)
+	rig.index = make(map[*core.Node]int, n)
+	for i, b := range rig.bones {
+		rig.index[b] = i
+	}
+	rig.parent = make([]int, n)
+	rig.base = make([]math32.Quaternion, n)
+	rig.baseMat = make([]math32.Matrix4, n)
+	rig.restPos = make([]math32.Vector3, n)
+	rig.restRot = make([]math32.Quaternion, n)
+	rig.restWorld = make([]math32.Quaternion, n)
+	rig.world = make([]math32.Quaternion, n)
+	rig.done = make([]bool, n)
+	for i, b := range rig.bones {
+		rig.parent[i] = -1
+		rig.base[i].SetIdentity()
+		rig.baseMat[i].Identity()
+		rig.restPos[i] = b.Position()
+		rig.restRot[i] = b.Quaternion()
+		if parent := b.Parent(); parent != nil {
+			pn := parent.GetNode()
+			if idx, ok := rig.index[pn]; ok {
+				rig.parent[i] = idx
+			} else {
+				rig.base[i] = restWorldQuaternion(pn)
+			}
+			rig.baseMat[i] = restWorldMatrix(pn)
+		}
+	}
+	rig.sample(0, nil)
+	copy(rig.restWorld, rig.world)
+}
+
+// find returns the index of the bone with the specified name or -1 if not found.
+func (rig *retargetRig) find(name string) int {
+
+	for i, b := range rig.bones {
+		if b.Name() == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// depth returns the number of ancestor bones of the specified bone.
+func (rig *retargetRig) depth(idx int) int {
+
+	depth := 0
+	for p := rig.parent[idx]; p >= 0; p = rig.parent[p] {
+		depth++
+	}
+	return depth
+}
+
+// distance returns the rest world distance between the specified bones.
+func (rig *retargetRig) distance(a, b int) float32 {
+
+	var pa, pb math32.Vector3
+	ma := restWorldMatrix(rig.bones[a])
+	mb := restWorldMatrix(rig.bones[b])
+	pa.SetFromMatrixPosition(&ma)
+	pb.SetFromMatrixPosition(&mb)
+	return pa.DistanceTo(&pb)
+}
+
+// local returns the local rotation of the specified bone from the sampled world rotations.
+func (rig *retargetRig) local(idx int) math32.Quaternion {
+
+	q := rig.base[idx]
+	if p := rig.parent[idx]; p >= 0 {
+		q = rig.world[p]
+	}
+	q.Conjugate()
+	q.Multiply(&rig.world[idx])
+	q.Normalize()
+	return q
+}
+
+// clear clears the sampled world rotations.
+func (rig *retargetRig) clear() {
+
+	for i := range rig.done {
+		rig.done[i] = false
+	}
+}
+
+// sample computes the world rotations of the bones at the specified time from the specified
+// rotation channels, using the rest rotations of the bones without channel.
+func (rig *retargetRig) sample(t float32, channels map[int]*RotationChannel) {
+
+	local := make([]math32.Quaternion, len(rig.bones))
+	for i := range rig.bones {
+		if ch := channels[i]; ch != nil {
+			ch.Value(t, &local[i])
+		} else {
+			local[i] = rig.restRot[i]
+		}
+	}
+	rig.clear()
+	var eval func(idx int) math32.Quaternion
+	eval = func(idx int) math32.Quaternion {
+		if rig.done[idx] {
+			return rig.world[idx]
+		}
+		q := rig.base[idx]
+		if p := rig.parent[idx]; p >= 0 {
+			q = eval(p)
+		}
+		q.Multiply(&local[idx])
+		rig.world[idx] = q
+		rig.done[idx] = true
+		return q
+	}
+	for i := range rig.bones {
+		eval(i)
+	}
+}
+
+// restWorldQuaternion returns the world rotation of the specified node from the local rotations of its ancestors.
+func restWorldQuaternion(n *core.Node) math32.Quaternion {
+
+	q := n.Quaternion()
+	if parent := n.Parent(); parent != nil {
+		pq := restWorldQuaternion(parent.GetNode())
+		q.MultiplyQuaternions(&pq, &q)
+	}
+	return q
+}
+
+// restWorldMatrix returns the world matrix of the specified node from the local transforms of its ancestors.
+func restWorldMatrix(n *core.Node) math32.Matrix4 {
+
+	var m math32.Matrix4
+	pos := n.Position()
+	q := n.Quaternion()
+	scale := n.Scale()
+	m.Compose(&pos, &q, &scale)
+	if parent := n.Parent(); parent != nil {
+		pm := restWorldMatrix(parent.GetNode())
+		m.MultiplyMatrices(&pm, &m)
+	}
+	return m
+}
+
+// normalizeBoneName returns the specified bone name in lower case without
+// namespace prefix and without characters other than letters and digits.
+func normalizeBoneName(name string) string {
+
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		name = name[i+1:]
+	}
+	var sb strings.Builder
+	for _, c := range strings.ToLower(name) {
+		if (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') {
+			sb.WriteRune(c)
+		}
+	}
+	return sb.String()
+}
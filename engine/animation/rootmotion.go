@@ -0,0 +1,280 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package animation
+
+import (
+	"github.com/wangzun/gogame/engine/core"
+	"github.com/wangzun/gogame/engine/math32"
+)
+
+// rootMotion is the root motion state of a mixer.
+type rootMotion struct {
+	bone     *core.Node     // Root bone or nil if root motion is disabled
+	vertical bool           // Whether the vertical translation is extracted
+	yaw      bool           // Whether the rotation around the vertical axis is extracted
+	delta    math32.Vector3 // Translation of the last update
+	deltaYaw float32        // Rotation around the vertical axis of the last update
+}
+
+// SetRootBone sets the root bone, usually the hips, whose horizontal translation and rotation
+// around the vertical axis are extracted from the animations played by the mixer as root motion,
+// so they are removed from the pose and the character can be moved by them with ApplyRootMotion
+// or by a character controller using RootMotion, without snapping back when the animations loop.
+// The root motion is in the space of the parent of the root bone, whose Y axis is the vertical.
+// A nil bone disables root motion.
+func (m *Mixer) SetRootBone(bone core.INode) {
+
+	m.root.bone = nil
+	if bone != nil {
+		m.root.bone = bone.GetNode()
+	}
+	m.root.delta.Zero()
+	m.root.deltaYaw = 0
+}
+
+// RootBone returns the root bone of the root motion or nil if root motion is disabled.
+func (m *Mixer) RootBone() *core.Node {
+
+	return m.root.bone
+}
+
+// SetRootMotionVertical sets whether the vertical translation of the root bone is also extracted
+// as root motion, for example for climbing animations. The default is false, which keeps the
+// vertical movement of the hips of walk cycles and jumps in the pose.
+func (m *Mixer) SetRootMotionVertical(state bool) {
+
+	m.root.vertical = state
+}
+
+// RootMotionVertical returns whether the vertical translation of the root bone is extracted.
+func (m *Mixer) RootMotionVertical() bool {
+
+	return m.root.vertical
+}
+
+// SetRootMotionYaw sets whether the rotation of the root bone around the vertical axis is
+// extracted as root motion (default = true). When extracted, the translation is relative to
+// the heading of the rest pose, so turning animations move the character along their curve.
+func (m *Mixer) SetRootMotionYaw(state bool) {
+
+	m.root.yaw = state
+}
+
+// RootMotionYaw returns whether the rotation of the root bone around the vertical axis is extracted.
+func (m *Mixer) RootMotionYaw() bool {
+
+	return m.root.yaw
+}
+
+// RootMotion returns the translation and the rotation in radians around the vertical axis of the
+// root bone extracted in the last update, in the space of the parent of the root bone.
+func (m *Mixer) RootMotion() (math32.Vector3, float32) {
+
+	return m.root.delta, m.root.deltaYaw
+}
+
+// ApplyRootMotion moves the specified node, usually the node which owns the skeleton,
+// by the root motion of the last update: the translation is converted from the space of
+// the parent of the root bone to the space of the parent of the node and the node is then
+// rotated around its Y axis. It must be called after the update of the mixer.
+func (m *Mixer) ApplyRootMotion(inode core.INode) {
+
+	if m.root.bone == nil {
+		return
+	}
+	node := inode.GetNode()
+
+	// Converts the translation to world space and then to the space of the parent of the node
+	delta := m.root.delta
+	if parent := m.root.bone.Parent(); parent != nil {
+		mw := parent.GetNode().MatrixWorld()
+		transformVector(&delta, &mw)
+	}
+	if parent := node.Parent(); parent != nil {
+		var inv math32.Matrix4
+		mw := parent.GetNode().MatrixWorld()
+		if inv.GetInverse(&mw) == nil {
+			transformVector(&delta, &inv)
+		}
+	}
+	pos := node.Position()
+	pos.Add(&delta)
+	node.SetPositionVec(&pos)
+	if m.root.deltaYaw != 0 {
+		node.RotateY(m.root.deltaYaw)
+	}
+}
+
+// updateRootMotion computes the root motion of the actions since the last update,
+// blended by layer with the same weights as the pose.
+func (m *Mixer) updateRootMotion() {
+
+	rm := &m.root
+	rm.delta.Zero()
+	rm.deltaYaw = 0
+	var restYaw float32
+	if ns := m.nodeMap[rm.bone]; ns != nil {
+		restYaw = heading(&ns.restRot)
+	}
+
+	for li, layer := range m.layers {
+		var sum math32.Vector3
+		var sumYaw, total float32
+		for _, a := range m.actions {
+			if a.layer != li {
+				continue
+			}
+			delta, deltaYaw := a.rootMotion(rm, restYaw)
+			w := a.EffectiveWeight()
+			if a.blend != BlendOverride || w <= 0 {
+				continue
+			}
+			sum.Add(delta.MultiplyScalar(w))
+			sumYaw += deltaYaw * w
+			total += w
+		}
+		f := layer.weight * layer.BoneWeight(rm.bone)
+		if total <= 0 || f <= 0 {
+			continue
+		}
+		k := f * math32.Min(total, 1)
+		sum.DivideScalar(total)
+		rm.delta.Lerp(&sum, k)
+		rm.deltaYaw += (sumYaw/total - rm.deltaYaw) * k
+	}
+}
+
+// stripRootMotion removes the extracted root motion from the blended pose of the root bone,
+// keeping the horizontal position and the heading of its rest pose.
+func (m *Mixer) stripRootMotion() {
+
+	ns := m.nodeMap[m.root.bone]
+	if ns == nil {
+		return
+	}
+	ns.pos.X = ns.restPos.X
+	ns.pos.Z = ns.restPos.Z
+	if m.root.vertical {
+		ns.pos.Y = ns.restPos.Y
+	}
+	if m.root.yaw {
+		var q math32.Quaternion
+		q.SetFromAxisAngle(&math32.Vector3{0, 1, 0}, heading(&ns.restRot)-heading(&ns.rot))
+		ns.rot.MultiplyQuaternions(&q, &ns.rot)
+	}
+}
+
+// rootMotion returns the root motion of the action since its time in the last update
+// and saves its current time. The time of a looping action is assumed to have moved by
+// less than half the length of its animation, in whichever direction is shorter, so the
+// root motion also follows actions whose time is set, as by a controller.
+func (a *Action) rootMotion(rm *rootMotion, restYaw float32) (math32.Vector3, float32) {
+
+	var delta math32.Vector3
+	var deltaYaw float32
+	prev := a.rootTime
+	a.rootTime = a.time
+	if !a.playing || !a.rootValid {
+		a.rootValid = a.playing
+		return delta, deltaYaw
+	}
+
+	// Finds the channels of the root bone
+	var pc *PositionChannel
+	var rc *RotationChannel
+	for _, ch := range a.anim.channels {
+		switch ch := ch.(type) {
+		case *PositionChannel:
+			if ch.target.GetNode() == rm.bone {
+				pc = ch
+			}
+		case *RotationChannel:
+			if ch.target.GetNode() == rm.bone {
+				rc = ch
+			}
+		}
+	}
+	if pc == nil && rc == nil {
+		return delta, deltaYaw
+	}
+
+	// Splits the movement at the end of the animation when it loops
+	first := a.anim.minTime
+	last := a.anim.maxTime
+	length := last - first
+	d := a.time - prev
+	segments := [][2]float32{{prev, a.time}}
+	if a.loop && length > 0 {
+		if d < -length/2 {
+			segments = [][2]float32{{prev, last}, {first, a.time}}
+		} else if d > length/2 {
+			segments = [][2]float32{{prev, first}, {last, a.time}}
+		}
+	}
+
+	for _, seg := range segments {
+		var yawFrom, yawTo float32
+		if rc != nil {
+			var q math32.Quaternion
+			rc.Value(seg[0], &q)
+			yawFrom = heading(&q)
+			rc.Value(seg[1], &q)
+			yawTo = heading(&q)
+		}
+		if pc != nil {
+			var from, to math32.Vector3
+			pc.Value(seg[0], &from)
+			pc.Value(seg[1], &to)
+			to.Sub(&from)
+			if !rm.vertical {
+				to.Y = 0
+			}
+			// Makes the translation relative to the heading of the rest pose
+			if rm.yaw && rc != nil {
+				to.ApplyAxisAngle(&math32.Vector3{0, 1, 0}, restYaw-yawFrom+deltaYaw)
+			}
+			delta.Add(&to)
+		}
+		if rm.yaw {
+			deltaYaw += wrapAngle(yawTo - yawFrom)
+		}
+	}
+	return delta, deltaYaw
+}
+
+// heading returns the rotation around the Y axis of the specified rotation,
+// from the direction of its Z axis, or of its X axis when the Z axis is vertical.
+func heading(q *math32.Quaternion) float32 {
+
+	dir := math32.Vector3{0, 0, 1}
+	dir.ApplyQuaternion(q)
+	if dir.X*dir.X+dir.Z*dir.Z > 1e-6 {
+		return math32.Atan2(dir.X, dir.Z)
+	}
+	dir.Set(1, 0, 0)
+	dir.ApplyQuaternion(q)
+	return math32.Atan2(dir.X, dir.Z) - math32.Pi/2
+}
+
+// wrapAngle returns the specified angle in radians wrapped to the range -Pi to Pi.
+func wrapAngle(angle float32) float32 {
+
+	for angle > math32.Pi {
+		angle -= 2 * math32.Pi
+	}
+	for angle < -math32.Pi {
+		angle += 2 * math32.Pi
+	}
+	return angle
+}
+
+// transformVector transforms the specified vector by the specified matrix without its translation.
+func transformVector(v *math32.Vector3, m *math32.Matrix4) {
+
+	x, y, z := v.X, v.Y, v.Z
+	v.X = m[0]*x + m[4]*y + m[8]*z
+	v.Y = m[1]*x + m[5]*y + m[9]*z
+	v.Z = m[2]*x + m[6]*y + m[10]*z
+}
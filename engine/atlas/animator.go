@@ -0,0 +1,69 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package atlas
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/wangzun/gogame/engine/texture"
+)
+
+// Frame returns an animation frame which shows the region for the specified
+// duration, or for the animator display time if zero, on a texture of its page.
+func (r *Region) Frame(duration time.Duration) texture.Frame {
+
+	f := texture.Frame{Duration: duration}
+	f.OffsetX, f.OffsetY = r.Offset()
+	f.RepeatX, f.RepeatY = r.Repeat()
+	return f
+}
+
+// NewAnimator creates and returns a texture animator whose frames are the regions with the
+// specified names, which must be in the same page and not rotated. The animator texture is a
+// new view of the page texture with the size of the first region, so it can be used directly by
+// gui.NewImageFromTex() or added to a sprite material, and should be disposed when no longer used.
+// Clips with the positions of the regions as frame indices can be added to the animator.
+func (a *Atlas) NewAnimator(names ...string) (*texture.Animator, error) {
+
+	regions := make([]*Region, 0, len(names))
+	for _, name := range names {
+		r := a.regions[name]
+		if r == nil {
+			return nil, fmt.Errorf("region %q not found", name)
+		}
+		regions = append(regions, r)
+	}
+	return newAnimator(regions, nil)
+}
+
+// newAnimator creates and returns a texture animator for the specified regions and optional
+// frame durations, playing all its frames from the start.
+func newAnimator(regions []*Region, durations []time.Duration) (*texture.Animator, error) {
+
+	if len(regions) == 0 {
+		return nil, fmt.Errorf("no animation frames")
+	}
+	page := regions[0].Page
+	tex := texture.NewTexture2DView(page.Texture(), regions[0].Width, regions[0].Height)
+	anim := texture.NewFrameAnimator(tex)
+	for i, r := range regions {
+		if r.Page != page {
+			tex.Dispose()
+			return nil, fmt.Errorf("region %q is not in page %s", r.Name, page.Name)
+		}
+		if r.Rotated {
+			tex.Dispose()
+			return nil, fmt.Errorf("region %q is rotated", r.Name)
+		}
+		var duration time.Duration
+		if i < len(durations) {
+			duration = durations[i]
+		}
+		anim.AddFrame(r.Frame(duration))
+	}
+	anim.Restart()
+	return anim, nil
+}
@@ -0,0 +1,81 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package atlas
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/wangzun/gogame/engine/texture"
+)
+
+// aseTag is an Aseprite animation tag
+type aseTag struct {
+	Name      string      `json:"name"`
+	From      int         `json:"from"`
+	To        int         `json:"to"`
+	Direction string      `json:"direction"`
+	Repeat    json.Number `json:"repeat,omitempty"` // Number of repeats (a string in newer versions)
+}
+
+// LoadAseprite loads the sprite sheet described by the specified Aseprite JSON export,
+// in the hash or array format, and its image as a new atlas with a region for each frame.
+// It also returns an animator with the frames, in the order and with the durations of the
+// export even if they have the same names, as with the "{tag}" file name format, and a
+// clip for each animation tag, such as "idle", "run" and "attack", with the tag frames,
+// direction and number of repeats. The tags are exported using the "--list-tags" command
+// line option or the "Tags" meta option of the export dialog.
+// The animator texture is a view of the atlas page with the size of the first frame,
+// which can be added to a sprite material or used by gui.NewImageFromTex(),
+// and the frames should be exported without trimming.
+func LoadAseprite(jsonfile string) (*Atlas, *texture.Animator, error) {
+
+	a := NewAtlas()
+	meta, frames, regions, err := a.loadPage(jsonfile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Creates a clip for each tag
+	clips := make([]*texture.Clip, 0, len(meta.FrameTags))
+	for _, tag := range meta.FrameTags {
+		if tag.From < 0 || tag.To >= len(frames) || tag.From > tag.To {
+			return nil, nil, fmt.Errorf("aseprite file:%s: invalid frames of tag %q", jsonfile, tag.Name)
+		}
+		c := texture.NewClipRange(tag.Name, tag.From, tag.To)
+		switch tag.Direction {
+		case "reverse":
+			c.SetMode(texture.ClipReverse)
+		case "pingpong":
+			c.SetMode(texture.ClipPingPong)
+		case "pingpong_reverse":
+			c.SetMode(texture.ClipPingPongReverse)
+		}
+		if tag.Repeat != "" {
+			repeat, err := tag.Repeat.Int64()
+			if err != nil {
+				return nil, nil, fmt.Errorf("aseprite file:%s: tag %q: %v", jsonfile, tag.Name, err)
+			}
+			c.SetRepeat(int(repeat))
+		}
+		clips = append(clips, c)
+	}
+
+	// Creates the animator with the frames in file order, which may have the same names
+	durations := make([]time.Duration, len(frames))
+	for i, fr := range frames {
+		durations[i] = time.Duration(fr.Duration) * time.Millisecond
+	}
+	anim, err := newAnimator(regions, durations)
+	if err != nil {
+		a.Dispose()
+		return nil, nil, fmt.Errorf("aseprite file:%s: %v", jsonfile, err)
+	}
+	for _, c := range clips {
+		anim.AddClip(c)
+	}
+	return a, anim, nil
+}
@@ -0,0 +1,189 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package atlas
+
+import (
+	"image"
+
+	"github.com/wangzun/gogame/engine/math32"
+	"github.com/wangzun/gogame/engine/texture"
+)
+
+// Atlas is a set of pages containing named image regions
+type Atlas struct {
+	Pages   []*Page            // Atlas pages
+	regions map[string]*Region // Regions by name
+	names   []string           // Region names in insertion order
+}
+
+// Page is a single image of an atlas
+type Page struct {
+	Name   string             // Image file name of the page (used when saving/loading)
+	Image  *image.RGBA        // Page image
+	Width  int                // Page width in pixels
+	Height int                // Page height in pixels
+	tex    *texture.Texture2D // Page texture created on demand
+}
+
+// Region is a named rectangle of an atlas page
+type Region struct {
+	Name         string // Region name
+	Page         *Page  // Page which contains the region
+	X            int    // Position X in pixels in the page image from left to right
+	Y            int    // Position Y in pixels in the page image from top to bottom
+	Width        int    // Region width in pixels (as stored in the page)
+	Height       int    // Region height in pixels (as stored in the page)
+	Rotated      bool   // Region is stored rotated 90 degrees clockwise in the page
+	OffsetX      int    // Horizontal offset of the region inside the original image (trimmed images)
+	OffsetY      int    // Vertical offset of the region inside the original image (trimmed images)
+	SourceWidth  int    // Width of the original image
+	SourceHeight int    // Height of the original image
+}
+
+// NewAtlas creates and returns a pointer to a new empty atlas.
+func NewAtlas() *Atlas {
+
+	a := new(Atlas)
+	a.regions = make(map[string]*Region)
+	return a
+}
+
+// AddPage adds a new page with the specified name and image and returns its pointer.
+func (a *Atlas) AddPage(name string, img *image.RGBA) *Page {
+
+	p := &Page{Name: name, Image: img}
+	if img != nil {
+		p.Width = img.Rect.Dx()
+		p.Height = img.Rect.Dy()
+	}
+	a.Pages = append(a.Pages, p)
+	return p
+}
+
+// AddRegion adds the specified region to the atlas.
+// A previous region with the same name is replaced.
+func (a *Atlas) AddRegion(r *Region) {
+
+	if _, ok := a.regions[r.Name]; !ok {
+		a.names = append(a.names, r.Name)
+	}
+	a.regions[r.Name] = r
+}
+
+// Region returns the region with the specified name or nil if not found.
+func (a *Atlas) Region(name string) *Region {
+
+	return a.regions[name]
+}
+
+// Names returns the names of all the regions of the atlas in insertion order.
+func (a *Atlas) Names() []string {
+
+	return a.names
+}
+
+// Regions returns the regions of the specified page in insertion order.
+func (a *Atlas) Regions(p *Page) []*Region {
+
+	regions := make([]*Region, 0)
+	for _, name := range a.names {
+		r := a.regions[name]
+		if r.Page == p {
+			regions = append(regions, r)
+		}
+	}
+	return regions
+}
+
+// Dispose releases the textures of all the atlas pages.
+// Region textures previously returned keep their pages textures alive until disposed.
+func (a *Atlas) Dispose() {
+
+	for _, p := range a.Pages {
+		if p.tex != nil {
+			p.tex.Dispose()
+			p.tex = nil
+		}
+	}
+}
+
+// Texture returns the texture of the page, creating it from the page image on the first call.
+func (p *Page) Texture() *texture.Texture2D {
+
+	if p.tex == nil {
+		p.tex = texture.NewTexture2DFromRGBA(p.Image)
+	}
+	return p.tex
+}
+
+// SubImage returns the image of the region, sharing the pixels of the page image.
+// Rotated regions are returned as stored in the page.
+func (r *Region) SubImage() *image.RGBA {
+
+	x := r.Page.Image.Rect.Min.X + r.X
+	y := r.Page.Image.Rect.Min.Y + r.Y
+	return r.Page.Image.SubImage(image.Rect(x, y, x+r.Width, y+r.Height)).(*image.RGBA)
+}
+
+// Image returns the upright image of the region, which shares the pixels of the page image
+// unless the region is rotated, in which case it is a rotated copy.
+func (r *Region) Image() *image.RGBA {
+
+	sub := r.SubImage()
+	if !r.Rotated {
+		return sub
+	}
+	// Rotates the region 90 degrees counter clockwise
+	img := image.NewRGBA(image.Rect(0, 0, r.Height, r.Width))
+	for y := 0; y < r.Width; y++ {
+		for x := 0; x < r.Height; x++ {
+			img.SetRGBA(x, y, sub.RGBAAt(sub.Rect.Min.X+r.Width-1-y, sub.Rect.Min.Y+x))
+		}
+	}
+	return img
+}
+
+// Offset returns the normalized position of the region in the page
+// from the top left corner, as used by the textures offset.
+func (r *Region) Offset() (float32, float32) {
+
+	return float32(r.X) / float32(r.Page.Width), float32(r.Y) / float32(r.Page.Height)
+}
+
+// Repeat returns the normalized size of the region in the page, as used by the textures repeat.
+func (r *Region) Repeat() (float32, float32) {
+
+	return float32(r.Width) / float32(r.Page.Width), float32(r.Height) / float32(r.Page.Height)
+}
+
+// UV returns the texture coordinates of the bottom left and top right corners
+// of the region in the page for textures with the default flipped Y coordinate,
+// as used by graphic.SpriteQuad, which rotates them for rotated regions.
+func (r *Region) UV() (uv0, uv1 math32.Vector2) {
+
+	ox, oy := r.Offset()
+	rx, ry := r.Repeat()
+	uv0 = math32.Vector2{X: ox, Y: 1 - (oy + ry)}
+	uv1 = math32.Vector2{X: ox + rx, Y: 1 - oy}
+	return uv0, uv1
+}
+
+// Texture creates and returns a new texture view of the region which
+// shares the page texture. The view size is the region size and its offset and
+// repeat select the region in the page, so it can be used directly by
+// gui.NewImageFromTex() or added to a sprite material.
+// Rotated regions can't be selected by the offset and repeat of a texture,
+// so a new texture with their upright image is returned instead.
+// The returned texture should be disposed when no longer used.
+func (r *Region) Texture() *texture.Texture2D {
+
+	if r.Rotated {
+		return texture.NewTexture2DFromRGBA(r.Image())
+	}
+	tex := texture.NewTexture2DView(r.Page.Texture(), r.Width, r.Height)
+	tex.SetOffset(r.Offset())
+	tex.SetRepeat(r.Repeat())
+	return tex
+}
@@ -0,0 +1,9 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package atlas implements texture atlases: images packed into one or more
+// pages with named regions which can be used by gui images, sprites and
+// sprite batches. Atlases can be packed at runtime or loaded from and saved
+// to the TexturePacker JSON format.
+package atlas
@@ -0,0 +1,311 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package atlas
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/wangzun/gogame/engine/texture"
+)
+
+// Packer packs images into the pages of an atlas using the MaxRects
+// algorithm with the best short side fit heuristic.
+type Packer struct {
+	maxWidth   int         // Maximum page width in pixels
+	maxHeight  int         // Maximum page height in pixels
+	padding    int         // Number of transparent pixels between regions
+	extrude    int         // Number of pixels the regions borders are repeated
+	powerOfTwo bool        // Page sizes rounded up to power of two
+	name       string      // Base name of the pages images
+	items      []*packItem // Images to pack
+}
+
+// packItem is an image to be packed
+type packItem struct {
+	name string
+	img  image.Image
+}
+
+// packRect is a rectangle used by the MaxRects algorithm
+type packRect struct {
+	x, y, w, h int
+}
+
+// NewPacker creates and returns a pointer to a new packer with the specified maximum page size.
+func NewPacker(maxWidth, maxHeight int) *Packer {
+
+	p := new(Packer)
+	p.maxWidth = maxWidth
+	p.maxHeight = maxHeight
+	p.padding = 2
+	p.extrude = 1
+	p.powerOfTwo = true
+	p.name = "atlas"
+	p.items = make([]*packItem, 0)
+	return p
+}
+
+// SetPadding sets the number of transparent pixels left between regions (default = 2).
+func (p *Packer) SetPadding(padding int) {
+
+	p.padding = padding
+}
+
+// SetExtrude sets the number of pixels the borders of each region are repeated
+// around it to avoid bleeding when filtering (default = 1).
+func (p *Packer) SetExtrude(extrude int) {
+
+	p.extrude = extrude
+}
+
+// SetPowerOfTwo sets whether the pages sizes are rounded up to powers of two (default = true).
+func (p *Packer) SetPowerOfTwo(state bool) {
+
+	p.powerOfTwo = state
+}
+
+// SetName sets the base name used for the pages images (default = "atlas").
+// Single page atlases use "<name>.png" and multiple page atlases use "<name>-<n>.png".
+func (p *Packer) SetName(name string) {
+
+	p.name = name
+}
+
+// Add adds an image with the specified region name to be packed.
+func (p *Packer) Add(name string, img image.Image) {
+
+	p.items = append(p.items, &packItem{name, img})
+}
+
+// AddFile decodes the specified image file and adds it to be packed
+// using the file name without extension as the region name.
+func (p *Packer) AddFile(imgfile string) error {
+
+	rgba, err := texture.DecodeImage(imgfile)
+	if err != nil {
+		return err
+	}
+	base := filepath.Base(imgfile)
+	p.Add(strings.TrimSuffix(base, filepath.Ext(base)), rgba)
+	return nil
+}
+
+// Pack packs all the added images and returns the resulting atlas.
+// Images which do not fit in the current page are placed in new pages.
+// It returns an error if an image is larger than the maximum page size.
+func (p *Packer) Pack() (*Atlas, error) {
+
+	// Sorts the images by their largest side and then by area, keeping the insertion order for equal sizes
+	items := make([]*packItem, len(p.items))
+	copy(items, p.items)
+	sort.SliceStable(items, func(i, j int) bool {
+		s1 := items[i].img.Bounds().Size()
+		s2 := items[j].img.Bounds().Size()
+		m1 := maxInt(s1.X, s1.Y)
+		m2 := maxInt(s2.X, s2.Y)
+		if m1 != m2 {
+			return m1 > m2
+		}
+		return s1.X*s1.Y > s2.X*s2.Y
+	})
+
+	// Checks that all images fit in an empty page
+	border := 2*p.extrude + p.padding
+	for _, it := range items {
+		size := it.img.Bounds().Size()
+		if size.X+2*p.extrude > p.maxWidth || size.Y+2*p.extrude > p.maxHeight {
+			return nil, fmt.Errorf("image:%s size:%dx%d larger than page size:%dx%d", it.name, size.X, size.Y, p.maxWidth, p.maxHeight)
+		}
+	}
+
+	type placed struct {
+		item *packItem
+		rect packRect
+	}
+	pages := make([][]placed, 0)
+	for len(items) > 0 {
+		// The page is enlarged by the padding so regions touching the right and bottom borders need no padding
+		free := []packRect{{0, 0, p.maxWidth + p.padding, p.maxHeight + p.padding}}
+		page := make([]placed, 0)
+		remaining := make([]*packItem, 0)
+		for _, it := range items {
+			size := it.img.Bounds().Size()
+			rect, ok := findPosition(free, size.X+border, size.Y+border)
+			if !ok {
+				remaining = append(remaining, it)
+				continue
+			}
+			free = splitFree(free, rect)
+			page = append(page, placed{it, rect})
+		}
+		pages = append(pages, page)
+		items = remaining
+	}
+
+	// Builds the pages images
+	a := NewAtlas()
+	for i, page := range pages {
+		width := 0
+		height := 0
+		for _, pl := range page {
+			width = maxInt(width, pl.rect.x+pl.rect.w-p.padding)
+			height = maxInt(height, pl.rect.y+pl.rect.h-p.padding)
+		}
+		if p.powerOfTwo {
+			width = minInt(nextPowerOfTwo(width), p.maxWidth)
+			height = minInt(nextPowerOfTwo(height), p.maxHeight)
+		}
+		name := p.name + ".png"
+		if len(pages) > 1 {
+			name = fmt.Sprintf("%s-%d.png", p.name, i)
+		}
+		pg := a.AddPage(name, image.NewRGBA(image.Rect(0, 0, width, height)))
+		for _, pl := range page {
+			size := pl.item.img.Bounds().Size()
+			r := &Region{
+				Name:         pl.item.name,
+				Page:         pg,
+				X:            pl.rect.x + p.extrude,
+				Y:            pl.rect.y + p.extrude,
+				Width:        size.X,
+				Height:       size.Y,
+				SourceWidth:  size.X,
+				SourceHeight: size.Y,
+			}
+			drawExtruded(pg.Image, pl.item.img, r.X, r.Y, p.extrude)
+			a.AddRegion(r)
+		}
+	}
+	return a, nil
+}
+
+// findPosition finds the free rectangle position for a rectangle with the specified size
+// using the best short side fit heuristic.
+func findPosition(free []packRect, w, h int) (packRect, bool) {
+
+	best := packRect{}
+	bestShort := -1
+	bestLong := -1
+	for _, f := range free {
+		if w > f.w || h > f.h {
+			continue
+		}
+		short := minInt(f.w-w, f.h-h)
+		long := maxInt(f.w-w, f.h-h)
+		if bestShort < 0 || short < bestShort || (short == bestShort && long < bestLong) {
+			best = packRect{f.x, f.y, w, h}
+			bestShort = short
+			bestLong = long
+		}
+	}
+	return best, bestShort >= 0
+}
+
+// splitFree removes the specified used rectangle from the free rectangles,
+// splitting the intersecting ones, and prunes the free rectangles contained in others.
+func splitFree(free []packRect, used packRect) []packRect {
+
+	result := make([]packRect, 0, len(free)+4)
+	for _, f := range free {
+		// No intersection
+		if used.x >= f.x+f.w || used.x+used.w <= f.x || used.y >= f.y+f.h || used.y+used.h <= f.y {
+			result = append(result, f)
+			continue
+		}
+		// Left, right, top and bottom remaining parts
+		if used.x > f.x {
+			result = append(result, packRect{f.x, f.y, used.x - f.x, f.h})
+		}
+		if used.x+used.w < f.x+f.w {
+			result = append(result, packRect{used.x + used.w, f.y, f.x + f.w - (used.x + used.w), f.h})
+		}
+		if used.y > f.y {
+			result = append(result, packRect{f.x, f.y, f.w, used.y - f.y})
+		}
+		if used.y+used.h < f.y+f.h {
+			result = append(result, packRect{f.x, used.y + used.h, f.w, f.y + f.h - (used.y + used.h)})
+		}
+	}
+
+	// Prunes rectangles contained in other rectangles
+	pruned := make([]packRect, 0, len(result))
+	for i, r1 := range result {
+		contained := false
+		for j, r2 := range result {
+			if i == j {
+				continue
+			}
+			if r1.x >= r2.x && r1.y >= r2.y && r1.x+r1.w <= r2.x+r2.w && r1.y+r1.h <= r2.y+r2.h {
+				// Keeps only the first of equal rectangles
+				if r1 == r2 && i < j {
+					continue
+				}
+				contained = true
+				break
+			}
+		}
+		if !contained {
+			pruned = append(pruned, r1)
+		}
+	}
+	return pruned
+}
+
+// drawExtruded draws the source image into the destination image at the specified position
+// repeating its border pixels the specified number of times around it.
+func drawExtruded(dst *image.RGBA, src image.Image, x, y, extrude int) {
+
+	b := src.Bounds()
+	w := b.Dx()
+	h := b.Dy()
+	draw.Draw(dst, image.Rect(x, y, x+w, y+h), src, b.Min, draw.Src)
+	if extrude <= 0 || w == 0 || h == 0 {
+		return
+	}
+	// Top and bottom rows
+	for e := 1; e <= extrude; e++ {
+		for i := 0; i < w; i++ {
+			dst.Set(x+i, y-e, dst.At(x+i, y))
+			dst.Set(x+i, y+h-1+e, dst.At(x+i, y+h-1))
+		}
+	}
+	// Left and right columns including the corners
+	for e := 1; e <= extrude; e++ {
+		for j := -extrude; j < h+extrude; j++ {
+			dst.Set(x-e, y+j, dst.At(x, y+j))
+			dst.Set(x+w-1+e, y+j, dst.At(x+w-1, y+j))
+		}
+	}
+}
+
+func nextPowerOfTwo(v int) int {
+
+	n := 1
+	for n < v {
+		n <<= 1
+	}
+	return n
+}
+
+func minInt(a, b int) int {
+
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+
+	if a > b {
+		return a
+	}
+	return b
+}
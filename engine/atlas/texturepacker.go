@@ -0,0 +1,259 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package atlas
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/wangzun/gogame/engine/texture"
+)
+
+// tpRect is a TexturePacker rectangle
+type tpRect struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	W int `json:"w"`
+	H int `json:"h"`
+}
+
+// tpSize is a TexturePacker size
+type tpSize struct {
+	W int `json:"w"`
+	H int `json:"h"`
+}
+
+// tpFrame is a TexturePacker frame
+type tpFrame struct {
+	Filename         string `json:"filename,omitempty"`
+	Frame            tpRect `json:"frame"`
+	Rotated          bool   `json:"rotated"`
+	Trimmed          bool   `json:"trimmed"`
+	SpriteSourceSize tpRect `json:"spriteSourceSize"`
+	SourceSize       tpSize `json:"sourceSize"`
+	Duration         int    `json:"duration,omitempty"` // Frame duration in milliseconds (Aseprite)
+}
+
+// tpMeta is the TexturePacker meta data object
+type tpMeta struct {
+	App               string   `json:"app"`
+	Version           string   `json:"version"`
+	Image             string   `json:"image"`
+	Format            string   `json:"format"`
+	Size              tpSize   `json:"size"`
+	Scale             string   `json:"scale"`
+	RelatedMultiPacks []string `json:"related_multi_packs,omitempty"`
+	FrameTags         []aseTag `json:"frameTags,omitempty"` // Animation tags (Aseprite)
+}
+
+// tpFile is a TexturePacker JSON file in the hash or array format
+type tpFile struct {
+	Frames json.RawMessage `json:"frames"`
+	Meta   tpMeta          `json:"meta"`
+}
+
+// tpHashFile is used to encode a TexturePacker JSON file in the hash format
+type tpHashFile struct {
+	Frames map[string]tpFrame `json:"frames"`
+	Meta   tpMeta             `json:"meta"`
+}
+
+// Load loads the atlas described by the specified TexturePacker JSON file
+// in the hash or array format, and the images of its pages.
+// Rotated frames are loaded as rotated regions.
+// Multiple page atlases are loaded following the "related_multi_packs" meta field.
+func Load(jsonfile string) (*Atlas, error) {
+
+	a := NewAtlas()
+	loaded := make(map[string]bool)
+	pending := []string{jsonfile}
+	for len(pending) > 0 {
+		fpath := pending[0]
+		pending = pending[1:]
+		abs, err := filepath.Abs(fpath)
+		if err != nil {
+			return nil, err
+		}
+		if loaded[abs] {
+			continue
+		}
+		loaded[abs] = true
+		meta, _, _, err := a.loadPage(fpath)
+		if err != nil {
+			return nil, err
+		}
+		for _, rel := range meta.RelatedMultiPacks {
+			pending = append(pending, filepath.Join(filepath.Dir(fpath), rel))
+		}
+	}
+	return a, nil
+}
+
+// loadPage loads the specified TexturePacker JSON file and its image as a new page of the atlas
+// and returns its meta data and its frames and their regions in file order. Frames with the same
+// name have their own regions, although only the last one can be found by name in the atlas.
+func (a *Atlas) loadPage(jsonfile string) (*tpMeta, []tpFrame, []*Region, error) {
+
+	data, err := ioutil.ReadFile(jsonfile)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	var f tpFile
+	err = json.Unmarshal(data, &f)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("atlas file:%s: %v", jsonfile, err)
+	}
+
+	// Decodes the frames which may be a JSON object (hash format) or a JSON array (array format)
+	frames := make([]tpFrame, 0)
+	raw := bytes.TrimSpace(f.Frames)
+	if len(raw) > 0 && raw[0] == '[' {
+		err = json.Unmarshal(raw, &frames)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("atlas file:%s: %v", jsonfile, err)
+		}
+	} else {
+		// Decodes hash keeping the order of the frames in the file
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		if _, err = dec.Token(); err != nil {
+			return nil, nil, nil, fmt.Errorf("atlas file:%s: %v", jsonfile, err)
+		}
+		for dec.More() {
+			tok, err := dec.Token()
+			if err != nil {
+				return nil, nil, nil, fmt.Errorf("atlas file:%s: %v", jsonfile, err)
+			}
+			var fr tpFrame
+			err = dec.Decode(&fr)
+			if err != nil {
+				return nil, nil, nil, fmt.Errorf("atlas file:%s: %v", jsonfile, err)
+			}
+			fr.Filename = tok.(string)
+			frames = append(frames, fr)
+		}
+	}
+
+	// Loads the page image
+	rgba, err := texture.DecodeImage(filepath.Join(filepath.Dir(jsonfile), f.Meta.Image))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	page := a.AddPage(f.Meta.Image, rgba)
+
+	regions := make([]*Region, len(frames))
+	for i, fr := range frames {
+		r := &Region{
+			Name:         fr.Filename,
+			Page:         page,
+			X:            fr.Frame.X,
+			Y:            fr.Frame.Y,
+			Width:        fr.Frame.W,
+			Height:       fr.Frame.H,
+			Rotated:      fr.Rotated,
+			OffsetX:      fr.SpriteSourceSize.X,
+			OffsetY:      fr.SpriteSourceSize.Y,
+			SourceWidth:  fr.SourceSize.W,
+			SourceHeight: fr.SourceSize.H,
+		}
+		// The frame size of rotated frames is their upright size
+		if r.Rotated {
+			r.Width, r.Height = r.Height, r.Width
+		}
+		if r.SourceWidth == 0 || r.SourceHeight == 0 {
+			r.SourceWidth = fr.Frame.W
+			r.SourceHeight = fr.Frame.H
+		}
+		a.AddRegion(r)
+		regions[i] = r
+	}
+	return &f.Meta, frames, regions, nil
+}
+
+// Save saves the pages images of the atlas as PNG files and their regions as
+// TexturePacker JSON files in the hash format.
+// The images are saved in the directory of the specified JSON file using the pages names.
+// For multiple page atlases a JSON file is saved for each page named as its image,
+// with the ".json" extension, and the files reference each other in the "related_multi_packs"
+// meta field. For single page atlases the specified JSON file name is used.
+func (a *Atlas) Save(jsonfile string) error {
+
+	dir := filepath.Dir(jsonfile)
+	jsonNames := make([]string, len(a.Pages))
+	for i, p := range a.Pages {
+		if len(a.Pages) == 1 {
+			jsonNames[i] = filepath.Base(jsonfile)
+		} else {
+			jsonNames[i] = strings.TrimSuffix(p.Name, filepath.Ext(p.Name)) + ".json"
+		}
+	}
+
+	for i, p := range a.Pages {
+		// Saves the page image
+		err := savePNG(filepath.Join(dir, p.Name), p)
+		if err != nil {
+			return err
+		}
+
+		// Builds the TexturePacker file
+		f := tpHashFile{Frames: make(map[string]tpFrame)}
+		f.Meta = tpMeta{
+			App:     "https://github.com/wangzun/gogame",
+			Version: "1.0",
+			Image:   p.Name,
+			Format:  "RGBA8888",
+			Size:    tpSize{p.Width, p.Height},
+			Scale:   "1",
+		}
+		for j, name := range jsonNames {
+			if j != i {
+				f.Meta.RelatedMultiPacks = append(f.Meta.RelatedMultiPacks, name)
+			}
+		}
+		for _, r := range a.Regions(p) {
+			// The frame size of rotated frames is their upright size
+			w, h := r.Width, r.Height
+			if r.Rotated {
+				w, h = h, w
+			}
+			f.Frames[r.Name] = tpFrame{
+				Frame:            tpRect{r.X, r.Y, w, h},
+				Rotated:          r.Rotated,
+				Trimmed:          r.OffsetX != 0 || r.OffsetY != 0 || r.SourceWidth != w || r.SourceHeight != h,
+				SpriteSourceSize: tpRect{r.OffsetX, r.OffsetY, w, h},
+				SourceSize:       tpSize{r.SourceWidth, r.SourceHeight},
+			}
+		}
+		data, err := json.MarshalIndent(&f, "", "\t")
+		if err != nil {
+			return err
+		}
+		err = ioutil.WriteFile(filepath.Join(dir, jsonNames[i]), data, 0644)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// savePNG saves the image of the specified page as a PNG file.
+func savePNG(fpath string, p *Page) error {
+
+	file, err := os.Create(fpath)
+	if err != nil {
+		return err
+	}
+	err = png.Encode(file, p.Image)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
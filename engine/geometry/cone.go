@@ -0,0 +1,17 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package geometry
+
+import (
+	"math"
+)
+
+// NewCone creates and returns a pointer to a new Cylinder geometry with a zero top radius,
+// the specified base radius, height and numbers of segments and an optional base cap.
+// The apex points to the positive Y axis.
+func NewCone(radius, height float64, radialSegments, heightSegments int, base bool) *Cylinder {
+
+	return NewCylinder(0, radius, height, radialSegments, heightSegments, 0, 2*math.Pi, false, base)
+}
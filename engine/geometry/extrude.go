@@ -0,0 +1,235 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package geometry
+
+import (
+	"github.com/wangzun/gogame/engine/gls"
+	"github.com/wangzun/gogame/engine/math32"
+	"github.com/wangzun/gogame/engine/text"
+)
+
+// ExtrudeOptions specifies how the shapes of an Extrude geometry are extruded.
+type ExtrudeOptions struct {
+	Depth          float32 // Extrusion depth along the positive Z axis
+	Steps          int     // Number of segments along the depth
+	BevelEnabled   bool    // Whether the edges of the caps are beveled
+	BevelThickness float32 // Depth of the bevels in front of and behind the extrusion
+	BevelSize      float32 // Distance the bevels extend the shapes outwards, which should be smaller than their details
+	BevelSegments  int     // Number of segments of each bevel
+}
+
+// NewExtrudeOptions creates and returns a pointer to new extrude options
+// with the specified depth and without bevels.
+func NewExtrudeOptions(depth float32) *ExtrudeOptions {
+
+	return &ExtrudeOptions{
+		Depth:          depth,
+		Steps:          1,
+		BevelThickness: depth / 10,
+		BevelSize:      depth / 20,
+		BevelSegments:  3,
+	}
+}
+
+// Extrude represents the geometry of 2D shapes with holes extruded along the Z axis.
+// The front cap is on the XY plane facing the negative Z axis, or in front of it by the bevel
+// thickness when beveled, and the back cap faces the positive Z axis at the extrusion depth.
+// The first group contains the caps and the second group the sides.
+type Extrude struct {
+	Geometry
+	Shapes  []*Shape
+	Options ExtrudeOptions
+}
+
+// extrudeLayer is a cross section of an extrusion.
+type extrudeLayer struct {
+	offset float32 // Distance the shape is extended outwards
+	z      float32 // Z coordinate
+	do     float32 // Derivative of the offset along the profile
+	dz     float32 // Derivative of the Z coordinate along the profile
+}
+
+// extrudeSmoothAngle is the cosine of the maximum angle between the sides
+// of a contour corner which is smoothly shaded.
+var extrudeSmoothAngle = math32.Cos(35 * math32.Pi / 180)
+
+// NewExtrude creates and returns a pointer to a new Extrude geometry with the specified shapes
+// extruded along the Z axis with the specified options.
+// The caps are texture mapped with the XY coordinates of the shapes and the sides with
+// the distance along the contours as U and the Z coordinate as V.
+func NewExtrude(shapes []*Shape, options *ExtrudeOptions) *Extrude {
+
+	e := new(Extrude)
+	e.Geometry.Init()
+	e.Shapes = shapes
+	e.Options = *options
+
+	opts := &e.Options
+	if opts.Steps < 1 {
+		opts.Steps = 1
+	}
+	if opts.BevelSegments < 1 {
+		opts.BevelSegments = 1
+	}
+
+	// Builds the cross sections from the front cap to the back cap
+	layers := make([]extrudeLayer, 0)
+	if opts.BevelEnabled {
+		bt := opts.BevelThickness
+		bs := opts.BevelSize
+		for k := 0; k < opts.BevelSegments; k++ {
+			t := float32(k) / float32(opts.BevelSegments) * math32.Pi / 2
+			s, c := math32.Sin(t), math32.Cos(t)
+			layers = append(layers, extrudeLayer{bs * s, -bt * c, bs * c, bt * s})
+		}
+		for s := 0; s <= opts.Steps; s++ {
+			layers = append(layers, extrudeLayer{bs, opts.Depth * float32(s) / float32(opts.Steps), 0, 1})
+		}
+		for k := opts.BevelSegments - 1; k >= 0; k-- {
+			t := float32(k) / float32(opts.BevelSegments) * math32.Pi / 2
+			s, c := math32.Sin(t), math32.Cos(t)
+			layers = append(layers, extrudeLayer{bs * s, opts.Depth + bt*c, -bs * c, bt * s})
+		}
+	} else {
+		for s := 0; s <= opts.Steps; s++ {
+			layers = append(layers, extrudeLayer{0, opts.Depth * float32(s) / float32(opts.Steps), 0, 1})
+		}
+	}
+
+	positions := math32.NewArrayF32(0, 0)
+	normals := math32.NewArrayF32(0, 0)
+	uvs := math32.NewArrayF32(0, 0)
+	caps := math32.NewArrayU32(0, 0)
+	sides := math32.NewArrayU32(0, 0)
+
+	for _, shape := range shapes {
+		if len(shape.Contour) < 3 {
+			continue
+		}
+		contour, holes := shape.normalized()
+		tris := triangulate(contour, holes)
+		points := append([]math32.Vector2(nil), contour...)
+		for _, h := range holes {
+			points = append(points, h...)
+		}
+
+		// Front cap facing the negative Z axis and back cap facing the positive Z axis
+		front := &layers[0]
+		back := &layers[len(layers)-1]
+		for c, layer := range []*extrudeLayer{front, back} {
+			base := uint32(positions.Len() / 3)
+			nz := float32(-1 + 2*c)
+			for i := range points {
+				p := &points[i]
+				positions.Append(p.X, p.Y, layer.z)
+				normals.Append(0, 0, nz)
+				uvs.Append(p.X, p.Y)
+			}
+			for i := 0; i+2 < len(tris); i += 3 {
+				if c == 0 {
+					caps.Append(base+tris[i], base+tris[i+2], base+tris[i+1])
+				} else {
+					caps.Append(base+tris[i], base+tris[i+1], base+tris[i+2])
+				}
+			}
+		}
+
+		// Sides of the contour and of the holes
+		e.extrudeLoop(contour, layers, &positions, &normals, &uvs, &sides)
+		for _, h := range holes {
+			e.extrudeLoop(h, layers, &positions, &normals, &uvs, &sides)
+		}
+	}
+
+	// Caps come first in the indices followed by the sides
+	indices := math32.NewArrayU32(0, caps.Len()+sides.Len())
+	indices.Append(caps...)
+	indices.Append(sides...)
+	e.AddGroup(0, caps.Len(), 0)
+	e.AddGroup(caps.Len(), sides.Len(), 1)
+
+	e.SetIndices(indices)
+	e.AddVBO(gls.NewVBO(positions).AddAttrib(gls.VertexPosition))
+	e.AddVBO(gls.NewVBO(normals).AddAttrib(gls.VertexNormal))
+	e.AddVBO(gls.NewVBO(uvs).AddAttrib(gls.VertexTexcoord))
+	return e
+}
+
+// NewExtrudedText creates and returns a pointer to a new Extrude geometry with the outlines
+// of the glyphs of the specified text with the specified font size in units extruded along
+// the Z axis. The quadratic curves of the glyphs are approximated by the specified number of segments.
+// The text starts at the origin on its baseline.
+func NewExtrudedText(font *text.Font, msg string, size float32, curveSegments int, options *ExtrudeOptions) *Extrude {
+
+	return NewExtrude(NewShapesFromContours(font.Contours(msg, size, curveSegments)), options)
+}
+
+// extrudeLoop appends the side vertices and triangles of the specified closed loop,
+// which is counter clockwise for outer contours and clockwise for holes, so that its
+// right side always faces out of the shape.
+func (e *Extrude) extrudeLoop(loop []math32.Vector2, layers []extrudeLayer, positions, normals, uvs *math32.ArrayF32, indices *math32.ArrayU32) {
+
+	n := len(loop)
+
+	// Outward normals of the edges starting at each point
+	edgeNormals := make([]math32.Vector2, n)
+	for i := range loop {
+		d := math32.Vector2{loop[(i+1)%n].X - loop[i].X, loop[(i+1)%n].Y - loop[i].Y}
+		edgeNormals[i] = math32.Vector2{d.Y, -d.X}
+		edgeNormals[i].Normalize()
+	}
+
+	// Offset directions of the points, whose dot product with the normals of
+	// the adjacent edges is one, and normals of the corners when smooth
+	miters := make([]math32.Vector2, n)
+	smooth := make([]math32.Vector2, n)
+	sharp := make([]bool, n)
+	for i := range loop {
+		n1 := &edgeNormals[(i+n-1)%n]
+		n2 := &edgeNormals[i]
+		m := math32.Vector2{n1.X + n2.X, n1.Y + n2.Y}
+		dot := n1.Dot(n2)
+		// Limits the offset of very sharp corners
+		miters[i] = m
+		miters[i].MultiplyScalar(1 / math32.Max(1+dot, 0.25))
+		smooth[i] = m
+		smooth[i].Normalize()
+		sharp[i] = dot < extrudeSmoothAngle
+	}
+
+	// Each edge gets its own columns of vertices, sharing the normals at smooth corners
+	var dist float32
+	for i := range loop {
+		j := (i + 1) % n
+		length := loop[i].DistanceTo(&loop[j])
+		if length == 0 {
+			continue
+		}
+		base := uint32(positions.Len() / 3)
+		for c, k := range [2]int{i, j} {
+			normal := smooth[k]
+			if sharp[k] {
+				normal = edgeNormals[i]
+			}
+			u := dist + float32(c)*length
+			for l := range layers {
+				layer := &layers[l]
+				positions.Append(loop[k].X+miters[k].X*layer.offset, loop[k].Y+miters[k].Y*layer.offset, layer.z)
+				// Normal perpendicular to the profile of the layers
+				nv := math32.Vector3{normal.X * layer.dz, normal.Y * layer.dz, -layer.do}
+				nv.Normalize()
+				normals.AppendVector3(&nv)
+				uvs.Append(u, layer.z)
+			}
+		}
+		nl := uint32(len(layers))
+		for l := uint32(0); l+1 < nl; l++ {
+			a := base + l
+			b := base + nl + l
+			indices.Append(a, b, b+1, a, b+1, a+1)
+		}
+		dist += length
+	}
+}
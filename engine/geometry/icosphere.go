@@ -0,0 +1,148 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package geometry
+
+import (
+	"github.com/wangzun/gogame/engine/gls"
+	"github.com/wangzun/gogame/engine/math32"
+)
+
+// Icosphere represents the geometry of a sphere built by subdividing an icosahedron,
+// whose triangles have nearly the same size unlike the ones of the Sphere geometry.
+type Icosphere struct {
+	Geometry
+	Radius float64
+	Detail int
+}
+
+// NewIcosphere creates and returns a pointer to a new Icosphere geometry with the
+// specified radius, where each subdivision level splits each triangle in four.
+// The texture coordinates are mapped as in the Sphere geometry.
+func NewIcosphere(radius float64, detail int) *Icosphere {
+
+	s := new(Icosphere)
+	s.Geometry.Init()
+	s.Radius = radius
+	s.Detail = detail
+
+	// Icosahedron vertices and faces
+	t := (1 + math32.Sqrt(5)) / 2
+	points := []math32.Vector3{
+		{-1, t, 0}, {1, t, 0}, {-1, -t, 0}, {1, -t, 0},
+		{0, -1, t}, {0, 1, t}, {0, -1, -t}, {0, 1, -t},
+		{t, 0, -1}, {t, 0, 1}, {-t, 0, -1}, {-t, 0, 1},
+	}
+	for i := range points {
+		points[i].Normalize()
+	}
+	faces := []uint32{
+		0, 11, 5, 0, 5, 1, 0, 1, 7, 0, 7, 10, 0, 10, 11,
+		1, 5, 9, 5, 11, 4, 11, 10, 2, 10, 7, 6, 7, 1, 8,
+		3, 9, 4, 3, 4, 2, 3, 2, 6, 3, 6, 8, 3, 8, 9,
+		4, 9, 5, 2, 4, 11, 6, 2, 10, 8, 6, 7, 9, 8, 1,
+	}
+
+	// Splits each triangle in four sharing the midpoints of the edges
+	for level := 0; level < detail; level++ {
+		midpoints := make(map[[2]uint32]uint32)
+		midpoint := func(a, b uint32) uint32 {
+			key := [2]uint32{a, b}
+			if a > b {
+				key = [2]uint32{b, a}
+			}
+			if idx, ok := midpoints[key]; ok {
+				return idx
+			}
+			var p math32.Vector3
+			p.AddVectors(&points[a], &points[b]).Normalize()
+			points = append(points, p)
+			idx := uint32(len(points) - 1)
+			midpoints[key] = idx
+			return idx
+		}
+		subdivided := make([]uint32, 0, len(faces)*4)
+		for i := 0; i < len(faces); i += 3 {
+			a, b, c := faces[i], faces[i+1], faces[i+2]
+			ab := midpoint(a, b)
+			bc := midpoint(b, c)
+			ca := midpoint(c, a)
+			subdivided = append(subdivided, a, ab, ca, b, bc, ab, c, ca, bc, ab, bc, ca)
+		}
+		faces = subdivided
+	}
+
+	// Spherical texture coordinates as in the Sphere geometry
+	sphereUV := func(p *math32.Vector3) math32.Vector2 {
+		u := math32.Atan2(p.Z, -p.X) / (2 * math32.Pi)
+		if u < 0 {
+			u++
+		}
+		return math32.Vector2{u, math32.Acos(math32.Clamp(p.Y, -1, 1)) / math32.Pi}
+	}
+
+	positions := math32.NewArrayF32(0, len(points)*3)
+	normals := math32.NewArrayF32(0, len(points)*3)
+	uvs := math32.NewArrayF32(0, len(points)*2)
+	indices := math32.NewArrayU32(0, len(faces))
+	r := float32(radius)
+
+	// Vertices are shared by the triangles with the same texture coordinates and
+	// duplicated along the texture seam and at the poles
+	type vertexKey struct {
+		point uint32
+		uv    math32.Vector2
+	}
+	vertices := make(map[vertexKey]uint32)
+	for i := 0; i < len(faces); i += 3 {
+		tri := faces[i : i+3]
+		var tuv [3]math32.Vector2
+		pole := -1
+		for k, idx := range tri {
+			tuv[k] = sphereUV(&points[idx])
+			if math32.Abs(points[idx].Y) > 0.999999 {
+				pole = k
+			}
+		}
+		// Triangles crossing the seam use U coordinates above one on the other side
+		maxU := math32.Max(tuv[0].X, math32.Max(tuv[1].X, tuv[2].X))
+		for k := range tuv {
+			if k != pole && maxU-tuv[k].X > 0.5 {
+				tuv[k].X++
+			}
+		}
+		// The U coordinate of a pole is the one in the middle of the triangle base
+		if pole >= 0 {
+			tuv[pole].X = (tuv[(pole+1)%3].X + tuv[(pole+2)%3].X) / 2
+		}
+		for k, idx := range tri {
+			key := vertexKey{idx, tuv[k]}
+			vi, ok := vertices[key]
+			if !ok {
+				p := &points[idx]
+				positions.Append(p.X*r, p.Y*r, p.Z*r)
+				normals.AppendVector3(p)
+				uvs.AppendVector2(&tuv[k])
+				vi = uint32(len(vertices))
+				vertices[key] = vi
+			}
+			indices.Append(vi)
+		}
+	}
+
+	s.SetIndices(indices)
+	s.AddVBO(gls.NewVBO(positions).AddAttrib(gls.VertexPosition))
+	s.AddVBO(gls.NewVBO(normals).AddAttrib(gls.VertexNormal))
+	s.AddVBO(gls.NewVBO(uvs).AddAttrib(gls.VertexTexcoord))
+
+	// Update bounding sphere
+	s.boundingSphere.Radius = r
+	s.boundingSphereValid = true
+
+	// Update bounding box
+	s.boundingBox = math32.Box3{math32.Vector3{-r, -r, -r}, math32.Vector3{r, r, r}}
+	s.boundingBoxValid = true
+
+	return s
+}
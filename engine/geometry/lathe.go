@@ -0,0 +1,164 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package geometry
+
+import (
+	"math"
+
+	"github.com/wangzun/gogame/engine/gls"
+	"github.com/wangzun/gogame/engine/math32"
+)
+
+// Lathe represents the geometry of a profile revolved around the Y axis,
+// such as vases, bottles and chess pieces.
+type Lathe struct {
+	Geometry
+	Points    []math32.Vector2
+	Segments  int
+	PhiStart  float64
+	PhiLength float64
+}
+
+// NewLathe creates and returns a pointer to a new Lathe geometry with the specified profile
+// points, whose X coordinates are the distances from the Y axis, revolved around the Y axis
+// with the specified number of segments from the start angle over the specified angle length.
+// The profile should go up with the points on its right facing outwards.
+func NewLathe(points []math32.Vector2, segments int, phiStart, phiLength float64) *Lathe {
+
+	l := new(Lathe)
+	l.Geometry.Init()
+	l.Points = points
+	l.Segments = segments
+	l.PhiStart = phiStart
+	l.PhiLength = phiLength
+
+	// Profile normals from the tangents at each point
+	n := len(points)
+	normals := make([]math32.Vector2, n)
+	vs := make([]float32, n)
+	for i := range points {
+		prev := &points[i]
+		if i > 0 {
+			prev = &points[i-1]
+		}
+		next := &points[i]
+		if i < n-1 {
+			next = &points[i+1]
+		}
+		normals[i] = math32.Vector2{next.Y - prev.Y, prev.X - next.X}
+		normals[i].Normalize()
+		if n > 1 {
+			vs[i] = float32(i) / float32(n-1)
+		}
+	}
+	buildLathe(&l.Geometry, points, normals, vs, segments, phiStart, phiLength)
+	return l
+}
+
+// Capsule represents the geometry of a cylinder with hemispherical caps
+// along the Y axis, commonly used for characters and their colliders.
+type Capsule struct {
+	Geometry
+	Radius         float64
+	Length         float64
+	CapSegments    int
+	RadialSegments int
+}
+
+// NewCapsule creates and returns a pointer to a new Capsule geometry with the specified radius,
+// length of the cylindrical part, number of segments of each hemisphere from its pole to the
+// cylinder and number of segments around the Y axis. The total height is length + 2*radius.
+func NewCapsule(radius, length float64, capSegments, radialSegments int) *Capsule {
+
+	c := new(Capsule)
+	c.Geometry.Init()
+	c.Radius = radius
+	c.Length = length
+	c.CapSegments = capSegments
+	c.RadialSegments = radialSegments
+
+	// Profile from the bottom pole to the top pole with the exact normals of the hemispheres
+	points := make([]math32.Vector2, 0, 2*capSegments+2)
+	normals := make([]math32.Vector2, 0, 2*capSegments+2)
+	half := length / 2
+	for i := 0; i <= 2*capSegments+1; i++ {
+		y := half
+		k := i - capSegments - 1
+		if i <= capSegments {
+			y = -half
+			k = i - capSegments
+		}
+		a := float64(k) / float64(capSegments) * math.Pi / 2
+		cos := float32(math.Cos(a))
+		// The poles are exactly on the axis
+		if k == -capSegments || k == capSegments {
+			cos = 0
+		}
+		points = append(points, math32.Vector2{float32(radius) * cos, float32(y + radius*math.Sin(a))})
+		normals = append(normals, math32.Vector2{cos, float32(math.Sin(a))})
+	}
+
+	// The V texture coordinate is proportional to the distance along the profile
+	vs := make([]float32, len(points))
+	for i := 1; i < len(points); i++ {
+		vs[i] = vs[i-1] + points[i].DistanceTo(&points[i-1])
+	}
+	for i := range vs {
+		vs[i] /= vs[len(vs)-1]
+	}
+
+	buildLathe(&c.Geometry, points, normals, vs, radialSegments, 0, 2*math.Pi)
+
+	r := float32(radius)
+	h := float32(half + radius)
+	c.boundingBox = math32.Box3{math32.Vector3{-r, -h, -r}, math32.Vector3{r, h, r}}
+	c.boundingBoxValid = true
+	return c
+}
+
+// buildLathe builds the specified geometry revolving the specified profile points with their
+// normals and V texture coordinates around the Y axis.
+func buildLathe(g *Geometry, points, pnormals []math32.Vector2, vs []float32, segments int, phiStart, phiLength float64) {
+
+	n := len(points)
+	count := (segments + 1) * n
+	positions := math32.NewArrayF32(0, count*3)
+	normals := math32.NewArrayF32(0, count*3)
+	uvs := math32.NewArrayF32(0, count*2)
+	indices := math32.NewArrayU32(0, segments*(n-1)*6)
+
+	for i := 0; i <= segments; i++ {
+		u := float64(i) / float64(segments)
+		phi := phiStart + u*phiLength
+		sin := float32(math.Sin(phi))
+		cos := float32(math.Cos(phi))
+		for j := range points {
+			p := &points[j]
+			pn := &pnormals[j]
+			positions.Append(p.X*sin, p.Y, p.X*cos)
+			normals.Append(pn.X*sin, pn.Y, pn.X*cos)
+			uvs.Append(float32(u), vs[j])
+		}
+	}
+
+	for i := 0; i < segments; i++ {
+		for j := 0; j < n-1; j++ {
+			a := uint32(i*n + j)
+			b := uint32((i+1)*n + j)
+			// Skips the triangles degenerated to lines on the axis
+			if points[j].X != 0 {
+				indices.Append(a, b, b+1)
+			}
+			if points[j+1].X != 0 {
+				indices.Append(a, b+1, a+1)
+			}
+		}
+	}
+
+	g.SetIndices(indices)
+	g.AddVBO(gls.NewVBO(positions).AddAttrib(gls.VertexPosition))
+	g.AddVBO(gls.NewVBO(normals).AddAttrib(gls.VertexNormal))
+	g.AddVBO(gls.NewVBO(uvs).AddAttrib(gls.VertexTexcoord))
+}
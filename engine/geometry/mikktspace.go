@@ -0,0 +1,245 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package geometry
+
+import (
+	"github.com/wangzun/gogame/engine/math32"
+)
+
+// mikkTriangle contains the tangent space information of a triangle
+type mikkTriangle struct {
+	verts      [3]int         // Merged vertices of the corners
+	neighbors  [3]int         // Triangles sharing the edge from each corner to the next one or -1
+	groups     [3]*mikkGroup  // Groups of the corners
+	os         math32.Vector3 // Derivative of the position by the first texture coordinate
+	ot         math32.Vector3 // Derivative of the position by the second texture coordinate
+	preserving bool           // Whether the texture coordinates preserve the triangle orientation
+	withAny    bool           // Whether the triangle can be grouped with any orientation
+	degenerate bool           // Whether two corners use the same merged vertex
+}
+
+// mikkGroup is a group of triangles around a merged vertex connected by their edges
+// and with the same orientation, which share the tangent space of the vertex
+type mikkGroup struct {
+	vertex     int   // Merged vertex
+	preserving bool  // Orientation of the triangles
+	faces      []int // Triangles of the group
+}
+
+// mikkTangents calculates the tangents of the corners of the specified triangles using the
+// MikkTSpace algorithm with its default angular threshold and returns them with the signs
+// of their bitangents. The corners of degenerate triangles get the tangent of another corner
+// with the same vertex or a zero tangent.
+func mikkTangents(positions, normals, uvs []math32.Vector3, elements math32.ArrayU32) ([]math32.Vector3, []float32) {
+
+	corners := len(elements) / 3 * 3
+	tangents := make([]math32.Vector3, corners)
+	signs := make([]float32, corners)
+
+	// Merges the vertices with the same position, normal and texture coordinates
+	type vertexKey struct {
+		position math32.Vector3
+		normal   math32.Vector3
+		uv       math32.Vector2
+	}
+	merged := make(map[vertexKey]int)
+	verts := make([]int, corners)
+	for c := 0; c < corners; c++ {
+		v := elements[c]
+		key := vertexKey{positions[v], normals[v], math32.Vector2{uvs[v].X, uvs[v].Y}}
+		idx, ok := merged[key]
+		if !ok {
+			idx = len(merged)
+			merged[key] = idx
+		}
+		verts[c] = idx
+	}
+
+	// Calculates the derivatives and orientation of the triangles
+	tris := make([]mikkTriangle, corners/3)
+	for t := range tris {
+		tri := &tris[t]
+		tri.neighbors = [3]int{-1, -1, -1}
+		copy(tri.verts[:], verts[t*3:t*3+3])
+		tri.degenerate = tri.verts[0] == tri.verts[1] || tri.verts[1] == tri.verts[2] || tri.verts[2] == tri.verts[0]
+		tri.withAny = true
+		if tri.degenerate {
+			continue
+		}
+		v0, v1, v2 := elements[t*3], elements[t*3+1], elements[t*3+2]
+		var d1, d2 math32.Vector3
+		d1.SubVectors(&positions[v1], &positions[v0])
+		d2.SubVectors(&positions[v2], &positions[v0])
+		t21x := uvs[v1].X - uvs[v0].X
+		t21y := uvs[v1].Y - uvs[v0].Y
+		t31x := uvs[v2].X - uvs[v0].X
+		t31y := uvs[v2].Y - uvs[v0].Y
+		area := t21x*t31y - t21y*t31x
+		tri.os.Copy(&d1).MultiplyScalar(t31y).Sub(d2.Clone().MultiplyScalar(t21y))
+		tri.ot.Copy(&d2).MultiplyScalar(t21x).Sub(d1.Clone().MultiplyScalar(t31x))
+		tri.preserving = area > 0
+		if area == 0 {
+			continue
+		}
+		// The derivatives point in the direction of increasing texture coordinates
+		if !tri.preserving {
+			tri.os.Negate()
+			tri.ot.Negate()
+		}
+		if tri.os.LengthSq() != 0 && tri.ot.LengthSq() != 0 {
+			tri.withAny = false
+		}
+	}
+
+	// Finds the neighbors of the triangles through their edges in opposite directions
+	edges := make(map[[2]int]int)
+	for t := range tris {
+		if tris[t].degenerate {
+			continue
+		}
+		for i := 0; i < 3; i++ {
+			edge := [2]int{tris[t].verts[i], tris[t].verts[(i+1)%3]}
+			if _, ok := edges[edge]; !ok {
+				edges[edge] = t
+			}
+		}
+	}
+	for t := range tris {
+		if tris[t].degenerate {
+			continue
+		}
+		for i := 0; i < 3; i++ {
+			if n, ok := edges[[2]int{tris[t].verts[(i+1)%3], tris[t].verts[i]}]; ok {
+				tris[t].neighbors[i] = n
+			}
+		}
+	}
+
+	// Groups the triangles around each vertex
+	groups := make([]*mikkGroup, 0)
+	var assign func(t int, g *mikkGroup) bool
+	assign = func(t int, g *mikkGroup) bool {
+		tri := &tris[t]
+		i := tri.corner(g.vertex)
+		if i < 0 {
+			return false
+		}
+		if tri.groups[i] == g {
+			return true
+		}
+		if tri.groups[i] != nil {
+			return false
+		}
+		// The first group of a triangle with degenerate texture coordinates sets its orientation
+		if tri.withAny && tri.groups[0] == nil && tri.groups[1] == nil && tri.groups[2] == nil {
+			tri.preserving = g.preserving
+		}
+		if tri.preserving != g.preserving {
+			return false
+		}
+		g.faces = append(g.faces, t)
+		tri.groups[i] = g
+		left, right := tri.neighbors[(i+2)%3], tri.neighbors[i]
+		if tri.preserving {
+			left, right = right, left
+		}
+		if left >= 0 {
+			assign(left, g)
+		}
+		if right >= 0 {
+			assign(right, g)
+		}
+		return true
+	}
+	for t := range tris {
+		if tris[t].degenerate {
+			continue
+		}
+		for i := 0; i < 3; i++ {
+			if tris[t].groups[i] == nil {
+				g := &mikkGroup{vertex: tris[t].verts[i], preserving: tris[t].preserving}
+				groups = append(groups, g)
+				assign(t, g)
+			}
+		}
+	}
+
+	// Calculates the tangent of each corner from the triangles of its group with similar tangents
+	var os, ot []math32.Vector3
+	for _, g := range groups {
+		os = os[:0]
+		ot = ot[:0]
+		for _, t := range g.faces {
+			n := &normals[elements[t*3+tris[t].corner(g.vertex)]]
+			os = append(os, *projectNormalized(&tris[t].os, n))
+			ot = append(ot, *projectNormalized(&tris[t].ot, n))
+		}
+		for k, f := range g.faces {
+			var sum math32.Vector3
+			for j, t := range g.faces {
+				withAny := tris[f].withAny || tris[t].withAny
+				if !withAny && f != t && (os[k].Dot(&os[j]) <= -1 || ot[k].Dot(&ot[j]) <= -1) {
+					continue
+				}
+				// Each triangle contributes with the angle of its corner in the normal plane
+				i := tris[t].corner(g.vertex)
+				p0 := &positions[elements[t*3+(i+2)%3]]
+				p1 := &positions[elements[t*3+i]]
+				p2 := &positions[elements[t*3+(i+1)%3]]
+				n := &normals[elements[t*3+i]]
+				var e1, e2 math32.Vector3
+				e1.SubVectors(p0, p1)
+				e2.SubVectors(p2, p1)
+				angle := math32.Acos(math32.Clamp(projectNormalized(&e1, n).Dot(projectNormalized(&e2, n)), -1, 1))
+				sum.Add(os[j].Clone().MultiplyScalar(angle))
+			}
+			c := f*3 + tris[f].corner(g.vertex)
+			tangents[c] = *sum.Normalize()
+			signs[c] = -1
+			if g.preserving {
+				signs[c] = 1
+			}
+		}
+	}
+
+	// Corners of degenerate triangles use the tangent of a corner of another triangle with the same vertex
+	first := make(map[int]int)
+	for c := 0; c < corners; c++ {
+		if !tris[c/3].degenerate {
+			if _, ok := first[verts[c]]; !ok {
+				first[verts[c]] = c
+			}
+		}
+	}
+	for c := 0; c < corners; c++ {
+		if tris[c/3].degenerate {
+			if src, ok := first[verts[c]]; ok {
+				tangents[c] = tangents[src]
+				signs[c] = signs[src]
+			}
+		}
+	}
+	return tangents, signs
+}
+
+// corner returns the index of the corner of the triangle with the specified merged vertex or -1.
+func (tri *mikkTriangle) corner(vertex int) int {
+
+	for i, v := range tri.verts {
+		if v == vertex {
+			return i
+		}
+	}
+	return -1
+}
+
+// projectNormalized returns a new vector with the specified vector projected on the
+// plane perpendicular to the specified unit normal and normalized, if not zero.
+func projectNormalized(v, n *math32.Vector3) *math32.Vector3 {
+
+	p := v.Clone()
+	p.Sub(n.Clone().MultiplyScalar(n.Dot(v)))
+	return p.Normalize()
+}
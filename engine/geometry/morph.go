@@ -15,15 +15,49 @@ import (
 )
 
 // MorphGeometry represents a base geometry and its morph targets.
+// Some targets are blended by the GPU in the vertex shader, as many as the vertex attributes
+// not used by the base geometry allow, up to MaxActiveMorphTargets.
+// When more targets have non-zero weights, the targets with the largest weights are blended
+// by the GPU and the remaining ones are summed by the CPU, considering only the vertices they
+// move, into one of the GPU slots, so any number of targets can be active at the same time.
 type MorphGeometry struct {
-	baseGeometry *Geometry   // The base geometry
-	targets      []*Geometry // The morph target geometries (containing deltas)
-	weights      []float32   // The weights for each morph target
-	uniWeights   gls.Uniform // Texture unit uniform location cache
-	morphGeom    *Geometry   // Cache of the last CPU-morphed geometry
+	baseGeometry *Geometry         // The base geometry
+	targets      []*Geometry       // The morph target geometries (containing deltas)
+	names        []string          // The names of the morph targets
+	weights      []float32         // The weights for each morph target
+	defaults     []float32         // The default weights for each morph target
+	positions    []math32.ArrayF32 // Position deltas of each morph target
+	normals      []math32.ArrayF32 // Normal deltas of each morph target or nil
+	sparse       [][]uint32        // Vertices moved by each morph target
+	slots        []morphSlot       // GPU morph target slots
+	maxSlots     int               // Number of GPU slots allowed by the vertex attributes or -1 if unknown
+	slotWeights  []float32         // Weights of the GPU slots
+	residual     morphResidual     // Morph targets summed by the CPU
+	uniWeights   gls.Uniform       // Weights uniform location cache
 }
 
-// MaxActiveMorphTargets is the maximum number of active morph targets.
+// morphSlot is one of the morph targets blended by the GPU.
+type morphSlot struct {
+	target    int      // Index of the morph target in the slot or -1
+	positions *gls.VBO // Position deltas VBO
+	normals   *gls.VBO // Normal deltas VBO or nil
+}
+
+// morphResidual contains the sum of the morph targets which don't fit in the GPU slots.
+type morphResidual struct {
+	slot      int             // Index of the slot with the sum or -1
+	targets   []int           // Summed morph targets
+	weights   []float32       // Weights of the summed morph targets
+	positions math32.ArrayF32 // Sum of the position deltas
+	normals   math32.ArrayF32 // Sum of the normal deltas
+}
+
+// MaxActiveMorphTargets is the maximum number of morph targets blended by the GPU.
+// Each one uses a vertex attribute for its position deltas and, if the targets have normals,
+// another one for its normal deltas. The vertex attributes available are shared with the
+// attributes of the base geometry, such as its positions, normals, texture coordinates and
+// skin weights, so the number of targets blended by the GPU is calculated for each geometry
+// from the maximum number of vertex attributes of the OpenGL context, which is at least 8.
 const MaxActiveMorphTargets = 8
 
 // NewMorphGeometry creates and returns a pointer to a new MorphGeometry.
@@ -34,8 +68,9 @@ func NewMorphGeometry(baseGeometry *Geometry) *MorphGeometry {
 
 	mg.targets = make([]*Geometry, 0)
 	mg.weights = make([]float32, 0)
+	mg.residual.slot = -1
+	mg.maxSlots = -1
 
-	mg.baseGeometry.ShaderDefines.Set("MORPHTARGETS", strconv.Itoa(MaxActiveMorphTargets))
 	mg.uniWeights.Init("morphTargetInfluences")
 	return mg
 }
@@ -46,13 +81,47 @@ func (mg *MorphGeometry) GetGeometry() *Geometry {
 	return mg.baseGeometry
 }
 
+// TargetCount returns the number of morph targets.
+func (mg *MorphGeometry) TargetCount() int {
+
+	return len(mg.targets)
+}
+
+// SetTargetNames sets the names of the morph targets in order.
+func (mg *MorphGeometry) SetTargetNames(names []string) {
+
+	for i := range mg.names {
+		mg.names[i] = ""
+		if i < len(names) {
+			mg.names[i] = names[i]
+		}
+	}
+}
+
+// TargetNames returns the names of the morph targets in order.
+func (mg *MorphGeometry) TargetNames() []string {
+
+	return mg.names
+}
+
+// TargetIndex returns the index of the morph target with the specified name or -1 if not found.
+func (mg *MorphGeometry) TargetIndex(name string) int {
+
+	for i, n := range mg.names {
+		if n == name {
+			return i
+		}
+	}
+	return -1
+}
+
 // SetWeights sets the morph target weights.
 func (mg *MorphGeometry) SetWeights(weights []float32) {
 
 	if len(weights) != len(mg.weights) {
 		panic("weights have invalid length")
 	}
-	mg.weights = weights
+	copy(mg.weights, weights)
 }
 
 // Weights returns the morph target weights.
@@ -61,12 +130,57 @@ func (mg *MorphGeometry) Weights() []float32 {
 	return mg.weights
 }
 
+// SetWeight sets the weight of the morph target with the specified index.
+func (mg *MorphGeometry) SetWeight(idx int, weight float32) {
+
+	mg.weights[idx] = weight
+}
+
+// Weight returns the weight of the morph target with the specified index.
+func (mg *MorphGeometry) Weight(idx int) float32 {
+
+	return mg.weights[idx]
+}
+
+// SetWeightByName sets the weight of the morph target with the specified name
+// and returns false if there is no morph target with that name.
+func (mg *MorphGeometry) SetWeightByName(name string, weight float32) bool {
+
+	idx := mg.TargetIndex(name)
+	if idx < 0 {
+		return false
+	}
+	mg.weights[idx] = weight
+	return true
+}
+
+// SetDefaultWeights sets the default morph target weights and the current weights to them.
+func (mg *MorphGeometry) SetDefaultWeights(weights []float32) {
+
+	if len(weights) != len(mg.defaults) {
+		panic("weights have invalid length")
+	}
+	copy(mg.defaults, weights)
+	copy(mg.weights, weights)
+}
+
+// DefaultWeights returns the default morph target weights.
+func (mg *MorphGeometry) DefaultWeights() []float32 {
+
+	return mg.defaults
+}
+
+// ResetWeights sets the morph target weights to the default weights.
+func (mg *MorphGeometry) ResetWeights() {
+
+	copy(mg.weights, mg.defaults)
+}
+
 // AddMorphTargets add multiple morph targets to the morph geometry.
 // Morph target deltas are calculated internally and the morph target geometries are altered to hold the deltas instead.
 func (mg *MorphGeometry) AddMorphTargets(morphTargets ...*Geometry) {
 
 	for i := range morphTargets {
-		mg.weights = append(mg.weights, 0)
 		// Calculate deltas for VertexPosition
 		vertexIdx := 0
 		baseVertices := mg.baseGeometry.VBO(gls.VertexPosition).Buffer()
@@ -93,58 +207,165 @@ func (mg *MorphGeometry) AddMorphTargets(morphTargets ...*Geometry) {
 		}
 		// TODO Calculate deltas for VertexTangents
 	}
-	mg.targets = append(mg.targets, morphTargets...)
-
-	// Update all target attributes if we have few enough that we are able to send them
-	// all to the shader without sorting and choosing the ones with highest current weight
-	if len(mg.targets) <= MaxActiveMorphTargets {
-		mg.UpdateTargetAttributes(mg.targets)
-	}
-
+	mg.AddMorphTargetDeltas(morphTargets...)
 }
 
 // AddMorphTargetDeltas add multiple morph target deltas to the morph geometry.
 func (mg *MorphGeometry) AddMorphTargetDeltas(morphTargetDeltas ...*Geometry) {
 
-	for range morphTargetDeltas {
+	for _, mt := range morphTargetDeltas {
+		mg.targets = append(mg.targets, mt)
+		mg.names = append(mg.names, "")
 		mg.weights = append(mg.weights, 0)
+		mg.defaults = append(mg.defaults, 0)
+
+		// Keeps the deltas as contiguous vectors and the vertices they move
+		positions := deltaArray(mt, gls.VertexPosition)
+		var normals math32.ArrayF32
+		if mg.baseGeometry.VBO(gls.VertexNormal) != nil {
+			normals = deltaArray(mt, gls.VertexNormal)
+		}
+		var moved []uint32
+		for v := 0; v < len(positions)/3; v++ {
+			p := positions[3*v : 3*v+3]
+			nonZero := p[0] != 0 || p[1] != 0 || p[2] != 0
+			if normals != nil {
+				n := normals[3*v : 3*v+3]
+				nonZero = nonZero || n[0] != 0 || n[1] != 0 || n[2] != 0
+			}
+			if nonZero {
+				moved = append(moved, uint32(v))
+			}
+		}
+		mg.positions = append(mg.positions, positions)
+		mg.normals = append(mg.normals, normals)
+		mg.sparse = append(mg.sparse, moved)
+	}
+	mg.updateSlots()
+}
+
+// deltaArray returns the array with the vectors of the specified attribute of the specified
+// morph target, which is the VBO buffer itself if it doesn't have other attributes.
+func deltaArray(mt *Geometry, atype gls.AttribType) math32.ArrayF32 {
+
+	vbo := mt.VBO(atype)
+	if vbo == nil {
+		return nil
+	}
+	if vbo.AttribCount() == 1 {
+		return *vbo.Buffer()
+	}
+	deltas := math32.NewArrayF32(0, 0)
+	vbo.ReadVectors3(atype, func(v math32.Vector3) bool {
+		deltas.AppendVector3(&v)
+		return false
+	})
+	return deltas
+}
+
+// SetupDefines calculates the number of morph targets which can be blended by the GPU
+// in the specified OpenGL context, from the vertex attributes not used by the base geometry,
+// and sets the shader defines of the base geometry accordingly.
+// It is called by the renderer before choosing the shader program of the geometry.
+func (mg *MorphGeometry) SetupDefines(gs *gls.GLS) {
+
+	if mg.maxSlots >= 0 || len(mg.targets) == 0 {
+		return
+	}
+	used := 0
+	for _, vbo := range mg.baseGeometry.VBOs() {
+		used += vbo.AttribCount()
+	}
+	perSlot := 1
+	if mg.hasNormals() {
+		perSlot = 2
+	}
+	mg.maxSlots = (gs.GetInteger(gls.MAX_VERTEX_ATTRIBS) - used) / perSlot
+	if mg.maxSlots > MaxActiveMorphTargets {
+		mg.maxSlots = MaxActiveMorphTargets
 	}
-	mg.targets = append(mg.targets, morphTargetDeltas...)
+	if mg.maxSlots < 0 {
+		mg.maxSlots = 0
+	}
+	mg.updateSlots()
+}
 
-	// Update all target attributes if we have few enough that we are able to send them
-	// all to the shader without sorting and choosing the ones with highest current weight
-	if len(mg.targets) <= MaxActiveMorphTargets {
-		mg.UpdateTargetAttributes(mg.targets)
+// hasNormals returns whether any morph target has normal deltas.
+func (mg *MorphGeometry) hasNormals() bool {
+
+	for _, n := range mg.normals {
+		if n != nil {
+			return true
+		}
 	}
+	return false
 }
 
-// ActiveMorphTargets sorts the morph targets by weight and returns the top n morph targets with largest weight.
+// updateSlots creates the GPU slots needed by the current morph targets, once the number
+// of slots allowed by the OpenGL context is known, and updates the shader defines.
+func (mg *MorphGeometry) updateSlots() {
+
+	if mg.maxSlots < 0 {
+		return
+	}
+	hasNormals := mg.hasNormals()
+	for len(mg.slots) < len(mg.targets) && len(mg.slots) < mg.maxSlots {
+		idx := strconv.Itoa(len(mg.slots))
+		slot := morphSlot{target: -1}
+		slot.positions = gls.NewVBO(math32.NewArrayF32(0, 0)).AddCustomAttrib("MorphPosition"+idx, 3)
+		mg.slots = append(mg.slots, slot)
+		mg.slotWeights = append(mg.slotWeights, 0)
+	}
+	if hasNormals {
+		for i := range mg.slots {
+			if mg.slots[i].normals == nil {
+				mg.slots[i].normals = gls.NewVBO(math32.NewArrayF32(0, 0)).AddCustomAttrib("MorphNormal"+strconv.Itoa(i), 3)
+				mg.slots[i].target = -1
+			}
+		}
+		mg.baseGeometry.ShaderDefines.Set("MORPHTARGETS_NORMAL", "")
+	}
+	if len(mg.slots) > 0 {
+		mg.baseGeometry.ShaderDefines.Set("MORPHTARGETS", strconv.Itoa(len(mg.slots)))
+	}
+}
+
+// ActiveMorphTargets returns the morph targets with non-zero weights sorted by decreasing
+// weight magnitude and their weights. At most as many targets as the GPU blends are returned,
+// or MaxActiveMorphTargets before the geometry is rendered.
 func (mg *MorphGeometry) ActiveMorphTargets() ([]*Geometry, []float32) {
 
-	numTargets := len(mg.targets)
-	if numTargets == 0 {
-		return nil, nil
+	order := mg.activeOrder()
+	limit := MaxActiveMorphTargets
+	if mg.maxSlots >= 0 {
+		limit = mg.maxSlots
 	}
+	if len(order) > limit {
+		order = order[:limit]
+	}
+	targets := make([]*Geometry, len(order))
+	weights := make([]float32, len(order))
+	for i, t := range order {
+		targets[i] = mg.targets[t]
+		weights[i] = mg.weights[t]
+	}
+	return targets, weights
+}
 
-	if numTargets <= MaxActiveMorphTargets {
-		// No need to sort - just return the targets and weights directly
-		return mg.targets, mg.weights
-	} else {
-		// Need to sort them by weight and only return the top N morph targets with largest weight (N = MaxActiveMorphTargets)
-		// TODO test this (more than [MaxActiveMorphTargets] morph targets)
-		sortedMorphTargets := make([]*Geometry, numTargets)
-		copy(sortedMorphTargets, mg.targets)
-		sort.Slice(sortedMorphTargets, func(i, j int) bool {
-			return mg.weights[i] > mg.weights[j]
-		})
+// activeOrder returns the indices of the morph targets with non-zero
+// weights sorted by decreasing weight magnitude.
+func (mg *MorphGeometry) activeOrder() []int {
 
-		sortedWeights := make([]float32, numTargets)
-		copy(sortedWeights, mg.weights)
-		sort.Slice(sortedWeights, func(i, j int) bool {
-			return mg.weights[i] > mg.weights[j]
-		})
-		return sortedMorphTargets, sortedWeights
+	order := make([]int, 0, len(mg.targets))
+	for i, w := range mg.weights {
+		if w != 0 {
+			order = append(order, i)
+		}
 	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return math32.Abs(mg.weights[order[i]]) > math32.Abs(mg.weights[order[j]])
+	})
+	return order
 }
 
 // SetIndices sets the indices array for this geometry.
@@ -162,8 +383,51 @@ func (mg *MorphGeometry) SetIndices(indices math32.ArrayU32) {
 // if so desired (loosing morphing ability).
 func (mg *MorphGeometry) ComputeMorphed(weights []float32) *Geometry {
 
+	base := mg.baseGeometry
 	morphed := NewGeometry()
-	// TODO
+	count := len(*base.VBO(gls.VertexPosition).Buffer()) / base.VBO(gls.VertexPosition).Stride()
+	order := make([]uint32, count)
+	for i := range order {
+		order[i] = uint32(i)
+	}
+	base.copyVertices(morphed, order)
+	if base.Indexed() {
+		morphed.SetIndices(append(math32.NewArrayU32(0, len(base.indices)), base.indices...))
+	}
+	morphed.AddGroupList(base.groups)
+	morphed.ShaderDefines.Add(&base.ShaderDefines)
+	morphed.ShaderDefines.Unset("MORPHTARGETS")
+	morphed.ShaderDefines.Unset("MORPHTARGETS_NORMAL")
+
+	// Adds the weighted deltas of the vertices moved by each target
+	for t, w := range weights {
+		if w == 0 || t >= len(mg.targets) {
+			continue
+		}
+		for _, d := range []struct {
+			atype  gls.AttribType
+			deltas math32.ArrayF32
+		}{{gls.VertexPosition, mg.positions[t]}, {gls.VertexNormal, mg.normals[t]}} {
+			vbo := morphed.VBO(d.atype)
+			if vbo == nil || d.deltas == nil {
+				continue
+			}
+			buf := *vbo.Buffer()
+			stride := vbo.Stride()
+			offset := vbo.AttribOffset(d.atype)
+			for _, v := range mg.sparse[t] {
+				for k := 0; k < 3; k++ {
+					buf[int(v)*stride+offset+k] += w * d.deltas[3*int(v)+k]
+				}
+			}
+		}
+	}
+	if vbo := morphed.VBO(gls.VertexNormal); vbo != nil {
+		vbo.OperateOnVectors3(gls.VertexNormal, func(n *math32.Vector3) bool {
+			n.Normalize()
+			return false
+		})
+	}
 	return morphed
 }
 
@@ -175,40 +439,168 @@ func (mg *MorphGeometry) Dispose() {
 	for i := range mg.targets {
 		mg.targets[i].Dispose()
 	}
+	for _, slot := range mg.slots {
+		slot.positions.Dispose()
+		if slot.normals != nil {
+			slot.normals.Dispose()
+		}
+	}
+}
+
+// assignSlot sets the morph target in the specified GPU slot,
+// which transfers its deltas the next time the slot is rendered.
+func (mg *MorphGeometry) assignSlot(idx, target int) {
+
+	slot := &mg.slots[idx]
+	slot.target = target
+	slot.positions.SetBuffer(mg.positions[target])
+	if slot.normals != nil {
+		normals := mg.normals[target]
+		if normals == nil {
+			normals = math32.NewArrayF32(len(mg.positions[target]), len(mg.positions[target]))
+			mg.normals[target] = normals
+		}
+		slot.normals.SetBuffer(normals)
+	}
+	if mg.residual.slot == idx {
+		mg.residual.slot = -1
+	}
+}
+
+// updateResidual sets the sum of the specified morph targets with their current weights
+// in the specified GPU slot, recomputing it only if the targets or the weights changed.
+func (mg *MorphGeometry) updateResidual(idx int, targets []int) {
+
+	res := &mg.residual
+	changed := res.slot != idx || len(res.targets) != len(targets)
+	for i := 0; !changed && i < len(targets); i++ {
+		changed = res.targets[i] != targets[i] || res.weights[i] != mg.weights[targets[i]]
+	}
+	if !changed {
+		return
+	}
+	res.targets = append(res.targets[:0], targets...)
+	res.weights = res.weights[:0]
+	for _, t := range targets {
+		res.weights = append(res.weights, mg.weights[t])
+	}
+
+	// Sums the deltas of the vertices moved by each target
+	size := len(mg.positions[targets[0]])
+	if len(res.positions) != size {
+		res.positions = math32.NewArrayF32(size, size)
+		res.normals = math32.NewArrayF32(size, size)
+	} else {
+		for i := range res.positions {
+			res.positions[i] = 0
+			res.normals[i] = 0
+		}
+	}
+	for _, t := range targets {
+		w := mg.weights[t]
+		positions := mg.positions[t]
+		normals := mg.normals[t]
+		for _, v := range mg.sparse[t] {
+			for k := 3 * int(v); k < 3*int(v)+3; k++ {
+				res.positions[k] += w * positions[k]
+				if normals != nil {
+					res.normals[k] += w * normals[k]
+				}
+			}
+		}
+	}
+
+	slot := &mg.slots[idx]
+	slot.target = -1
+	slot.positions.SetBuffer(res.positions)
+	if slot.normals != nil {
+		slot.normals.SetBuffer(res.normals)
+	}
+	res.slot = idx
 }
 
-// UpdateTargetAttributes updates the attribute names of the specified morph targets in order.
-func (mg *MorphGeometry) UpdateTargetAttributes(morphTargets []*Geometry) {
+// updateWeights assigns the morph targets with the largest weights to the GPU slots, keeping
+// the targets which are already in a slot, and sums the remaining active targets in a slot.
+func (mg *MorphGeometry) updateWeights() {
+
+	n := len(mg.slots)
+	if len(mg.targets) <= n {
+		// All the targets fit in the slots
+		for i := range mg.slots {
+			if mg.slots[i].target != i {
+				mg.assignSlot(i, i)
+			}
+			mg.slotWeights[i] = mg.weights[i]
+		}
+		return
+	}
 
-	for i, mt := range morphTargets {
-		mt.SetAttributeName(gls.VertexPosition, "MorphPosition"+strconv.Itoa(i))
-		mt.SetAttributeName(gls.VertexNormal, "MorphNormal"+strconv.Itoa(i))
-		mt.SetAttributeName(gls.VertexTangent, "MorphTangent"+strconv.Itoa(i))
+	order := mg.activeOrder()
+	var rest []int
+	if len(order) > n {
+		rest = order[n-1:]
+		order = order[:n-1]
+	}
+	wanted := make(map[int]bool, len(order))
+	for _, t := range order {
+		wanted[t] = true
+	}
+	free := make([]int, 0, n)
+	for i, slot := range mg.slots {
+		if slot.target >= 0 && wanted[slot.target] {
+			mg.slotWeights[i] = mg.weights[slot.target]
+			delete(wanted, slot.target)
+		} else {
+			mg.slotWeights[i] = 0
+			free = append(free, i)
+		}
+	}
+	// The residual stays in its slot if it's still free
+	resSlot := -1
+	if len(rest) > 0 {
+		resSlot = free[len(free)-1]
+		for k, i := range free {
+			if i == mg.residual.slot {
+				resSlot = i
+			}
+			if i == resSlot {
+				free = append(free[:k:k], free[k+1:]...)
+				break
+			}
+		}
+	}
+	for _, t := range order {
+		if wanted[t] {
+			mg.assignSlot(free[0], t)
+			mg.slotWeights[free[0]] = mg.weights[t]
+			free = free[1:]
+		}
+	}
+	if resSlot >= 0 {
+		mg.updateResidual(resSlot, rest)
+		mg.slotWeights[resSlot] = 1
 	}
 }
 
 // RenderSetup is called by the renderer before drawing the geometry.
 func (mg *MorphGeometry) RenderSetup(gs *gls.GLS) {
 
+	mg.SetupDefines(gs)
 	mg.baseGeometry.RenderSetup(gs)
-
-	// Sort weights and find top 8 morph targets with largest current weight (8 is the max sent to shader)
-	activeMorphTargets, activeWeights := mg.ActiveMorphTargets()
-
-	// If the morph geometry has more targets than the shader supports we need to update attribute names
-	// as weights change - we only send the top morph targets with highest weights
-	if len(mg.targets) > MaxActiveMorphTargets {
-		mg.UpdateTargetAttributes(activeMorphTargets)
+	if len(mg.slots) == 0 {
+		return
 	}
 
-	// Transfer morphed geometry VBOs
-	for _, mt := range activeMorphTargets {
-		for _, vbo := range mt.VBOs() {
-			vbo.Transfer(gs)
+	// Transfer the deltas of the GPU slots with the vertex array of the base geometry bound
+	mg.updateWeights()
+	for _, slot := range mg.slots {
+		slot.positions.Transfer(gs)
+		if slot.normals != nil {
+			slot.normals.Transfer(gs)
 		}
 	}
 
-	// Transfer active weights uniform
+	// Transfer slot weights uniform
 	location := mg.uniWeights.Location(gs)
-	gs.Uniform1fv(gl.Uniform{Value: location}, int32(len(activeWeights)), activeWeights)
+	gs.Uniform1fv(gl.Uniform{Value: location}, int32(len(mg.slotWeights)), mg.slotWeights)
 }
@@ -0,0 +1,216 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package geometry
+
+import (
+	"github.com/wangzun/gogame/engine/gls"
+	"github.com/wangzun/gogame/engine/math32"
+)
+
+// ComputeVertexNormals computes the normals of the vertices of the triangles of the geometry
+// as the average of the normals of the triangles which share them weighted by their areas,
+// replacing the existing normals or adding a VBO with them.
+// Triangles which don't share vertices get flat normals.
+func (g *Geometry) ComputeVertexNormals() {
+
+	count := g.VertexCount()
+	if count == 0 {
+		return
+	}
+	positions := g.attribValues(gls.VertexPosition)
+	elements := g.ElementIndices()
+
+	// The cross product of the edges is the face normal scaled by twice the area
+	normals := make([]math32.Vector3, count)
+	var e1, e2, n math32.Vector3
+	for i := 0; i+2 < len(elements); i += 3 {
+		a, b, c := elements[i], elements[i+1], elements[i+2]
+		e1.SubVectors(&positions[b], &positions[a])
+		e2.SubVectors(&positions[c], &positions[a])
+		n.CrossVectors(&e1, &e2)
+		normals[a].Add(&n)
+		normals[b].Add(&n)
+		normals[c].Add(&n)
+	}
+	for i := range normals {
+		normals[i].Normalize()
+	}
+	g.setAttribValues(gls.VertexNormal, normals, nil)
+}
+
+// ComputeFlatNormals converts the geometry into a non-indexed geometry and
+// sets the normals of the vertices of each triangle to the triangle normal.
+func (g *Geometry) ComputeFlatNormals() {
+
+	g.ToNonIndexed()
+	g.ComputeVertexNormals()
+}
+
+// ComputeTangents computes the tangents of the vertices of the triangles of the geometry from
+// their positions, normals and texture coordinates using the MikkTSpace algorithm, as needed
+// for normal mapping with the normal maps baked by most tools, replacing the existing tangents
+// or adding a VBO with them. The W component has the sign of the bitangent.
+// Shared vertices of indexed geometries whose corners get different tangents, as on texture
+// mirroring seams, are duplicated. Vertices without a tangent, such as those of triangles
+// with degenerate texture coordinates only, get any tangent perpendicular to their normal.
+// The geometry must have positions, normals and texture coordinates.
+func (g *Geometry) ComputeTangents() {
+
+	count := g.VertexCount()
+	if count == 0 || g.VBO(gls.VertexNormal) == nil || g.VBO(gls.VertexTexcoord) == nil {
+		return
+	}
+	positions := g.attribValues(gls.VertexPosition)
+	normals := g.attribValues(gls.VertexNormal)
+	uvs := g.attribValues(gls.VertexTexcoord)
+	elements := g.ElementIndices()
+	tangents, signs := mikkTangents(positions, normals, uvs, elements)
+
+	// Assigns the tangents of the corners to their vertices, duplicating
+	// the vertices whose corners have different tangents
+	type tangentSpace struct {
+		tangent math32.Vector3
+		sign    float32
+	}
+	spaces := make([]tangentSpace, count)
+	assigned := make([]bool, count)
+	order := make([]uint32, count)
+	for i := range order {
+		order[i] = uint32(i)
+	}
+	type vertexSpace struct {
+		vertex uint32
+		space  tangentSpace
+	}
+	copies := make(map[vertexSpace]uint32)
+	for c := range tangents {
+		ts := tangentSpace{tangents[c], signs[c]}
+		v := elements[c]
+		if !assigned[v] {
+			spaces[v] = ts
+			assigned[v] = true
+			continue
+		}
+		if spaces[v] == ts || !g.Indexed() {
+			continue
+		}
+		dup, ok := copies[vertexSpace{v, ts}]
+		if !ok {
+			dup = uint32(len(order))
+			order = append(order, v)
+			spaces = append(spaces, ts)
+			normals = append(normals, normals[v])
+			copies[vertexSpace{v, ts}] = dup
+		}
+		g.indices[c] = dup
+	}
+	if len(order) > count {
+		g.gatherVertices(order)
+		g.SetIndices(g.indices)
+	}
+
+	result := make([]math32.Vector3, len(spaces))
+	handedness := make([]float32, len(spaces))
+	for i := range spaces {
+		t := spaces[i].tangent
+		handedness[i] = spaces[i].sign
+		if t.LengthSq() < 1e-12 {
+			// Any vector perpendicular to the normal
+			n := &normals[i]
+			t.Set(1, 0, 0)
+			if math32.Abs(n.X) > 0.9 {
+				t.Set(0, 1, 0)
+			}
+			t.Sub(n.Clone().MultiplyScalar(n.Dot(&t))).Normalize()
+			handedness[i] = 1
+		}
+		result[i] = t
+	}
+	g.setAttribValues(gls.VertexTangent, result, handedness)
+}
+
+// ApplyMatrix4 transforms the positions of the geometry by the specified matrix
+// and its normals and tangents by the corresponding rotation.
+func (g *Geometry) ApplyMatrix4(m *math32.Matrix4) {
+
+	g.OperateOnVertices(func(vertex *math32.Vector3) bool {
+		vertex.ApplyMatrix4(m)
+		return false
+	})
+	var normalMatrix, rotation math32.Matrix3
+	normalMatrix.GetNormalMatrix(m)
+	rotation.SetFromMatrix4(m)
+	g.OperateOnVertexNormals(func(normal *math32.Vector3) bool {
+		normal.ApplyMatrix3(&normalMatrix).Normalize()
+		return false
+	})
+	if vbo := g.VBO(gls.VertexTangent); vbo != nil {
+		vbo.OperateOnVectors3(gls.VertexTangent, func(tangent *math32.Vector3) bool {
+			tangent.ApplyMatrix3(&rotation).Normalize()
+			return false
+		})
+	}
+}
+
+// attribValues returns the values of the first three elements of the specified attribute of all the vertices.
+func (g *Geometry) attribValues(atype gls.AttribType) []math32.Vector3 {
+
+	vbo := g.VBO(atype)
+	if vbo == nil {
+		return nil
+	}
+	buf := *vbo.Buffer()
+	stride := vbo.Stride()
+	attrib := vbo.Attrib(atype)
+	offset := int(attrib.ByteOffset) / 4
+	size := int(attrib.NumElements)
+	if size > 3 {
+		size = 3
+	}
+	values := make([]math32.Vector3, buf.Size()/stride)
+	for i := range values {
+		for k := 0; k < size; k++ {
+			values[i].SetComponent(k, buf[i*stride+offset+k])
+		}
+	}
+	return values
+}
+
+// setAttribValues sets the values of the specified attribute of all the vertices, with the
+// optional fourth element, in its VBO or in a new VBO if the geometry doesn't have the attribute.
+func (g *Geometry) setAttribValues(atype gls.AttribType, values []math32.Vector3, w []float32) {
+
+	vbo := g.VBO(atype)
+	if vbo == nil {
+		size := 3
+		if w != nil {
+			size = 4
+		}
+		vbo = gls.NewVBO(math32.NewArrayF32(len(values)*size, len(values)*size)).AddAttrib(atype)
+		vbo.Attrib(atype).NumElements = int32(size)
+		g.AddVBO(vbo)
+	}
+	buf := *vbo.Buffer()
+	stride := vbo.Stride()
+	// Grows buffers which don't have the attribute for all the vertices
+	if buf.Size() < len(values)*stride {
+		grown := math32.NewArrayF32(len(values)*stride, len(values)*stride)
+		copy(grown, buf)
+		buf = grown
+		vbo.SetBuffer(buf)
+	}
+	attrib := vbo.Attrib(atype)
+	offset := int(attrib.ByteOffset) / 4
+	for i := range values {
+		pos := i*stride + offset
+		for k := 0; k < 3 && k < int(attrib.NumElements); k++ {
+			buf[pos+k] = values[i].Component(k)
+		}
+		if w != nil && attrib.NumElements > 3 {
+			buf[pos+3] = w[i]
+		}
+	}
+	vbo.Update()
+}
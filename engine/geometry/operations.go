@@ -0,0 +1,408 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package geometry
+
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/wangzun/gogame/engine/gls"
+	"github.com/wangzun/gogame/engine/math32"
+)
+
+// VertexCount returns the number of vertices of the geometry from its position VBO.
+func (g *Geometry) VertexCount() int {
+
+	vbo := g.VBO(gls.VertexPosition)
+	if vbo == nil || vbo.Stride() == 0 {
+		return 0
+	}
+	return vbo.Buffer().Size() / vbo.Stride()
+}
+
+// ElementIndices returns the indices of the vertices of the geometry elements,
+// which are the geometry indices when indexed or the sequence of all vertices otherwise.
+func (g *Geometry) ElementIndices() math32.ArrayU32 {
+
+	if g.Indexed() {
+		return g.indices
+	}
+	count := g.VertexCount()
+	indices := math32.NewArrayU32(count, count)
+	for i := range indices {
+		indices[i] = uint32(i)
+	}
+	return indices
+}
+
+// ToNonIndexed converts an indexed geometry into a non-indexed one, duplicating
+// the vertices shared by several elements. The groups are kept unchanged.
+func (g *Geometry) ToNonIndexed() {
+
+	if !g.Indexed() {
+		return
+	}
+	g.gatherVertices(g.indices)
+	g.SetIndices(math32.NewArrayU32(0, 0))
+}
+
+// ToIndexed converts a non-indexed geometry into an indexed one, sharing the
+// vertices with exactly the same attributes. It is the same as Weld(0).
+func (g *Geometry) ToIndexed() {
+
+	g.Weld(0)
+}
+
+// Weld merges the vertices of the geometry whose attributes all differ by at most the specified
+// tolerance, making the geometry indexed, and removes the triangles which became degenerate.
+// Vertices are merged transitively, so chains of close vertices are merged into one even if their
+// ends are farther apart. A zero tolerance merges only vertices with identical attributes.
+// Merged vertices keep the attributes of the first one.
+// The geometry must be made of triangles and its groups are updated.
+func (g *Geometry) Weld(tolerance float32) {
+
+	elements := g.ElementIndices()
+	count := g.VertexCount()
+	if count == 0 {
+		return
+	}
+	var remap, order []uint32
+	if tolerance > 0 {
+		remap, order = g.weldClose(count, tolerance)
+	} else {
+		remap, order = g.weldIdentical(count)
+	}
+
+	// Remaps the triangles removing the degenerate ones, counting the ones kept before each element
+	indices := math32.NewArrayU32(0, len(elements))
+	kept := make([]int, len(elements)/3+1)
+	for i := 0; i+2 < len(elements); i += 3 {
+		a := remap[elements[i]]
+		b := remap[elements[i+1]]
+		c := remap[elements[i+2]]
+		kept[i/3] = len(indices)
+		if a != b && b != c && c != a {
+			indices.Append(a, b, c)
+		}
+	}
+	kept[len(kept)-1] = len(indices)
+	keptAt := func(element int) int {
+		if element/3 >= len(kept) {
+			return kept[len(kept)-1]
+		}
+		return kept[element/3]
+	}
+	for i := range g.groups {
+		gr := &g.groups[i]
+		start := keptAt(gr.Start)
+		gr.Count = keptAt(gr.Start+gr.Count) - start
+		gr.Start = start
+	}
+
+	g.gatherVertices(order)
+	g.SetIndices(indices)
+}
+
+// weldIdentical returns the index of the merged vertex of each vertex and the first
+// vertex of each merged vertex, merging the vertices with identical attributes.
+func (g *Geometry) weldIdentical(count int) ([]uint32, []uint32) {
+
+	remap := make([]uint32, count)
+	order := make([]uint32, 0, count)
+	unique := make(map[string]uint32, count)
+	key := make([]byte, 0, 64)
+	for v := 0; v < count; v++ {
+		key = key[:0]
+		for _, vbo := range g.vbos {
+			buf := *vbo.Buffer()
+			stride := vbo.Stride()
+			for _, value := range buf[v*stride : (v+1)*stride] {
+				key = binary.LittleEndian.AppendUint32(key, math.Float32bits(value))
+			}
+		}
+		idx, ok := unique[string(key)]
+		if !ok {
+			idx = uint32(len(order))
+			unique[string(key)] = idx
+			order = append(order, uint32(v))
+		}
+		remap[v] = idx
+	}
+	return remap, order
+}
+
+// weldClose returns the index of the merged vertex of each vertex and the first vertex of each
+// merged vertex, merging the vertices whose attributes all differ by at most the specified tolerance.
+// The vertices are hashed by their positions in cells with the tolerance as size, so the
+// vertices close to each one are in its cell or in the neighboring ones.
+func (g *Geometry) weldClose(count int, tolerance float32) ([]uint32, []uint32) {
+
+	positions := g.attribValues(gls.VertexPosition)
+	cell := func(value float32) int64 {
+		return int64(math.Floor(float64(value / tolerance)))
+	}
+	near := func(a, b int) bool {
+		for _, vbo := range g.vbos {
+			buf := *vbo.Buffer()
+			stride := vbo.Stride()
+			va := buf[a*stride : (a+1)*stride]
+			vb := buf[b*stride : (b+1)*stride]
+			for i := range va {
+				if math32.Abs(va[i]-vb[i]) > tolerance {
+					return false
+				}
+			}
+		}
+		return true
+	}
+
+	// Joins the sets of the close vertices, each set identified by its first vertex
+	parent := make([]int, count)
+	var find func(v int) int
+	find = func(v int) int {
+		if parent[v] != v {
+			parent[v] = find(parent[v])
+		}
+		return parent[v]
+	}
+	cells := make(map[[3]int64][]int, count)
+	for v := 0; v < count; v++ {
+		parent[v] = v
+		p := &positions[v]
+		cx, cy, cz := cell(p.X), cell(p.Y), cell(p.Z)
+		for dx := int64(-1); dx <= 1; dx++ {
+			for dy := int64(-1); dy <= 1; dy++ {
+				for dz := int64(-1); dz <= 1; dz++ {
+					for _, other := range cells[[3]int64{cx + dx, cy + dy, cz + dz}] {
+						if !near(v, other) {
+							continue
+						}
+						a, b := find(v), find(other)
+						if a < b {
+							parent[b] = a
+						} else if b < a {
+							parent[a] = b
+						}
+					}
+				}
+			}
+		}
+		key := [3]int64{cx, cy, cz}
+		cells[key] = append(cells[key], v)
+	}
+
+	remap := make([]uint32, count)
+	order := make([]uint32, 0, count)
+	first := make(map[int]uint32)
+	for v := 0; v < count; v++ {
+		root := find(v)
+		idx, ok := first[root]
+		if !ok {
+			idx = uint32(len(order))
+			first[root] = idx
+			order = append(order, uint32(root))
+		}
+		remap[v] = idx
+	}
+	return remap, order
+}
+
+// SplitByGroup returns a new geometry for each group of the geometry with the elements of the
+// group and only the vertices they use. Each new geometry has one group with the material
+// index and id of the original group. Geometries without groups return a copy of themselves.
+func (g *Geometry) SplitByGroup() []*Geometry {
+
+	groups := g.groups
+	elements := g.ElementIndices()
+	if len(groups) == 0 {
+		groups = []Group{{Start: 0, Count: len(elements)}}
+	}
+
+	geoms := make([]*Geometry, 0, len(groups))
+	for _, gr := range groups {
+		end := gr.Start + gr.Count
+		if end > len(elements) {
+			end = len(elements)
+		}
+		geom := NewGeometry()
+		if g.Indexed() {
+			// Keeps only the vertices used by the group
+			remap := make(map[uint32]uint32)
+			order := make([]uint32, 0)
+			indices := math32.NewArrayU32(0, end-gr.Start)
+			for _, idx := range elements[gr.Start:end] {
+				ni, ok := remap[idx]
+				if !ok {
+					ni = uint32(len(order))
+					remap[idx] = ni
+					order = append(order, idx)
+				}
+				indices.Append(ni)
+			}
+			g.copyVertices(geom, order)
+			geom.SetIndices(indices)
+		} else {
+			g.copyVertices(geom, elements[gr.Start:end])
+		}
+		geom.groups = append(geom.groups, Group{0, end - gr.Start, gr.Matindex, gr.Matid})
+		geoms = append(geoms, geom)
+	}
+	return geoms
+}
+
+// Merge creates and returns a new geometry with the vertices and elements of the specified
+// geometries, with one VBO for each of the attributes of the first geometry, which are
+// filled with zeros for the geometries without them. The result is indexed if any of the
+// geometries is indexed. The groups of the geometries are kept and the geometries without
+// groups get one group whose material index is the position of the geometry in the list.
+func Merge(geometries ...*Geometry) *Geometry {
+
+	merged := NewGeometry()
+	if len(geometries) == 0 {
+		return merged
+	}
+
+	// Attributes of the merged geometry
+	attribs := make([]gls.VBOattrib, 0)
+	for _, vbo := range geometries[0].vbos {
+		attribs = append(attribs, vbo.Attributes()...)
+	}
+	buffers := make([]math32.ArrayF32, len(attribs))
+
+	indexed := false
+	for _, g := range geometries {
+		indexed = indexed || g.Indexed()
+	}
+
+	indices := math32.NewArrayU32(0, 0)
+	base := 0
+	for gi, g := range geometries {
+		count := g.VertexCount()
+		for ai, attrib := range attribs {
+			size := int(attrib.NumElements)
+			vbo, src := g.findAttrib(&attrib)
+			for v := 0; v < count; v++ {
+				if vbo == nil {
+					for k := 0; k < size; k++ {
+						buffers[ai].Append(0)
+					}
+					continue
+				}
+				buf := *vbo.Buffer()
+				offset := v*vbo.Stride() + int(src.ByteOffset)/4
+				for k := 0; k < size; k++ {
+					if k < int(src.NumElements) {
+						buffers[ai].Append(buf[offset+k])
+					} else {
+						buffers[ai].Append(0)
+					}
+				}
+			}
+		}
+
+		// Elements and groups offset by the ones of the previous geometries
+		start := base
+		if indexed {
+			start = indices.Size()
+			for _, idx := range g.ElementIndices() {
+				indices.Append(idx + uint32(base))
+			}
+		}
+		if len(g.groups) == 0 {
+			elements := count
+			if g.Indexed() {
+				elements = g.indices.Size()
+			}
+			merged.groups = append(merged.groups, Group{start, elements, gi, ""})
+		}
+		for _, gr := range g.groups {
+			gr.Start += start
+			merged.groups = append(merged.groups, gr)
+		}
+		base += count
+	}
+
+	for ai, attrib := range attribs {
+		vbo := gls.NewVBO(buffers[ai])
+		if attrib.Type != gls.Undefined {
+			vbo.AddAttrib(attrib.Type)
+		} else {
+			vbo.AddCustomAttrib(attrib.Name, attrib.NumElements)
+		}
+		vbo.AttribAt(0).Name = attrib.Name
+		vbo.AttribAt(0).NumElements = attrib.NumElements
+		merged.AddVBO(vbo)
+	}
+	if indexed {
+		merged.SetIndices(indices)
+	}
+	return merged
+}
+
+// findAttrib returns the VBO of the geometry with an attribute of the same type as the
+// specified attribute or, for custom attributes, with the same name and the attribute itself.
+func (g *Geometry) findAttrib(attrib *gls.VBOattrib) (*gls.VBO, *gls.VBOattrib) {
+
+	if attrib.Type != gls.Undefined {
+		if vbo := g.VBO(attrib.Type); vbo != nil {
+			return vbo, vbo.Attrib(attrib.Type)
+		}
+		return nil, nil
+	}
+	if vbo := g.VBOName(attrib.Name); vbo != nil {
+		return vbo, vbo.AttribName(attrib.Name)
+	}
+	return nil, nil
+}
+
+// gatherVertices replaces the buffers of all the VBOs of the geometry with the
+// attributes of the specified sequence of vertices.
+func (g *Geometry) gatherVertices(order []uint32) {
+
+	for _, vbo := range g.vbos {
+		vbo.SetBuffer(gatherBuffer(vbo, order))
+	}
+	g.invalidateProperties()
+}
+
+// copyVertices adds to the specified geometry copies of the VBOs of the geometry
+// with the attributes of the specified sequence of vertices.
+func (g *Geometry) copyVertices(dst *Geometry, order []uint32) {
+
+	for _, vbo := range g.vbos {
+		nvbo := gls.NewVBO(gatherBuffer(vbo, order))
+		for _, attrib := range vbo.Attributes() {
+			if attrib.Type != gls.Undefined {
+				nvbo.AddAttribOffset(attrib.Type, attrib.ByteOffset)
+			} else {
+				nvbo.AddCustomAttribOffset(attrib.Name, attrib.NumElements, attrib.ByteOffset)
+			}
+			*nvbo.AttribAt(nvbo.AttribCount() - 1) = attrib
+		}
+		dst.AddVBO(nvbo)
+	}
+}
+
+// gatherBuffer returns a new buffer with the attributes of the specified sequence of vertices of the VBO.
+func gatherBuffer(vbo *gls.VBO, order []uint32) math32.ArrayF32 {
+
+	buf := *vbo.Buffer()
+	stride := vbo.Stride()
+	gathered := math32.NewArrayF32(0, len(order)*stride)
+	for _, v := range order {
+		gathered.Append(buf[int(v)*stride : int(v+1)*stride]...)
+	}
+	return gathered
+}
+
+// invalidateProperties marks the geometric properties as needing to be recalculated.
+func (g *Geometry) invalidateProperties() {
+
+	g.boundingBoxValid = false
+	g.boundingSphereValid = false
+	g.areaValid = false
+	g.volumeValid = false
+	g.rotInertiaValid = false
+}
@@ -0,0 +1,142 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package geometry
+
+import (
+	"github.com/wangzun/gogame/engine/gls"
+	"github.com/wangzun/gogame/engine/math32"
+)
+
+// RoundedBox represents the geometry of a rectangular cuboid with rounded edges and corners.
+// As in the Box geometry each face has its own group in the order +X, -X, +Y, -Y, +Z, -Z.
+type RoundedBox struct {
+	Geometry
+	Width    float32
+	Height   float32
+	Length   float32
+	Radius   float32
+	Segments int
+}
+
+// NewRoundedBox creates and returns a pointer to a new RoundedBox geometry with the specified
+// width, height, length and radius of the edges and corners, which are made of 2*segments segments.
+func NewRoundedBox(width, height, length, radius float32, segments int) *RoundedBox {
+
+	box := new(RoundedBox)
+	box.Geometry.Init()
+
+	if segments < 1 {
+		segments = 1
+	}
+	radius = math32.Min(radius, math32.Min(width, math32.Min(height, length))/2)
+	box.Width = width
+	box.Height = height
+	box.Length = length
+	box.Radius = radius
+	box.Segments = segments
+
+	half := math32.Vector3{width / 2, height / 2, length / 2}
+	inner := math32.Vector3{half.X - radius, half.Y - radius, half.Z - radius}
+
+	// Coordinates along an axis of the specified half size, where each rounded
+	// side covers 45 degrees of the edges with evenly spaced angles
+	coords := func(h, in float32) []float32 {
+		if radius == 0 {
+			return []float32{-h, h}
+		}
+		list := make([]float32, 0, 2*segments+2)
+		for k := 0; k <= segments; k++ {
+			list = append(list, -in-radius*math32.Tan(math32.Pi/4*float32(segments-k)/float32(segments)))
+		}
+		for k := 0; k <= segments; k++ {
+			// Skips the duplicated middle coordinate when the faces are fully rounded
+			if k == 0 && in == 0 {
+				continue
+			}
+			list = append(list, in+radius*math32.Tan(math32.Pi/4*float32(k)/float32(segments)))
+		}
+		return list
+	}
+
+	positions := math32.NewArrayF32(0, 16)
+	normals := math32.NewArrayF32(0, 16)
+	uvs := math32.NewArrayF32(0, 16)
+	indices := math32.NewArrayU32(0, 16)
+
+	// Internal function to build each of the six faces as in the Box geometry
+	buildPlane := func(u, v string, udir, vdir int, w string, wsign float32, materialIndex int) {
+
+		offset := positions.Len() / 3
+		ucoords := coords(half.Component(axisIndex(u)), inner.Component(axisIndex(u)))
+		vcoords := coords(half.Component(axisIndex(v)), inner.Component(axisIndex(v)))
+		uSize := 2 * half.Component(axisIndex(u))
+		vSize := 2 * half.Component(axisIndex(v))
+
+		for iy := range vcoords {
+			for ix := range ucoords {
+				// Point on the face of the box moved onto the rounded surface
+				var point math32.Vector3
+				point.SetByName(u, ucoords[ix]*float32(udir))
+				point.SetByName(v, vcoords[iy]*float32(vdir))
+				point.SetByName(w, half.Component(axisIndex(w))*wsign)
+				var core, normal math32.Vector3
+				core.Copy(&point).Clamp(&math32.Vector3{-inner.X, -inner.Y, -inner.Z}, &inner)
+				normal.SubVectors(&point, &core).Normalize()
+				point.Copy(&normal).MultiplyScalar(radius).Add(&core)
+				if radius == 0 {
+					normal = math32.Vector3{}
+					normal.SetByName(w, wsign)
+				}
+				positions.AppendVector3(&point)
+				normals.AppendVector3(&normal)
+				uvs.Append((ucoords[ix]+uSize/2)/uSize, 1-(vcoords[iy]+vSize/2)/vSize)
+			}
+		}
+
+		gridX1 := len(ucoords)
+		gstart := indices.Size()
+		for iy := 0; iy < len(vcoords)-1; iy++ {
+			for ix := 0; ix < gridX1-1; ix++ {
+				a := ix + gridX1*iy
+				b := ix + gridX1*(iy+1)
+				c := (ix + 1) + gridX1*(iy+1)
+				d := (ix + 1) + gridX1*iy
+				indices.Append(uint32(a+offset), uint32(b+offset), uint32(d+offset), uint32(b+offset), uint32(c+offset), uint32(d+offset))
+			}
+		}
+		box.AddGroup(gstart, indices.Size()-gstart, materialIndex)
+	}
+
+	buildPlane("z", "y", -1, -1, "x", 1, 0)  // px
+	buildPlane("z", "y", 1, -1, "x", -1, 1)  // nx
+	buildPlane("x", "z", 1, 1, "y", 1, 2)    // py
+	buildPlane("x", "z", 1, -1, "y", -1, 3)  // ny
+	buildPlane("x", "y", 1, -1, "z", 1, 4)   // pz
+	buildPlane("x", "y", -1, -1, "z", -1, 5) // nz
+
+	box.SetIndices(indices)
+	box.AddVBO(gls.NewVBO(positions).AddAttrib(gls.VertexPosition))
+	box.AddVBO(gls.NewVBO(normals).AddAttrib(gls.VertexNormal))
+	box.AddVBO(gls.NewVBO(uvs).AddAttrib(gls.VertexTexcoord))
+
+	// Update bounding box
+	box.boundingBox.Min = math32.Vector3{-half.X, -half.Y, -half.Z}
+	box.boundingBox.Max = half
+	box.boundingBoxValid = true
+
+	return box
+}
+
+// axisIndex returns the index of the vector component with the specified name.
+func axisIndex(name string) int {
+
+	switch name {
+	case "y":
+		return 1
+	case "z":
+		return 2
+	}
+	return 0
+}
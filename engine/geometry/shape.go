@@ -0,0 +1,341 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package geometry
+
+import (
+	"sort"
+
+	"github.com/wangzun/gogame/engine/math32"
+)
+
+// Shape is a 2D polygon with optional holes used to build extruded geometries.
+type Shape struct {
+	Contour []math32.Vector2   // Outer contour
+	Holes   [][]math32.Vector2 // Contours of the holes
+}
+
+// NewShape creates and returns a pointer to a new shape with the specified outer contour.
+func NewShape(contour []math32.Vector2) *Shape {
+
+	s := new(Shape)
+	s.Contour = append([]math32.Vector2(nil), contour...)
+	return s
+}
+
+// NewShapesFromContours creates and returns the shapes formed by the specified closed contours
+// in any orientation, where contours inside an odd number of other contours are holes.
+// It is used to build shapes from glyph outlines and other vector paths.
+func NewShapesFromContours(contours [][]math32.Vector2) []*Shape {
+
+	// Sorts the contours by decreasing area, so containers come before the contours they contain
+	order := make([]int, 0, len(contours))
+	for i := range contours {
+		if len(contours[i]) >= 3 {
+			order = append(order, i)
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return math32.Abs(polygonArea(contours[order[a]])) > math32.Abs(polygonArea(contours[order[b]]))
+	})
+
+	shapes := make([]*Shape, 0)
+	outers := make([]int, 0) // indices in contours of the shape contours
+	for k, i := range order {
+		c := contours[i]
+		depth := 0
+		parent := -1
+		for _, j := range order[:k] {
+			if pointInPolygon(&c[0], contours[j]) {
+				depth++
+				// The smallest container is the last one found
+				parent = j
+			}
+		}
+		if depth%2 == 0 {
+			shapes = append(shapes, NewShape(c))
+			outers = append(outers, i)
+			continue
+		}
+		for s, o := range outers {
+			if o == parent {
+				shapes[s].AddHole(c)
+				break
+			}
+		}
+	}
+	return shapes
+}
+
+// AddHole adds a hole with the specified contour to the shape.
+func (s *Shape) AddHole(hole []math32.Vector2) *Shape {
+
+	s.Holes = append(s.Holes, append([]math32.Vector2(nil), hole...))
+	return s
+}
+
+// normalized returns the contour with counter clockwise orientation and the holes
+// with clockwise orientation, as expected by the triangulation and extrusion.
+func (s *Shape) normalized() ([]math32.Vector2, [][]math32.Vector2) {
+
+	contour := append([]math32.Vector2(nil), s.Contour...)
+	if polygonArea(contour) < 0 {
+		reverseVectors2(contour)
+	}
+	holes := make([][]math32.Vector2, 0, len(s.Holes))
+	for _, h := range s.Holes {
+		if len(h) < 3 {
+			continue
+		}
+		hole := append([]math32.Vector2(nil), h...)
+		if polygonArea(hole) > 0 {
+			reverseVectors2(hole)
+		}
+		holes = append(holes, hole)
+	}
+	return contour, holes
+}
+
+// Triangulate returns the indices of the counter clockwise triangles which fill the shape,
+// where the vertices are the points of the outer contour followed by the points of the holes in order.
+func (s *Shape) Triangulate() []uint32 {
+
+	contour, holes := s.normalized()
+	tris := triangulate(contour, holes)
+
+	// Maps the indices of the normalized contours to the original points
+	mapping := make([]uint32, 0, len(tris))
+	base := uint32(0)
+	appendMapping := func(src []math32.Vector2, reversed bool) {
+		n := uint32(len(src))
+		for i := uint32(0); i < n; i++ {
+			if reversed {
+				mapping = append(mapping, base+n-1-i)
+			} else {
+				mapping = append(mapping, base+i)
+			}
+		}
+		base += n
+	}
+	appendMapping(s.Contour, polygonArea(s.Contour) < 0)
+	for _, h := range s.Holes {
+		if len(h) < 3 {
+			base += uint32(len(h))
+			continue
+		}
+		appendMapping(h, polygonArea(h) > 0)
+	}
+	for i := range tris {
+		tris[i] = mapping[tris[i]]
+	}
+	return tris
+}
+
+// triangulate triangulates the specified counter clockwise contour with clockwise holes
+// by ear clipping after joining the holes to the contour with bridges.
+// The returned indices refer to the contour points followed by the hole points.
+func triangulate(contour []math32.Vector2, holes [][]math32.Vector2) []uint32 {
+
+	// All points and polygon with indices into them
+	points := append([]math32.Vector2(nil), contour...)
+	poly := make([]int, len(contour))
+	for i := range poly {
+		poly[i] = i
+	}
+
+	// Joins the holes from the one with the rightmost point
+	type holeInfo struct {
+		start int // index of the first hole point
+		count int // number of hole points
+		right int // index of the rightmost hole point
+	}
+	infos := make([]holeInfo, 0, len(holes))
+	for _, h := range holes {
+		hi := holeInfo{start: len(points), count: len(h)}
+		hi.right = hi.start
+		points = append(points, h...)
+		for i := hi.start; i < hi.start+hi.count; i++ {
+			if points[i].X > points[hi.right].X {
+				hi.right = i
+			}
+		}
+		infos = append(infos, hi)
+	}
+	sort.Slice(infos, func(a, b int) bool {
+		return points[infos[a].right].X > points[infos[b].right].X
+	})
+	for _, hi := range infos {
+		poly = bridgeHole(points, poly, hi.start, hi.count, hi.right)
+	}
+	return earClip(points, poly)
+}
+
+// bridgeHole returns the polygon with the specified hole joined to it
+// by a bridge from the rightmost hole point to a visible polygon point.
+func bridgeHole(points []math32.Vector2, poly []int, start, count, right int) []int {
+
+	m := points[right]
+
+	// Finds the closest edge crossed by the ray from the hole point to the right
+	best := -1
+	bestX := math32.Inf(1)
+	for i := range poly {
+		a := points[poly[i]]
+		b := points[poly[(i+1)%len(poly)]]
+		if (a.Y > m.Y) == (b.Y > m.Y) {
+			if a.Y == m.Y && a.X >= m.X && a.X < bestX {
+				bestX = a.X
+				best = i
+			}
+			continue
+		}
+		x := a.X + (m.Y-a.Y)*(b.X-a.X)/(b.Y-a.Y)
+		if x >= m.X && x < bestX {
+			bestX = x
+			// Candidate is the edge point with the largest x
+			if a.X > b.X {
+				best = i
+			} else {
+				best = (i + 1) % len(poly)
+			}
+		}
+	}
+	if best < 0 {
+		// The hole is not inside the polygon
+		return poly
+	}
+
+	// Checks for reflex polygon points inside the triangle formed by the hole point,
+	// the intersection and the candidate, choosing the one with the smallest angle
+	p := points[poly[best]]
+	inter := math32.Vector2{bestX, m.Y}
+	bestAngle := float32(-1)
+	for i := range poly {
+		if i == best {
+			continue
+		}
+		q := points[poly[i]]
+		if q == m || !pointInTriangle(&q, &m, &inter, &p) {
+			continue
+		}
+		d := math32.Vector2{q.X - m.X, q.Y - m.Y}
+		cos := d.X / (d.Length() + 1e-12)
+		if cos > bestAngle {
+			bestAngle = cos
+			best = i
+		}
+	}
+
+	// Splices the hole into the polygon after the bridge point
+	bridged := make([]int, 0, len(poly)+count+2)
+	bridged = append(bridged, poly[:best+1]...)
+	for k := 0; k <= count; k++ {
+		bridged = append(bridged, start+(right-start+k)%count)
+	}
+	bridged = append(bridged, poly[best])
+	bridged = append(bridged, poly[best+1:]...)
+	return bridged
+}
+
+// earClip triangulates the specified counter clockwise polygon of point indices by ear clipping.
+func earClip(points []math32.Vector2, poly []int) []uint32 {
+
+	tris := make([]uint32, 0, 3*len(poly))
+	poly = append([]int(nil), poly...)
+	for len(poly) > 3 {
+		n := len(poly)
+		ear := -1
+		for i := 0; i < n && ear < 0; i++ {
+			a := &points[poly[(i+n-1)%n]]
+			b := &points[poly[i]]
+			c := &points[poly[(i+1)%n]]
+			if cross2(a, b, c) <= 0 {
+				continue
+			}
+			ear = i
+			for j := 0; j < n; j++ {
+				p := &points[poly[j]]
+				if j == i || j == (i+n-1)%n || j == (i+1)%n || *p == *a || *p == *b || *p == *c {
+					continue
+				}
+				if pointInTriangle(p, a, b, c) {
+					ear = -1
+					break
+				}
+			}
+		}
+		if ear < 0 {
+			// Degenerate polygon: clips the vertex with the largest angle to make progress
+			ear = 0
+			best := float32(-math32.Inf(1))
+			for i := 0; i < n; i++ {
+				c := cross2(&points[poly[(i+n-1)%n]], &points[poly[i]], &points[poly[(i+1)%n]])
+				if c > best {
+					best = c
+					ear = i
+				}
+			}
+		}
+		a := poly[(ear+n-1)%n]
+		c := poly[(ear+1)%n]
+		if cross2(&points[a], &points[poly[ear]], &points[c]) > 0 {
+			tris = append(tris, uint32(a), uint32(poly[ear]), uint32(c))
+		}
+		poly = append(poly[:ear], poly[ear+1:]...)
+	}
+	if len(poly) == 3 && cross2(&points[poly[0]], &points[poly[1]], &points[poly[2]]) > 0 {
+		tris = append(tris, uint32(poly[0]), uint32(poly[1]), uint32(poly[2]))
+	}
+	return tris
+}
+
+// cross2 returns the z component of the cross product of the edges a-b and b-c,
+// which is positive when the corner at b turns counter clockwise.
+func cross2(a, b, c *math32.Vector2) float32 {
+
+	return (b.X-a.X)*(c.Y-b.Y) - (b.Y-a.Y)*(c.X-b.X)
+}
+
+// pointInTriangle returns whether the point is inside or on the edges of the counter clockwise triangle.
+func pointInTriangle(p, a, b, c *math32.Vector2) bool {
+
+	d1 := (b.X-a.X)*(p.Y-a.Y) - (b.Y-a.Y)*(p.X-a.X)
+	d2 := (c.X-b.X)*(p.Y-b.Y) - (c.Y-b.Y)*(p.X-b.X)
+	d3 := (a.X-c.X)*(p.Y-c.Y) - (a.Y-c.Y)*(p.X-c.X)
+	neg := d1 < 0 || d2 < 0 || d3 < 0
+	pos := d1 > 0 || d2 > 0 || d3 > 0
+	return !(neg && pos)
+}
+
+// pointInPolygon returns whether the point is inside the polygon using the even-odd rule.
+func pointInPolygon(p *math32.Vector2, poly []math32.Vector2) bool {
+
+	inside := false
+	for i, j := 0, len(poly)-1; i < len(poly); j, i = i, i+1 {
+		a := &poly[i]
+		b := &poly[j]
+		if (a.Y > p.Y) != (b.Y > p.Y) && p.X < (b.X-a.X)*(p.Y-a.Y)/(b.Y-a.Y)+a.X {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// polygonArea returns the signed area of the polygon, positive when counter clockwise.
+func polygonArea(poly []math32.Vector2) float32 {
+
+	var area float32
+	for i, j := 0, len(poly)-1; i < len(poly); j, i = i, i+1 {
+		area += poly[j].X*poly[i].Y - poly[i].X*poly[j].Y
+	}
+	return area / 2
+}
+
+// reverseVectors2 reverses the order of the specified points in place.
+func reverseVectors2(points []math32.Vector2) {
+
+	for i, j := 0, len(points)-1; i < j; i, j = i+1, j-1 {
+		points[i], points[j] = points[j], points[i]
+	}
+}
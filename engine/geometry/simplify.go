@@ -0,0 +1,522 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package geometry
+
+import (
+	"math"
+	"sort"
+
+	"github.com/wangzun/gogame/engine/gls"
+	"github.com/wangzun/gogame/engine/math32"
+)
+
+// TriangleCount returns the number of triangles of the geometry.
+func (g *Geometry) TriangleCount() int {
+
+	if g.Indexed() {
+		return g.indices.Size() / 3
+	}
+	return g.VertexCount() / 3
+}
+
+// Simplify creates and returns a new geometry with the triangles of the geometry reduced
+// to the specified number by collapsing the edges which change the surface the least, as
+// measured by quadric error metrics, and the error of the result relative to the size of the
+// geometry. The simplification also stops before exceeding the specified maximum relative
+// error if it's not zero, so a zero target count simplifies as much as the error allows.
+// Vertices are collapsed onto their neighbors keeping their attributes, so the texture
+// coordinates, normals and skin weights of the remaining vertices are unchanged.
+// UV and normal seams, open borders and the boundaries between groups are only simplified
+// along themselves and the groups are kept, so the result can be used with the same
+// materials as a lower level of detail of the geometry.
+// The geometry must be made of triangles. The vertices of non-indexed geometries are shared
+// by the triangles with identical ones, as by ToIndexed(), in a copy of the geometry before
+// simplifying it, so the geometry itself is not changed.
+func (g *Geometry) Simplify(targetTriangles int, maxError float32) (*Geometry, float32) {
+
+	if !g.Indexed() {
+		indexed := NewGeometry()
+		indexed.ShaderDefines.Add(&g.ShaderDefines)
+		order := make([]uint32, g.VertexCount())
+		for i := range order {
+			order[i] = uint32(i)
+		}
+		g.copyVertices(indexed, order)
+		indexed.AddGroupList(g.groups)
+		indexed.ToIndexed()
+		g = indexed
+	}
+	s := newSimplifier(g)
+	if maxError <= 0 {
+		maxError = float32(math.Inf(1))
+	}
+	for s.triangles > targetTriangles {
+		if s.pass(targetTriangles, float64(maxError)) == 0 {
+			break
+		}
+	}
+	return s.build(g), float32(s.err)
+}
+
+// simplifyBorderWeight is the weight of the planes which keep the
+// seams, borders and group boundaries in place.
+const simplifyBorderWeight = 10
+
+// simplifyFlipCos is the minimum cosine of the angle between the normals
+// of a triangle before and after a collapse.
+const simplifyFlipCos = 0.2
+
+// quadric is a symmetric 4x4 matrix measuring the squared distance
+// of a point to a set of planes weighted by their areas.
+type quadric struct {
+	a00, a01, a02, a03 float64
+	a11, a12, a13      float64
+	a22, a23           float64
+	a33                float64
+	w                  float64
+}
+
+// addPlane adds the plane with the specified normal and distance to the origin with the specified weight.
+func (q *quadric) addPlane(nx, ny, nz, d, w float64) {
+
+	q.a00 += w * nx * nx
+	q.a01 += w * nx * ny
+	q.a02 += w * nx * nz
+	q.a03 += w * nx * d
+	q.a11 += w * ny * ny
+	q.a12 += w * ny * nz
+	q.a13 += w * ny * d
+	q.a22 += w * nz * nz
+	q.a23 += w * nz * d
+	q.a33 += w * d * d
+	q.w += w
+}
+
+// add adds the specified quadric to this one.
+func (q *quadric) add(o *quadric) {
+
+	q.a00 += o.a00
+	q.a01 += o.a01
+	q.a02 += o.a02
+	q.a03 += o.a03
+	q.a11 += o.a11
+	q.a12 += o.a12
+	q.a13 += o.a13
+	q.a22 += o.a22
+	q.a23 += o.a23
+	q.a33 += o.a33
+	q.w += o.w
+}
+
+// eval returns the weighted squared distance of the specified point to the planes of the quadric.
+func (q *quadric) eval(p *math32.Vector3) float64 {
+
+	x, y, z := float64(p.X), float64(p.Y), float64(p.Z)
+	return x*(q.a00*x+2*(q.a01*y+q.a02*z+q.a03)) +
+		y*(q.a11*y+2*(q.a12*z+q.a13)) +
+		z*(q.a22*z+2*q.a23) + q.a33
+}
+
+// simplifier keeps the state of the simplification of a geometry, whose vertices with the
+// same position, which differ by other attributes at seams, are collapsed together.
+type simplifier struct {
+	positions []math32.Vector3 // Positions of the vertices
+	posOf     []uint32         // Index of the first vertex with the same position of each vertex
+	tris      []uint32         // Vertices of the triangles
+	groups    []int            // Group of each triangle or -1
+	alive     []bool           // Whether each triangle wasn't removed
+	quadrics  []quadric        // Quadrics of the positions
+	triangles int              // Number of triangles alive
+	scale     float64          // Size of the geometry
+	err       float64          // Largest relative error of the collapses
+}
+
+// simplifyCollapse is a candidate collapse of a position onto a neighbor position.
+type simplifyCollapse struct {
+	v, t uint32  // Positions collapsed and kept
+	cost float64 // Relative error of the collapse
+}
+
+// newSimplifier creates and returns a pointer to a new simplifier for the specified geometry.
+func newSimplifier(g *Geometry) *simplifier {
+
+	s := new(simplifier)
+	s.positions = g.attribValues(gls.VertexPosition)
+	s.tris = append([]uint32(nil), g.ElementIndices()...)
+	s.tris = s.tris[:len(s.tris)/3*3]
+	ntris := len(s.tris) / 3
+	s.triangles = ntris
+
+	// Groups of the triangles
+	s.groups = make([]int, ntris)
+	for i := range s.groups {
+		s.groups[i] = -1
+	}
+	for gi, gr := range g.groups {
+		for t := gr.Start / 3; t < (gr.Start+gr.Count)/3 && t < ntris; t++ {
+			s.groups[t] = gi
+		}
+	}
+	s.alive = make([]bool, ntris)
+	for i := range s.alive {
+		s.alive[i] = true
+	}
+
+	// Vertices with the same position
+	s.posOf = make([]uint32, len(s.positions))
+	first := make(map[math32.Vector3]uint32, len(s.positions))
+	var box math32.Box3
+	box.MakeEmpty()
+	for i := range s.positions {
+		idx, ok := first[s.positions[i]]
+		if !ok {
+			idx = uint32(i)
+			first[s.positions[i]] = idx
+		}
+		s.posOf[i] = idx
+		box.ExpandByPoint(&s.positions[i])
+	}
+	size := box.Max.DistanceTo(&box.Min)
+	s.scale = float64(size)
+	if s.scale == 0 {
+		s.scale = 1
+	}
+
+	// Quadrics of the planes of the triangles weighted by their areas
+	s.quadrics = make([]quadric, len(s.positions))
+	for t := 0; t < ntris; t++ {
+		p0, p1, p2 := s.triPositions(t)
+		n, area := triangleNormal(p0, p1, p2)
+		if area == 0 {
+			continue
+		}
+		d := -float64(n.Dot(p0))
+		for k := 0; k < 3; k++ {
+			s.quadrics[s.posOf[s.tris[3*t+k]]].addPlane(float64(n.X), float64(n.Y), float64(n.Z), d, float64(area))
+		}
+	}
+
+	// Keeps the constrained edges in place with planes through them perpendicular to their triangles
+	edges := s.edges()
+	for key, e := range edges {
+		if !s.constrained(edges, key[0], key[1]) {
+			continue
+		}
+		p0, p1, p2 := s.triPositions(e.tri)
+		n, area := triangleNormal(p0, p1, p2)
+		if area == 0 {
+			continue
+		}
+		var dir, pn math32.Vector3
+		dir.SubVectors(&s.positions[key[1]], &s.positions[key[0]])
+		length := dir.Length()
+		if length == 0 {
+			continue
+		}
+		pn.CrossVectors(&dir, &n).DivideScalar(length)
+		d := -float64(pn.Dot(&s.positions[key[0]]))
+		w := simplifyBorderWeight * float64(length*length)
+		for _, p := range key {
+			s.quadrics[p].addPlane(float64(pn.X), float64(pn.Y), float64(pn.Z), d, w)
+		}
+	}
+	return s
+}
+
+// triPositions returns pointers to the positions of the vertices of the specified triangle.
+func (s *simplifier) triPositions(t int) (*math32.Vector3, *math32.Vector3, *math32.Vector3) {
+
+	return &s.positions[s.posOf[s.tris[3*t]]], &s.positions[s.posOf[s.tris[3*t+1]]], &s.positions[s.posOf[s.tris[3*t+2]]]
+}
+
+// triangleNormal returns the unit normal and the area of the specified triangle.
+func triangleNormal(p0, p1, p2 *math32.Vector3) (math32.Vector3, float32) {
+
+	var e1, e2, n math32.Vector3
+	e1.SubVectors(p1, p0)
+	e2.SubVectors(p2, p0)
+	n.CrossVectors(&e1, &e2)
+	length := n.Length()
+	if length == 0 {
+		return n, 0
+	}
+	n.DivideScalar(length)
+	return n, length / 2
+}
+
+// pass collapses the cheapest edges whose neighborhoods don't overlap until the target number of
+// triangles is reached or no edge can be collapsed within the maximum error and returns the
+// number of collapses.
+func (s *simplifier) pass(target int, maxError float64) int {
+
+	// Triangles around each position
+	vtris := make(map[uint32][]int)
+	for t := range s.alive {
+		if !s.alive[t] {
+			continue
+		}
+		for k := 0; k < 3; k++ {
+			p := s.posOf[s.tris[3*t+k]]
+			vtris[p] = append(vtris[p], t)
+		}
+	}
+
+	// Positions on constrained edges can only move along them and only when on a single line
+	edges := s.edges()
+	cneighbors := make(map[uint32][]uint32)
+	for key := range edges {
+		if !s.constrained(edges, key[0], key[1]) {
+			continue
+		}
+		for _, pq := range [2][2]uint32{{key[0], key[1]}, {key[1], key[0]}} {
+			list := cneighbors[pq[0]]
+			found := false
+			for _, q := range list {
+				found = found || q == pq[1]
+			}
+			if !found {
+				cneighbors[pq[0]] = append(list, pq[1])
+			}
+		}
+	}
+
+	// Candidate collapses sorted by cost
+	candidates := make([]simplifyCollapse, 0, len(edges))
+	for key := range edges {
+		v, t := key[0], key[1]
+		if cn, ok := cneighbors[v]; ok {
+			if len(cn) != 2 || !s.constrained(edges, v, t) {
+				continue
+			}
+		}
+		q := s.quadrics[v]
+		q.add(&s.quadrics[t])
+		cost := 0.0
+		if q.w > 0 {
+			cost = math.Sqrt(math.Max(q.eval(&s.positions[t])/q.w, 0)) / s.scale
+		}
+		if cost <= maxError {
+			candidates = append(candidates, simplifyCollapse{v, t, cost})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].cost != candidates[j].cost {
+			return candidates[i].cost < candidates[j].cost
+		}
+		if candidates[i].v != candidates[j].v {
+			return candidates[i].v < candidates[j].v
+		}
+		return candidates[i].t < candidates[j].t
+	})
+
+	// Collapses only a part of the vertices in each pass, as the costs change after collapses
+	limit := len(vtris)/6 + 1
+	touched := make(map[uint32]bool)
+	collapses := 0
+	for _, c := range candidates {
+		if s.triangles <= target || collapses >= limit {
+			break
+		}
+		if touched[c.v] || touched[c.t] {
+			continue
+		}
+		wedges, ok := s.checkCollapse(c.v, c.t, vtris)
+		if !ok {
+			continue
+		}
+
+		// Moves the vertices of the collapsed position to the kept position
+		for _, t := range vtris[c.v] {
+			if !s.alive[t] {
+				continue
+			}
+			for k := 0; k < 3; k++ {
+				if s.posOf[s.tris[3*t+k]] == c.v {
+					s.tris[3*t+k] = wedges[s.tris[3*t+k]]
+				}
+			}
+			a, b, d := s.posOf[s.tris[3*t]], s.posOf[s.tris[3*t+1]], s.posOf[s.tris[3*t+2]]
+			if a == b || b == d || d == a {
+				s.alive[t] = false
+				s.triangles--
+			}
+			for k := 0; k < 3; k++ {
+				touched[s.posOf[s.tris[3*t+k]]] = true
+			}
+		}
+		s.quadrics[c.t].add(&s.quadrics[c.v])
+		touched[c.v] = true
+		touched[c.t] = true
+		if c.cost > s.err {
+			s.err = c.cost
+		}
+		collapses++
+	}
+	return collapses
+}
+
+// simplifyEdge is a directed edge between two positions of the triangles of a simplifier.
+type simplifyEdge struct {
+	a, b  uint32 // Vertices of the edge
+	tri   int    // First triangle with the edge
+	count int    // Number of triangles with the edge in this direction
+}
+
+// edges returns the directed edges between the positions of the remaining triangles.
+func (s *simplifier) edges() map[[2]uint32]*simplifyEdge {
+
+	edges := make(map[[2]uint32]*simplifyEdge)
+	for t := range s.alive {
+		if !s.alive[t] {
+			continue
+		}
+		for k := 0; k < 3; k++ {
+			a := s.tris[3*t+k]
+			b := s.tris[3*t+(k+1)%3]
+			key := [2]uint32{s.posOf[a], s.posOf[b]}
+			if e, ok := edges[key]; ok {
+				e.count++
+				continue
+			}
+			edges[key] = &simplifyEdge{a, b, t, 1}
+		}
+	}
+	return edges
+}
+
+// constrained returns whether the edge between the specified positions can't be crossed by
+// collapses because it is an open border, a seam, a boundary between groups or non-manifold.
+func (s *simplifier) constrained(edges map[[2]uint32]*simplifyEdge, p, q uint32) bool {
+
+	e1 := edges[[2]uint32{p, q}]
+	e2 := edges[[2]uint32{q, p}]
+	if e1 == nil || e2 == nil {
+		return true
+	}
+	return e1.count > 1 || e2.count > 1 || s.groups[e1.tri] != s.groups[e2.tri] || e1.a != e2.b || e1.b != e2.a
+}
+
+// checkCollapse checks whether the specified position can be collapsed onto the specified neighbor
+// position without changing the topology or flipping triangles and returns the vertex of the
+// kept position which replaces each vertex of the collapsed position.
+func (s *simplifier) checkCollapse(v, t uint32, vtris map[uint32][]int) (map[uint32]uint32, bool) {
+
+	// The positions must share exactly the neighbors of their common triangles
+	shared := 0
+	vn := make(map[uint32]bool)
+	for _, tri := range vtris[v] {
+		hasT := false
+		for k := 0; k < 3; k++ {
+			p := s.posOf[s.tris[3*tri+k]]
+			vn[p] = true
+			hasT = hasT || p == t
+		}
+		if hasT {
+			shared++
+		}
+	}
+	common := 0
+	seen := make(map[uint32]bool)
+	for _, tri := range vtris[t] {
+		for k := 0; k < 3; k++ {
+			p := s.posOf[s.tris[3*tri+k]]
+			if p != v && p != t && vn[p] && !seen[p] {
+				seen[p] = true
+				common++
+			}
+		}
+	}
+	if shared == 0 || common != shared {
+		return nil, false
+	}
+
+	// Each vertex of the collapsed position is replaced by the vertex of the
+	// kept position which shares a triangle with it
+	wedges := make(map[uint32]uint32)
+	for _, tri := range vtris[v] {
+		var wv, wt uint32
+		hasV, hasT := false, false
+		for k := 0; k < 3; k++ {
+			idx := s.tris[3*tri+k]
+			switch s.posOf[idx] {
+			case v:
+				wv, hasV = idx, true
+			case t:
+				wt, hasT = idx, true
+			}
+		}
+		if hasV && hasT {
+			if prev, ok := wedges[wv]; ok && prev != wt {
+				return nil, false
+			}
+			wedges[wv] = wt
+		}
+	}
+	for _, tri := range vtris[v] {
+		for k := 0; k < 3; k++ {
+			idx := s.tris[3*tri+k]
+			if _, ok := wedges[idx]; s.posOf[idx] == v && !ok {
+				return nil, false
+			}
+		}
+	}
+
+	// The remaining triangles must not flip or become degenerate
+	for _, tri := range vtris[v] {
+		p0, p1, p2 := s.triPositions(tri)
+		if s.posOf[s.tris[3*tri]] == t || s.posOf[s.tris[3*tri+1]] == t || s.posOf[s.tris[3*tri+2]] == t {
+			continue
+		}
+		before, _ := triangleNormal(p0, p1, p2)
+		pos := [3]*math32.Vector3{p0, p1, p2}
+		for k := 0; k < 3; k++ {
+			if s.posOf[s.tris[3*tri+k]] == v {
+				pos[k] = &s.positions[t]
+			}
+		}
+		after, area := triangleNormal(pos[0], pos[1], pos[2])
+		if area == 0 || before.Dot(&after) < simplifyFlipCos {
+			return nil, false
+		}
+	}
+	return wedges, true
+}
+
+// build creates and returns the simplified geometry with the vertices
+// of the specified geometry used by the remaining triangles.
+func (s *simplifier) build(g *Geometry) *Geometry {
+
+	out := NewGeometry()
+	out.ShaderDefines.Add(&g.ShaderDefines)
+	remap := make(map[uint32]uint32)
+	order := make([]uint32, 0)
+	indices := math32.NewArrayU32(0, s.triangles*3)
+	appendGroup := func(group int) {
+		for t := range s.alive {
+			if !s.alive[t] || s.groups[t] != group {
+				continue
+			}
+			for k := 0; k < 3; k++ {
+				idx := s.tris[3*t+k]
+				ni, ok := remap[idx]
+				if !ok {
+					ni = uint32(len(order))
+					remap[idx] = ni
+					order = append(order, idx)
+				}
+				indices.Append(ni)
+			}
+		}
+	}
+	appendGroup(-1)
+	for gi, gr := range g.groups {
+		start := indices.Size()
+		appendGroup(gi)
+		out.groups = append(out.groups, Group{start, indices.Size() - start, gr.Matindex, gr.Matid})
+	}
+	g.copyVertices(out, order)
+	out.SetIndices(indices)
+	return out
+}
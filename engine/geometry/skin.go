@@ -0,0 +1,191 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package geometry
+
+import (
+	"github.com/wangzun/gogame/engine/gls"
+	"github.com/wangzun/gogame/engine/math32"
+)
+
+// SplitByBonePalette splits the skinned geometry made of triangles into geometries whose
+// vertices are influenced by at most the specified number of bones, so each one can be drawn
+// with fewer bone matrices than supported by the shader. It returns the new geometries and
+// for each one its bone palette, which maps its bone indices to the original bone indices.
+// The triangles are distributed in order and the vertices shared by triangles of different
+// geometries are duplicated. The bone indices of the vertices are remapped into the palette
+// and the ones with zero weights are set to zero. The groups of the geometry are not kept.
+// The specified number of bones must be at least three times the number of influences per
+// vertex, which is the maximum number of bones of a triangle.
+// Returns nil if the geometry has no bone indices or weights.
+func (g *Geometry) SplitByBonePalette(maxBones int) ([]*Geometry, [][]int) {
+
+	geoms, palettes, _ := g.splitByBonePalette(maxBones)
+	return geoms, palettes
+}
+
+// SplitByBonePalette splits the skinned base geometry and the morph targets into morph geometries
+// whose vertices are influenced by at most the specified number of bones, as the base geometry
+// SplitByBonePalette(). The new morph geometries have the morph targets of their vertices, with the
+// same names, weights and default weights. Returns nil if the base geometry has no bone indices or weights.
+func (mg *MorphGeometry) SplitByBonePalette(maxBones int) ([]*MorphGeometry, [][]int) {
+
+	geoms, palettes, vertices := mg.baseGeometry.splitByBonePalette(maxBones)
+	if geoms == nil {
+		return nil, nil
+	}
+	morphs := make([]*MorphGeometry, len(geoms))
+	for i, geom := range geoms {
+		m := NewMorphGeometry(geom)
+		targets := make([]*Geometry, len(mg.targets))
+		for t, target := range mg.targets {
+			targets[t] = NewGeometry()
+			target.copyVertices(targets[t], vertices[i])
+		}
+		m.AddMorphTargetDeltas(targets...)
+		m.SetTargetNames(mg.names)
+		copy(m.defaults, mg.defaults)
+		copy(m.weights, mg.weights)
+		morphs[i] = m
+	}
+	return morphs, palettes
+}
+
+// splitByBonePalette splits the geometry as SplitByBonePalette()
+// and also returns the original vertices of each new geometry.
+func (g *Geometry) splitByBonePalette(maxBones int) ([]*Geometry, [][]int, [][]uint32) {
+
+	indices, weights := g.skinAccessors()
+	if indices == nil || weights == nil {
+		return nil, nil, nil
+	}
+
+	// Distributes the triangles into the palettes
+	elements := g.ElementIndices()
+	var orders [][]uint32
+	var palettes [][]int
+	var order []uint32
+	var palette []int
+	slots := make(map[int]bool)
+	var bones []int
+	for t := 0; t+2 < len(elements); t += 3 {
+		// Collects the bones of the triangle and counts the ones missing from the palette
+		bones = bones[:0]
+		missing := 0
+		for _, v := range elements[t : t+3] {
+			for i := 0; i < 4; i++ {
+				bone := int(indices.get(int(v), i))
+				if weights.get(int(v), i) == 0 || containsInt(bones, bone) {
+					continue
+				}
+				bones = append(bones, bone)
+				if !slots[bone] {
+					missing++
+				}
+			}
+		}
+		// Starts a new palette when the triangle bones don't fit
+		if len(palette)+missing > maxBones && len(order) > 0 {
+			orders = append(orders, order)
+			palettes = append(palettes, palette)
+			order = nil
+			palette = nil
+			slots = make(map[int]bool)
+		}
+		for _, bone := range bones {
+			if !slots[bone] {
+				slots[bone] = true
+				palette = append(palette, bone)
+			}
+		}
+		order = append(order, elements[t:t+3]...)
+	}
+	if len(order) > 0 {
+		orders = append(orders, order)
+		palettes = append(palettes, palette)
+	}
+
+	// Creates the indexed geometries with the remapped bone indices
+	geoms := make([]*Geometry, len(orders))
+	verts := make([][]uint32, len(orders))
+	for n, order := range orders {
+		slots := make(map[int]int, len(palettes[n]))
+		for slot, bone := range palettes[n] {
+			slots[bone] = slot
+		}
+		remap := make(map[uint32]uint32)
+		vertices := make([]uint32, 0)
+		elems := math32.NewArrayU32(0, len(order))
+		for _, idx := range order {
+			ni, ok := remap[idx]
+			if !ok {
+				ni = uint32(len(vertices))
+				remap[idx] = ni
+				vertices = append(vertices, idx)
+			}
+			elems.Append(ni)
+		}
+		geom := NewGeometry()
+		g.copyVertices(geom, vertices)
+		geom.SetIndices(elems)
+		gindices, gweights := geom.skinAccessors()
+		for v := range vertices {
+			for i := 0; i < 4; i++ {
+				slot := 0
+				if gweights.get(v, i) != 0 {
+					slot = slots[int(gindices.get(v, i))]
+				}
+				gindices.set(v, i, float32(slot))
+			}
+		}
+		geoms[n] = geom
+		verts[n] = vertices
+	}
+	return geoms, palettes, verts
+}
+
+// skinAccessor accesses one vec4 attribute of the vertices of a possibly interleaved VBO.
+type skinAccessor struct {
+	buf    math32.ArrayF32 // Buffer of the VBO
+	stride int             // Number of floats per vertex
+	offset int             // Offset of the attribute in floats
+}
+
+// get returns the specified component of the attribute of the specified vertex.
+func (sa *skinAccessor) get(vertex, comp int) float32 {
+
+	return sa.buf[vertex*sa.stride+sa.offset+comp]
+}
+
+// set sets the specified component of the attribute of the specified vertex.
+func (sa *skinAccessor) set(vertex, comp int, value float32) {
+
+	sa.buf[vertex*sa.stride+sa.offset+comp] = value
+}
+
+// skinAccessors returns accessors to the bone indices and weights
+// of the geometry or nil for the missing attributes.
+func (g *Geometry) skinAccessors() (*skinAccessor, *skinAccessor) {
+
+	accessor := func(atype gls.AttribType) *skinAccessor {
+		vbo := g.VBO(atype)
+		if vbo == nil {
+			return nil
+		}
+		attrib := vbo.Attrib(atype)
+		return &skinAccessor{*vbo.Buffer(), vbo.Stride(), int(attrib.ByteOffset) / 4}
+	}
+	return accessor(gls.SkinIndex), accessor(gls.SkinWeight)
+}
+
+// containsInt returns whether the slice contains the specified value.
+func containsInt(list []int, value int) bool {
+
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,146 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package geometry
+
+import (
+	"math"
+
+	"github.com/wangzun/gogame/engine/gls"
+	"github.com/wangzun/gogame/engine/math32"
+)
+
+// Tube represents the geometry of a tube along a curve, such as pipes, cables and paths.
+type Tube struct {
+	Geometry
+	Path            math32.Curve
+	TubularSegments int
+	Radius          float64
+	RadialSegments  int
+	Closed          bool
+}
+
+// NewTube creates and returns a pointer to a new Tube geometry with the specified radius
+// along the specified curve, with the specified number of segments along the curve and
+// around it. A closed tube joins its end to its start without twisting.
+// The U texture coordinate goes along the curve and the V coordinate around it.
+func NewTube(path math32.Curve, tubularSegments int, radius float64, radialSegments int, closed bool) *Tube {
+
+	t := new(Tube)
+	t.Geometry.Init()
+	t.Path = path
+	t.TubularSegments = tubularSegments
+	t.Radius = radius
+	t.RadialSegments = radialSegments
+	t.Closed = closed
+
+	points, normals, binormals := computeFrames(path, tubularSegments, closed)
+
+	count := (tubularSegments + 1) * (radialSegments + 1)
+	positions := math32.NewArrayF32(0, count*3)
+	vnormals := math32.NewArrayF32(0, count*3)
+	uvs := math32.NewArrayF32(0, count*2)
+	indices := math32.NewArrayU32(0, tubularSegments*radialSegments*6)
+
+	for i := 0; i <= tubularSegments; i++ {
+		// The last ring of closed tubes repeats the first one with other texture coordinates
+		k := i
+		if closed && i == tubularSegments {
+			k = 0
+		}
+		for j := 0; j <= radialSegments; j++ {
+			v := float64(j) / float64(radialSegments) * 2 * math.Pi
+			sin := float32(math.Sin(v))
+			cos := float32(math.Cos(v))
+			var normal math32.Vector3
+			normal.Set(
+				cos*normals[k].X+sin*binormals[k].X,
+				cos*normals[k].Y+sin*binormals[k].Y,
+				cos*normals[k].Z+sin*binormals[k].Z,
+			).Normalize()
+			var vertex math32.Vector3
+			vertex.Copy(&normal).MultiplyScalar(float32(radius)).Add(&points[k])
+			positions.AppendVector3(&vertex)
+			vnormals.AppendVector3(&normal)
+			uvs.Append(float32(i)/float32(tubularSegments), float32(j)/float32(radialSegments))
+		}
+	}
+
+	stride := radialSegments + 1
+	for i := 0; i < tubularSegments; i++ {
+		for j := 0; j < radialSegments; j++ {
+			a := uint32(i*stride + j)
+			b := uint32(i*stride + j + 1)
+			c := uint32((i+1)*stride + j + 1)
+			d := uint32((i+1)*stride + j)
+			indices.Append(a, b, c, a, c, d)
+		}
+	}
+
+	t.SetIndices(indices)
+	t.AddVBO(gls.NewVBO(positions).AddAttrib(gls.VertexPosition))
+	t.AddVBO(gls.NewVBO(vnormals).AddAttrib(gls.VertexNormal))
+	t.AddVBO(gls.NewVBO(uvs).AddAttrib(gls.VertexTexcoord))
+	return t
+}
+
+// computeFrames returns the points of the specified curve at the specified number of
+// segments and their normals and binormals computed by parallel transport,
+// which avoids the sudden twists of Frenet frames.
+func computeFrames(path math32.Curve, segments int, closed bool) (points, normals, binormals []math32.Vector3) {
+
+	n := segments + 1
+	points = make([]math32.Vector3, n)
+	tangents := make([]math32.Vector3, n)
+	normals = make([]math32.Vector3, n)
+	binormals = make([]math32.Vector3, n)
+	for i := 0; i < n; i++ {
+		u := float32(i) / float32(segments)
+		points[i] = path.Point(u)
+		tangents[i] = path.Tangent(u)
+		tangents[i].Normalize()
+	}
+
+	// Initial normal perpendicular to the tangent along its smallest component
+	t0 := &tangents[0]
+	var axis math32.Vector3
+	ax, ay, az := math32.Abs(t0.X), math32.Abs(t0.Y), math32.Abs(t0.Z)
+	if ax <= ay && ax <= az {
+		axis.Set(1, 0, 0)
+	} else if ay <= az {
+		axis.Set(0, 1, 0)
+	} else {
+		axis.Set(0, 0, 1)
+	}
+	var vec math32.Vector3
+	vec.CrossVectors(t0, &axis).Normalize()
+	normals[0].CrossVectors(t0, &vec)
+	binormals[0].CrossVectors(t0, &normals[0])
+
+	// Rotates the previous normal by the rotation between consecutive tangents
+	for i := 1; i < n; i++ {
+		normals[i] = normals[i-1]
+		vec.CrossVectors(&tangents[i-1], &tangents[i])
+		if vec.Length() > 1e-6 {
+			vec.Normalize()
+			theta := math32.Acos(math32.Clamp(tangents[i-1].Dot(&tangents[i]), -1, 1))
+			normals[i].ApplyAxisAngle(&vec, theta)
+		}
+		binormals[i].CrossVectors(&tangents[i], &normals[i])
+	}
+
+	// Distributes the twist between the last and first normals of closed curves along the curve
+	if closed {
+		theta := math32.Acos(math32.Clamp(normals[0].Dot(&normals[n-1]), -1, 1)) / float32(segments)
+		vec.CrossVectors(&normals[0], &normals[n-1])
+		if tangents[0].Dot(&vec) > 0 {
+			theta = -theta
+		}
+		for i := 1; i < n; i++ {
+			normals[i].ApplyAxisAngle(&tangents[i], theta*float32(i))
+			binormals[i].CrossVectors(&tangents[i], &normals[i])
+		}
+	}
+	return points, normals, binormals
+}
@@ -0,0 +1,124 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gls
+
+import (
+	"strings"
+
+	"golang.org/x/mobile/gl"
+)
+
+// Compressed texture internal formats from OpenGL ES and its extensions
+const (
+	ETC1_RGB8_OES                             = 0x8D64
+	COMPRESSED_R11_EAC                        = 0x9270
+	COMPRESSED_SIGNED_R11_EAC                 = 0x9271
+	COMPRESSED_RG11_EAC                       = 0x9272
+	COMPRESSED_SIGNED_RG11_EAC                = 0x9273
+	COMPRESSED_RGB8_ETC2                      = 0x9274
+	COMPRESSED_SRGB8_ETC2                     = 0x9275
+	COMPRESSED_RGB8_PUNCHTHROUGH_ALPHA1_ETC2  = 0x9276
+	COMPRESSED_SRGB8_PUNCHTHROUGH_ALPHA1_ETC2 = 0x9277
+	COMPRESSED_RGBA8_ETC2_EAC                 = 0x9278
+	COMPRESSED_SRGB8_ALPHA8_ETC2_EAC          = 0x9279
+	COMPRESSED_RGB_PVRTC_4BPPV1_IMG           = 0x8C00
+	COMPRESSED_RGB_PVRTC_2BPPV1_IMG           = 0x8C01
+	COMPRESSED_RGBA_PVRTC_4BPPV1_IMG          = 0x8C02
+	COMPRESSED_RGBA_PVRTC_2BPPV1_IMG          = 0x8C03
+	COMPRESSED_RGBA_ASTC_4x4_KHR              = 0x93B0
+	COMPRESSED_RGBA_ASTC_5x4_KHR              = 0x93B1
+	COMPRESSED_RGBA_ASTC_5x5_KHR              = 0x93B2
+	COMPRESSED_RGBA_ASTC_6x5_KHR              = 0x93B3
+	COMPRESSED_RGBA_ASTC_6x6_KHR              = 0x93B4
+	COMPRESSED_RGBA_ASTC_8x5_KHR              = 0x93B5
+	COMPRESSED_RGBA_ASTC_8x6_KHR              = 0x93B6
+	COMPRESSED_RGBA_ASTC_8x8_KHR              = 0x93B7
+	COMPRESSED_RGBA_ASTC_10x5_KHR             = 0x93B8
+	COMPRESSED_RGBA_ASTC_10x6_KHR             = 0x93B9
+	COMPRESSED_RGBA_ASTC_10x8_KHR             = 0x93BA
+	COMPRESSED_RGBA_ASTC_10x10_KHR            = 0x93BB
+	COMPRESSED_RGBA_ASTC_12x10_KHR            = 0x93BC
+	COMPRESSED_RGBA_ASTC_12x12_KHR            = 0x93BD
+	COMPRESSED_SRGB8_ALPHA8_ASTC_4x4_KHR      = 0x93D0
+	COMPRESSED_SRGB8_ALPHA8_ASTC_12x12_KHR    = 0x93DD
+)
+
+// CompressedTexImage2D specifies a two-dimensional texture image in a compressed format.
+// ETC1 images are uploaded as COMPRESSED_RGB8_ETC2, which can decode them, on
+// OpenGL ES 3 contexts without the ETC1 extension.
+func (gs *GLS) CompressedTexImage2D(target uint32, level int32, iformat uint32, width int32, height int32, border int32, data []uint8) {
+
+	if iformat == ETC1_RGB8_OES && gs.SupportsCompressedFormat(ETC1_RGB8_OES) && gs.etc1AsETC2 {
+		iformat = COMPRESSED_RGB8_ETC2
+	}
+	gs.context.CompressedTexImage2D(gl.Enum(target), int(level), gl.Enum(iformat), int(width), int(height), int(border), data)
+	gs.DoCheck()
+}
+
+// Extensions returns the names of the OpenGL extensions supported by the current context.
+// The names are queried only on the first call.
+func (gs *GLS) Extensions() []string {
+
+	if gs.extensions == nil {
+		gs.extensions = strings.Fields(gs.GetString(EXTENSIONS))
+	}
+	return gs.extensions
+}
+
+// HasExtension returns whether the specified OpenGL extension is supported by the current context.
+func (gs *GLS) HasExtension(name string) bool {
+
+	for _, ext := range gs.Extensions() {
+		if ext == name {
+			return true
+		}
+	}
+	return false
+}
+
+// SupportsCompressedFormat returns whether the specified compressed
+// texture internal format can be uploaded using CompressedTexImage2D().
+// It checks the formats reported by the driver, the OpenGL ES version and the
+// compressed texture extensions. The result for each format is cached.
+func (gs *GLS) SupportsCompressedFormat(format uint32) bool {
+
+	if gs.compressedFormats == nil {
+		gs.compressedFormats = make(map[uint32]bool)
+		count := gs.GetInteger(NUM_COMPRESSED_TEXTURE_FORMATS)
+		if count > 0 {
+			formats := make([]int32, count)
+			gs.context.GetIntegerv(formats, COMPRESSED_TEXTURE_FORMATS)
+			gs.DoCheck()
+			for _, f := range formats {
+				gs.compressedFormats[uint32(f)] = true
+			}
+		}
+		// Some drivers do not report all the formats supported by the extensions
+		es3 := strings.Contains(gs.GetString(gl.VERSION), "OpenGL ES 3")
+		etc1 := gs.compressedFormats[ETC1_RGB8_OES] || gs.HasExtension("GL_OES_compressed_ETC1_RGB8_texture")
+		etc2 := es3 || gs.HasExtension("GL_ARB_ES3_compatibility")
+		// ETC2 is a superset of ETC1, so ETC1 images can be uploaded in the ETC2 format
+		gs.etc1AsETC2 = !etc1 && etc2
+		etc1 = etc1 || etc2
+		pvrtc := gs.HasExtension("GL_IMG_texture_compression_pvrtc")
+		astc := gs.HasExtension("GL_KHR_texture_compression_astc_ldr")
+		if etc1 {
+			gs.compressedFormats[ETC1_RGB8_OES] = true
+		}
+		for f := uint32(COMPRESSED_R11_EAC); f <= COMPRESSED_SRGB8_ALPHA8_ETC2_EAC; f++ {
+			gs.compressedFormats[f] = gs.compressedFormats[f] || etc2
+		}
+		for f := uint32(COMPRESSED_RGB_PVRTC_4BPPV1_IMG); f <= COMPRESSED_RGBA_PVRTC_2BPPV1_IMG; f++ {
+			gs.compressedFormats[f] = gs.compressedFormats[f] || pvrtc
+		}
+		for f := uint32(COMPRESSED_RGBA_ASTC_4x4_KHR); f <= COMPRESSED_RGBA_ASTC_12x12_KHR; f++ {
+			gs.compressedFormats[f] = gs.compressedFormats[f] || astc
+		}
+		for f := uint32(COMPRESSED_SRGB8_ALPHA8_ASTC_4x4_KHR); f <= COMPRESSED_SRGB8_ALPHA8_ASTC_12x12_KHR; f++ {
+			gs.compressedFormats[f] = gs.compressedFormats[f] || astc
+		}
+	}
+	return gs.compressedFormats[format]
+}
@@ -48,6 +48,9 @@ type GLS struct {
 	polygonModeMode     uint32            // cached last set polygon mode mode
 	polygonOffsetFactor float32           // cached last set polygon offset factor
 	polygonOffsetUnits  float32           // cached last set polygon offset units
+	extensions          []string          // cached supported extensions names
+	compressedFormats   map[uint32]bool   // cached supported compressed texture formats
+	etc1AsETC2          bool              // ETC1 textures are uploaded in the ETC2 format
 	gobuf               []byte            // conversion buffer with GO memory
 	cbuf                []byte            // conversion buffer with C memory
 	log                 *logger.Logger
@@ -243,6 +246,19 @@ func (gs *GLS) BindBuffer(target gl.Enum, vbo gl.Buffer) {
 	gs.DoCheck()
 }
 
+// BindFramebuffer binds the specified framebuffer object to the target.
+// The zero framebuffer is the default framebuffer of the window.
+func (gs *GLS) BindFramebuffer(target gl.Enum, fb gl.Framebuffer) {
+	gs.context.BindFramebuffer(target, fb)
+	gs.DoCheck()
+}
+
+// BindRenderbuffer binds the specified renderbuffer object to the target.
+func (gs *GLS) BindRenderbuffer(target gl.Enum, rb gl.Renderbuffer) {
+	gs.context.BindRenderbuffer(target, rb)
+	gs.DoCheck()
+}
+
 // BindTexture lets you create or use a named texture.
 func (gs *GLS) BindTexture(target gl.Enum, tex gl.Texture) {
 	gs.context.BindTexture(target, tex)
@@ -321,6 +337,15 @@ func (gs *GLS) BufferData(target uint32, size int, data []byte, usage uint32) {
 	gs.DoCheck()
 }
 
+// CheckFramebufferStatus returns the completeness status
+// of the framebuffer object bound to the target.
+func (gs *GLS) CheckFramebufferStatus(target gl.Enum) gl.Enum {
+
+	status := gs.context.CheckFramebufferStatus(target)
+	gs.DoCheck()
+	return status
+}
+
 // ClearColor specifies the red, green, blue, and alpha values
 // used by glClear to clear the color buffers.
 func (gs *GLS) ClearColor(r, g, b, a float32) {
@@ -379,6 +404,18 @@ func (gs *GLS) DeleteBuffers(bufs ...gl.Buffer) {
 	gs.stats.Buffers -= len(bufs)
 }
 
+// DeleteFramebuffer deletes the specified framebuffer object.
+func (gs *GLS) DeleteFramebuffer(fb gl.Framebuffer) {
+	gs.context.DeleteFramebuffer(fb)
+	gs.DoCheck()
+}
+
+// DeleteRenderbuffer deletes the specified renderbuffer object.
+func (gs *GLS) DeleteRenderbuffer(rb gl.Renderbuffer) {
+	gs.context.DeleteRenderbuffer(rb)
+	gs.DoCheck()
+}
+
 // DeleteShader frees the memory and invalidates the name
 // associated with the specified shader object.
 func (gs *GLS) DeleteShader(shader gl.Shader) {
@@ -521,6 +558,20 @@ func (gs *GLS) FrontFace(mode uint32) {
 	gs.frontFace = mode
 }
 
+// FramebufferRenderbuffer attaches the specified renderbuffer object
+// to the attachment point of the framebuffer bound to the target.
+func (gs *GLS) FramebufferRenderbuffer(target, attachment, rbTarget gl.Enum, rb gl.Renderbuffer) {
+	gs.context.FramebufferRenderbuffer(target, attachment, rbTarget, rb)
+	gs.DoCheck()
+}
+
+// FramebufferTexture2D attaches the specified level of a texture object
+// to the attachment point of the framebuffer bound to the target.
+func (gs *GLS) FramebufferTexture2D(target, attachment, texTarget gl.Enum, tex gl.Texture, level int) {
+	gs.context.FramebufferTexture2D(target, attachment, texTarget, tex, level)
+	gs.DoCheck()
+}
+
 // GenBuffer generates a​buffer object name.
 func (gs *GLS) GenBuffer() gl.Buffer {
 
@@ -530,6 +581,22 @@ func (gs *GLS) GenBuffer() gl.Buffer {
 	return buf
 }
 
+// GenFramebuffer generates a framebuffer object name.
+func (gs *GLS) GenFramebuffer() gl.Framebuffer {
+
+	fb := gs.context.CreateFramebuffer()
+	gs.DoCheck()
+	return fb
+}
+
+// GenRenderbuffer generates a renderbuffer object name.
+func (gs *GLS) GenRenderbuffer() gl.Renderbuffer {
+
+	rb := gs.context.CreateRenderbuffer()
+	gs.DoCheck()
+	return rb
+}
+
 // GenerateMipmap generates mipmaps for the specified texture target.
 func (gs *GLS) GenerateMipmap(target uint32) {
 	gs.context.GenerateMipmap(gl.Enum(target))
@@ -666,6 +733,13 @@ func (gs *GLS) GetShaderiv(shader gl.Shader, pname uint32, params *int32) {
 	*params = int32(p)
 }
 
+// RenderbufferStorage establishes the data storage, format and dimensions
+// of the image of the renderbuffer object bound to the target.
+func (gs *GLS) RenderbufferStorage(target, internalFormat gl.Enum, width, height int) {
+	gs.context.RenderbufferStorage(target, internalFormat, width, height)
+	gs.DoCheck()
+}
+
 // Scissor defines the scissor box rectangle in window coordinates.
 func (gs *GLS) Scissor(x, y int32, width, height uint32) {
 
@@ -681,6 +755,35 @@ func (gs *GLS) ShaderSource(shader gl.Shader, src string) {
 	gs.DoCheck()
 }
 
+// ClearStencil specifies the index used by Clear() to clear the stencil buffer.
+func (gs *GLS) ClearStencil(s int32) {
+
+	gs.context.ClearStencil(int(s))
+	gs.DoCheck()
+}
+
+// StencilFunc sets the function, reference value and mask used by the stencil test.
+func (gs *GLS) StencilFunc(fn uint32, ref int32, mask uint32) {
+
+	gs.context.StencilFunc(gl.Enum(fn), int(ref), mask)
+	gs.DoCheck()
+}
+
+// StencilMask controls the writing of individual bits in the stencil buffer.
+func (gs *GLS) StencilMask(mask uint32) {
+
+	gs.context.StencilMask(mask)
+	gs.DoCheck()
+}
+
+// StencilOp sets the actions taken when the stencil test fails, when it passes but the
+// depth test fails and when both the stencil and depth tests pass.
+func (gs *GLS) StencilOp(sfail, dpfail, dppass uint32) {
+
+	gs.context.StencilOp(gl.Enum(sfail), gl.Enum(dpfail), gl.Enum(dppass))
+	gs.DoCheck()
+}
+
 // TexImage2D specifies a two-dimensional texture image.
 
 // "encoding/gob"
@@ -701,6 +804,13 @@ func (gs *GLS) TexImage2D(target uint32, level int32, iformat int32, width int32
 
 // }
 
+// TexSubImage2D specifies a two-dimensional texture subimage.
+func (gs *GLS) TexSubImage2D(target uint32, level int32, xoffset, yoffset, width, height int32, format uint32, itype uint32, data []uint8) {
+
+	gs.context.TexSubImage2D(gl.Enum(target), int(level), int(xoffset), int(yoffset), int(width), int(height), gl.Enum(format), gl.Enum(itype), data)
+	gs.DoCheck()
+}
+
 // TexParameteri sets the specified texture parameter on the specified texture.
 func (gs *GLS) TexParameteri(target uint32, pname uint32, param int32) {
 
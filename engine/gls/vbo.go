@@ -65,7 +65,7 @@ var attribTypeNameMap = map[AttribType]string{
 var attribTypeSizeMap = map[AttribType]int32{
 	VertexPosition:  3,
 	VertexNormal:    3,
-	VertexTangent:   3, // TODO
+	VertexTangent:   4,
 	VertexColor:     3,
 	VertexTexcoord:  2,
 	VertexTexcoord2: 2,
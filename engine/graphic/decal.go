@@ -0,0 +1,376 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package graphic
+
+import (
+	"github.com/wangzun/gogame/engine/core"
+	"github.com/wangzun/gogame/engine/geometry"
+	"github.com/wangzun/gogame/engine/gls"
+	"github.com/wangzun/gogame/engine/material"
+	"github.com/wangzun/gogame/engine/math32"
+)
+
+// Decal is a mesh which projects a texture through an oriented box onto the
+// triangles of other meshes. Its geometry is built on the CPU by clipping the
+// target triangles which face the projector against the box.
+// The texture is projected along the negative Z axis of the box with its
+// top in the direction of the positive Y axis.
+// The decal vertices are in the box coordinates and its position and rotation are
+// set to the box ones, so it must be added to a node with identity world transform,
+// normally the scene root or a DecalPool.
+type Decal struct {
+	Mesh                    // Embedded mesh
+	size     math32.Vector3 // Size of the projection box
+	opacity  float32        // Opacity before fading
+	fade     float32        // Current fading factor
+	lifetime float32        // Time in seconds before fading starts (0 = forever)
+	fadeTime float32        // Fading duration in seconds
+	age      float32        // Time in seconds since creation
+}
+
+// decalMaterial wraps the material of a decal to render it with the decal opacity,
+// so decals sharing a material can have different opacities and fade independently.
+type decalMaterial struct {
+	material.IMaterial        // Wrapped material
+	decal              *Decal // Decal rendered with the material
+}
+
+// RenderSetup sets the wrapped material opacity to the decal one while its uniforms are
+// transferred, if the material supports it, and restores it afterwards.
+func (dm *decalMaterial) RenderSetup(gs *gls.GLS) {
+
+	om, ok := dm.IMaterial.(interface {
+		SetOpacity(float32)
+		Opacity() float32
+	})
+	if !ok {
+		dm.IMaterial.RenderSetup(gs)
+		return
+	}
+	prev := om.Opacity()
+	om.SetOpacity(prev * dm.decal.opacity * dm.decal.fade)
+	dm.IMaterial.RenderSetup(gs)
+	om.SetOpacity(prev)
+}
+
+// decalVertex is a vertex of a polygon being clipped in projector coordinates
+type decalVertex struct {
+	pos    math32.Vector3
+	normal math32.Vector3
+}
+
+// NewDecal creates and returns a pointer to a new decal projecting the specified material
+// through a box with the specified world position, orientation and size onto the triangles
+// of the specified target graphics. Skinned and morphed targets are used in their bind pose.
+// The specified material is changed for decal rendering: its polygon offset is set to avoid
+// z-fighting with the targets, its depth mask is disabled and it is made transparent, so it
+// should only be used by decals. It can be shared by many decals, each one keeping its own
+// reference to it, which is released when the decal is disposed, and rendering it with its
+// own opacity and fading.
+func NewDecal(position *math32.Vector3, orientation *math32.Quaternion, size *math32.Vector3, imat material.IMaterial, targets ...IGraphic) *Decal {
+
+	d := new(Decal)
+	d.size = *size
+	d.opacity = 1
+	d.fade = 1
+
+	// Projector matrix and its inverse
+	var projector, inverse math32.Matrix4
+	projector.Compose(position, orientation, &math32.Vector3{1, 1, 1})
+	inverse.GetInverse(&projector)
+
+	geom := geometry.NewGeometry()
+	positions := math32.NewArrayF32(0, 0)
+	normals := math32.NewArrayF32(0, 0)
+	uvs := math32.NewArrayF32(0, 0)
+	for _, igr := range targets {
+		d.clipTarget(igr, &inverse, &positions, &normals, &uvs)
+	}
+	geom.AddVBO(gls.NewVBO(positions).AddAttrib(gls.VertexPosition))
+	geom.AddVBO(gls.NewVBO(normals).AddAttrib(gls.VertexNormal))
+	geom.AddVBO(gls.NewVBO(uvs).AddAttrib(gls.VertexTexcoord))
+
+	mat := imat.GetMaterial()
+	mat.SetPolygonOffset(-1, -4)
+	mat.SetDepthMask(false)
+	mat.SetTransparent(true)
+
+	mat.Incref()
+	d.Mesh.Init(geom, &decalMaterial{IMaterial: imat, decal: d})
+	d.SetPositionVec(position)
+	d.SetQuaternionQuat(orientation)
+	return d
+}
+
+// Size returns the size of the decal projection box.
+func (d *Decal) Size() math32.Vector3 {
+
+	return d.size
+}
+
+// SetOpacity sets the opacity of the decal before fading (default = 1).
+// It multiplies the opacity of its material, if the material has the SetOpacity
+// and Opacity methods, without changing it for other decals.
+func (d *Decal) SetOpacity(opacity float32) {
+
+	d.opacity = opacity
+}
+
+// SetLifetime sets the time in seconds the decal is shown with its full opacity
+// and the time it takes to fade out after that. A zero lifetime shows the decal forever.
+func (d *Decal) SetLifetime(lifetime, fadeTime float32) {
+
+	d.lifetime = lifetime
+	d.fadeTime = fadeTime
+}
+
+// Age returns the time in seconds since the decal was created.
+func (d *Decal) Age() float32 {
+
+	return d.age
+}
+
+// Expired returns whether the decal lifetime and fading time have elapsed.
+func (d *Decal) Expired() bool {
+
+	return d.lifetime > 0 && d.age >= d.lifetime+d.fadeTime
+}
+
+// Update advances the age of the decal by the specified time in seconds and updates its fading.
+func (d *Decal) Update(delta float32) {
+
+	d.age += delta
+	if d.lifetime <= 0 || d.age < d.lifetime {
+		return
+	}
+	d.fade = 0
+	if d.fadeTime > 0 && d.age < d.lifetime+d.fadeTime {
+		d.fade = 1 - (d.age-d.lifetime)/d.fadeTime
+	}
+}
+
+// clipTarget clips the triangles of the specified graphic against the decal box
+// and appends the resulting triangles to the specified arrays.
+func (d *Decal) clipTarget(igr IGraphic, inverse *math32.Matrix4, positions, normals, uvs *math32.ArrayF32) {
+
+	gr := igr.GetGraphic()
+	if gr.mode != gls.TRIANGLES {
+		return
+	}
+	geom := igr.GetGeometry()
+
+	// Transform from the target model coordinates to the projector coordinates
+	var toProj math32.Matrix4
+	mw := igr.GetNode().MatrixWorld()
+	toProj.MultiplyMatrices(inverse, &mw)
+	var nm math32.Matrix3
+	nm.GetNormalMatrix(&toProj)
+
+	// Reads the target vertices
+	verts := make([]decalVertex, 0)
+	geom.ReadVertices(func(v math32.Vector3) bool {
+		verts = append(verts, decalVertex{pos: *v.ApplyMatrix4(&toProj)})
+		return false
+	})
+	hasNormals := geom.VBO(gls.VertexNormal) != nil
+	i := 0
+	geom.ReadVertexNormals(func(n math32.Vector3) bool {
+		if i < len(verts) {
+			verts[i].normal = *n.ApplyMatrix3(&nm).Normalize()
+		}
+		i++
+		return false
+	})
+
+	// Clips each triangle
+	indices := geom.Indices()
+	count := len(verts)
+	if geom.Indexed() {
+		count = indices.Size()
+	}
+	half := math32.Vector3{d.size.X / 2, d.size.Y / 2, d.size.Z / 2}
+	poly := make([]decalVertex, 0, 9)
+	for t := 0; t+2 < count; t += 3 {
+		poly = poly[:0]
+		for k := 0; k < 3; k++ {
+			idx := t + k
+			if geom.Indexed() {
+				idx = int(indices[idx])
+			}
+			if idx >= len(verts) {
+				break
+			}
+			poly = append(poly, verts[idx])
+		}
+		if len(poly) < 3 {
+			continue
+		}
+		// Skips triangles facing away from the projector
+		var e1, e2, fn math32.Vector3
+		e1.SubVectors(&poly[1].pos, &poly[0].pos)
+		e2.SubVectors(&poly[2].pos, &poly[0].pos)
+		fn.CrossVectors(&e1, &e2)
+		if fn.Z <= 0 {
+			continue
+		}
+		if !hasNormals {
+			fn.Normalize()
+			for k := range poly {
+				poly[k].normal = fn
+			}
+		}
+		poly = clipDecalPolygon(poly, &half)
+		// Triangulates the clipped convex polygon as a fan
+		for k := 1; k+1 < len(poly); k++ {
+			for _, v := range [3]*decalVertex{&poly[0], &poly[k], &poly[k+1]} {
+				positions.AppendVector3(&v.pos)
+				normals.AppendVector3(&v.normal)
+				uvs.Append(v.pos.X/d.size.X+0.5, v.pos.Y/d.size.Y+0.5)
+			}
+		}
+	}
+}
+
+// Squared distance below which clipped vertices are considered equal
+const decalEpsilon = 1e-10
+
+// appendDecalVertex appends the specified vertex to the polygon if it is not equal to its last vertex.
+func appendDecalVertex(poly []decalVertex, v *decalVertex) []decalVertex {
+
+	if len(poly) > 0 && poly[len(poly)-1].pos.DistanceToSquared(&v.pos) < decalEpsilon {
+		return poly
+	}
+	return append(poly, *v)
+}
+
+// clipDecalPolygon clips the specified convex polygon against the six planes
+// of the box centered at the origin with the specified half size.
+func clipDecalPolygon(poly []decalVertex, half *math32.Vector3) []decalVertex {
+
+	for axis := 0; axis < 3; axis++ {
+		for _, sign := range [2]float32{1, -1} {
+			if len(poly) == 0 {
+				return poly
+			}
+			// Signed distance inside the plane (positive when inside)
+			dist := func(v *decalVertex) float32 {
+				return half.Component(axis) - sign*v.pos.Component(axis)
+			}
+			out := make([]decalVertex, 0, len(poly)+1)
+			for i := range poly {
+				a := &poly[i]
+				b := &poly[(i+1)%len(poly)]
+				da := dist(a)
+				db := dist(b)
+				if da >= 0 {
+					out = appendDecalVertex(out, a)
+				}
+				// Edge crosses the plane
+				if (da >= 0) != (db >= 0) {
+					t := da / (da - db)
+					var v decalVertex
+					v.pos = a.pos
+					v.pos.Lerp(&b.pos, t)
+					v.normal = a.normal
+					v.normal.Lerp(&b.normal, t).Normalize()
+					out = appendDecalVertex(out, &v)
+				}
+			}
+			// Removes closing vertex equal to the first one
+			if len(out) > 1 && out[0].pos.DistanceToSquared(&out[len(out)-1].pos) < decalEpsilon {
+				out = out[:len(out)-1]
+			}
+			poly = out
+		}
+	}
+	return poly
+}
+
+// DecalPool is a node which contains decals, recycling the oldest decals when
+// the maximum number of decals is reached and removing expired decals.
+// It must be added to a node with identity world transform, normally the scene root.
+type DecalPool struct {
+	core.Node          // Embedded node
+	max       int      // Maximum number of decals
+	decals    []*Decal // Decals in creation order
+}
+
+// NewDecalPool creates and returns a pointer to a new decal pool with the specified maximum number of decals.
+func NewDecalPool(max int) *DecalPool {
+
+	p := new(DecalPool)
+	p.Node.Init()
+	p.max = max
+	return p
+}
+
+// SetMax sets the maximum number of decals, recycling the oldest decals if necessary.
+func (p *DecalPool) SetMax(max int) {
+
+	p.max = max
+	p.recycle(0)
+}
+
+// Max returns the maximum number of decals.
+func (p *DecalPool) Max() int {
+
+	return p.max
+}
+
+// Count returns the current number of decals.
+func (p *DecalPool) Count() int {
+
+	return len(p.decals)
+}
+
+// AddDecal adds the specified decal to the pool, disposing the oldest decal if the pool is full.
+func (p *DecalPool) AddDecal(d *Decal) {
+
+	p.recycle(1)
+	p.decals = append(p.decals, d)
+	p.Add(d)
+}
+
+// Update updates the fading of all decals by the specified time
+// in seconds and disposes the expired decals.
+func (p *DecalPool) Update(delta float32) {
+
+	alive := p.decals[:0]
+	for _, d := range p.decals {
+		d.Update(delta)
+		if d.Expired() {
+			p.Remove(d)
+			d.Dispose()
+			continue
+		}
+		alive = append(alive, d)
+	}
+	for i := len(alive); i < len(p.decals); i++ {
+		p.decals[i] = nil
+	}
+	p.decals = alive
+}
+
+// Clear removes and disposes all decals.
+func (p *DecalPool) Clear() {
+
+	for _, d := range p.decals {
+		p.Remove(d)
+		d.Dispose()
+	}
+	p.decals = nil
+}
+
+// recycle disposes the oldest decals so that the specified number of decals can be added.
+func (p *DecalPool) recycle(adding int) {
+
+	for len(p.decals) > 0 && len(p.decals)+adding > p.max {
+		d := p.decals[0]
+		p.decals[0] = nil
+		p.decals = p.decals[1:]
+		p.Remove(d)
+		d.Dispose()
+	}
+}
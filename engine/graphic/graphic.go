@@ -24,6 +24,9 @@ type Graphic struct {
 	renderable  bool               // Renderable flag
 	cullable    bool               // Cullable flag
 	renderOrder int                // Render order
+	outlined    bool               // Outline enabled flag
+	outline     *material.Outline  // Outline material created on demand
+	outlineMats []GraphicMaterial  // Outline graphic materials
 
 	ShaderDefines gls.ShaderDefines // Graphic-specific shader defines
 
@@ -106,6 +109,9 @@ func (gr *Graphic) Dispose() {
 	for i := 0; i < len(gr.materials); i++ {
 		gr.materials[i].imat.Dispose()
 	}
+	if gr.outline != nil {
+		gr.outline.Dispose()
+	}
 }
 
 // Clone clones the graphic and satisfies the INode interface.
@@ -121,6 +127,11 @@ func (gr *Graphic) Clone() core.INode {
 	clone.renderable = gr.renderable
 	clone.cullable = gr.cullable
 	clone.renderOrder = gr.renderOrder
+	clone.outlined = gr.outlined
+	if gr.outline != nil {
+		color := gr.outline.Color()
+		clone.outline = material.NewOutline(&color, gr.outline.Width())
+	}
 	clone.ShaderDefines = gr.ShaderDefines
 	clone.materials = make([]GraphicMaterial, len(gr.materials))
 
@@ -174,6 +185,66 @@ func (gr *Graphic) RenderOrder() int {
 	return gr.renderOrder
 }
 
+// SetOutlined sets whether the renderer draws an outline around this graphic (default = false).
+// The outline is drawn using the stencil buffer after the opaque or transparent
+// graphics which contain this graphic and supports rigged and morphed meshes.
+func (gr *Graphic) SetOutlined(state bool) {
+
+	gr.outlined = state
+}
+
+// Outlined returns whether the renderer draws an outline around this graphic.
+func (gr *Graphic) Outlined() bool {
+
+	return gr.outlined
+}
+
+// SetOutlineColor sets the color of the outline of this graphic.
+func (gr *Graphic) SetOutlineColor(color *math32.Color4) {
+
+	gr.outlineMaterial().SetColor(color)
+}
+
+// OutlineColor returns the color of the outline of this graphic.
+func (gr *Graphic) OutlineColor() math32.Color4 {
+
+	return gr.outlineMaterial().Color()
+}
+
+// SetOutlineWidth sets the width in pixels of the outline of this graphic (default = 3).
+func (gr *Graphic) SetOutlineWidth(width float32) {
+
+	gr.outlineMaterial().SetWidth(width)
+}
+
+// OutlineWidth returns the width in pixels of the outline of this graphic.
+func (gr *Graphic) OutlineWidth() float32 {
+
+	return gr.outlineMaterial().Width()
+}
+
+// OutlineMaterials returns the graphic materials used by the renderer
+// to draw the outline of this graphic, one for each of its materials.
+func (gr *Graphic) OutlineMaterials() []GraphicMaterial {
+
+	omat := gr.outlineMaterial()
+	gr.outlineMats = gr.outlineMats[:0]
+	for _, grmat := range gr.materials {
+		grmat.imat = omat
+		gr.outlineMats = append(gr.outlineMats, grmat)
+	}
+	return gr.outlineMats
+}
+
+// outlineMaterial returns the outline material of this graphic creating it if necessary.
+func (gr *Graphic) outlineMaterial() *material.Outline {
+
+	if gr.outline == nil {
+		gr.outline = material.NewOutline(&math32.Color4{1, 0.6, 0, 1}, 3)
+	}
+	return gr.outline
+}
+
 // AddMaterial adds a material for the specified subset of vertices.
 // If the material applies to all vertices, start and count must be 0.
 func (gr *Graphic) AddMaterial(igr IGraphic, imat material.IMaterial, start, count int) {
@@ -17,6 +17,7 @@ import (
 type Points struct {
 	Graphic             // Embedded graphic
 	uniMVPm gls.Uniform // Model view projection matrix uniform location cache
+	uniMVm  gls.Uniform // Model view matrix uniform location cache
 }
 
 // NewPoints creates and returns a graphic points object with the specified
@@ -29,6 +30,7 @@ func NewPoints(igeom geometry.IGeometry, imat material.IMaterial) *Points {
 		p.AddMaterial(p, imat, 0, 0)
 	}
 	p.uniMVPm.Init("MVP")
+	p.uniMVm.Init("ModelViewMatrix")
 	return p
 }
 
@@ -41,6 +43,11 @@ func (p *Points) RenderSetup(gs *gls.GLS, rinfo *core.RenderInfo) {
 	// gs.UniformMatrix4fv(location, 1, false, &mvpm[0])
 
 	gs.UniformMatrix4fv(gl.Uniform{Value: location}, 1, false, mvpm[:])
+
+	// Transfer model view matrix uniform (used by fog)
+	mvm := p.ModelViewMatrix()
+	location = p.uniMVm.Location(gs)
+	gs.UniformMatrix4fv(gl.Uniform{Value: location}, 1, false, mvm[:])
 }
 
 // Raycast satisfies the INode interface and checks the intersections
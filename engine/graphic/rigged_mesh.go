@@ -16,10 +16,18 @@ import (
 // MaxBoneInfluencers is the maximum number of bone influencers per vertex.
 const MaxBoneInfluencers = 4
 
+// MaxPaletteBones is the default maximum number of bones of a rigged mesh, whose matrices
+// fit in the 128 vertex uniform vectors guaranteed by OpenGL ES 2.0 along with the other
+// uniforms of the shaders. Meshes with more bones are split by bone palette when loaded.
+const MaxPaletteBones = 24
+
 // RiggedMesh is a Mesh associated with a skeleton.
+// Several rigged meshes, such as the primitives of a skinned mesh, may share the same skeleton.
 type RiggedMesh struct {
-	*Mesh    // Embedded mesh
-	skeleton *Skeleton
+	*Mesh              // Embedded mesh
+	skeleton *Skeleton // Skeleton deforming the mesh
+	palette  []int     // Optional skeleton bone index of each bone index of the vertices
+	dualQuat bool      // Dual quaternion skinning
 	mBones   gls.Uniform
 }
 
@@ -40,16 +48,60 @@ func NewRiggedMesh(mesh *Mesh) *RiggedMesh {
 func (rm *RiggedMesh) SetSkeleton(sk *Skeleton) {
 
 	rm.skeleton = sk
-	// fmt.Printf("bones : %d", len(rm.skeleton.Bones()))
-	rm.ShaderDefines.Set("TOTAL_BONES", strconv.Itoa(len(rm.skeleton.Bones())))
+	rm.updateTotalBones()
 }
 
-// SetSkeleton returns the skeleton used by the rigged mesh.
+// Skeleton returns the skeleton used by the rigged mesh.
 func (rm *RiggedMesh) Skeleton() *Skeleton {
 
 	return rm.skeleton
 }
 
+// SetBonePalette sets the skeleton bone index of each bone index of the vertices, as returned
+// by Geometry.SplitByBonePalette, so only the matrices of these bones are sent to the shader,
+// or nil to use the bone indices of the skeleton directly.
+func (rm *RiggedMesh) SetBonePalette(palette []int) {
+
+	rm.palette = palette
+	rm.updateTotalBones()
+}
+
+// BonePalette returns the skeleton bone index of each bone index of the vertices or nil if not set.
+func (rm *RiggedMesh) BonePalette() []int {
+
+	return rm.palette
+}
+
+// SetDualQuaternion sets whether the mesh is skinned by blending the bone transforms as dual
+// quaternions instead of matrices, which keeps the volume at twisted joints and avoids the
+// candy-wrapper artifact. Each bone uses half the uniforms of a matrix, but the scale of the
+// bones relative to the mesh is ignored.
+func (rm *RiggedMesh) SetDualQuaternion(state bool) {
+
+	rm.dualQuat = state
+	if state {
+		rm.ShaderDefines.Set("BONE_DQS", "")
+	} else {
+		rm.ShaderDefines.Unset("BONE_DQS")
+	}
+}
+
+// DualQuaternion returns whether the mesh is skinned with dual quaternions.
+func (rm *RiggedMesh) DualQuaternion() bool {
+
+	return rm.dualQuat
+}
+
+// updateTotalBones updates the number of bones sent to the shader.
+func (rm *RiggedMesh) updateTotalBones() {
+
+	total := len(rm.palette)
+	if rm.palette == nil && rm.skeleton != nil {
+		total = len(rm.skeleton.Bones())
+	}
+	rm.ShaderDefines.Set("TOTAL_BONES", strconv.Itoa(total))
+}
+
 // RenderSetup is called by the renderer before drawing the geometry.
 func (rm *RiggedMesh) RenderSetup(gs *gls.GLS, rinfo *core.RenderInfo) {
 
@@ -65,17 +117,38 @@ func (rm *RiggedMesh) RenderSetup(gs *gls.GLS, rinfo *core.RenderInfo) {
 		log.Error("Skeleton.BoneMatrices: inverting matrix failed!")
 	}
 
-	// Transfer bone matrices
+	// Selects the bone matrices of the palette
 	boneMatrices := rm.skeleton.BoneMatrices(&invMat)
+	if rm.palette != nil {
+		selected := make([]math32.Matrix4, len(rm.palette))
+		for i, bone := range rm.palette {
+			selected[i] = boneMatrices[bone]
+		}
+		boneMatrices = selected
+	}
 	location := rm.mBones.Location(gs)
-	// gs.UniformMatrix4fv(location, int32(len(boneMatrices)), false, &boneMatrices[0][0])
 
-	data := make([]float32, 0)
-	for _, v := range boneMatrices {
-		for _, v1 := range v {
-			data = append(data, v1)
+	// Transfer the real and dual parts of the dual quaternion of each bone
+	if rm.dualQuat {
+		data := make([]float32, 0, 8*len(boneMatrices))
+		var t, scale math32.Vector3
+		var q math32.Quaternion
+		for i := range boneMatrices {
+			boneMatrices[i].Decompose(&t, &q, &scale)
+			data = append(data, q.X, q.Y, q.Z, q.W,
+				0.5*(q.W*t.X+t.Y*q.Z-t.Z*q.Y),
+				0.5*(q.W*t.Y+t.Z*q.X-t.X*q.Z),
+				0.5*(q.W*t.Z+t.X*q.Y-t.Y*q.X),
+				-0.5*(t.X*q.X+t.Y*q.Y+t.Z*q.Z))
 		}
+		gs.Uniform4fv(gl.Uniform{Value: location}, data)
+		return
+	}
+
+	// Transfer bone matrices
+	data := make([]float32, 0, 16*len(boneMatrices))
+	for _, v := range boneMatrices {
+		data = append(data, v[:]...)
 	}
-	// fmt.Println(len(data))
 	gs.UniformMatrix4fv(gl.Uniform{Value: location}, int32(len(boneMatrices)), false, data)
 }
@@ -17,6 +17,7 @@ import (
 type Sprite struct {
 	Graphic             // Embedded graphic
 	uniMVPM gls.Uniform // Model view projection matrix uniform location cache
+	uniMVM  gls.Uniform // Model view matrix uniform location cache
 }
 
 // NewSprite creates and returns a pointer to a sprite with the specified dimensions and material
@@ -53,6 +54,7 @@ func NewSprite(width, height float32, imat material.IMaterial) *Sprite {
 	s.AddMaterial(s, imat, 0, 0)
 
 	s.uniMVPM.Init("MVP")
+	s.uniMVM.Init("ModelViewMatrix")
 	return s
 }
 
@@ -84,6 +86,10 @@ func (s *Sprite) RenderSetup(gs *gls.GLS, rinfo *core.RenderInfo) {
 	location := s.uniMVPM.Location(gs)
 	// gs.UniformMatrix4fv(location, 1, false, &mvpm[0])
 	gs.UniformMatrix4fv(gl.Uniform{Value: location}, 1, false, mvpm[:])
+
+	// Transfer model view matrix uniform (used by fog)
+	location = s.uniMVM.Location(gs)
+	gs.UniformMatrix4fv(gl.Uniform{Value: location}, 1, false, mvmNew[:])
 }
 
 // Raycast checks intersections between this geometry and the specified raycaster
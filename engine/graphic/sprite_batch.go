@@ -0,0 +1,298 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package graphic
+
+import (
+	"sort"
+
+	"github.com/wangzun/gogame/engine/atlas"
+	"github.com/wangzun/gogame/engine/core"
+	"github.com/wangzun/gogame/engine/geometry"
+	"github.com/wangzun/gogame/engine/gls"
+	"github.com/wangzun/gogame/engine/material"
+	"github.com/wangzun/gogame/engine/math32"
+	"github.com/wangzun/gogame/engine/texture"
+	"golang.org/x/mobile/gl"
+)
+
+// SpriteBatch is a Graphic which draws many textured quads using a single
+// dynamic vertex buffer. Quads are grouped by texture so that each group of
+// consecutive quads sharing a texture is rendered with a single draw call.
+// Quads are specified in the batch local XY plane and are normally viewed
+// with an orthographic camera.
+type SpriteBatch struct {
+	Graphic                                             // Embedded graphic
+	quads     []SpriteQuad                              // Quads added since the last Begin()
+	order     []int                                     // Quads indices sorted by depth and texture
+	texIndex  map[*texture.Texture2D]int                // Maps texture to its first use order
+	mats      map[*texture.Texture2D]*material.Material // Material cache by texture
+	positions math32.ArrayF32                           // Interleaved vertex buffer
+	indices   math32.ArrayU32                           // Indices buffer
+	vbo       *gls.VBO                                  // Dynamic vertex buffer object
+	snap      bool                                      // Pixel snapping flag
+	uniMVPm   gls.Uniform                               // Model view projection matrix uniform location cache
+	uniVport  gls.Uniform                               // Viewport size uniform location cache
+}
+
+// SpriteQuad describes a single textured quad of a SpriteBatch.
+type SpriteQuad struct {
+	Texture  *texture.Texture2D // Texture of the quad
+	Position math32.Vector2     // Position of the quad center in the batch XY plane
+	Size     math32.Vector2     // Width and height of the quad before scaling
+	Scale    math32.Vector2     // Scale factors (zero components are considered as 1)
+	Rotation float32            // Rotation around the quad center in radians
+	Color    math32.Color4      // Tint color multiplied by the texture color
+	UV0      math32.Vector2     // Texture coordinates of the bottom left corner
+	UV1      math32.Vector2     // Texture coordinates of the top right corner
+	Rotated  bool               // The texture region is stored rotated 90 degrees clockwise
+	Depth    float32            // Z coordinate of the quad. Quads with greater depth are drawn later.
+}
+
+// Number of float32 elements per vertex: position(3) + texcoord(2) + tint(4)
+const spriteBatchStride = 9
+
+// NewSpriteBatch creates and returns a pointer to a new empty sprite batch.
+func NewSpriteBatch() *SpriteBatch {
+
+	sb := new(SpriteBatch)
+	sb.quads = make([]SpriteQuad, 0)
+	sb.order = make([]int, 0)
+	sb.texIndex = make(map[*texture.Texture2D]int)
+	sb.mats = make(map[*texture.Texture2D]*material.Material)
+	sb.positions = math32.NewArrayF32(0, 0)
+	sb.indices = math32.NewArrayU32(0, 0)
+
+	// Creates geometry with a single interleaved dynamic VBO
+	geom := geometry.NewGeometry()
+	sb.vbo = gls.NewVBO(sb.positions).
+		AddAttrib(gls.VertexPosition).
+		AddAttrib(gls.VertexTexcoord).
+		AddCustomAttrib("VertexTint", 4)
+	sb.vbo.SetUsage(gls.DYNAMIC_DRAW)
+	geom.AddVBO(sb.vbo)
+
+	sb.Graphic.Init(geom, gls.TRIANGLES)
+	// The batch bounding box changes every frame
+	sb.SetCullable(false)
+	sb.uniMVPm.Init("MVP")
+	sb.uniVport.Init("Viewport")
+	return sb
+}
+
+// SetPixelSnap sets whether the quads vertices are snapped to
+// the nearest pixel of the viewport (default = false).
+func (sb *SpriteBatch) SetPixelSnap(state bool) {
+
+	sb.snap = state
+	if state {
+		sb.ShaderDefines.Set("PIXEL_SNAP", "1")
+	} else {
+		sb.ShaderDefines.Unset("PIXEL_SNAP")
+	}
+}
+
+// PixelSnap returns the current pixel snapping state.
+func (sb *SpriteBatch) PixelSnap() bool {
+
+	return sb.snap
+}
+
+// Begin removes all the quads from the batch.
+// It should be called before adding the quads for a new frame.
+func (sb *SpriteBatch) Begin() {
+
+	sb.quads = sb.quads[0:0]
+}
+
+// Draw adds an untinted and unrotated quad showing the whole specified
+// texture, centered at the specified position with the specified size.
+func (sb *SpriteBatch) Draw(tex *texture.Texture2D, x, y, width, height float32) {
+
+	sb.quads = append(sb.quads, SpriteQuad{
+		Texture:  tex,
+		Position: math32.Vector2{X: x, Y: y},
+		Size:     math32.Vector2{X: width, Y: height},
+		Scale:    math32.Vector2{X: 1, Y: 1},
+		Color:    math32.Color4{R: 1, G: 1, B: 1, A: 1},
+		UV0:      math32.Vector2{X: 0, Y: 0},
+		UV1:      math32.Vector2{X: 1, Y: 1},
+	})
+}
+
+// DrawRegion adds an untinted and unrotated quad showing the specified atlas region,
+// centered at the specified position with the specified size.
+func (sb *SpriteBatch) DrawRegion(r *atlas.Region, x, y, width, height float32) {
+
+	uv0, uv1 := r.UV()
+	sb.quads = append(sb.quads, SpriteQuad{
+		Texture:  r.Page.Texture(),
+		Position: math32.Vector2{X: x, Y: y},
+		Size:     math32.Vector2{X: width, Y: height},
+		Scale:    math32.Vector2{X: 1, Y: 1},
+		Color:    math32.Color4{R: 1, G: 1, B: 1, A: 1},
+		UV0:      uv0,
+		UV1:      uv1,
+		Rotated:  r.Rotated,
+	})
+}
+
+// DrawQuad adds a copy of the specified quad to the batch.
+func (sb *SpriteBatch) DrawQuad(q *SpriteQuad) {
+
+	sb.quads = append(sb.quads, *q)
+}
+
+// QuadCount returns the number of quads currently in the batch.
+func (sb *SpriteBatch) QuadCount() int {
+
+	return len(sb.quads)
+}
+
+// End builds the batch vertex buffer and the per texture materials
+// from the quads added since the last call to Begin().
+// The materials of the textures which were used by the previous batch but not
+// by this one are released, so the batch does not keep references to old textures.
+// It must be called before the batch is rendered.
+func (sb *SpriteBatch) End() {
+
+	// Assigns an index to each texture in the order of first use
+	// and sorts the quads by depth and then by texture, so quads with
+	// the same depth and texture are contiguous in the buffer.
+	for k := range sb.texIndex {
+		delete(sb.texIndex, k)
+	}
+	sb.order = sb.order[0:0]
+	for i := range sb.quads {
+		tex := sb.quads[i].Texture
+		if _, ok := sb.texIndex[tex]; !ok {
+			sb.texIndex[tex] = len(sb.texIndex)
+		}
+		sb.order = append(sb.order, i)
+	}
+	sort.SliceStable(sb.order, func(i, j int) bool {
+		q1 := &sb.quads[sb.order[i]]
+		q2 := &sb.quads[sb.order[j]]
+		if q1.Depth != q2.Depth {
+			return q1.Depth < q2.Depth
+		}
+		return sb.texIndex[q1.Texture] < sb.texIndex[q2.Texture]
+	})
+
+	// Fills the vertex and index buffers and adds a material
+	// for each run of quads sharing the same texture.
+	sb.positions = sb.positions[0:0]
+	sb.indices = sb.indices[0:0]
+	sb.Graphic.ClearMaterials()
+	var lastTex *texture.Texture2D
+	start := 0
+	for n, qi := range sb.order {
+		q := &sb.quads[qi]
+		if n > 0 && q.Texture != lastTex {
+			sb.addRun(lastTex, start, sb.indices.Size()-start)
+			start = sb.indices.Size()
+		}
+		lastTex = q.Texture
+		sb.appendQuad(q)
+	}
+	if len(sb.order) > 0 {
+		sb.addRun(lastTex, start, sb.indices.Size()-start)
+	}
+
+	// Releases the materials, and their textures, of the textures not used by this batch
+	for tex, mat := range sb.mats {
+		if _, ok := sb.texIndex[tex]; !ok {
+			mat.Dispose()
+			delete(sb.mats, tex)
+		}
+	}
+
+	sb.vbo.SetBuffer(sb.positions)
+	sb.GetGeometry().SetIndices(sb.indices)
+}
+
+// appendQuad appends the vertices and indices of the specified quad to the buffers.
+func (sb *SpriteBatch) appendQuad(q *SpriteQuad) {
+
+	sx := q.Scale.X
+	if sx == 0 {
+		sx = 1
+	}
+	sy := q.Scale.Y
+	if sy == 0 {
+		sy = 1
+	}
+	hw := q.Size.X * sx / 2
+	hh := q.Size.Y * sy / 2
+	sin := math32.Sin(q.Rotation)
+	cos := math32.Cos(q.Rotation)
+
+	// Corners in counter clockwise order starting at bottom left
+	corners := [4][4]float32{
+		{-hw, -hh, q.UV0.X, q.UV0.Y},
+		{hw, -hh, q.UV1.X, q.UV0.Y},
+		{hw, hh, q.UV1.X, q.UV1.Y},
+		{-hw, hh, q.UV0.X, q.UV1.Y},
+	}
+	// The bottom left corner of a region rotated clockwise is at the top left of the texture region
+	if q.Rotated {
+		uvs := corners
+		for i := range corners {
+			corners[i][2] = uvs[(i+3)%4][2]
+			corners[i][3] = uvs[(i+3)%4][3]
+		}
+	}
+	base := uint32(sb.positions.Size() / spriteBatchStride)
+	for _, c := range corners {
+		x := q.Position.X + c[0]*cos - c[1]*sin
+		y := q.Position.Y + c[0]*sin + c[1]*cos
+		sb.positions.Append(x, y, q.Depth, c[2], c[3], q.Color.R, q.Color.G, q.Color.B, q.Color.A)
+	}
+	sb.indices.Append(base, base+1, base+2, base, base+2, base+3)
+}
+
+// addRun adds a material for the specified range of indices using the specified texture.
+func (sb *SpriteBatch) addRun(tex *texture.Texture2D, start, count int) {
+
+	mat, ok := sb.mats[tex]
+	if !ok {
+		mat = material.NewMaterial()
+		mat.SetShader("spritebatch")
+		mat.SetUseLights(material.UseLightNone)
+		mat.SetTransparent(true)
+		mat.SetSide(material.SideDouble)
+		if tex != nil {
+			mat.AddTexture(tex.Incref())
+		}
+		sb.mats[tex] = mat
+	}
+	sb.Graphic.AddMaterial(sb, mat, start, count)
+}
+
+// Dispose releases the OpenGL resources and the materials used by this batch.
+func (sb *SpriteBatch) Dispose() {
+
+	sb.GetGeometry().Dispose()
+	for tex, mat := range sb.mats {
+		mat.Dispose()
+		delete(sb.mats, tex)
+	}
+	sb.Graphic.ClearMaterials()
+}
+
+// RenderSetup is called by the engine before drawing the batch geometry.
+func (sb *SpriteBatch) RenderSetup(gs *gls.GLS, rinfo *core.RenderInfo) {
+
+	// Transfer model view projection matrix uniform
+	mvpm := sb.ModelViewProjectionMatrix()
+	location := sb.uniMVPm.Location(gs)
+	gs.UniformMatrix4fv(gl.Uniform{Value: location}, 1, false, mvpm[:])
+
+	// Transfer viewport size for pixel snapping
+	if sb.snap {
+		_, _, width, height := gs.GetViewport()
+		location = sb.uniVport.Location(gs)
+		gs.Uniform2f(gl.Uniform{Value: location}, float32(width), float32(height))
+	}
+}
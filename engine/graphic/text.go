@@ -0,0 +1,336 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package graphic
+
+import (
+	"github.com/wangzun/gogame/engine/core"
+	"github.com/wangzun/gogame/engine/geometry"
+	"github.com/wangzun/gogame/engine/gls"
+	"github.com/wangzun/gogame/engine/material"
+	"github.com/wangzun/gogame/engine/math32"
+	"github.com/wangzun/gogame/engine/text"
+	"golang.org/x/mobile/gl"
+)
+
+// TextMode specifies the orientation of a Text graphic
+type TextMode int
+
+// The orientations of Text graphics
+const (
+	TextBillboard TextMode = iota // Always faces the camera like a sprite
+	TextFlat                      // Lies on the XY plane of the node facing its positive Z axis
+)
+
+// TextSizeMode specifies how the size of a Text graphic is interpreted
+type TextSizeMode int
+
+// The size modes of Text graphics
+const (
+	TextSizeWorld  TextSizeMode = iota // Font size in world units, so the text gets smaller with distance
+	TextSizeScreen                     // Font size in screen pixels regardless of the distance
+)
+
+// Text is a graphic which renders text in the scene from the signed distance
+// field atlas of a font. It can always face the camera or lie on the XY plane
+// of the node and its font size can be in world units or in screen pixels.
+// It is normally used for names, damage numbers and other labels attached to nodes.
+type Text struct {
+	Graphic                      // Embedded graphic
+	mat         *material.Text   // Text material
+	msg         string           // Text being displayed
+	size        float32          // Font size in world units or screen pixels
+	sizeMode    TextSizeMode     // Size mode
+	mode        TextMode         // Orientation mode
+	lineSpacing float32          // Spacing between lines in terms of the line height
+	anchor      math32.Vector2   // Text point at the node origin
+	width       float32          // Text width in font size units
+	height      float32          // Text height in font size units
+	scale       float32          // World units per font size unit of the last render
+	quads       []text.GlyphQuad // Glyph quads of the current text
+	uniMVPM     gls.Uniform      // Model view projection matrix uniform location cache
+	uniMVM      gls.Uniform      // Model view matrix uniform location cache
+}
+
+// NewText creates and returns a pointer to a new camera facing text graphic
+// with the specified font, text and font size in world units.
+// The text is centered at the node origin and occluded by other objects.
+func NewText(font *text.Font, msg string, size float32) *Text {
+
+	t := new(Text)
+	t.mat = material.NewText(font)
+	t.mat.SetTransparent(true)
+	t.mat.SetDepthMask(false)
+	t.mat.SetSide(material.SideDouble)
+	t.size = size
+	t.scale = size
+	t.lineSpacing = 1
+	t.anchor = math32.Vector2{0.5, 0.5}
+
+	geom := geometry.NewGeometry()
+	geom.AddVBO(gls.NewVBO(math32.NewArrayF32(0, 0)).
+		AddAttrib(gls.VertexPosition).
+		AddAttrib(gls.VertexTexcoord),
+	)
+	t.Graphic.Init(geom, gls.TRIANGLES)
+	t.AddMaterial(t, t.mat, 0, 0)
+
+	// The geometry is scaled when rendering, so its bounding volumes can't be used for culling
+	t.SetCullable(false)
+
+	t.uniMVPM.Init("MVP")
+	t.uniMVM.Init("ModelViewMatrix")
+	t.SetText(msg)
+	return t
+}
+
+// Material returns the text material, which can be used to set the text color, outline and shadow.
+func (t *Text) Material() *material.Text {
+
+	return t.mat
+}
+
+// SetText sets the text, which can contain line breaks.
+func (t *Text) SetText(msg string) {
+
+	t.msg = msg
+	t.quads, t.width, t.height = t.mat.Atlas().Layout(msg, 1, t.lineSpacing, t.quads[:0])
+	t.updateGeometry()
+}
+
+// Text returns the text.
+func (t *Text) Text() string {
+
+	return t.msg
+}
+
+// SetColor sets the text color.
+func (t *Text) SetColor(color *math32.Color4) {
+
+	t.mat.SetColor(color)
+}
+
+// Color returns the text color.
+func (t *Text) Color() math32.Color4 {
+
+	return t.mat.Color()
+}
+
+// SetSize sets the font size in world units or screen pixels depending on the size mode.
+func (t *Text) SetSize(size float32) {
+
+	t.size = size
+}
+
+// Size returns the font size in world units or screen pixels depending on the size mode.
+func (t *Text) Size() float32 {
+
+	return t.size
+}
+
+// SetSizeMode sets whether the font size is in world units (default) or screen pixels.
+func (t *Text) SetSizeMode(mode TextSizeMode) {
+
+	t.sizeMode = mode
+}
+
+// SizeMode returns whether the font size is in world units or screen pixels.
+func (t *Text) SizeMode() TextSizeMode {
+
+	return t.sizeMode
+}
+
+// SetMode sets whether the text faces the camera (default) or lies on the XY plane of the node.
+func (t *Text) SetMode(mode TextMode) {
+
+	t.mode = mode
+}
+
+// Mode returns whether the text faces the camera or lies on the XY plane of the node.
+func (t *Text) Mode() TextMode {
+
+	return t.mode
+}
+
+// SetLineSpacing sets the spacing between lines in terms of the line height (default = 1).
+func (t *Text) SetLineSpacing(spacing float32) {
+
+	t.lineSpacing = spacing
+	t.SetText(t.msg)
+}
+
+// LineSpacing returns the spacing between lines in terms of the line height.
+func (t *Text) LineSpacing() float32 {
+
+	return t.lineSpacing
+}
+
+// SetAnchor sets the point of the text which is placed at the node origin,
+// where (0,0) is the bottom left corner and (1,1) the top right corner of the text.
+// The default anchor (0.5,0.5) centers the text.
+func (t *Text) SetAnchor(x, y float32) {
+
+	t.anchor = math32.Vector2{x, y}
+	t.updateGeometry()
+}
+
+// Anchor returns the point of the text which is placed at the node origin.
+func (t *Text) Anchor() math32.Vector2 {
+
+	return t.anchor
+}
+
+// SetOcclusion sets whether the text is hidden by the objects in front of it (default = true).
+func (t *Text) SetOcclusion(state bool) {
+
+	t.mat.SetDepthTest(state)
+}
+
+// Occlusion returns whether the text is hidden by the objects in front of it.
+func (t *Text) Occlusion() bool {
+
+	return t.mat.DepthTest()
+}
+
+// TextSize returns the width and height of the text in terms of the font size.
+func (t *Text) TextSize() (float32, float32) {
+
+	return t.width, t.height
+}
+
+// updateGeometry rebuilds the geometry with the glyph quads of the current text
+// in font size units positioned relative to the anchor.
+func (t *Text) updateGeometry() {
+
+	ox := -t.anchor.X * t.width
+	oy := (1 - t.anchor.Y) * t.height
+	positions := math32.NewArrayF32(0, len(t.quads)*20)
+	indices := math32.NewArrayU32(0, len(t.quads)*6)
+	for i := range t.quads {
+		q := &t.quads[i]
+		x0 := ox + q.X
+		y0 := oy - q.Y
+		x1 := x0 + q.Width
+		y1 := y0 - q.Height
+		base := uint32(i * 4)
+		positions.Append(
+			x0, y0, 0, q.U0, q.V0,
+			x0, y1, 0, q.U0, q.V1,
+			x1, y1, 0, q.U1, q.V1,
+			x1, y0, 0, q.U1, q.V0,
+		)
+		indices.Append(base, base+1, base+2, base, base+2, base+3)
+	}
+	geom := t.GetGeometry()
+	geom.VBO(gls.VertexPosition).SetBuffer(positions)
+	geom.SetIndices(indices)
+}
+
+// modelView returns the model view matrix for the specified view matrix
+// without the font size scale, removing the rotation of billboard text.
+func (t *Text) modelView(view *math32.Matrix4) math32.Matrix4 {
+
+	mw := t.MatrixWorld()
+	var mv math32.Matrix4
+	mv.MultiplyMatrices(view, &mw)
+	if t.mode != TextBillboard {
+		return mv
+	}
+
+	// Removes any rotation in X and Y axes as Sprite does
+	var position math32.Vector3
+	var quaternion math32.Quaternion
+	var scale math32.Vector3
+	mv.Decompose(&position, &quaternion, &scale)
+	rotation := t.Rotation()
+	rotation.X = 0
+	rotation.Y = 0
+	quaternion.SetFromEuler(&rotation)
+	mv.Compose(&position, &quaternion, &scale)
+	return mv
+}
+
+// RenderSetup sets up the rendering of the text.
+func (t *Text) RenderSetup(gs *gls.GLS, rinfo *core.RenderInfo) {
+
+	mv := t.modelView(&rinfo.ViewMatrix)
+
+	// Number of screen pixels per world unit at the text origin
+	pm := &rinfo.ProjMatrix
+	w := pm[3]*mv[12] + pm[7]*mv[13] + pm[11]*mv[14] + pm[15]
+	_, _, _, height := gs.GetViewport()
+	ppu := float32(0)
+	if w > 0 {
+		ppu = pm[5] * float32(height) / (2 * w)
+	}
+
+	// Scales the text to the font size
+	t.scale = t.size
+	if t.sizeMode == TextSizeScreen && ppu > 0 {
+		t.scale = t.size / ppu
+	}
+	mv.Scale(&math32.Vector3{t.scale, t.scale, t.scale})
+
+	// Updates the material scale used to smooth the glyph edges for the next render
+	var axis math32.Vector3
+	axis.Set(mv[4], mv[5], mv[6])
+	t.mat.SetScale(axis.Length() * ppu / float32(t.mat.Atlas().Size))
+
+	var mvp math32.Matrix4
+	mvp.MultiplyMatrices(&rinfo.ProjMatrix, &mv)
+	location := t.uniMVPM.Location(gs)
+	gs.UniformMatrix4fv(gl.Uniform{Value: location}, 1, false, mvp[:])
+
+	// Transfer model view matrix uniform (used by fog)
+	location = t.uniMVM.Location(gs)
+	gs.UniformMatrix4fv(gl.Uniform{Value: location}, 1, false, mv[:])
+}
+
+// Raycast checks intersections between the text rectangle and the specified raycaster
+// and if any found appends it to the specified intersects array.
+// Text with size in screen pixels uses the scale of its last render.
+func (t *Text) Raycast(rc *core.Raycaster, intersects *[]core.Intersect) {
+
+	// Copy and convert ray to camera coordinates
+	var ray math32.Ray
+	ray.Copy(&rc.Ray).ApplyMatrix4(&rc.ViewMatrix)
+
+	mv := t.modelView(&rc.ViewMatrix)
+	mv.Scale(&math32.Vector3{t.scale, t.scale, t.scale})
+
+	// Text rectangle corners in camera coordinates
+	x0 := -t.anchor.X * t.width
+	y0 := -t.anchor.Y * t.height
+	corners := [4]math32.Vector3{
+		{x0, y0, 0},
+		{x0 + t.width, y0, 0},
+		{x0 + t.width, y0 + t.height, 0},
+		{x0, y0 + t.height, 0},
+	}
+	for i := range corners {
+		corners[i].ApplyMatrix4(&mv)
+	}
+	var point math32.Vector3
+	if !ray.IntersectTriangle(&corners[0], &corners[1], &corners[2], false, &point) &&
+		!ray.IntersectTriangle(&corners[0], &corners[2], &corners[3], false, &point) {
+		return
+	}
+
+	// Checks if distance is between the bounds of the raycaster
+	origin := ray.Origin()
+	distance := origin.DistanceTo(&point)
+	if distance < rc.Near || distance > rc.Far {
+		return
+	}
+
+	// Converts the intersection point to world coordinates
+	var viewInv math32.Matrix4
+	viewInv.GetInverse(&rc.ViewMatrix)
+	point.ApplyMatrix4(&viewInv)
+	*intersects = append(*intersects, core.Intersect{
+		Distance: distance,
+		Point:    point,
+		Object:   t,
+	})
+}
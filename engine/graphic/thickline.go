@@ -0,0 +1,344 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package graphic
+
+import (
+	"github.com/wangzun/gogame/engine/core"
+	"github.com/wangzun/gogame/engine/geometry"
+	"github.com/wangzun/gogame/engine/gls"
+	"github.com/wangzun/gogame/engine/material"
+	"github.com/wangzun/gogame/engine/math32"
+	"golang.org/x/mobile/gl"
+)
+
+// LineJoin specifies how the segments of thick lines are joined
+type LineJoin int
+
+// The join types of thick lines
+const (
+	LineJoinMiter LineJoin = iota // Extends the segment sides until they meet
+	LineJoinRound                 // Rounds the join with an arc
+	LineJoinBevel                 // Cuts the join with a straight edge
+)
+
+// LineCap specifies how the ends of open thick lines are drawn
+type LineCap int
+
+// The cap types of thick lines
+const (
+	LineCapButt   LineCap = iota // Ends the line at its end points
+	LineCapSquare                // Extends the line by half its width
+	LineCapRound                 // Ends the line with a half circle
+)
+
+// Number of segments used to draw half circles of round joins and caps
+const lineRoundSegments = 8
+
+// Kinds of thick line vertices (must match the thickline shader)
+const (
+	lineKindBody   = 0
+	lineKindJoin   = 1
+	lineKindMiter  = 2
+	lineKindCap    = 3
+	lineKindSquare = 4
+	lineKindCenter = 5
+)
+
+// polyline contains the points and colors of one of the polylines of a ThickLine
+type polyline struct {
+	points []math32.Vector3
+	colors []math32.Color
+	closed bool
+}
+
+// ThickLine is a Graphic which draws polylines with any width by expanding them
+// into triangles in the vertex shader, as the line width supported by most
+// OpenGL ES drivers is limited to one pixel.
+// The width, color and dashes are set in its ThickLine material.
+type ThickLine struct {
+	Graphic                     // Embedded graphic
+	join        LineJoin        // Join type
+	lineCap     LineCap         // Cap type
+	polylines   []polyline      // Polylines in model coordinates
+	uniMVPm     gls.Uniform     // Model view projection matrix uniform location cache
+	uniMVm      gls.Uniform     // Model view matrix uniform location cache
+	uniProjm    gls.Uniform     // Projection matrix uniform location cache
+	buffer      math32.ArrayF32 // Vertex buffer being built
+	indices     math32.ArrayU32 // Indices being built
+	vertexCount uint32          // Number of vertices being built
+}
+
+// NewThickLine creates and returns a pointer to a new thick line graphic without
+// polylines using the specified material, with miter joins and butt caps.
+func NewThickLine(mat *material.ThickLine) *ThickLine {
+
+	l := new(ThickLine)
+	geom := geometry.NewGeometry()
+	geom.AddVBO(gls.NewVBO(math32.NewArrayF32(0, 0)).
+		AddAttrib(gls.VertexPosition).
+		AddAttrib(gls.VertexColor).
+		AddCustomAttrib("LinePrev", 3).
+		AddCustomAttrib("LineNext", 3).
+		AddCustomAttrib("LineVertex", 4),
+	)
+	l.Graphic.Init(geom, gls.TRIANGLES)
+	l.AddMaterial(l, mat, 0, 0)
+	l.uniMVPm.Init("MVP")
+	l.uniMVm.Init("ModelViewMatrix")
+	l.uniProjm.Init("ProjMatrix")
+	return l
+}
+
+// AddPolyline adds a polyline with the specified points and optional colors (one per point).
+// Closed polylines also join their last point to the first one and have no caps.
+func (l *ThickLine) AddPolyline(points []math32.Vector3, colors []math32.Color, closed bool) {
+
+	l.polylines = append(l.polylines, polyline{
+		points: append([]math32.Vector3(nil), points...),
+		colors: append([]math32.Color(nil), colors...),
+		closed: closed,
+	})
+	l.update()
+}
+
+// AddSegment adds a single segment between the specified points with the specified color.
+func (l *ThickLine) AddSegment(a, b *math32.Vector3, color *math32.Color) {
+
+	l.AddPolyline([]math32.Vector3{*a, *b}, []math32.Color{*color, *color}, false)
+}
+
+// Clear removes all the polylines.
+func (l *ThickLine) Clear() {
+
+	l.polylines = l.polylines[:0]
+	l.update()
+}
+
+// PolylineCount returns the number of polylines.
+func (l *ThickLine) PolylineCount() int {
+
+	return len(l.polylines)
+}
+
+// SetJoin sets how the segments of the polylines are joined.
+func (l *ThickLine) SetJoin(join LineJoin) {
+
+	l.join = join
+	l.update()
+}
+
+// Join returns how the segments of the polylines are joined.
+func (l *ThickLine) Join() LineJoin {
+
+	return l.join
+}
+
+// SetCap sets how the ends of the open polylines are drawn.
+func (l *ThickLine) SetCap(lineCap LineCap) {
+
+	l.lineCap = lineCap
+	l.update()
+}
+
+// Cap returns how the ends of the open polylines are drawn.
+func (l *ThickLine) Cap() LineCap {
+
+	return l.lineCap
+}
+
+// RenderSetup is called by the engine before drawing this geometry.
+func (l *ThickLine) RenderSetup(gs *gls.GLS, rinfo *core.RenderInfo) {
+
+	mvpm := l.ModelViewProjectionMatrix()
+	location := l.uniMVPm.Location(gs)
+	gs.UniformMatrix4fv(gl.Uniform{Value: location}, 1, false, mvpm[:])
+
+	mvm := l.ModelViewMatrix()
+	location = l.uniMVm.Location(gs)
+	gs.UniformMatrix4fv(gl.Uniform{Value: location}, 1, false, mvm[:])
+
+	location = l.uniProjm.Location(gs)
+	gs.UniformMatrix4fv(gl.Uniform{Value: location}, 1, false, rinfo.ProjMatrix[:])
+}
+
+// Raycast satisfies the INode interface and checks the intersections
+// of the center of the polylines with the specified raycaster.
+func (l *ThickLine) Raycast(rc *core.Raycaster, intersects *[]core.Intersect) {
+
+	// Copy ray and transform to model coordinates
+	matrixWorld := l.MatrixWorld()
+	var inverseMatrix math32.Matrix4
+	var ray math32.Ray
+	inverseMatrix.GetInverse(&matrixWorld)
+	ray.Copy(&rc.Ray).ApplyMatrix4(&inverseMatrix)
+
+	var interSegment math32.Vector3
+	var interRay math32.Vector3
+	precisionSq := rc.LinePrecision * rc.LinePrecision
+	index := uint32(0)
+	for _, pl := range l.polylines {
+		count := len(pl.points) - 1
+		if pl.closed {
+			count++
+		}
+		for i := 0; i < count; i++ {
+			a := &pl.points[i]
+			b := &pl.points[(i+1)%len(pl.points)]
+			index++
+			distSq := ray.DistanceSqToSegment(a, b, &interRay, &interSegment)
+			if distSq > precisionSq {
+				continue
+			}
+			// Move back to world coordinates for distance calculation
+			interRay.ApplyMatrix4(&matrixWorld)
+			origin := rc.Ray.Origin()
+			distance := origin.DistanceTo(&interRay)
+			if distance < rc.Near || distance > rc.Far {
+				continue
+			}
+			interSegment.ApplyMatrix4(&matrixWorld)
+			*intersects = append(*intersects, core.Intersect{
+				Distance: distance,
+				Point:    interSegment,
+				Index:    index - 1,
+				Object:   l,
+			})
+		}
+	}
+}
+
+// update rebuilds the geometry from the polylines.
+func (l *ThickLine) update() {
+
+	l.buffer = math32.NewArrayF32(0, 0)
+	l.indices = math32.NewArrayU32(0, 0)
+	l.vertexCount = 0
+	for i := range l.polylines {
+		l.build(&l.polylines[i])
+	}
+	geom := l.GetGeometry()
+	geom.VBO(gls.VertexPosition).SetBuffer(l.buffer)
+	geom.SetIndices(l.indices)
+	l.buffer = nil
+	l.indices = nil
+}
+
+// build appends the vertices and indices of the specified polyline.
+func (l *ThickLine) build(pl *polyline) {
+
+	n := len(pl.points)
+	if n < 2 {
+		return
+	}
+	white := math32.Color{1, 1, 1}
+	color := func(i int) *math32.Color {
+		if i < len(pl.colors) {
+			return &pl.colors[i]
+		}
+		return &white
+	}
+
+	// Distances along the polyline used for dashes
+	dist := make([]float32, n+1)
+	for i := 1; i <= n; i++ {
+		dist[i] = dist[i-1] + pl.points[i-1].DistanceTo(&pl.points[i%n])
+	}
+
+	// Segment bodies
+	segments := n - 1
+	if pl.closed {
+		segments = n
+	}
+	for i := 0; i < segments; i++ {
+		j := (i + 1) % n
+		a := &pl.points[i]
+		b := &pl.points[j]
+		v0 := l.vertex(a, color(i), a, b, lineKindBody, 1, 0, dist[i])
+		v1 := l.vertex(a, color(i), a, b, lineKindBody, -1, 0, dist[i])
+		v2 := l.vertex(b, color(j), a, b, lineKindBody, -1, 0, dist[i+1])
+		v3 := l.vertex(b, color(j), a, b, lineKindBody, 1, 0, dist[i+1])
+		l.indices.Append(v0, v1, v2, v0, v2, v3)
+	}
+
+	// Joins at the inner points or at all points of closed polylines
+	for i := 0; i < n; i++ {
+		if !pl.closed && (i == 0 || i == n-1) {
+			continue
+		}
+		prev := &pl.points[(i+n-1)%n]
+		next := &pl.points[(i+1)%n]
+		l.buildJoin(&pl.points[i], color(i), prev, next, dist[i])
+	}
+
+	// Caps at the ends of open polylines
+	if !pl.closed {
+		l.buildCap(&pl.points[0], color(0), &pl.points[0], &pl.points[1], -1, dist[0])
+		l.buildCap(&pl.points[n-1], color(n-1), &pl.points[n-2], &pl.points[n-1], 1, dist[n-1])
+	}
+}
+
+// buildJoin appends the triangles of the join at the specified point of the polyline.
+// The join is expanded by the shader on the outer side of the turn only, so translucent
+// lines are not blended twice where the segment bodies overlap.
+func (l *ThickLine) buildJoin(p *math32.Vector3, color *math32.Color, prev, next *math32.Vector3, dist float32) {
+
+	center := l.vertex(p, color, prev, next, lineKindCenter, 0, 0, dist)
+	switch l.join {
+	case LineJoinMiter:
+		v0 := l.vertex(p, color, prev, next, lineKindJoin, 0, 0, dist)
+		v1 := l.vertex(p, color, prev, next, lineKindMiter, 0, 0, dist)
+		v2 := l.vertex(p, color, prev, next, lineKindJoin, 0, 1, dist)
+		l.indices.Append(center, v0, v1, center, v1, v2)
+	case LineJoinBevel:
+		v0 := l.vertex(p, color, prev, next, lineKindJoin, 0, 0, dist)
+		v1 := l.vertex(p, color, prev, next, lineKindJoin, 0, 1, dist)
+		l.indices.Append(center, v0, v1)
+	case LineJoinRound:
+		l.buildFan(center, p, color, prev, next, lineKindJoin, 0, dist)
+	}
+}
+
+// buildCap appends the triangles of the cap at the specified end point of the polyline,
+// where side is -1 for the start of the polyline and 1 for its end.
+func (l *ThickLine) buildCap(p *math32.Vector3, color *math32.Color, prev, next *math32.Vector3, side, dist float32) {
+
+	switch l.lineCap {
+	case LineCapSquare:
+		v0 := l.vertex(p, color, prev, next, lineKindSquare, side, 0, dist)
+		v1 := l.vertex(p, color, prev, next, lineKindSquare, side, 1, dist)
+		v2 := l.vertex(p, color, prev, next, lineKindSquare, side, 2, dist)
+		v3 := l.vertex(p, color, prev, next, lineKindSquare, side, 3, dist)
+		l.indices.Append(v0, v1, v2, v0, v2, v3)
+	case LineCapRound:
+		center := l.vertex(p, color, prev, next, lineKindCenter, side, 0, dist)
+		l.buildFan(center, p, color, prev, next, lineKindCap, side, dist)
+	}
+}
+
+// buildFan appends a triangle fan around the specified center vertex
+// with edge vertices of the specified kind.
+func (l *ThickLine) buildFan(center uint32, p *math32.Vector3, color *math32.Color, prev, next *math32.Vector3, kind int, side, dist float32) {
+
+	last := l.vertex(p, color, prev, next, kind, side, 0, dist)
+	for k := 1; k <= lineRoundSegments; k++ {
+		v := l.vertex(p, color, prev, next, kind, side, float32(k)/lineRoundSegments, dist)
+		l.indices.Append(center, last, v)
+		last = v
+	}
+}
+
+// vertex appends a vertex with the specified attributes and returns its index.
+func (l *ThickLine) vertex(p *math32.Vector3, color *math32.Color, prev, next *math32.Vector3, kind int, side, fraction, dist float32) uint32 {
+
+	l.buffer.Append(
+		p.X, p.Y, p.Z,
+		color.R, color.G, color.B,
+		prev.X, prev.Y, prev.Z,
+		next.X, next.Y, next.Z,
+		float32(kind), side, fraction, dist,
+	)
+	l.vertexCount++
+	return l.vertexCount - 1
+}
@@ -0,0 +1,143 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package graphic
+
+import (
+	"github.com/wangzun/gogame/engine/camera"
+	"github.com/wangzun/gogame/engine/geometry"
+	"github.com/wangzun/gogame/engine/gls"
+	"github.com/wangzun/gogame/engine/material"
+	"github.com/wangzun/gogame/engine/math32"
+)
+
+// Water is a mesh with a horizontal grid on the XZ plane drawn with the Water material,
+// which displaces its vertices with waves, so the grid must have enough segments for the
+// wavelengths used. It may be translated but not rotated or scaled.
+//
+// For reflections, the scene is rendered every frame from the camera mirrored by the
+// water plane into a render target whose texture is the reflection map of the material:
+//
+//	water.SetVisible(false)
+//	water.MirrorCamera(cam, reflCam)
+//	err := rend.RenderTo(target, reflCam)
+//	water.SetVisible(true)
+//	mat.SetReflectionMap(target.Texture())
+type Water struct {
+	Mesh                   // Embedded mesh
+	mat    *material.Water // Water material
+	width  float32         // Size along the X axis
+	depth  float32         // Size along the Z axis
+	depths *gls.VBO        // Floor depth buffer created by SetFloor
+}
+
+// NewWater creates and returns a pointer to a new water surface centered on its origin with
+// the specified size along the X and Z axes, number of segments along each axis and material.
+// The texture coordinates go from 0 to 1 over the surface.
+func NewWater(width, depth float32, widthSegments, depthSegments int, mat *material.Water) *Water {
+
+	w := new(Water)
+	w.mat = mat
+	w.width = width
+	w.depth = depth
+
+	positions := math32.NewArrayF32(0, (widthSegments+1)*(depthSegments+1)*3)
+	normals := math32.NewArrayF32(0, (widthSegments+1)*(depthSegments+1)*3)
+	uvs := math32.NewArrayF32(0, (widthSegments+1)*(depthSegments+1)*2)
+	indices := math32.NewArrayU32(0, widthSegments*depthSegments*6)
+	for j := 0; j <= depthSegments; j++ {
+		v := float32(j) / float32(depthSegments)
+		for i := 0; i <= widthSegments; i++ {
+			u := float32(i) / float32(widthSegments)
+			positions.Append((u-0.5)*width, 0, (v-0.5)*depth)
+			normals.Append(0, 1, 0)
+			uvs.Append(u, 1-v)
+		}
+	}
+	for j := 0; j < depthSegments; j++ {
+		for i := 0; i < widthSegments; i++ {
+			a := uint32(j*(widthSegments+1) + i)
+			b := a + uint32(widthSegments+1)
+			indices.Append(a, b, b+1, a, b+1, a+1)
+		}
+	}
+
+	geom := geometry.NewGeometry()
+	geom.SetIndices(indices)
+	geom.AddVBO(gls.NewVBO(positions).AddAttrib(gls.VertexPosition))
+	geom.AddVBO(gls.NewVBO(normals).AddAttrib(gls.VertexNormal))
+	geom.AddVBO(gls.NewVBO(uvs).AddAttrib(gls.VertexTexcoord))
+	w.Mesh.Init(geom, mat)
+
+	// The waves displace the vertices beyond the bounding box of the geometry
+	w.SetCullable(false)
+	return w
+}
+
+// Material returns the water material.
+func (w *Water) Material() *material.Water {
+
+	return w.mat
+}
+
+// Size returns the size of the water surface along the X and Z axes.
+func (w *Water) Size() (float32, float32) {
+
+	return w.width, w.depth
+}
+
+// SetFloor sets the function which returns the height in world coordinates of the floor below
+// the water at the specified world X and Z coordinates, such as the HeightAt method of a
+// terrain, or nil to remove the floor. The depths of the water are computed for the vertices
+// at the current world position of the water, so the material can tint the shallow water,
+// fade it at the shore and attenuate the waves. It must be called again if the water moves.
+func (w *Water) SetFloor(floor func(x, z float32) float32) {
+
+	geom := w.GetGeometry()
+	if floor == nil {
+		geom.ShaderDefines.Unset("WATER_DEPTH")
+		return
+	}
+
+	w.UpdateMatrixWorld()
+	mw := w.MatrixWorld()
+	positions := geom.VBO(gls.VertexPosition).Buffer()
+	depths := math32.NewArrayF32(0, positions.Len()/3)
+	var pos math32.Vector3
+	for i := 0; i < positions.Len(); i += 3 {
+		positions.GetVector3(i, &pos)
+		pos.ApplyMatrix4(&mw)
+		depths.Append(pos.Y - floor(pos.X, pos.Z))
+	}
+	if w.depths == nil {
+		w.depths = gls.NewVBO(depths).AddCustomAttrib("WaterDepth", 1)
+		geom.AddVBO(w.depths)
+	} else {
+		w.depths.SetBuffer(depths)
+	}
+	geom.ShaderDefines.Set("WATER_DEPTH", "")
+}
+
+// MirrorCamera sets the specified destination camera, which should have the same projection
+// as the source camera and the default up vector, to view the scene from the source camera
+// position mirrored by the water plane, as needed to render the reflection map.
+// The destination camera must not have a parent node.
+func (w *Water) MirrorCamera(src, dst camera.ICamera) {
+
+	w.UpdateMatrixWorld()
+	mw := w.MatrixWorld()
+	level := mw[13]
+
+	var pos, dir math32.Vector3
+	src.GetCamera().WorldPosition(&pos)
+	src.GetCamera().WorldDirection(&dir)
+	pos.Y = 2*level - pos.Y
+	dir.Y = -dir.Y
+	target := pos
+	target.Add(&dir)
+
+	cam := dst.GetCamera()
+	cam.SetPositionVec(&pos)
+	cam.LookAt(&target)
+}
@@ -5,20 +5,25 @@
 package gui
 
 import (
+	"github.com/wangzun/gogame/engine/geometry"
 	"github.com/wangzun/gogame/engine/gls"
+	"github.com/wangzun/gogame/engine/material"
 	"github.com/wangzun/gogame/engine/math32"
 	"github.com/wangzun/gogame/engine/text"
-	"github.com/wangzun/gogame/engine/texture"
 )
 
-// Label is a panel which contains a texture with text.
-// The content size of the label panel is the exact size of the texture.
+// Label is a panel which contains text.
+// The text glyphs are rendered from the signed distance field atlas of the font,
+// so they remain crisp at any size and can have an outline and a drop shadow.
+// The content size of the label panel is the exact size of the text.
 type Label struct {
 	Panel                    // Embedded Panel
 	font  *text.Font         // TrueType font face
-	tex   *texture.Texture2D // Texture with text
 	style *LabelStyle        // The style of the panel and font attributes
 	text  string             // Text being displayed
+	tmat  *material.Text     // Material used to render the glyphs
+	quads []text.GlyphQuad   // Glyph quads of the current text
+	geom  *geometry.Geometry // Geometry with the panel quad followed by the glyph quads
 }
 
 // LabelStyle contains all the styling attributes of a Label.
@@ -55,7 +60,12 @@ func NewLabelWithFont(msg string, font *text.Font) *Label {
 func (l *Label) initialize(msg string, font *text.Font) {
 
 	l.font = font
-	l.Panel.Initialize(0, 0)
+	l.geom = geometry.NewGeometry()
+	l.geom.AddVBO(gls.NewVBO(math32.NewArrayF32(0, 0)).
+		AddAttrib(gls.VertexPosition).
+		AddAttrib(gls.VertexTexcoord),
+	)
+	l.Panel.initialize(0, 0, l.geom)
 
 	// TODO: Remove this hack in an elegant way e.g. set the label style depending of if it's an icon or text label and have two defaults (one for icon labels one for text tabels)
 	if font != StyleDefault().FontIcon {
@@ -66,38 +76,87 @@ func (l *Label) initialize(msg string, font *text.Font) {
 	styleCopy := StyleDefault().Label
 	l.style = &styleCopy
 
+	// The glyph positions are relative to the panel size
+	l.Subscribe(OnResize, func(evname string, ev interface{}) { l.updateGeometry() })
 	l.SetText(msg)
 }
 
-// SetText sets and draws the label text using the font.
+// SetText sets and lays out the label text using the font atlas.
 func (l *Label) SetText(text string) {
 
-	// Need at least a character to get dimensions
 	l.text = text
-	if text == "" {
-		text = " "
-	}
 
-	// Set font properties
-	l.font.SetAttributes(&l.style.FontAttributes)
-	l.font.SetColor(&l.style.FgColor)
-
-	// Create an image with the text
-	textImage := l.font.DrawText(text)
-
-	// Create texture if it doesn't exist yet
-	if l.tex == nil {
-		l.tex = texture.NewTexture2DFromRGBA(textImage)
-		l.tex.SetMagFilter(gls.NEAREST)
-		l.tex.SetMinFilter(gls.NEAREST)
-		l.Panel.Material().AddTexture(l.tex)
-		// Otherwise update texture with new image
-	} else {
-		l.tex.SetFromRGBA(textImage)
+	// Creates the text material if necessary or if the font changed
+	if l.tmat == nil || l.tmat.Font() != l.font {
+		prev := l.tmat
+		l.tmat = material.NewText(l.font)
+		l.tmat.SetShader("sdftext")
+		l.tmat.SetShaderUnique(true)
+		if prev != nil {
+			color, width := prev.Outline()
+			l.tmat.SetOutline(&color, width)
+			color, dx, dy := prev.Shadow()
+			l.tmat.SetShadow(&color, dx, dy)
+			prev.Dispose()
+		}
 	}
 
-	// Update label panel dimensions
-	l.Panel.SetContentSize(float32(textImage.Rect.Dx()), float32(textImage.Rect.Dy()))
+	// Lays out the text glyphs with the font size in pixels
+	atlas := l.tmat.Atlas()
+	size := float32(l.style.PointSize * l.style.DPI / 72)
+	var width, height float32
+	l.quads, width, height = atlas.Layout(text, size, float32(l.style.LineSpacing), l.quads[:0])
+	l.tmat.SetColor(&l.style.FgColor)
+	l.tmat.SetScale(size / float32(atlas.Size))
+
+	// Update label panel dimensions, which updates the geometry
+	l.Panel.SetContentSize(math32.Ceil(width), math32.Ceil(height))
+}
+
+// updateGeometry rebuilds the label geometry with the panel quad and
+// the glyph quads positioned in the content area and sets its materials.
+func (l *Label) updateGeometry() {
+
+	if l.tmat == nil {
+		return
+	}
+	positions := math32.NewArrayF32(0, (len(l.quads)+1)*20)
+	positions.Append(
+		0, 0, 0, 0, 1,
+		0, -1, 0, 0, 0,
+		1, -1, 0, 1, 0,
+		1, 0, 0, 1, 1,
+	)
+	indices := math32.NewArrayU32(0, (len(l.quads)+1)*6)
+	indices.Append(0, 1, 2, 0, 2, 3)
+
+	// Glyph quads in the panel unit quad coordinates
+	if l.width > 0 && l.height > 0 {
+		for i := range l.quads {
+			q := &l.quads[i]
+			x0 := (l.content.X + q.X) / l.width
+			y0 := -(l.content.Y + q.Y) / l.height
+			x1 := (l.content.X + q.X + q.Width) / l.width
+			y1 := -(l.content.Y + q.Y + q.Height) / l.height
+			base := uint32(positions.Len() / 5)
+			positions.Append(
+				x0, y0, 0, q.U0, q.V0,
+				x0, y1, 0, q.U0, q.V1,
+				x1, y1, 0, q.U1, q.V1,
+				x1, y0, 0, q.U1, q.V0,
+			)
+			indices.Append(base, base+1, base+2, base, base+2, base+3)
+		}
+	}
+	l.geom.VBO(gls.VertexPosition).SetBuffer(positions)
+	l.geom.SetIndices(indices)
+
+	// Panel material renders the panel quad and the text material the glyphs
+	l.ClearMaterials()
+	l.AddMaterial(l, l.mat, 0, 6)
+	if count := indices.Size() - 6; count > 0 {
+		l.AddMaterial(l, l.tmat, 6, count)
+	}
 }
 
 // Text returns the label text.
@@ -209,33 +268,32 @@ func (l *Label) LineSpacing() float64 {
 	return l.style.LineSpacing
 }
 
-// setTextCaret sets the label text and draws a caret at the
-// specified line and column.
-// It is normally used by the Edit widget.
-func (l *Label) setTextCaret(msg string, mx, width, line, col int) {
+// SetOutline sets the color and width in pixels of the outline drawn around the text glyphs.
+// A zero width disables the outline.
+func (l *Label) SetOutline(color *math32.Color4, width float32) *Label {
 
-	// Set font properties
-	l.font.SetAttributes(&l.style.FontAttributes)
-	l.font.SetColor(&l.style.FgColor)
+	l.tmat.SetOutline(color, width)
+	l.SetChanged(true)
+	return l
+}
 
-	// Create canvas and draw text
-	_, height := l.font.MeasureText(msg)
-	canvas := text.NewCanvas(width, height, &l.style.BgColor)
-	canvas.DrawTextCaret(mx, 0, msg, l.font, line, col)
+// Outline returns the color and width in pixels of the text outline.
+func (l *Label) Outline() (math32.Color4, float32) {
 
-	// Creates texture if if doesnt exist.
-	if l.tex == nil {
-		l.tex = texture.NewTexture2DFromRGBA(canvas.RGBA)
-		l.Panel.Material().AddTexture(l.tex)
-		// Otherwise update texture with new image
-	} else {
-		l.tex.SetFromRGBA(canvas.RGBA)
-	}
-	// Set texture filtering parameters for text
-	l.tex.SetMagFilter(gls.NEAREST)
-	l.tex.SetMinFilter(gls.NEAREST)
+	return l.tmat.Outline()
+}
+
+// SetShadow sets the color and offset in pixels of the drop shadow drawn under the text.
+// Positive offsets move the shadow to the right and down. A transparent color disables the shadow.
+func (l *Label) SetShadow(color *math32.Color4, dx, dy float32) *Label {
+
+	l.tmat.SetShadow(color, dx, dy)
+	l.SetChanged(true)
+	return l
+}
+
+// Shadow returns the color and offset in pixels of the text drop shadow.
+func (l *Label) Shadow() (math32.Color4, float32, float32) {
 
-	// Updates label panel dimensions
-	l.Panel.SetContentSize(float32(width), float32(height))
-	l.text = msg
+	return l.tmat.Shadow()
 }
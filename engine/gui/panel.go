@@ -133,9 +133,6 @@ func NewPanel(width, height float32) *Panel {
 // Initialize initializes this panel and is normally used by other types which embed a panel.
 func (p *Panel) Initialize(width, height float32) {
 
-	p.width = width
-	p.height = height
-
 	// If necessary, creates panel quad geometry
 	if panelQuadGeometry == nil {
 
@@ -160,6 +157,15 @@ func (p *Panel) Initialize(width, height float32) {
 		)
 		panelQuadGeometry = geom
 	}
+	p.initialize(width, height, panelQuadGeometry.Incref())
+}
+
+// initialize initializes this panel with the specified geometry, which must
+// start with the panel quad, and is used by panels with additional primitives.
+func (p *Panel) initialize(width, height float32, geom *geometry.Geometry) {
+
+	p.width = width
+	p.height = height
 
 	// Initialize material
 	p.mat = material.NewMaterial()
@@ -167,7 +173,7 @@ func (p *Panel) Initialize(width, height float32) {
 	p.mat.SetShaderUnique(true)
 
 	// Initialize graphic
-	p.Graphic = graphic.NewGraphic(panelQuadGeometry.Incref(), gls.TRIANGLES)
+	p.Graphic = graphic.NewGraphic(geom, gls.TRIANGLES)
 	p.AddMaterial(p, p.mat, 0, 0)
 
 	// Initialize uniforms location caches
@@ -0,0 +1,170 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ik
+
+import (
+	"github.com/wangzun/gogame/engine/core"
+	"github.com/wangzun/gogame/engine/math32"
+)
+
+// Aim is a look-at constraint which rotates a node so one of its local axes points towards the
+// target, such as the head of a character looking at something. The rotation can be restricted
+// to a hinge axis, such as the yaw of a turret and the pitch of its cannon, and limited to a
+// range of angles from the rest rotation of the node, which is its local rotation when the
+// constraint was created, and to a maximum turning speed.
+type Aim struct {
+	solver
+	node     *core.Node        // Constrained node
+	forward  math32.Vector3    // Local axis which points towards the target
+	rest     math32.Quaternion // Rest local rotation
+	hinge    bool              // Whether the rotation is restricted to the hinge axis
+	axis     math32.Vector3    // Hinge axis in the rest frame of the node
+	min      float32           // Minimum hinge angle
+	max      float32           // Maximum hinge angle
+	maxAngle float32           // Maximum angle between the aimed and the rest forward axis
+	speed    float32           // Maximum turning speed in radians per second or 0 for unlimited
+}
+
+// NewAim creates and returns a pointer to a new aim constraint which rotates the specified
+// node so its local +Z axis points towards the target.
+func NewAim(node core.INode) *Aim {
+
+	s := new(Aim)
+	s.solver.init()
+	s.node = node.GetNode()
+	s.forward.Set(0, 0, 1)
+	s.rest = s.node.Quaternion()
+	s.maxAngle = math32.Pi
+	return s
+}
+
+// Node returns the node rotated by the constraint.
+func (s *Aim) Node() *core.Node {
+
+	return s.node
+}
+
+// SetForward sets the local axis of the node which points towards the target (default = +Z).
+func (s *Aim) SetForward(axis math32.Vector3) {
+
+	s.forward = axis
+	s.forward.Normalize()
+}
+
+// Forward returns the local axis of the node which points towards the target.
+func (s *Aim) Forward() math32.Vector3 {
+
+	return s.forward
+}
+
+// SetRest sets the rest local rotation of the node, from which the angles are limited,
+// to its current local rotation.
+func (s *Aim) SetRest() {
+
+	s.rest = s.node.Quaternion()
+}
+
+// SetHinge restricts the rotation of the node to the specified axis of its rest frame, with
+// angles from min to max radians from the rest rotation, such as the Y axis for the yaw of a
+// turret. Use -Pi and Pi for an unlimited hinge.
+func (s *Aim) SetHinge(axis math32.Vector3, min, max float32) {
+
+	s.hinge = true
+	s.axis = axis
+	s.axis.Normalize()
+	s.min = min
+	s.max = max
+}
+
+// ClearHinge removes the hinge restriction of the rotation.
+func (s *Aim) ClearHinge() {
+
+	s.hinge = false
+}
+
+// SetMaxAngle sets the maximum angle in radians between the forward axis and its rest direction
+// when not restricted to a hinge (default = Pi), such as how far a head can turn.
+func (s *Aim) SetMaxAngle(angle float32) {
+
+	s.maxAngle = angle
+}
+
+// MaxAngle returns the maximum angle in radians between the forward axis and its rest direction.
+func (s *Aim) MaxAngle() float32 {
+
+	return s.maxAngle
+}
+
+// SetSpeed sets the maximum turning speed in radians per second, or 0 for unlimited (default).
+// The node turns from its current rotation, so it should not be animated.
+func (s *Aim) SetSpeed(speed float32) {
+
+	s.speed = speed
+}
+
+// Speed returns the maximum turning speed in radians per second.
+func (s *Aim) Speed() float32 {
+
+	return s.speed
+}
+
+// Solve rotates the node towards the target, turning at most its maximum speed
+// multiplied by the specified time in seconds.
+func (s *Aim) Solve(delta float32) {
+
+	// Direction to the target in the rest frame of the node
+	target := s.TargetPosition()
+	pos := worldPosition(s.node)
+	var dir math32.Vector3
+	dir.SubVectors(&target, &pos)
+	if dir.LengthSq() < 1e-12 {
+		return
+	}
+	inv := parentQuaternion(s.node)
+	inv.MultiplyQuaternions(&inv, &s.rest)
+	inv.Conjugate()
+	dir.ApplyQuaternion(&inv).Normalize()
+
+	// Rotation relative to the rest rotation which points the forward axis towards the target
+	current := s.rest
+	current.Conjugate()
+	q := s.node.Quaternion()
+	current.Multiply(&q)
+	var d math32.Quaternion
+	if s.hinge {
+		angle := math32.Clamp(signedAngle(&s.forward, &dir, &s.axis), s.min, s.max)
+		if s.speed > 0 {
+			cur := twistAngle(&current, &s.axis)
+			step := s.speed * delta
+			angle = cur + math32.Clamp(wrapAngle(angle-cur), -step, step)
+		}
+		d.SetFromAxisAngle(&s.axis, angle)
+	} else {
+		d.SetFromUnitVectors(&s.forward, &dir)
+		angle := 2 * math32.Acos(math32.Min(math32.Abs(d.W), 1))
+		if angle > s.maxAngle {
+			var id math32.Quaternion
+			id.SetIdentity()
+			id.Slerp(&d, s.maxAngle/angle)
+			d = id
+		}
+		if s.speed > 0 {
+			diff := math32.Acos(math32.Min(math32.Abs(current.Dot(&d)), 1)) * 2
+			step := s.speed * delta
+			if diff > step {
+				current.Slerp(&d, step/diff)
+				d = current
+			}
+		}
+	}
+
+	// Blends the solution over the current rotation
+	d.MultiplyQuaternions(&s.rest, &d)
+	if s.weight < 1 {
+		q.Slerp(&d, s.weight)
+		d = q
+	}
+	s.node.SetQuaternionQuat(&d)
+}
@@ -0,0 +1,46 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ik
+
+import (
+	"github.com/wangzun/gogame/engine/core"
+)
+
+// CCD is a cyclic coordinate descent solver which reaches a target with the end of a chain of
+// joints of any length by rotating each joint in turn, from the last to the first, so that the
+// end effector points towards the target. It handles joint limits well and tends to curl the
+// end of the chain first, which suits tails, tentacles and spines.
+type CCD struct {
+	chain
+}
+
+// NewCCD creates and returns a pointer to a new CCD solver for the specified
+// joints, from the root of the chain to the end effector, such as a hand.
+func NewCCD(joints ...core.INode) *CCD {
+
+	s := new(CCD)
+	s.chain.init(joints)
+	return s
+}
+
+// Solve rotates the joints so the end effector reaches the target.
+func (s *CCD) Solve(delta float32) {
+
+	n := len(s.joints)
+	if n < 2 {
+		return
+	}
+	target := s.TargetPosition()
+	saved := saveRotations(s.joints)
+	for it := 0; it < s.iterations && !s.reached(&target); it++ {
+		for i := n - 2; i >= 0; i-- {
+			joint := worldPosition(s.joints[i])
+			end := worldPosition(s.joints[n-1])
+			rotateTowards(s.joints[i], &joint, &end, &target)
+			s.limit(i)
+		}
+	}
+	blendRotations(s.joints, saved, s.weight)
+}
@@ -0,0 +1,104 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ik
+
+import (
+	"github.com/wangzun/gogame/engine/core"
+	"github.com/wangzun/gogame/engine/math32"
+)
+
+// chain contains the common state of the chain solvers.
+type chain struct {
+	solver
+	joints     []*core.Node // Joints from the root of the chain to the end effector
+	limits     []*Limit     // Optional limit of each joint
+	iterations int          // Maximum number of iterations
+	tolerance  float32      // Distance to the target at which the solver stops
+}
+
+// init initializes the chain with the specified joints.
+func (c *chain) init(joints []core.INode) {
+
+	c.solver.init()
+	c.joints = make([]*core.Node, len(joints))
+	for i, j := range joints {
+		c.joints[i] = j.GetNode()
+	}
+	c.limits = make([]*Limit, len(joints))
+	c.iterations = 10
+	c.tolerance = 0.001
+}
+
+// Joints returns the joints of the chain from its root to the end effector.
+func (c *chain) Joints() []*core.Node {
+
+	return c.joints
+}
+
+// SetLimit sets the limit of the rotation of the joint with the specified index, relative to
+// its current local rotation, or removes it if nil. The end effector is never rotated.
+// The joint keeps a copy of the limit, so the same limit can be set on several joints.
+func (c *chain) SetLimit(idx int, limit *Limit) {
+
+	c.limits[idx] = nil
+	if limit == nil {
+		return
+	}
+	l := *limit
+	if idx+1 < len(c.joints) {
+		l.setRest(c.joints[idx].Quaternion(), c.joints[idx+1].Position())
+	}
+	c.limits[idx] = &l
+}
+
+// Limit returns the copy of the limit of the joint with the specified index or nil if not limited.
+func (c *chain) Limit(idx int) *Limit {
+
+	return c.limits[idx]
+}
+
+// SetIterations sets the maximum number of iterations per solve (default = 10).
+func (c *chain) SetIterations(iterations int) {
+
+	c.iterations = iterations
+}
+
+// Iterations returns the maximum number of iterations per solve.
+func (c *chain) Iterations() int {
+
+	return c.iterations
+}
+
+// SetTolerance sets the distance between the end effector and the target
+// at which the solver stops iterating (default = 0.001).
+func (c *chain) SetTolerance(tolerance float32) {
+
+	c.tolerance = tolerance
+}
+
+// Tolerance returns the distance at which the solver stops iterating.
+func (c *chain) Tolerance() float32 {
+
+	return c.tolerance
+}
+
+// limit applies the limit of the joint with the specified index to its local rotation.
+func (c *chain) limit(idx int) {
+
+	l := c.limits[idx]
+	if l == nil {
+		return
+	}
+	q := c.joints[idx].Quaternion()
+	l.apply(&q)
+	c.joints[idx].SetQuaternionQuat(&q)
+}
+
+// reached returns whether the end effector is within the tolerance of the specified target.
+func (c *chain) reached(target *math32.Vector3) bool {
+
+	end := worldPosition(c.joints[len(c.joints)-1])
+	return end.DistanceTo(target) <= c.tolerance
+}
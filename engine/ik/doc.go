@@ -0,0 +1,11 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package ik implements inverse kinematics solvers which rotate the bones of
+// skeletons, or any other nodes, to reach or look at targets: two-bone solvers
+// for limbs, CCD and FABRIK solvers for chains of any length with joint limits,
+// and aim constraints for heads and turrets. The solvers of a Rig are run every
+// frame after the animations set the pose and before the scene is rendered,
+// which is when the skinned meshes are deformed by the bones.
+package ik
@@ -0,0 +1,90 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ik
+
+import (
+	"github.com/wangzun/gogame/engine/core"
+	"github.com/wangzun/gogame/engine/math32"
+)
+
+// FABRIK is a forward and backward reaching inverse kinematics solver which reaches a target
+// with the end of a chain of joints of any length by moving the joint positions alternately
+// from the target and from the root of the chain, keeping the bone lengths, and then rotating
+// the joints to the new positions. It converges quickly and spreads the rotation evenly along
+// the chain, which suits arms, necks and ropes. The joint limits are applied when rotating
+// the joints, so chains with tight limits may need more iterations.
+type FABRIK struct {
+	chain
+	pos     []math32.Vector3 // World positions of the joints
+	lengths []float32        // Lengths of the bones
+}
+
+// NewFABRIK creates and returns a pointer to a new FABRIK solver for the specified
+// joints, from the root of the chain to the end effector, such as a hand.
+func NewFABRIK(joints ...core.INode) *FABRIK {
+
+	s := new(FABRIK)
+	s.chain.init(joints)
+	s.pos = make([]math32.Vector3, len(joints))
+	s.lengths = make([]float32, len(joints))
+	return s
+}
+
+// Solve rotates the joints so the end effector reaches the target.
+func (s *FABRIK) Solve(delta float32) {
+
+	n := len(s.joints)
+	if n < 2 {
+		return
+	}
+	target := s.TargetPosition()
+	saved := saveRotations(s.joints)
+	for it := 0; it < s.iterations && !s.reached(&target); it++ {
+		for i, j := range s.joints {
+			s.pos[i] = worldPosition(j)
+		}
+		for i := 0; i < n-1; i++ {
+			s.lengths[i] = s.pos[i].DistanceTo(&s.pos[i+1])
+		}
+		root := s.pos[0]
+
+		// Backward pass from the target
+		s.pos[n-1] = target
+		for i := n - 2; i >= 0; i-- {
+			s.place(i, i+1)
+		}
+		// Forward pass from the root
+		s.pos[0] = root
+		for i := 0; i < n-1; i++ {
+			s.place(i+1, i)
+		}
+
+		// Rotates the joints from the root to point to the new positions
+		for i := 0; i < n-1; i++ {
+			joint := worldPosition(s.joints[i])
+			child := worldPosition(s.joints[i+1])
+			rotateTowards(s.joints[i], &joint, &child, &s.pos[i+1])
+			s.limit(i)
+		}
+	}
+	blendRotations(s.joints, saved, s.weight)
+}
+
+// place moves the position of the specified joint towards the position
+// of the specified neighbor joint to the length of the bone between them.
+func (s *FABRIK) place(idx, from int) {
+
+	bone := idx
+	if from < idx {
+		bone = from
+	}
+	var dir math32.Vector3
+	dir.SubVectors(&s.pos[idx], &s.pos[from])
+	if dir.LengthSq() == 0 {
+		return
+	}
+	dir.SetLength(s.lengths[bone])
+	s.pos[idx].AddVectors(&s.pos[from], &dir)
+}
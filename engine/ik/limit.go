@@ -0,0 +1,141 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ik
+
+import (
+	"github.com/wangzun/gogame/engine/math32"
+)
+
+// Limit restricts the rotation of a joint relative to its rest rotation, which is its local
+// rotation when the limit is set on a solver. A hinge limit only allows rotating around an axis,
+// such as for knees and elbows, and a ball limit restricts how far the bone swings away from
+// its rest direction and twists around it, such as for shoulders and spines.
+// Solvers keep a copy of the limits set on their joints, so one limit can be shared
+// by similar joints, such as both knees.
+type Limit struct {
+	hinge    bool              // Hinge limit or ball limit
+	axis     math32.Vector3    // Hinge axis in the rest frame of the joint
+	min      float32           // Minimum hinge angle
+	max      float32           // Maximum hinge angle
+	swing    float32           // Maximum swing angle of the ball limit
+	twist    float32           // Maximum twist angle of the ball limit
+	rest     math32.Quaternion // Rest local rotation of the joint
+	boneAxis math32.Vector3    // Direction of the bone in the rest frame of the joint
+}
+
+// NewHingeLimit creates and returns a pointer to a new limit which only allows a joint to rotate
+// around the specified axis of its rest frame by angles from min to max radians.
+func NewHingeLimit(axis math32.Vector3, min, max float32) *Limit {
+
+	l := new(Limit)
+	l.hinge = true
+	l.axis = axis
+	l.axis.Normalize()
+	l.min = min
+	l.max = max
+	return l
+}
+
+// NewBallLimit creates and returns a pointer to a new limit which allows the bone of a joint
+// to swing up to the specified angle in radians away from its rest direction and to twist up
+// to the specified angle in radians around it. An angle of Pi does not limit the movement.
+func NewBallLimit(swing, twist float32) *Limit {
+
+	l := new(Limit)
+	l.swing = swing
+	l.twist = twist
+	return l
+}
+
+// Hinge returns whether this is a hinge limit and its axis and angle range.
+func (l *Limit) Hinge() (bool, math32.Vector3, float32, float32) {
+
+	return l.hinge, l.axis, l.min, l.max
+}
+
+// Ball returns the maximum swing and twist angles of a ball limit.
+func (l *Limit) Ball() (float32, float32) {
+
+	return l.swing, l.twist
+}
+
+// setRest sets the rest local rotation of the joint and the direction of its bone in the
+// rest frame of the joint, which is the local position of its child.
+func (l *Limit) setRest(rest math32.Quaternion, boneAxis math32.Vector3) {
+
+	l.rest = rest
+	l.boneAxis = boneAxis
+	l.boneAxis.Normalize()
+	if l.boneAxis.LengthSq() == 0 {
+		l.boneAxis.Set(0, 1, 0)
+	}
+}
+
+// apply restricts the specified local rotation of the joint.
+func (l *Limit) apply(q *math32.Quaternion) {
+
+	// Rotation relative to the rest rotation
+	d := l.rest
+	d.Conjugate()
+	d.Multiply(q)
+
+	if l.hinge {
+		angle := twistAngle(&d, &l.axis)
+		angle = math32.Clamp(angle, l.min, l.max)
+		d.SetFromAxisAngle(&l.axis, angle)
+	} else {
+		// Decomposes the rotation into a swing of the bone followed by a twist around it
+		twist := twistPart(&d, &l.boneAxis)
+		swing := twist
+		swing.Conjugate()
+		swing.MultiplyQuaternions(&d, &swing)
+		swingAngle := 2 * math32.Acos(math32.Min(math32.Abs(swing.W), 1))
+		if swingAngle > l.swing && swingAngle > 0 {
+			var id math32.Quaternion
+			id.SetIdentity()
+			id.Slerp(&swing, l.swing/swingAngle)
+			swing = id
+		}
+		angle := math32.Clamp(twistAngle(&d, &l.boneAxis), -l.twist, l.twist)
+		twist.SetFromAxisAngle(&l.boneAxis, angle)
+		d.MultiplyQuaternions(&swing, &twist)
+	}
+
+	q.MultiplyQuaternions(&l.rest, &d)
+	q.Normalize()
+}
+
+// twistPart returns the twist of the specified rotation around the specified normalized axis.
+func twistPart(q *math32.Quaternion, axis *math32.Vector3) math32.Quaternion {
+
+	p := axis.X*q.X + axis.Y*q.Y + axis.Z*q.Z
+	t := math32.Quaternion{X: axis.X * p, Y: axis.Y * p, Z: axis.Z * p, W: q.W}
+	if t.Length() < 1e-6 {
+		t.SetIdentity()
+		return t
+	}
+	t.Normalize()
+	return t
+}
+
+// twistAngle returns the angle in radians from -Pi to Pi of the twist
+// of the specified rotation around the specified normalized axis.
+func twistAngle(q *math32.Quaternion, axis *math32.Vector3) float32 {
+
+	p := axis.X*q.X + axis.Y*q.Y + axis.Z*q.Z
+	return wrapAngle(2 * math32.Atan2(p, q.W))
+}
+
+// wrapAngle returns the specified angle in radians wrapped to the range -Pi to Pi.
+func wrapAngle(angle float32) float32 {
+
+	for angle > math32.Pi {
+		angle -= 2 * math32.Pi
+	}
+	for angle < -math32.Pi {
+		angle += 2 * math32.Pi
+	}
+	return angle
+}
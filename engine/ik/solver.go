@@ -0,0 +1,260 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ik
+
+import (
+	"github.com/wangzun/gogame/engine/core"
+	"github.com/wangzun/gogame/engine/math32"
+)
+
+// ISolver is the interface for all inverse kinematics solvers.
+type ISolver interface {
+	Enabled() bool
+	Solve(delta float32)
+}
+
+// Rig runs inverse kinematics solvers in order, so for example a solver which places
+// the hips can run before the solvers which place the feet.
+type Rig struct {
+	solvers []ISolver
+}
+
+// NewRig creates and returns a pointer to a new rig with the specified solvers.
+func NewRig(solvers ...ISolver) *Rig {
+
+	r := new(Rig)
+	r.solvers = solvers
+	return r
+}
+
+// Add adds the specified solver after the solvers of the rig.
+func (r *Rig) Add(s ISolver) {
+
+	r.solvers = append(r.solvers, s)
+}
+
+// Remove removes the specified solver from the rig.
+func (r *Rig) Remove(s ISolver) {
+
+	for i, rs := range r.solvers {
+		if rs == s {
+			copy(r.solvers[i:], r.solvers[i+1:])
+			r.solvers[len(r.solvers)-1] = nil
+			r.solvers = r.solvers[:len(r.solvers)-1]
+			return
+		}
+	}
+}
+
+// Solvers returns the solvers of the rig.
+func (r *Rig) Solvers() []ISolver {
+
+	return r.solvers
+}
+
+// Update runs the enabled solvers of the rig with the specified time in seconds since
+// the last update. It must be called every frame after updating the animations, for
+// example after the mixer in an OnBeforeRender handler, as the solvers start from the
+// current pose of the nodes.
+func (r *Rig) Update(delta float32) {
+
+	for _, s := range r.solvers {
+		if s.Enabled() {
+			s.Solve(delta)
+		}
+	}
+}
+
+// solver contains the common state of the solvers.
+type solver struct {
+	enabled bool           // Whether the solver is enabled
+	weight  float32        // Blending weight of the solution over the current pose
+	target  *core.Node     // Optional target node
+	pos     math32.Vector3 // Target world position when there is no target node
+}
+
+// init initializes the common state of a solver.
+func (s *solver) init() {
+
+	s.enabled = true
+	s.weight = 1
+}
+
+// SetEnabled sets whether the solver is run by its rig.
+func (s *solver) SetEnabled(state bool) {
+
+	s.enabled = state
+}
+
+// Enabled returns whether the solver is run by its rig.
+func (s *solver) Enabled() bool {
+
+	return s.enabled
+}
+
+// SetWeight sets the weight from 0 to 1 of the solution over the current pose (default = 1),
+// for example to fade the solver in and out.
+func (s *solver) SetWeight(weight float32) {
+
+	s.weight = math32.Clamp(weight, 0, 1)
+}
+
+// Weight returns the weight of the solution over the current pose.
+func (s *solver) Weight() float32 {
+
+	return s.weight
+}
+
+// SetTarget sets the node whose world position is the target of the solver.
+func (s *solver) SetTarget(target core.INode) {
+
+	s.target = nil
+	if target != nil {
+		s.target = target.GetNode()
+	}
+}
+
+// Target returns the target node of the solver or nil if it has a target position.
+func (s *solver) Target() *core.Node {
+
+	return s.target
+}
+
+// SetTargetPosition sets the target world position of the solver and removes its target node.
+func (s *solver) SetTargetPosition(pos *math32.Vector3) {
+
+	s.target = nil
+	s.pos = *pos
+}
+
+// TargetPosition returns the current target world position of the solver.
+func (s *solver) TargetPosition() math32.Vector3 {
+
+	if s.target != nil {
+		return worldPosition(s.target)
+	}
+	return s.pos
+}
+
+// saveRotations returns the local rotations of the specified nodes.
+func saveRotations(nodes []*core.Node) []math32.Quaternion {
+
+	saved := make([]math32.Quaternion, len(nodes))
+	for i, n := range nodes {
+		saved[i] = n.Quaternion()
+	}
+	return saved
+}
+
+// blendRotations blends the specified saved local rotations of the specified nodes
+// over their current rotations with the specified weight of the current rotations.
+func blendRotations(nodes []*core.Node, saved []math32.Quaternion, weight float32) {
+
+	if weight >= 1 {
+		return
+	}
+	for i, n := range nodes {
+		q := saved[i]
+		solved := n.Quaternion()
+		q.Slerp(&solved, weight)
+		n.SetQuaternionQuat(&q)
+	}
+}
+
+// worldMatrix returns the world matrix of the specified node computed from the current
+// local transforms of its ancestors, which may not have updated their world matrices.
+func worldMatrix(n *core.Node) math32.Matrix4 {
+
+	var m math32.Matrix4
+	pos := n.Position()
+	q := n.Quaternion()
+	scale := n.Scale()
+	m.Compose(&pos, &q, &scale)
+	if parent := n.Parent(); parent != nil {
+		pm := worldMatrix(parent.GetNode())
+		m.MultiplyMatrices(&pm, &m)
+	}
+	return m
+}
+
+// worldPosition returns the world position of the specified node.
+func worldPosition(n *core.Node) math32.Vector3 {
+
+	var pos math32.Vector3
+	m := worldMatrix(n)
+	pos.SetFromMatrixPosition(&m)
+	return pos
+}
+
+// worldQuaternion returns the world rotation of the specified node.
+func worldQuaternion(n *core.Node) math32.Quaternion {
+
+	q := n.Quaternion()
+	if parent := n.Parent(); parent != nil {
+		pq := worldQuaternion(parent.GetNode())
+		q.MultiplyQuaternions(&pq, &q)
+	}
+	return q
+}
+
+// parentQuaternion returns the world rotation of the parent of the specified node.
+func parentQuaternion(n *core.Node) math32.Quaternion {
+
+	if parent := n.Parent(); parent != nil {
+		return worldQuaternion(parent.GetNode())
+	}
+	var q math32.Quaternion
+	q.SetIdentity()
+	return q
+}
+
+// setWorldQuaternion sets the local rotation of the specified node so its world rotation is the specified one.
+func setWorldQuaternion(n *core.Node, q *math32.Quaternion) {
+
+	local := parentQuaternion(n)
+	local.Conjugate()
+	local.Multiply(q)
+	local.Normalize()
+	n.SetQuaternionQuat(&local)
+}
+
+// rotateWorld rotates the specified node by the specified rotation in world space.
+func rotateWorld(n *core.Node, r *math32.Quaternion) {
+
+	q := worldQuaternion(n)
+	q.MultiplyQuaternions(r, &q)
+	setWorldQuaternion(n, &q)
+}
+
+// rotateTowards rotates the specified node in world space by the smallest rotation which turns
+// the direction from the specified pivot to the specified point towards the specified target.
+func rotateTowards(n *core.Node, pivot, point, target *math32.Vector3) {
+
+	var from, to math32.Vector3
+	from.SubVectors(point, pivot)
+	to.SubVectors(target, pivot)
+	if from.LengthSq() < 1e-12 || to.LengthSq() < 1e-12 {
+		return
+	}
+	from.Normalize()
+	to.Normalize()
+	var r math32.Quaternion
+	r.SetFromUnitVectors(&from, &to)
+	rotateWorld(n, &r)
+}
+
+// signedAngle returns the angle in radians around the specified normalized axis from the
+// projection of the first vector to the projection of the second vector on the plane
+// perpendicular to the axis.
+func signedAngle(from, to, axis *math32.Vector3) float32 {
+
+	a := *from
+	b := *to
+	a.ProjectOnPlane(axis)
+	b.ProjectOnPlane(axis)
+	var c math32.Vector3
+	c.CrossVectors(&a, &b)
+	return math32.Atan2(c.Dot(axis), a.Dot(&b))
+}
@@ -0,0 +1,177 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ik
+
+import (
+	"github.com/wangzun/gogame/engine/core"
+	"github.com/wangzun/gogame/engine/math32"
+)
+
+// TwoBone is an analytic solver for limbs of two bones, such as legs and arms, which bends
+// the middle joint so the end joint reaches the target and turns the limb so the middle joint
+// points towards an optional pole, such as a point in front of the knee. Typical uses are
+// placing the feet on the terrain and the hands on a weapon, whose rotation the end joint can
+// also match.
+type TwoBone struct {
+	solver
+	root       *core.Node     // Upper joint, such as the thigh
+	mid        *core.Node     // Middle joint, such as the knee
+	end        *core.Node     // End joint, such as the foot
+	pole       *core.Node     // Optional pole node
+	polePos    math32.Vector3 // Pole world position when there is no pole node
+	hasPole    bool           // Whether the solver has a pole
+	matchRot   bool           // Whether the end joint matches the rotation of the target node
+	maxStretch float32        // Fraction of the limb length which may be straightened
+}
+
+// NewTwoBone creates and returns a pointer to a new two-bone solver for the specified upper,
+// middle and end joints, such as the thigh, knee and foot bones of a leg.
+func NewTwoBone(root, mid, end core.INode) *TwoBone {
+
+	s := new(TwoBone)
+	s.solver.init()
+	s.root = root.GetNode()
+	s.mid = mid.GetNode()
+	s.end = end.GetNode()
+	s.maxStretch = 0.999
+	return s
+}
+
+// Joints returns the upper, middle and end joints of the limb.
+func (s *TwoBone) Joints() (*core.Node, *core.Node, *core.Node) {
+
+	return s.root, s.mid, s.end
+}
+
+// SetPole sets the node towards whose world position the middle joint points.
+// A nil node removes the pole, so the limb keeps bending in its current plane.
+func (s *TwoBone) SetPole(pole core.INode) {
+
+	s.pole = nil
+	s.hasPole = pole != nil
+	if pole != nil {
+		s.pole = pole.GetNode()
+	}
+}
+
+// SetPolePosition sets the world position towards which the middle joint points.
+func (s *TwoBone) SetPolePosition(pos *math32.Vector3) {
+
+	s.pole = nil
+	s.hasPole = true
+	s.polePos = *pos
+}
+
+// SetMatchRotation sets whether the end joint takes the world rotation of the target node,
+// for example to align a foot with the slope of the terrain or a hand with a weapon grip.
+func (s *TwoBone) SetMatchRotation(state bool) {
+
+	s.matchRot = state
+}
+
+// MatchRotation returns whether the end joint takes the world rotation of the target node.
+func (s *TwoBone) MatchRotation() bool {
+
+	return s.matchRot
+}
+
+// SetMaxStretch sets the fraction of the length of the limb it may be straightened to when the
+// target is out of reach (default = 0.999), which keeps a slight bend so the limb does not snap.
+func (s *TwoBone) SetMaxStretch(fraction float32) {
+
+	s.maxStretch = fraction
+}
+
+// MaxStretch returns the fraction of the length of the limb it may be straightened to.
+func (s *TwoBone) MaxStretch() float32 {
+
+	return s.maxStretch
+}
+
+// Solve rotates the upper and middle joints so the end joint reaches the target.
+func (s *TwoBone) Solve(delta float32) {
+
+	nodes := []*core.Node{s.root, s.mid, s.end}
+	saved := saveRotations(nodes)
+	target := s.TargetPosition()
+
+	a := worldPosition(s.root)
+	b := worldPosition(s.mid)
+	c := worldPosition(s.end)
+	lab := a.DistanceTo(&b)
+	lcb := b.DistanceTo(&c)
+	if lab == 0 || lcb == 0 {
+		return
+	}
+	lat := math32.Clamp(a.DistanceTo(&target), math32.Abs(lab-lcb)+1e-4, (lab+lcb)*s.maxStretch)
+
+	// Bends the middle joint so the distance from the upper to the end joint is the target distance
+	var ba, bc, axis math32.Vector3
+	ba.SubVectors(&a, &b).Normalize()
+	bc.SubVectors(&c, &b).Normalize()
+	axis.CrossVectors(&ba, &bc)
+	if axis.LengthSq() < 1e-8 {
+		// The limb is straight, so it bends away from the pole and is turned towards it below
+		var ac, ap math32.Vector3
+		ac.SubVectors(&c, &a)
+		if s.hasPole {
+			pole := s.polePosition()
+			ap.SubVectors(&pole, &a)
+		} else {
+			ap = perpendicular(&ac)
+		}
+		axis.CrossVectors(&ac, &ap)
+	}
+	axis.Normalize()
+	current := math32.Acos(math32.Clamp(ba.Dot(&bc), -1, 1))
+	wanted := math32.Acos(math32.Clamp((lab*lab+lcb*lcb-lat*lat)/(2*lab*lcb), -1, 1))
+	var r math32.Quaternion
+	r.SetFromAxisAngle(&axis, wanted-current)
+	rotateWorld(s.mid, &r)
+
+	// Turns the limb so the end joint points to the target
+	c = worldPosition(s.end)
+	rotateTowards(s.root, &a, &c, &target)
+
+	// Twists the limb around its axis so the middle joint points towards the pole
+	if s.hasPole {
+		var at, ab, ap math32.Vector3
+		pole := s.polePosition()
+		at.SubVectors(&target, &a).Normalize()
+		b = worldPosition(s.mid)
+		ab.SubVectors(&b, &a)
+		ap.SubVectors(&pole, &a)
+		angle := signedAngle(&ab, &ap, &at)
+		r.SetFromAxisAngle(&at, angle)
+		rotateWorld(s.root, &r)
+	}
+
+	if s.matchRot && s.target != nil {
+		q := worldQuaternion(s.target)
+		setWorldQuaternion(s.end, &q)
+	}
+	blendRotations(nodes, saved, s.weight)
+}
+
+// polePosition returns the current world position of the pole.
+func (s *TwoBone) polePosition() math32.Vector3 {
+
+	if s.pole != nil {
+		return worldPosition(s.pole)
+	}
+	return s.polePos
+}
+
+// perpendicular returns a vector perpendicular to the specified vector.
+func perpendicular(v *math32.Vector3) math32.Vector3 {
+
+	var p math32.Vector3
+	if math32.Abs(v.X) < math32.Abs(v.Y) {
+		p.Set(0, -v.Z, v.Y)
+	} else {
+		p.Set(-v.Y, v.X, 0)
+	}
+	return p
+}
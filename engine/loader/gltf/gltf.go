@@ -15,6 +15,7 @@ import (
 	"github.com/wangzun/gogame/engine/graphic"
 	"github.com/wangzun/gogame/engine/material"
 	"github.com/wangzun/gogame/engine/math32"
+	"github.com/wangzun/gogame/engine/texture"
 )
 
 // glTF Extensions.
@@ -23,6 +24,7 @@ const (
 	KhrMaterialsUnlit                 = "KHR_materials_unlit"
 	KhrMaterialsCommon                = "KHR_materials_common" // TODO this is officially part of glTF 1.0 (remove?)
 	KhrMaterialsPbrSpecularGlossiness = "KHR_materials_pbrSpecularGlossiness"
+	KhrTextureBasisu                  = "KHR_texture_basisu"
 )
 
 // GLTF is the root object for a glTF asset.
@@ -47,8 +49,9 @@ type GLTF struct {
 	Extensions         map[string]interface{} // Dictionary object with extension-specific objects. Not required.
 	Extras             interface{}            // Application-specific data. Not required.
 
-	path string // File path for resources.
-	data []byte // Binary file Chunk 1 data.
+	path     string // File path for resources.
+	data     []byte // Binary file Chunk 1 data.
+	maxBones int    // Maximum number of bones of skinned meshes. Zero for the default.
 }
 
 // Accessor is a typed view into a BufferView.
@@ -155,7 +158,8 @@ type Image struct {
 	Extensions map[string]interface{} // Dictionary object with extension-specific objects. Not required.
 	Extras     interface{}            // Application-specific data. Not required.
 
-	cache *image.RGBA // Cached image.
+	cache      *image.RGBA              // Cached image.
+	compressed *texture.CompressedImage // Cached compressed image (KTX).
 }
 
 // Indices of those attributes that deviate from their initialization value.
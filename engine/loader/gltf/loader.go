@@ -149,6 +149,16 @@ func readChunk(r io.Reader, chunkType uint32) ([]byte, error) {
 	return data, nil
 }
 
+// SetMaxBones sets the maximum number of bones of the rigged meshes of the skinned meshes
+// loaded afterwards (default = graphic.MaxPaletteBones). The primitives influenced by more
+// bones are split by bone palette. It should be increased when the shaders of the target
+// platforms support more uniforms, as each palette needs another draw call. The value
+// must be at least three times graphic.MaxBoneInfluencers.
+func (g *GLTF) SetMaxBones(maxBones int) {
+
+	g.maxBones = maxBones
+}
+
 // LoadScene creates a parent Node which contains all nodes contained by
 // the specified scene index from the GLTF Scenes array.
 func (g *GLTF) LoadScene(sceneIdx int) (core.INode, error) {
@@ -201,22 +211,14 @@ func (g *GLTF) LoadNode(nodeIdx int) (core.INode, error) {
 		}
 
 		if nodeData.Skin != nil {
-			// fmt.Println("0000000000000")
-			children := in.GetNode().Children()
-			if len(children) > 1 {
-				//log.Error("skinning/rigging meshes with more than a single primitive is not supported")
-				return nil, fmt.Errorf("skinning/rigging meshes with more than a single primitive is not supported")
-			}
-			mesh := children[0].(*graphic.Mesh)
-			// Create RiggedMesh
-			rm := graphic.NewRiggedMesh(mesh)
 			skeleton, err := g.LoadSkin(*nodeData.Skin)
 			if err != nil {
 				return nil, err
 			}
-			// fmt.Println("skkkkkkkkk")
-			rm.SetSkeleton(skeleton)
-			in = rm
+			in, err = g.rigMeshes(in, skeleton)
+			if err != nil {
+				return nil, err
+			}
 		}
 
 		// Check if the node is Camera
@@ -269,6 +271,74 @@ func (g *GLTF) LoadNode(nodeIdx int) (core.INode, error) {
 	return in, nil
 }
 
+// rigMeshes replaces the primitive meshes of the specified mesh container node by rigged
+// meshes sharing the specified skeleton. The primitives influenced by more bones than the
+// maximum, with their morph targets if any, are split by bone palette. Returns the only
+// rigged mesh if there is a single one or the container node otherwise.
+func (g *GLTF) rigMeshes(in core.INode, skeleton *graphic.Skeleton) (core.INode, error) {
+
+	maxBones := g.maxBones
+	if maxBones == 0 {
+		maxBones = graphic.MaxPaletteBones
+	}
+	node := in.GetNode()
+	children := append([]core.INode(nil), node.Children()...)
+	var rigged []*graphic.RiggedMesh
+	for _, child := range children {
+		mesh, ok := child.(*graphic.Mesh)
+		if !ok {
+			log.Warn("skinning is only supported for triangle primitives")
+			continue
+		}
+		node.Remove(mesh)
+		if len(skeleton.Bones()) <= maxBones {
+			rm := graphic.NewRiggedMesh(mesh)
+			rm.SetSkeleton(skeleton)
+			rigged = append(rigged, rm)
+			continue
+		}
+		// Morph geometries are split with their morph targets
+		var geoms []geometry.IGeometry
+		var palettes [][]int
+		if mg, ok := mesh.IGeometry().(*geometry.MorphGeometry); ok {
+			morphs, mpalettes := mg.SplitByBonePalette(maxBones)
+			for _, m := range morphs {
+				geoms = append(geoms, m)
+			}
+			palettes = mpalettes
+		} else {
+			split, spalettes := mesh.GetGeometry().SplitByBonePalette(maxBones)
+			for _, geom := range split {
+				geoms = append(geoms, geom)
+			}
+			palettes = spalettes
+		}
+		if len(geoms) == 0 {
+			return nil, fmt.Errorf("primitive with %d bones and no joints or weights can't be split by bone palette", len(skeleton.Bones()))
+		}
+		// The split meshes share the material, each one with its own reference,
+		// and the geometry of the original mesh is no longer used
+		imat := mesh.GetMaterial(0)
+		for i := range geoms {
+			if i > 0 {
+				imat.GetMaterial().Incref()
+			}
+			rm := graphic.NewRiggedMesh(graphic.NewMesh(geoms[i], imat))
+			rm.SetSkeleton(skeleton)
+			rm.SetBonePalette(palettes[i])
+			rigged = append(rigged, rm)
+		}
+		mesh.IGeometry().Dispose()
+	}
+	if len(rigged) == 1 && len(node.Children()) == 0 {
+		return rigged[0], nil
+	}
+	for _, rm := range rigged {
+		node.Add(rm)
+	}
+	return in, nil
+}
+
 // LoadSkin loads the skin with specified index.
 func (g *GLTF) LoadSkin(skinIdx int) (*graphic.Skeleton, error) {
 
@@ -349,6 +419,7 @@ func (g *GLTF) LoadAnimation(animIdx int) (*animation.Animation, error) {
 		var validComponentTypes []int
 
 		var ch animation.IChannel
+		var extra []animation.IChannel
 		if target.Path == "translation" {
 			validTypes = []string{VEC3}
 			validComponentTypes = []int{FLOAT}
@@ -364,12 +435,15 @@ func (g *GLTF) LoadAnimation(animIdx int) (*animation.Animation, error) {
 		} else if target.Path == "weights" {
 			validTypes = []string{SCALAR}
 			validComponentTypes = []int{FLOAT, BYTE, UNSIGNED_BYTE, SHORT, UNSIGNED_SHORT}
-			children := node.GetNode().Children()
-			if len(children) > 1 {
-				return nil, fmt.Errorf("animating meshes with more than a single primitive is not supported")
+			// All the primitives of the mesh share the weights
+			morphGeoms := morphGeometries(node)
+			if len(morphGeoms) == 0 {
+				return nil, fmt.Errorf("animated morph target weights of node without morph targets")
 			}
-			morphGeom := children[0].(graphic.IGraphic).IGeometry().(*geometry.MorphGeometry)
-			ch = animation.NewMorphChannel(morphGeom)
+			for _, morphGeom := range morphGeoms[1:] {
+				extra = append(extra, animation.NewMorphChannel(morphGeom))
+			}
+			ch = animation.NewMorphChannel(morphGeoms[0])
 		}
 
 		// TODO what if Input and Output accessors are interleaved? probably de-interleave in these 2 cases
@@ -382,13 +456,53 @@ func (g *GLTF) LoadAnimation(animIdx int) (*animation.Animation, error) {
 		if err != nil {
 			return nil, err
 		}
-		ch.SetBuffers(keyframes, values)
-		ch.SetInterpolationType(animation.InterpolationType(sampler.Interpolation))
-		anim.AddChannel(ch)
+		for _, c := range append([]animation.IChannel{ch}, extra...) {
+			c.SetBuffers(keyframes, values)
+			c.SetInterpolationType(animation.InterpolationType(sampler.Interpolation))
+			anim.AddChannel(c)
+		}
 	}
 	return anim, nil
 }
 
+// morphGeometries returns the morph geometries of the primitives of the specified mesh node,
+// which is either a graphic, such as a rigged mesh, or a node with a graphic per primitive.
+func morphGeometries(node core.INode) []*geometry.MorphGeometry {
+
+	nodes := []core.INode{node}
+	if _, ok := node.(graphic.IGraphic); !ok {
+		nodes = node.GetNode().Children()
+	}
+	var morphGeoms []*geometry.MorphGeometry
+	for _, n := range nodes {
+		if igr, ok := n.(graphic.IGraphic); ok {
+			if mg, ok := igr.IGeometry().(*geometry.MorphGeometry); ok {
+				morphGeoms = append(morphGeoms, mg)
+			}
+		}
+	}
+	return morphGeoms
+}
+
+// targetNames returns the morph target names in the "targetNames" array
+// of the specified mesh extras or nil if not present.
+func targetNames(extras interface{}) []string {
+
+	m, ok := extras.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	list, ok := m["targetNames"].([]interface{})
+	if !ok {
+		return nil
+	}
+	names := make([]string, len(list))
+	for i, v := range list {
+		names[i], _ = v.(string)
+	}
+	return names
+}
+
 // LoadCamera creates and returns a Camera Node
 // from the specified GLTF.Cameras index.
 func (g *GLTF) LoadCamera(camIdx int) (core.INode, error) {
@@ -476,6 +590,12 @@ func (g *GLTF) LoadMesh(meshIdx int) (core.INode, error) {
 			grMat = g.newDefaultMaterial()
 		}
 
+		// Default mode is 4 (TRIANGLES)
+		mode := TRIANGLES
+		if p.Mode != nil {
+			mode = *p.Mode
+		}
+
 		// Create geometry
 		var igeom geometry.IGeometry
 		igeom = geometry.NewGeometry()
@@ -486,13 +606,22 @@ func (g *GLTF) LoadMesh(meshIdx int) (core.INode, error) {
 			return nil, err
 		}
 
+		// Computes the flat normals of triangles and the tangents of normal mapped
+		// primitives when not specified, as required by the specification.
+		// Primitives with morph targets can't have their vertices converted.
+		if _, ok := p.Attributes["NORMAL"]; !ok && len(p.Targets) == 0 && mode == TRIANGLES {
+			geom.ComputeFlatNormals()
+		}
+		_, hasTangents := p.Attributes["TANGENT"]
+		_, hasTexcoords := p.Attributes["TEXCOORD_0"]
+		if !hasTangents && hasTexcoords && p.Material != nil && g.Materials[*p.Material].NormalTexture != nil {
+			geom.ComputeTangents()
+		}
+
 		// If primitive has targets then the geometry should be a morph geometry
 		if len(p.Targets) > 0 {
 			morphGeom := geometry.NewMorphGeometry(geom)
 
-			// TODO Load morph target names if present in extras under "targetNames"
-			// TODO Update morph target weights if present in Mesh.Weights
-
 			// Load targets
 			for i := range p.Targets {
 				tGeom := geometry.NewGeometry()
@@ -504,13 +633,13 @@ func (g *GLTF) LoadMesh(meshIdx int) (core.INode, error) {
 				morphGeom.AddMorphTargetDeltas(tGeom)
 			}
 
-			igeom = morphGeom
-		}
+			// Load morph target names from the extras and default weights
+			morphGeom.SetTargetNames(targetNames(meshData.Extras))
+			if len(meshData.Weights) == len(p.Targets) {
+				morphGeom.SetDefaultWeights(meshData.Weights)
+			}
 
-		// Default mode is 4 (TRIANGLES)
-		mode := TRIANGLES
-		if p.Mode != nil {
-			mode = *p.Mode
+			igeom = morphGeom
 		}
 
 		// Create Mesh
@@ -558,7 +687,7 @@ func (g *GLTF) loadAttributes(geom *geometry.Geometry, attributes map[string]int
 			if ok {
 				// Already created VBO for this buffer view
 				// Add attribute with correct byteOffset
-				g.addAttributeToVBO(vbo, name, uint32(*accessor.ByteOffset))
+				g.addAttributeToVBO(vbo, name, accessor, uint32(*accessor.ByteOffset))
 			} else {
 				// Load data and create vbo
 				buf, err := g.loadBufferView(bvIdx)
@@ -570,7 +699,7 @@ func (g *GLTF) loadAttributes(geom *geometry.Geometry, attributes map[string]int
 					return err
 				}
 				vbo := gls.NewVBO(data)
-				g.addAttributeToVBO(vbo, name, 0)
+				g.addAttributeToVBO(vbo, name, accessor, 0)
 				// Save reference to VBO keyed by index of the buffer view
 				interleavedVBOs[bvIdx] = vbo
 				// Add VBO to geometry
@@ -586,7 +715,7 @@ func (g *GLTF) loadAttributes(geom *geometry.Geometry, attributes map[string]int
 				return err
 			}
 			vbo := gls.NewVBO(data)
-			g.addAttributeToVBO(vbo, name, 0)
+			g.addAttributeToVBO(vbo, name, accessor, 0)
 			// Add VBO to geometry
 			geom.AddVBO(vbo)
 		}
@@ -607,7 +736,9 @@ func (g *GLTF) loadIndices(ai int) (math32.ArrayU32, error) {
 }
 
 // addAttributeToVBO adds the appropriate attribute to the provided vbo based on the glTF attribute name.
-func (g *GLTF) addAttributeToVBO(vbo *gls.VBO, attribName string, byteOffset uint32) {
+// The number of elements of the attribute is the one of the accessor, as tangents are VEC4 in
+// primitives and VEC3 in morph targets.
+func (g *GLTF) addAttributeToVBO(vbo *gls.VBO, attribName string, accessor Accessor, byteOffset uint32) {
 
 	aType, ok := AttributeName[attribName]
 	if !ok {
@@ -615,6 +746,7 @@ func (g *GLTF) addAttributeToVBO(vbo *gls.VBO, attribName string, byteOffset uin
 		return
 	}
 	vbo.AddAttribOffset(aType, byteOffset)
+	vbo.Attrib(aType).NumElements = int32(TypeSizes[accessor.Type])
 }
 
 // validateAccessorAttribute validates the specified accessor for the given attribute name.
@@ -734,16 +866,38 @@ func (g *GLTF) LoadTexture(texIdx int) (*texture.Texture2D, error) {
 	log.Debug("Loading Texture %d", texIdx)
 	// fmt.Println("Loading Texture %d", texIdx)
 
-	// Load texture image
-	img, err := g.LoadImage(texData.Source)
-	if err != nil {
-		return nil, err
+	// Compressed textures reference a KTX2 image in the KHR_texture_basisu extension.
+	// If it cannot be loaded, or its format is not supported by the GPU,
+	// the texture source image is used as fallback.
+	var tex *texture.Texture2D
+	if ext, ok := texData.Extensions[KhrTextureBasisu]; ok {
+		var err error
+		tex, err = g.loadTextureBasisu(ext, texData.Source)
+		if err != nil {
+			log.Warn("texture:%d %s: %v (using source image)", texIdx, KhrTextureBasisu, err)
+		}
+	}
+
+	// Load texture image which may be a KTX compressed image
+	if tex == nil {
+		ci, err := g.LoadCompressedImage(texData.Source)
+		if err != nil {
+			return nil, err
+		}
+		if ci != nil {
+			tex = texture.NewTexture2DFromCompressed(ci)
+		} else {
+			img, err := g.LoadImage(texData.Source)
+			if err != nil {
+				return nil, err
+			}
+			tex = texture.NewTexture2DFromRGBA(img)
+		}
 	}
-	tex := texture.NewTexture2DFromRGBA(img)
 
 	// Get sampler and apply texture parameters
 	if texData.Sampler != nil {
-		err = g.applySampler(*texData.Sampler, tex)
+		err := g.applySampler(*texData.Sampler, tex)
 		if err != nil {
 			return nil, err
 		}
@@ -752,6 +906,32 @@ func (g *GLTF) LoadTexture(texIdx int) (*texture.Texture2D, error) {
 	return tex, nil
 }
 
+// loadTextureBasisu loads the compressed texture referenced by the KHR_texture_basisu extension data,
+// with the specified texture source image as fallback if it is a different image.
+func (g *GLTF) loadTextureBasisu(ext interface{}, fallback int) (*texture.Texture2D, error) {
+
+	data, ok := ext.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid extension data")
+	}
+	source, ok := data["source"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("missing source")
+	}
+	ci, err := g.LoadCompressedImage(int(source))
+	if err != nil {
+		return nil, err
+	}
+	if ci == nil {
+		return nil, fmt.Errorf("image:%d is not a KTX image", int(source))
+	}
+	tex := texture.NewTexture2DFromCompressed(ci)
+	if fallback != int(source) && fallback >= 0 && fallback < len(g.Images) {
+		tex.SetFallback(func() (*image.RGBA, error) { return g.LoadImage(fallback) })
+	}
+	return tex, nil
+}
+
 // applySamplers applies the specified Sampler to the provided texture.
 func (g *GLTF) applySampler(samplerIdx int, tex *texture.Texture2D) error {
 
@@ -809,22 +989,7 @@ func (g *GLTF) LoadImage(imgIdx int) (*image.RGBA, error) {
 	}
 	log.Debug("Loading Image %d", imgIdx)
 
-	var data []byte
-	var err error
-	// If Uri is empty, load image from GLB binary chunk
-	if imgData.Uri == "" {
-		if imgData.BufferView == nil {
-			return nil, fmt.Errorf("image has empty URI and no BufferView")
-		}
-		data, err = g.loadBufferView(*imgData.BufferView)
-	} else if isDataURL(imgData.Uri) {
-		// Checks if image URI is data URL
-		data, err = loadDataURL(imgData.Uri)
-	} else {
-		// Load image data from file
-		data, err = g.loadFileBytes(imgData.Uri)
-	}
-
+	data, err := g.loadImageData(imgIdx)
 	if err != nil {
 		return nil, err
 	}
@@ -849,6 +1014,69 @@ func (g *GLTF) LoadImage(imgIdx int) (*image.RGBA, error) {
 	return rgba, nil
 }
 
+// LoadCompressedImage loads the KTX 1.1 or KTX 2.0 image specified by the index of GLTF.Images.
+// It returns nil without error if the image is not a KTX image.
+func (g *GLTF) LoadCompressedImage(imgIdx int) (*texture.CompressedImage, error) {
+
+	// Check if provided image index is valid
+	if imgIdx < 0 || imgIdx >= len(g.Images) {
+		return nil, fmt.Errorf("invalid image index")
+	}
+	imgData := g.Images[imgIdx]
+	// Return cached if available
+	if imgData.compressed != nil {
+		log.Debug("Fetching compressed Image %d (cached)", imgIdx)
+		return imgData.compressed, nil
+	}
+	if imgData.cache != nil {
+		return nil, nil
+	}
+	// Checks the image type before loading its data
+	switch {
+	case imgData.MimeType == mimeKTX || imgData.MimeType == mimeKTX2:
+	case imgData.MimeType == "" && (strings.HasSuffix(imgData.Uri, ".ktx") || strings.HasSuffix(imgData.Uri, ".ktx2")):
+	default:
+		return nil, nil
+	}
+	log.Debug("Loading compressed Image %d", imgIdx)
+
+	data, err := g.loadImageData(imgIdx)
+	if err != nil {
+		return nil, err
+	}
+	if !texture.IsKTX(data) {
+		return nil, nil
+	}
+	ci, err := texture.DecodeKTX(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Cache image
+	g.Images[imgIdx].compressed = ci
+	return ci, nil
+}
+
+// loadImageData loads the encoded data of the image specified by the index of GLTF.Images
+// from the binary chunk, a data URI or an external file.
+func (g *GLTF) loadImageData(imgIdx int) ([]byte, error) {
+
+	imgData := g.Images[imgIdx]
+	// If Uri is empty, load image from GLB binary chunk
+	if imgData.Uri == "" {
+		if imgData.BufferView == nil {
+			return nil, fmt.Errorf("image has empty URI and no BufferView")
+		}
+		return g.loadBufferView(*imgData.BufferView)
+	}
+	// Checks if image URI is data URL
+	if isDataURL(imgData.Uri) {
+		return loadDataURL(imgData.Uri)
+	}
+	// Load image data from file
+	return g.loadFileBytes(imgData.Uri)
+}
+
 // bytesToArrayU32 converts a byte array to ArrayU32.
 func (g *GLTF) bytesToArrayU32(data []byte, componentType, count int) (math32.ArrayU32, error) {
 
@@ -1135,9 +1363,11 @@ const (
 	mimeBIN       = "application/octet-stream"
 	mimePNG       = "image/png"
 	mimeJPEG      = "image/jpeg"
+	mimeKTX       = "image/ktx"
+	mimeKTX2      = "image/ktx2"
 )
 
-var validMediaTypes = []string{mimeBIN, mimePNG, mimeJPEG}
+var validMediaTypes = []string{mimeBIN, mimePNG, mimeJPEG, mimeKTX, mimeKTX2}
 
 // isDataURL checks if the specified string has the prefix of data URL.
 func isDataURL(url string) bool {
@@ -263,6 +263,12 @@ func (dec *Decoder) NewGeometry(obj *Object) (*geometry.Geometry, error) {
 	geom.AddVBO(gls.NewVBO(normals).AddAttrib(gls.VertexNormal))
 	geom.AddVBO(gls.NewVBO(uvs).AddAttrib(gls.VertexTexcoord))
 
+	// Computes the normals if any face doesn't specify them, which are
+	// flat as the faces don't share vertices
+	if normals.Size() != positions.Size() {
+		geom.ComputeVertexNormals()
+	}
+
 	return geom, nil
 }
 
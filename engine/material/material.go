@@ -233,6 +233,12 @@ func (mat *Material) SetDepthTest(state bool) {
 	mat.depthTest = state
 }
 
+// DepthTest returns whether the depth test is enabled for this material.
+func (mat *Material) DepthTest() bool {
+
+	return mat.depthTest
+}
+
 func (mat *Material) SetBlending(blending Blending) {
 
 	mat.blending = blending
@@ -243,6 +249,8 @@ func (mat *Material) SetLineWidth(width float32) {
 	mat.lineWidth = width
 }
 
+// SetPolygonOffset sets the polygon offset factor and units used to offset the depth
+// values of the triangles drawn with this material. Zero values disable the offset.
 func (mat *Material) SetPolygonOffset(factor, units float32) {
 
 	mat.polyOffsetFactor = factor
@@ -281,7 +289,12 @@ func (mat *Material) RenderSetup(gs *gls.GLS) {
 	// }
 
 	// Set polygon offset if requested
-	gs.PolygonOffset(mat.polyOffsetFactor, mat.polyOffsetUnits)
+	if mat.polyOffsetFactor != 0 || mat.polyOffsetUnits != 0 {
+		gs.Enable(gls.POLYGON_OFFSET_FILL)
+		gs.PolygonOffset(mat.polyOffsetFactor, mat.polyOffsetUnits)
+	} else {
+		gs.Disable(gls.POLYGON_OFFSET_FILL)
+	}
 
 	// Sets line width
 	gs.LineWidth(mat.lineWidth)
@@ -0,0 +1,79 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package material
+
+import (
+	"github.com/wangzun/gogame/engine/gls"
+	"github.com/wangzun/gogame/engine/math32"
+	"golang.org/x/mobile/gl"
+)
+
+// Outline is the material used by the renderer to draw the outline of graphics.
+// It draws the back faces of the graphic geometry extruded along the vertex normals
+// by the outline width in pixels, where the stencil buffer was not marked by the graphic.
+type Outline struct {
+	Material                  // Embedded material
+	color       math32.Color4 // Outline color
+	width       float32       // Outline width in pixels
+	uniColor    gls.Uniform   // Outline color uniform location cache
+	uniWidth    gls.Uniform   // Outline width uniform location cache
+	uniViewport gls.Uniform   // Viewport size uniform location cache
+}
+
+// NewOutline creates and returns a pointer to a new outline material
+// with the specified color and width in pixels.
+func NewOutline(color *math32.Color4, width float32) *Outline {
+
+	mo := new(Outline)
+	mo.Material.Init()
+	mo.SetShader("outline")
+	mo.SetUseLights(UseLightNone)
+	mo.SetSide(SideBack)
+	mo.SetDepthMask(false)
+	mo.color = *color
+	mo.width = width
+	mo.uniColor.Init("OutlineColor")
+	mo.uniWidth.Init("OutlineWidth")
+	mo.uniViewport.Init("OutlineViewport")
+	return mo
+}
+
+// SetColor sets the outline color.
+func (mo *Outline) SetColor(color *math32.Color4) {
+
+	mo.color = *color
+}
+
+// Color returns the outline color.
+func (mo *Outline) Color() math32.Color4 {
+
+	return mo.color
+}
+
+// SetWidth sets the outline width in pixels.
+func (mo *Outline) SetWidth(width float32) {
+
+	mo.width = width
+}
+
+// Width returns the outline width in pixels.
+func (mo *Outline) Width() float32 {
+
+	return mo.width
+}
+
+// RenderSetup is called by the engine before drawing the object
+// which uses this material.
+func (mo *Outline) RenderSetup(gs *gls.GLS) {
+
+	mo.Material.RenderSetup(gs)
+	location := mo.uniColor.Location(gs)
+	gs.Uniform4f(gl.Uniform{Value: location}, mo.color.R, mo.color.G, mo.color.B, mo.color.A)
+	location = mo.uniWidth.Location(gs)
+	gs.Uniform1f(gl.Uniform{Value: location}, mo.width)
+	_, _, width, height := gs.GetViewport()
+	location = mo.uniViewport.Location(gs)
+	gs.Uniform2f(gl.Uniform{Value: location}, float32(width), float32(height))
+}
@@ -57,6 +57,12 @@ func (m *Physical) SetBaseColorFactor(c *math32.Color4) *Physical {
 	return m
 }
 
+// BaseColorFactor returns this material base color.
+func (m *Physical) BaseColorFactor() math32.Color4 {
+
+	return m.udata.baseColorFactor
+}
+
 // SetMetallicFactor sets this material metallic factor.
 // Its default value is 1.
 // Returns pointer to this updated material.
@@ -86,6 +92,12 @@ func (m *Physical) SetEmissiveFactor(c *math32.Color) *Physical {
 	return m
 }
 
+// EmissiveFactor returns the emissive color of the material.
+func (m *Physical) EmissiveFactor() math32.Color {
+
+	return math32.Color{m.udata.emissiveFactor.R, m.udata.emissiveFactor.G, m.udata.emissiveFactor.B}
+}
+
 // SetBaseColorMap sets this material optional texture base color.
 // Returns pointer to this updated material.
 func (m *Physical) SetBaseColorMap(tex *texture.Texture2D) *Physical {
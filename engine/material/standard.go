@@ -76,6 +76,12 @@ func (ms *Standard) SetColor(color *math32.Color) {
 	ms.udata.ambient = *color
 }
 
+// Color returns the material diffuse color.
+func (ms *Standard) Color() math32.Color {
+
+	return ms.udata.diffuse
+}
+
 // SetEmissiveColor sets the material emissive color
 // The default is {0,0,0}
 func (ms *Standard) SetEmissiveColor(color *math32.Color) {
@@ -108,6 +114,12 @@ func (ms *Standard) SetOpacity(opacity float32) {
 	ms.udata.opacity = opacity
 }
 
+// Opacity returns the material opacity (alpha).
+func (ms *Standard) Opacity() float32 {
+
+	return ms.udata.opacity
+}
+
 // RenderSetup is called by the engine before drawing the object
 // which uses this material
 func (ms *Standard) RenderSetup(gs *gls.GLS) {
@@ -0,0 +1,105 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package material
+
+import (
+	"github.com/wangzun/gogame/engine/gls"
+	"github.com/wangzun/gogame/engine/math32"
+	"github.com/wangzun/gogame/engine/texture"
+)
+
+// TerrainMaxLayers is the maximum number of texture layers of a terrain material.
+const TerrainMaxLayers = 4
+
+// Terrain is the material used by terrain.Terrain, lit in the fragment shader like the
+// Phong material, which blends up to four texture layers repeated over the terrain
+// according to the weights in the channels of a splat map stretched over the terrain.
+// The red channel of the splat map is the weight of the first layer, the green channel
+// of the second one and so on. Without splat map only the first layer is used.
+// It also morphs the vertices between the levels of detail of the terrain chunks.
+type Terrain struct {
+	Standard                      // Embedded standard material
+	splat    *texture.Texture2D   // Splat map with the layer weights
+	layers   []*texture.Texture2D // Layer textures
+}
+
+// NewTerrain creates and returns a pointer to a new terrain material
+// with the specified color which multiplies the layers colors.
+func NewTerrain(color *math32.Color) *Terrain {
+
+	mt := new(Terrain)
+	mt.Standard.Init("terrain", color)
+	mt.SetSpecularColor(&math32.Color{0, 0, 0})
+	return mt
+}
+
+// SetSplatMap sets the texture whose red, green, blue and alpha channels
+// are the weights of the layers over the terrain or nil to remove it.
+func (mt *Terrain) SetSplatMap(tex *texture.Texture2D) {
+
+	mt.splat = tex
+	if tex != nil {
+		mt.ShaderDefines.Set("TERRAIN_SPLAT", "")
+	} else {
+		mt.ShaderDefines.Unset("TERRAIN_SPLAT")
+	}
+	mt.updateTextures()
+}
+
+// SplatMap returns the splat map texture or nil if not set.
+func (mt *Terrain) SplatMap() *texture.Texture2D {
+
+	return mt.splat
+}
+
+// AddLayer adds a texture layer repeated the specified number of times over the terrain
+// and returns its index or -1 if the material already has the maximum number of layers.
+// The texture wrap mode is set to repeat.
+func (mt *Terrain) AddLayer(tex *texture.Texture2D, repeat float32) int {
+
+	if len(mt.layers) >= TerrainMaxLayers {
+		return -1
+	}
+	tex.SetWrapS(gls.REPEAT)
+	tex.SetWrapT(gls.REPEAT)
+	tex.SetRepeat(repeat, repeat)
+	mt.layers = append(mt.layers, tex)
+	mt.updateTextures()
+	return len(mt.layers) - 1
+}
+
+// Layer returns the texture of the layer with the specified index.
+func (mt *Terrain) Layer(idx int) *texture.Texture2D {
+
+	return mt.layers[idx]
+}
+
+// LayerCount returns the number of texture layers.
+func (mt *Terrain) LayerCount() int {
+
+	return len(mt.layers)
+}
+
+// ClearLayers removes all the texture layers.
+func (mt *Terrain) ClearLayers() {
+
+	mt.layers = mt.layers[:0]
+	mt.updateTextures()
+}
+
+// updateTextures sets the textures of the material in the order expected by
+// the shader: the splat map, if set, followed by the layers.
+func (mt *Terrain) updateTextures() {
+
+	for mt.TextureCount() > 0 {
+		mt.RemoveTexture(mt.textures[0])
+	}
+	if mt.splat != nil {
+		mt.AddTexture(mt.splat)
+	}
+	for _, tex := range mt.layers {
+		mt.AddTexture(tex)
+	}
+}
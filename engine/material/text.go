@@ -0,0 +1,186 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package material
+
+import (
+	"github.com/wangzun/gogame/engine/gls"
+	"github.com/wangzun/gogame/engine/math32"
+	"github.com/wangzun/gogame/engine/text"
+	"github.com/wangzun/gogame/engine/texture"
+	"golang.org/x/mobile/gl"
+)
+
+// Size in pixels and spread of the glyphs of the font atlases used by text materials
+const (
+	textAtlasSize   = 48
+	textAtlasSpread = 6
+)
+
+// textAtlas contains the signed distance field atlas of a font and its texture
+type textAtlas struct {
+	atlas   *text.Atlas        // Signed distance field atlas
+	tex     *texture.Texture2D // Texture with the atlas image
+	version int                // Atlas version transferred to the texture
+}
+
+// Text atlases shared by all text materials using the same font
+var textAtlases = make(map[*text.Font]*textAtlas)
+
+// fontAtlas returns the text atlas of the specified font creating it if necessary.
+func fontAtlas(font *text.Font) *textAtlas {
+
+	ta := textAtlases[font]
+	if ta == nil {
+		ta = new(textAtlas)
+		ta.atlas = text.NewSDFAtlas(font, textAtlasSize, textAtlasSpread)
+		ta.tex = texture.NewTexture2DFromRGBA(ta.atlas.Image)
+		ta.tex.SetMinFilter(gls.LINEAR)
+		ta.version = ta.atlas.Version()
+		textAtlases[font] = ta
+	}
+	return ta
+}
+
+// update transfers the atlas image to the texture if glyphs were added.
+func (ta *textAtlas) update() {
+
+	if ta.version == ta.atlas.Version() {
+		return
+	}
+	ta.tex.SetFromRGBA(ta.atlas.Image)
+	ta.version = ta.atlas.Version()
+}
+
+// Text is the material used to render text glyphs from the signed distance
+// field atlas of a font with an optional outline and drop shadow.
+// The atlas is shared by all text materials using the same font.
+// The geometry texture coordinates must be the glyph coordinates in the atlas
+// image as returned by Atlas().Layout().
+type Text struct {
+	Material                    // Embedded material
+	font         *text.Font     // Font
+	atlas        *textAtlas     // Font atlas
+	color        math32.Color4  // Text color
+	outlineColor math32.Color4  // Outline color
+	outlineWidth float32        // Outline width in pixels
+	shadowColor  math32.Color4  // Shadow color
+	shadowOffset math32.Vector2 // Shadow offset in pixels
+	scale        float32        // Screen pixels per atlas pixel
+	uniColor     gls.Uniform    // Text color uniform location cache
+	uniOutline   gls.Uniform    // Outline color uniform location cache
+	uniShadow    gls.Uniform    // Shadow color uniform location cache
+	uniParams    gls.Uniform    // Text parameters uniform location cache
+}
+
+// NewText creates and returns a pointer to a new text material
+// for the specified font using the "text" shader.
+func NewText(font *text.Font) *Text {
+
+	mt := new(Text)
+	mt.Material.Init()
+	mt.SetShader("text")
+	mt.SetUseLights(UseLightNone)
+	mt.font = font
+	mt.atlas = fontAtlas(font)
+	mt.color = math32.Color4{1, 1, 1, 1}
+	mt.scale = 1
+	mt.AddTexture(mt.atlas.tex.Incref())
+	mt.uniColor.Init("TextColor")
+	mt.uniOutline.Init("TextOutlineColor")
+	mt.uniShadow.Init("TextShadowColor")
+	mt.uniParams.Init("TextParams")
+	return mt
+}
+
+// Font returns the font of this material.
+func (mt *Text) Font() *text.Font {
+
+	return mt.font
+}
+
+// Atlas returns the signed distance field atlas of the material font,
+// which is used to lay out the text glyphs.
+func (mt *Text) Atlas() *text.Atlas {
+
+	return mt.atlas.atlas
+}
+
+// SetColor sets the text color.
+func (mt *Text) SetColor(color *math32.Color4) {
+
+	mt.color = *color
+}
+
+// Color returns the text color.
+func (mt *Text) Color() math32.Color4 {
+
+	return mt.color
+}
+
+// SetOutline sets the color and width in screen pixels of the outline drawn
+// around the glyphs. A zero width disables the outline.
+func (mt *Text) SetOutline(color *math32.Color4, width float32) {
+
+	mt.outlineColor = *color
+	mt.outlineWidth = width
+}
+
+// Outline returns the color and width in screen pixels of the outline.
+func (mt *Text) Outline() (math32.Color4, float32) {
+
+	return mt.outlineColor, mt.outlineWidth
+}
+
+// SetShadow sets the color and offset in screen pixels of the drop shadow drawn under the glyphs.
+// Positive offsets move the shadow to the right and down. A transparent color disables the shadow.
+func (mt *Text) SetShadow(color *math32.Color4, dx, dy float32) {
+
+	mt.shadowColor = *color
+	mt.shadowOffset = math32.Vector2{dx, dy}
+}
+
+// Shadow returns the color and offset in screen pixels of the drop shadow.
+func (mt *Text) Shadow() (math32.Color4, float32, float32) {
+
+	return mt.shadowColor, mt.shadowOffset.X, mt.shadowOffset.Y
+}
+
+// SetScale sets the number of screen pixels covered by an atlas pixel,
+// which is used to keep the glyph edges, outline and shadow sharp.
+func (mt *Text) SetScale(scale float32) {
+
+	mt.scale = scale
+}
+
+// Scale returns the number of screen pixels covered by an atlas pixel.
+func (mt *Text) Scale() float32 {
+
+	return mt.scale
+}
+
+// RenderSetup is called by the engine before drawing the object
+// which uses this material.
+func (mt *Text) RenderSetup(gs *gls.GLS) {
+
+	mt.atlas.update()
+	mt.Material.RenderSetup(gs)
+
+	location := mt.uniColor.Location(gs)
+	gs.Uniform4f(gl.Uniform{Value: location}, mt.color.R, mt.color.G, mt.color.B, mt.color.A)
+	location = mt.uniOutline.Location(gs)
+	gs.Uniform4f(gl.Uniform{Value: location}, mt.outlineColor.R, mt.outlineColor.G, mt.outlineColor.B, mt.outlineColor.A)
+	location = mt.uniShadow.Location(gs)
+	gs.Uniform4f(gl.Uniform{Value: location}, mt.shadowColor.R, mt.shadowColor.G, mt.shadowColor.B, mt.shadowColor.A)
+
+	// Converts the sizes in screen pixels to distance field units,
+	// where the spread in atlas pixels corresponds to 0.5
+	scale := math32.Max(mt.scale, 0.01)
+	unit := 1 / (2 * float32(mt.atlas.atlas.Spread) * scale)
+	img := mt.atlas.atlas.Image.Rect.Size()
+	sx := mt.shadowOffset.X / scale / float32(img.X)
+	sy := mt.shadowOffset.Y / scale / float32(img.Y)
+	location = mt.uniParams.Location(gs)
+	gs.Uniform4f(gl.Uniform{Value: location}, 0.7*unit, mt.outlineWidth*unit, sx, sy)
+}
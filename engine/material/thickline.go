@@ -0,0 +1,144 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package material
+
+import (
+	"github.com/wangzun/gogame/engine/gls"
+	"github.com/wangzun/gogame/engine/math32"
+	"golang.org/x/mobile/gl"
+)
+
+// ThickLine is the material used by graphic.ThickLine to expand its polylines
+// into triangles in the vertex shader, so lines wider than the line width
+// supported by the OpenGL driver can be drawn.
+type ThickLine struct {
+	Material                   // Embedded material
+	color       math32.Color4  // Line color multiplied by the vertex colors
+	width       float32        // Line width in pixels or world units
+	worldUnits  bool           // Width is in world units instead of pixels
+	miterLimit  float32        // Maximum miter length in terms of the line width
+	dash        math32.Vector3 // Dash length, gap length and offset in the model space of the lines
+	uniColor    gls.Uniform    // Line color uniform location cache
+	uniParams   gls.Uniform    // Line parameters uniform location cache
+	uniDash     gls.Uniform    // Dash parameters uniform location cache
+	uniViewport gls.Uniform    // Viewport size uniform location cache
+}
+
+// NewThickLine creates and returns a pointer to a new thick line material
+// with the specified color and width in pixels.
+func NewThickLine(color *math32.Color4, width float32) *ThickLine {
+
+	ml := new(ThickLine)
+	ml.Material.Init()
+	ml.SetShader("thickline")
+	ml.SetUseLights(UseLightNone)
+	ml.SetSide(SideDouble)
+	ml.color = *color
+	ml.width = width
+	ml.miterLimit = 4
+	ml.uniColor.Init("LineColor")
+	ml.uniParams.Init("LineParams")
+	ml.uniDash.Init("LineDash")
+	ml.uniViewport.Init("LineViewport")
+	return ml
+}
+
+// SetColor sets the line color, which is multiplied by the vertex colors.
+func (ml *ThickLine) SetColor(color *math32.Color4) {
+
+	ml.color = *color
+}
+
+// Color returns the line color.
+func (ml *ThickLine) Color() math32.Color4 {
+
+	return ml.color
+}
+
+// SetWidth sets the line width in pixels or world units depending on WorldUnits().
+func (ml *ThickLine) SetWidth(width float32) {
+
+	ml.width = width
+}
+
+// Width returns the line width in pixels or world units depending on WorldUnits().
+func (ml *ThickLine) Width() float32 {
+
+	return ml.width
+}
+
+// SetWorldUnits sets whether the line width is in world units, so lines get
+// thinner with distance, instead of pixels (default).
+func (ml *ThickLine) SetWorldUnits(state bool) {
+
+	ml.worldUnits = state
+}
+
+// WorldUnits returns whether the line width is in world units instead of pixels.
+func (ml *ThickLine) WorldUnits() bool {
+
+	return ml.worldUnits
+}
+
+// SetMiterLimit sets the maximum length of miter joins in terms of the line width (default = 4).
+// Miter joins longer than the limit are drawn as bevel joins.
+func (ml *ThickLine) SetMiterLimit(limit float32) {
+
+	ml.miterLimit = limit
+}
+
+// MiterLimit returns the maximum length of miter joins in terms of the line width.
+func (ml *ThickLine) MiterLimit() float32 {
+
+	return ml.miterLimit
+}
+
+// SetDash sets the lengths of the dashes and of the gaps between them measured along
+// the lines in their model space, so they are scaled with the lines and are in world units
+// only for lines without scaling. A zero dash length draws solid lines (default).
+func (ml *ThickLine) SetDash(dash, gap float32) {
+
+	ml.dash.X = dash
+	ml.dash.Y = gap
+}
+
+// Dash returns the lengths in model space of the dashes and of the gaps between them.
+func (ml *ThickLine) Dash() (float32, float32) {
+
+	return ml.dash.X, ml.dash.Y
+}
+
+// SetDashOffset sets the distance in model space the dash pattern is shifted along the lines,
+// which can be changed every frame to animate the dashes.
+func (ml *ThickLine) SetDashOffset(offset float32) {
+
+	ml.dash.Z = offset
+}
+
+// DashOffset returns the distance in model space the dash pattern is shifted along the lines.
+func (ml *ThickLine) DashOffset() float32 {
+
+	return ml.dash.Z
+}
+
+// RenderSetup is called by the engine before drawing the object
+// which uses this material.
+func (ml *ThickLine) RenderSetup(gs *gls.GLS) {
+
+	ml.Material.RenderSetup(gs)
+	location := ml.uniColor.Location(gs)
+	gs.Uniform4f(gl.Uniform{Value: location}, ml.color.R, ml.color.G, ml.color.B, ml.color.A)
+	world := float32(0)
+	if ml.worldUnits {
+		world = 1
+	}
+	location = ml.uniParams.Location(gs)
+	gs.Uniform4f(gl.Uniform{Value: location}, ml.width, world, ml.miterLimit, 0)
+	location = ml.uniDash.Location(gs)
+	gs.Uniform4f(gl.Uniform{Value: location}, ml.dash.X, ml.dash.Y, ml.dash.Z, 0)
+	_, _, width, height := gs.GetViewport()
+	location = ml.uniViewport.Location(gs)
+	gs.Uniform2f(gl.Uniform{Value: location}, float32(width), float32(height))
+}
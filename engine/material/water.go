@@ -0,0 +1,325 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package material
+
+import (
+	"github.com/wangzun/gogame/engine/gls"
+	"github.com/wangzun/gogame/engine/math32"
+	"github.com/wangzun/gogame/engine/texture"
+	"golang.org/x/mobile/gl"
+)
+
+// WaterMaxWaves is the maximum number of Gerstner waves of a water material.
+const WaterMaxWaves = 4
+
+// Water is the material used by graphic.Water, lit in the fragment shader like the Phong
+// material, which displaces the vertices with Gerstner waves and perturbs the normals with
+// a normal map scrolling in two directions. The water color is blended by the fresnel
+// factor with the sky horizon and zenith colors or with the reflection map, which is
+// normally the texture of a render target where the scene was rendered mirrored by the
+// water plane. When the water graphic has the depth of its floor, the color goes from
+// the shallow color at the shore to the deep color, which is the diffuse color.
+// The material is transparent by default.
+type Water struct {
+	Standard                             // Embedded standard material
+	normalMap   *texture.Texture2D       // Detail normal map
+	reflection  *texture.Texture2D       // Screen space reflection map
+	waves       [WaterMaxWaves]waterWave // Gerstner waves
+	waveCount   int                      // Number of waves
+	time        float32                  // Animation time in seconds
+	normalScale float32                  // Size of the normal map tiles in world units
+	strength    float32                  // Strength of the normal map perturbation
+	fresnelBias float32                  // Reflectance when looking straight down
+	scroll1     math32.Vector2           // Velocity of the first normal map sample
+	scroll2     math32.Vector2           // Velocity of the second normal map sample
+	shallow     math32.Color             // Color at the shore
+	shoreDepth  float32                  // Depth where the water gets the deep color
+	edgeFade    float32                  // Depth over which the water fades in at the shore
+	horizon     math32.Color             // Sky color reflected at the horizon
+	zenith      math32.Color             // Sky color reflected straight up
+	distortion  float32                  // Distortion of the reflection map by the normals
+	uniWaves    gls.Uniform              // Waves uniform location cache
+	uniParams   gls.Uniform              // Parameters uniform location cache
+	uniViewport gls.Uniform              // Viewport uniform location cache
+}
+
+// waterWave describes a Gerstner wave.
+type waterWave struct {
+	direction  math32.Vector2 // Normalized direction on the XZ plane
+	steepness  float32        // Steepness from 0 to 1
+	wavelength float32        // Distance between crests in world units
+}
+
+// NewWater creates and returns a pointer to a new water material
+// with the specified deep water color and no waves.
+func NewWater(color *math32.Color) *Water {
+
+	mw := new(Water)
+	mw.Standard.Init("water", color)
+	mw.SetSpecularColor(&math32.Color{1, 1, 1})
+	mw.SetShininess(100)
+	mw.SetOpacity(0.8)
+	mw.SetTransparent(true)
+	mw.normalScale = 4
+	mw.strength = 0.5
+	mw.fresnelBias = 0.02
+	mw.scroll1 = math32.Vector2{0.03, 0.02}
+	mw.scroll2 = math32.Vector2{-0.02, 0.03}
+	mw.shallow = math32.Color{0.1, 0.6, 0.6}
+	mw.shoreDepth = 2
+	mw.edgeFade = 0.3
+	mw.horizon = math32.Color{0.8, 0.85, 0.9}
+	mw.zenith = math32.Color{0.3, 0.5, 0.8}
+	mw.distortion = 0.02
+	mw.uniWaves.Init("WaterWaves")
+	mw.uniParams.Init("WaterParams")
+	mw.uniViewport.Init("WaterViewport")
+	return mw
+}
+
+// AddWave adds a Gerstner wave traveling in the specified direction on the XZ plane
+// with the specified steepness from 0 to 1 and distance between crests in world units,
+// and returns its index or -1 if the material already has the maximum number of waves.
+// The sum of the steepnesses of the waves should not exceed 1 to avoid loops at the crests.
+func (mw *Water) AddWave(dirX, dirZ, steepness, wavelength float32) int {
+
+	if mw.waveCount >= WaterMaxWaves {
+		return -1
+	}
+	w := &mw.waves[mw.waveCount]
+	w.direction.Set(dirX, dirZ)
+	w.direction.Normalize()
+	w.steepness = steepness
+	w.wavelength = wavelength
+	mw.waveCount++
+	return mw.waveCount - 1
+}
+
+// WaveCount returns the number of waves.
+func (mw *Water) WaveCount() int {
+
+	return mw.waveCount
+}
+
+// ClearWaves removes all the waves.
+func (mw *Water) ClearWaves() {
+
+	mw.waveCount = 0
+}
+
+// Update advances the animation time of the waves and of the normal map
+// by the specified time in seconds. It should be called every frame.
+func (mw *Water) Update(delta float32) {
+
+	mw.time += delta
+}
+
+// SetTime sets the animation time in seconds.
+func (mw *Water) SetTime(time float32) {
+
+	mw.time = time
+}
+
+// Time returns the animation time in seconds.
+func (mw *Water) Time() float32 {
+
+	return mw.time
+}
+
+// SetNormalMap sets the tangent space normal map which perturbs the wave normals or nil to
+// remove it. Its wrap mode is set to repeat as it tiles the water in world coordinates.
+func (mw *Water) SetNormalMap(tex *texture.Texture2D) {
+
+	if mw.normalMap != nil {
+		mw.RemoveTexture(mw.normalMap)
+	}
+	mw.normalMap = tex
+	if tex != nil {
+		tex.SetUniformNames("WaterNormalSampler", "WaterNormalTexParams")
+		tex.SetWrapS(gls.REPEAT)
+		tex.SetWrapT(gls.REPEAT)
+		mw.ShaderDefines.Set("WATER_NORMALMAP", "")
+		mw.AddTexture(tex)
+	} else {
+		mw.ShaderDefines.Unset("WATER_NORMALMAP")
+	}
+}
+
+// NormalMap returns the normal map or nil if not set.
+func (mw *Water) NormalMap() *texture.Texture2D {
+
+	return mw.normalMap
+}
+
+// SetNormalScale sets the size in world units of the normal map tiles (default = 4).
+func (mw *Water) SetNormalScale(size float32) {
+
+	mw.normalScale = size
+}
+
+// NormalScale returns the size in world units of the normal map tiles.
+func (mw *Water) NormalScale() float32 {
+
+	return mw.normalScale
+}
+
+// SetNormalStrength sets how much the normal map perturbs the wave normals (default = 0.5).
+func (mw *Water) SetNormalStrength(strength float32) {
+
+	mw.strength = strength
+}
+
+// NormalStrength returns how much the normal map perturbs the wave normals.
+func (mw *Water) NormalStrength() float32 {
+
+	return mw.strength
+}
+
+// SetScroll sets the velocities in tiles per second of the two normal map samples.
+func (mw *Water) SetScroll(v1, v2 *math32.Vector2) {
+
+	mw.scroll1 = *v1
+	mw.scroll2 = *v2
+}
+
+// Scroll returns the velocities in tiles per second of the two normal map samples.
+func (mw *Water) Scroll() (math32.Vector2, math32.Vector2) {
+
+	return mw.scroll1, mw.scroll2
+}
+
+// SetFresnelBias sets the fraction of the reflected color when looking
+// straight down at the water (default = 0.02).
+func (mw *Water) SetFresnelBias(bias float32) {
+
+	mw.fresnelBias = bias
+}
+
+// FresnelBias returns the fraction of the reflected color when looking straight down at the water.
+func (mw *Water) FresnelBias() float32 {
+
+	return mw.fresnelBias
+}
+
+// SetShallowColor sets the color of the water at the shore.
+func (mw *Water) SetShallowColor(color *math32.Color) {
+
+	mw.shallow = *color
+}
+
+// ShallowColor returns the color of the water at the shore.
+func (mw *Water) ShallowColor() math32.Color {
+
+	return mw.shallow
+}
+
+// SetShoreDepth sets the depth where the water gets the deep color and the waves get
+// their full height (default = 2). It is only used when the water has its floor depth.
+func (mw *Water) SetShoreDepth(depth float32) {
+
+	mw.shoreDepth = depth
+}
+
+// ShoreDepth returns the depth where the water gets the deep color.
+func (mw *Water) ShoreDepth() float32 {
+
+	return mw.shoreDepth
+}
+
+// SetEdgeFade sets the depth over which the water fades in at the shore (default = 0.3).
+// Zero disables the fading. It is only used when the water has its floor depth.
+func (mw *Water) SetEdgeFade(depth float32) {
+
+	mw.edgeFade = depth
+}
+
+// EdgeFade returns the depth over which the water fades in at the shore.
+func (mw *Water) EdgeFade() float32 {
+
+	return mw.edgeFade
+}
+
+// SetSkyColors sets the sky colors reflected at the horizon and straight up, normally the
+// colors of the skybox or of the background gradient, used without reflection map.
+func (mw *Water) SetSkyColors(horizon, zenith *math32.Color) {
+
+	mw.horizon = *horizon
+	mw.zenith = *zenith
+}
+
+// SkyColors returns the sky colors reflected at the horizon and straight up.
+func (mw *Water) SkyColors() (math32.Color, math32.Color) {
+
+	return mw.horizon, mw.zenith
+}
+
+// SetReflectionMap sets the texture with the scene mirrored by the water plane and rendered
+// with the size of the viewport, normally the texture of a texture.RenderTarget, or nil to
+// remove it. The reflected sky colors are used without reflection map.
+func (mw *Water) SetReflectionMap(tex *texture.Texture2D) {
+
+	if mw.reflection != nil {
+		mw.RemoveTexture(mw.reflection)
+	}
+	mw.reflection = tex
+	if tex != nil {
+		tex.SetUniformNames("WaterReflectionSampler", "WaterReflectionTexParams")
+		mw.ShaderDefines.Set("WATER_REFLECTION", "")
+		mw.AddTexture(tex)
+	} else {
+		mw.ShaderDefines.Unset("WATER_REFLECTION")
+	}
+}
+
+// ReflectionMap returns the reflection map or nil if not set.
+func (mw *Water) ReflectionMap() *texture.Texture2D {
+
+	return mw.reflection
+}
+
+// SetDistortion sets how much the normal map distorts the reflection map (default = 0.02).
+func (mw *Water) SetDistortion(distortion float32) {
+
+	mw.distortion = distortion
+}
+
+// Distortion returns how much the normal map distorts the reflection map.
+func (mw *Water) Distortion() float32 {
+
+	return mw.distortion
+}
+
+// RenderSetup is called by the engine before drawing the object
+// which uses this material.
+func (mw *Water) RenderSetup(gs *gls.GLS) {
+
+	mw.Standard.RenderSetup(gs)
+
+	// Waves with zero wavelength are ignored by the shader
+	waves := make([]float32, 4*WaterMaxWaves)
+	for i := 0; i < mw.waveCount; i++ {
+		w := &mw.waves[i]
+		waves[4*i] = w.direction.X
+		waves[4*i+1] = w.direction.Y
+		waves[4*i+2] = w.steepness
+		waves[4*i+3] = w.wavelength
+	}
+	location := mw.uniWaves.Location(gs)
+	gs.Uniform4fv(gl.Uniform{Value: location}, waves)
+
+	params := []float32{
+		mw.time, mw.normalScale, mw.strength, mw.fresnelBias,
+		mw.scroll1.X, mw.scroll1.Y, mw.scroll2.X, mw.scroll2.Y,
+		mw.shallow.R, mw.shallow.G, mw.shallow.B, mw.shoreDepth,
+		mw.horizon.R, mw.horizon.G, mw.horizon.B, mw.edgeFade,
+		mw.zenith.R, mw.zenith.G, mw.zenith.B, mw.distortion,
+	}
+	location = mw.uniParams.Location(gs)
+	gs.Uniform4fv(gl.Uniform{Value: location}, params)
+
+	x, y, width, height := gs.GetViewport()
+	location = mw.uniViewport.Location(gs)
+	gs.Uniform4f(gl.Uniform{Value: location}, float32(x), float32(y), float32(width), float32(height))
+}
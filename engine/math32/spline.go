@@ -4,14 +4,20 @@
 
 package math32
 
-import (
-//"math"
-)
+// Curve is the interface for parametric curves in 3D space,
+// which are evaluated with a parameter from 0 at the start to 1 at the end.
+type Curve interface {
+	Point(t float32) Vector3
+	Tangent(t float32) Vector3
+}
 
+// Spline is a Catmull-Rom curve which passes through all its points.
 type Spline struct {
 	points []Vector3
+	closed bool
 }
 
+// NewSpline creates and returns a pointer to a new open spline through the specified points.
 func NewSpline(points []Vector3) *Spline {
 
 	this := new(Spline)
@@ -20,11 +26,156 @@ func NewSpline(points []Vector3) *Spline {
 	return this
 }
 
+// InitFromArray sets the spline points from an array with their x, y and z coordinates.
 func (this *Spline) InitFromArray(a []float32) {
 
-	// PEND array of what ?
-	//this.points = [];
-	//for ( var i = 0; i < a.length; i ++ ) {
-	//    this.points[ i ] = { x: a[ i ][ 0 ], y: a[ i ][ 1 ], z: a[ i ][ 2 ] };
-	//}
+	this.points = this.points[:0]
+	for i := 0; i+2 < len(a); i += 3 {
+		this.points = append(this.points, Vector3{a[i], a[i+1], a[i+2]})
+	}
+}
+
+// Points returns the points of the spline.
+func (this *Spline) Points() []Vector3 {
+
+	return this.points
+}
+
+// SetClosed sets whether the spline continues from its last point back to the first one.
+func (this *Spline) SetClosed(closed bool) {
+
+	this.closed = closed
+}
+
+// Closed returns whether the spline continues from its last point back to the first one.
+func (this *Spline) Closed() bool {
+
+	return this.closed
+}
+
+// segment returns the four points of the spline segment at the specified parameter
+// and the parameter relative to the segment.
+func (this *Spline) segment(t float32) (p0, p1, p2, p3 *Vector3, weight float32) {
+
+	n := len(this.points)
+	segments := n - 1
+	if this.closed {
+		segments = n
+	}
+	point := Clamp(t, 0, 1) * float32(segments)
+	intPoint := int(point)
+	if intPoint >= segments {
+		intPoint = segments - 1
+	}
+	weight = point - float32(intPoint)
+	index := func(i int) *Vector3 {
+		if this.closed {
+			return &this.points[((i%n)+n)%n]
+		}
+		if i < 0 {
+			i = 0
+		} else if i > n-1 {
+			i = n - 1
+		}
+		return &this.points[i]
+	}
+	return index(intPoint - 1), index(intPoint), index(intPoint + 1), index(intPoint + 2), weight
+}
+
+// Point returns the point of the spline at the specified parameter between 0 and 1.
+func (this *Spline) Point(t float32) Vector3 {
+
+	switch len(this.points) {
+	case 0:
+		return Vector3{}
+	case 1:
+		return this.points[0]
+	}
+	p0, p1, p2, p3, w := this.segment(t)
+	return Vector3{
+		catmullRom(p0.X, p1.X, p2.X, p3.X, w),
+		catmullRom(p0.Y, p1.Y, p2.Y, p3.Y, w),
+		catmullRom(p0.Z, p1.Z, p2.Z, p3.Z, w),
+	}
+}
+
+// Tangent returns the normalized tangent of the spline at the specified parameter between 0 and 1.
+func (this *Spline) Tangent(t float32) Vector3 {
+
+	if len(this.points) < 2 {
+		return Vector3{0, 0, 1}
+	}
+	p0, p1, p2, p3, w := this.segment(t)
+	v := Vector3{
+		catmullRomDerivative(p0.X, p1.X, p2.X, p3.X, w),
+		catmullRomDerivative(p0.Y, p1.Y, p2.Y, p3.Y, w),
+		catmullRomDerivative(p0.Z, p1.Z, p2.Z, p3.Z, w),
+	}
+	if v.LengthSq() == 0 {
+		v.SubVectors(p2, p1)
+	}
+	return *v.Normalize()
+}
+
+// catmullRom returns the interpolated value of a Catmull-Rom segment between p1 and p2.
+func catmullRom(p0, p1, p2, p3, t float32) float32 {
+
+	v0 := (p2 - p0) * 0.5
+	v1 := (p3 - p1) * 0.5
+	t2 := t * t
+	t3 := t * t2
+	return (2*p1-2*p2+v0+v1)*t3 + (-3*p1+3*p2-2*v0-v1)*t2 + v0*t + p1
+}
+
+// catmullRomDerivative returns the derivative of a Catmull-Rom segment between p1 and p2.
+func catmullRomDerivative(p0, p1, p2, p3, t float32) float32 {
+
+	v0 := (p2 - p0) * 0.5
+	v1 := (p3 - p1) * 0.5
+	return 3*(2*p1-2*p2+v0+v1)*t*t + 2*(-3*p1+3*p2-2*v0-v1)*t + v0
+}
+
+// CubicBezier is a cubic Bezier curve defined by its end points and two control points.
+type CubicBezier struct {
+	P0, P1, P2, P3 Vector3
+}
+
+// NewCubicBezier creates and returns a pointer to a new cubic Bezier curve from p0 to p3
+// with the control points p1 and p2.
+func NewCubicBezier(p0, p1, p2, p3 *Vector3) *CubicBezier {
+
+	return &CubicBezier{*p0, *p1, *p2, *p3}
+}
+
+// Point returns the point of the curve at the specified parameter between 0 and 1.
+func (c *CubicBezier) Point(t float32) Vector3 {
+
+	mt := 1 - t
+	a := mt * mt * mt
+	b := 3 * mt * mt * t
+	d := 3 * mt * t * t
+	e := t * t * t
+	return Vector3{
+		a*c.P0.X + b*c.P1.X + d*c.P2.X + e*c.P3.X,
+		a*c.P0.Y + b*c.P1.Y + d*c.P2.Y + e*c.P3.Y,
+		a*c.P0.Z + b*c.P1.Z + d*c.P2.Z + e*c.P3.Z,
+	}
+}
+
+// Tangent returns the normalized tangent of the curve at the specified parameter between 0 and 1.
+func (c *CubicBezier) Tangent(t float32) Vector3 {
+
+	mt := 1 - t
+	a := 3 * mt * mt
+	b := 6 * mt * t
+	d := 3 * t * t
+	v := Vector3{
+		a*(c.P1.X-c.P0.X) + b*(c.P2.X-c.P1.X) + d*(c.P3.X-c.P2.X),
+		a*(c.P1.Y-c.P0.Y) + b*(c.P2.Y-c.P1.Y) + d*(c.P3.Y-c.P2.Y),
+		a*(c.P1.Z-c.P0.Z) + b*(c.P2.Z-c.P1.Z) + d*(c.P3.Z-c.P2.Z),
+	}
+	if v.LengthSq() == 0 {
+		v.SubVectors(&c.P3, &c.P0)
+	}
+	return *v.Normalize()
 }
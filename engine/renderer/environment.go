@@ -0,0 +1,226 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package renderer
+
+import (
+	"github.com/wangzun/gogame/engine/core"
+	"github.com/wangzun/gogame/engine/geometry"
+	"github.com/wangzun/gogame/engine/gls"
+	"github.com/wangzun/gogame/engine/graphic"
+	"github.com/wangzun/gogame/engine/material"
+	"github.com/wangzun/gogame/engine/math32"
+	"golang.org/x/mobile/gl"
+)
+
+// FogMode specifies how the fog density varies with the distance from the camera.
+type FogMode int
+
+// The fog modes
+const (
+	FogNone   = FogMode(iota) // No fog
+	FogLinear                 // Fog increases linearly between the near and far distances
+	FogExp                    // Fog increases exponentially with the distance using the density
+	FogExp2                   // Fog increases exponentially with the squared distance using the density
+)
+
+// DefaultBackgroundColor is the background color of new environments.
+var DefaultBackgroundColor = math32.Color{0.5, 0.5, 0.5}
+
+// Environment contains the scene background and fog configuration used by the renderer.
+// The fog is applied by the standard, phong, physical, point and sprite shaders using
+// shader defines, so scenes without fog do not pay for it.
+type Environment struct {
+	bgColor     math32.Color // Background clear color
+	bgGradient  bool         // Background gradient enabled flag
+	bgTop       math32.Color // Background gradient top color
+	bgBottom    math32.Color // Background gradient bottom color
+	fogMode     FogMode      // Current fog mode
+	fogColor    math32.Color // Fog color
+	fogNear     float32      // Linear fog start distance from the camera
+	fogFar      float32      // Linear fog end distance from the camera
+	fogDensity  float32      // Exponential fog density
+	defines     gls.ShaderDefines
+	uniFogColor gls.Uniform // Fog color uniform location cache
+	uniFogParam gls.Uniform // Fog parameters uniform location cache
+	background  *background // Background gradient graphic created on demand
+}
+
+// NewEnvironment creates and returns a pointer to a new environment
+// with a gray background and no fog.
+func NewEnvironment() *Environment {
+
+	e := new(Environment)
+	e.bgColor = DefaultBackgroundColor
+	e.fogColor = math32.Color{0.5, 0.5, 0.5}
+	e.fogNear = 1
+	e.fogFar = 1000
+	e.fogDensity = 0.01
+	e.defines = *gls.NewShaderDefines()
+	e.uniFogColor.Init("FogColor")
+	e.uniFogParam.Init("FogParams")
+	return e
+}
+
+// SetBackgroundColor sets the color used to clear the screen and disables the background gradient.
+func (e *Environment) SetBackgroundColor(color *math32.Color) {
+
+	e.bgColor = *color
+	e.bgGradient = false
+}
+
+// BackgroundColor returns the color used to clear the screen.
+func (e *Environment) BackgroundColor() math32.Color {
+
+	return e.bgColor
+}
+
+// SetBackgroundGradient sets a vertical background gradient from the
+// specified bottom color to the specified top color of the viewport.
+func (e *Environment) SetBackgroundGradient(top, bottom *math32.Color) {
+
+	e.bgTop = *top
+	e.bgBottom = *bottom
+	e.bgGradient = true
+}
+
+// BackgroundGradient returns the background gradient top and bottom colors
+// and if the gradient is enabled.
+func (e *Environment) BackgroundGradient() (top, bottom math32.Color, enabled bool) {
+
+	return e.bgTop, e.bgBottom, e.bgGradient
+}
+
+// SetFog sets the fog mode and color.
+func (e *Environment) SetFog(mode FogMode, color *math32.Color) {
+
+	e.fogMode = mode
+	e.fogColor = *color
+	e.defines = *gls.NewShaderDefines()
+	switch mode {
+	case FogLinear:
+		e.defines.Set("FOG", "")
+		e.defines.Set("FOG_LINEAR", "")
+	case FogExp:
+		e.defines.Set("FOG", "")
+		e.defines.Set("FOG_EXP", "")
+	case FogExp2:
+		e.defines.Set("FOG", "")
+		e.defines.Set("FOG_EXP2", "")
+	}
+}
+
+// Fog returns the current fog mode and color.
+func (e *Environment) Fog() (FogMode, math32.Color) {
+
+	return e.fogMode, e.fogColor
+}
+
+// SetFogRange sets the distances from the camera where the linear fog starts and
+// where it completely covers the objects (default = 1, 1000).
+func (e *Environment) SetFogRange(near, far float32) {
+
+	e.fogNear = near
+	e.fogFar = far
+}
+
+// FogRange returns the linear fog start and end distances.
+func (e *Environment) FogRange() (near, far float32) {
+
+	return e.fogNear, e.fogFar
+}
+
+// SetFogDensity sets the density of the exponential fog modes (default = 0.01).
+func (e *Environment) SetFogDensity(density float32) {
+
+	e.fogDensity = density
+}
+
+// FogDensity returns the density of the exponential fog modes.
+func (e *Environment) FogDensity() float32 {
+
+	return e.fogDensity
+}
+
+// ShaderDefines returns the shader defines for the current fog mode.
+func (e *Environment) ShaderDefines() *gls.ShaderDefines {
+
+	return &e.defines
+}
+
+// RenderSetup transfers the fog uniforms to the current shader program.
+func (e *Environment) RenderSetup(gs *gls.GLS) {
+
+	if e.fogMode == FogNone {
+		return
+	}
+	location := e.uniFogColor.Location(gs)
+	gs.Uniform3f(gl.Uniform{Value: location}, e.fogColor.R, e.fogColor.G, e.fogColor.B)
+	location = e.uniFogParam.Location(gs)
+	gs.Uniform3f(gl.Uniform{Value: location}, e.fogNear, e.fogFar, e.fogDensity)
+}
+
+// backgroundGraphic returns the graphic material used to draw the background
+// gradient or nil if the gradient is not enabled.
+func (e *Environment) backgroundGraphic() *graphic.GraphicMaterial {
+
+	if !e.bgGradient {
+		return nil
+	}
+	if e.background == nil {
+		e.background = newBackground(e)
+	}
+	return &e.background.Materials()[0]
+}
+
+// background is a graphic which covers the viewport with the environment background gradient.
+type background struct {
+	graphic.Graphic
+	env       *Environment
+	uniTop    gls.Uniform // Gradient top color uniform location cache
+	uniBottom gls.Uniform // Gradient bottom color uniform location cache
+}
+
+// newBackground creates and returns a pointer to a new background graphic for the specified environment.
+func newBackground(env *Environment) *background {
+
+	bg := new(background)
+	bg.env = env
+
+	// Quad in clip coordinates covering the viewport
+	positions := math32.NewArrayF32(0, 12)
+	positions.Append(
+		-1, -1, 0,
+		1, -1, 0,
+		1, 1, 0,
+		-1, 1, 0,
+	)
+	indices := math32.NewArrayU32(0, 6)
+	indices.Append(0, 1, 2, 0, 2, 3)
+	geom := geometry.NewGeometry()
+	geom.SetIndices(indices)
+	geom.AddVBO(gls.NewVBO(positions).AddAttrib(gls.VertexPosition))
+
+	mat := material.NewMaterial()
+	mat.SetShader("background")
+	mat.SetUseLights(material.UseLightNone)
+	mat.SetDepthTest(false)
+	mat.SetDepthMask(false)
+	mat.SetSide(material.SideDouble)
+
+	bg.Graphic.Init(geom, gls.TRIANGLES)
+	bg.AddMaterial(bg, mat, 0, 0)
+	bg.uniTop.Init("BackgroundTop")
+	bg.uniBottom.Init("BackgroundBottom")
+	return bg
+}
+
+// RenderSetup transfers the gradient colors uniforms.
+func (bg *background) RenderSetup(gs *gls.GLS, rinfo *core.RenderInfo) {
+
+	location := bg.uniTop.Location(gs)
+	gs.Uniform3f(gl.Uniform{Value: location}, bg.env.bgTop.R, bg.env.bgTop.G, bg.env.bgTop.B)
+	location = bg.uniBottom.Location(gs)
+	gs.Uniform3f(gl.Uniform{Value: location}, bg.env.bgBottom.R, bg.env.bgBottom.G, bg.env.bgBottom.B)
+}
@@ -17,6 +17,7 @@ import (
 
 	"github.com/wangzun/gogame/engine/light"
 	"github.com/wangzun/gogame/engine/math32"
+	"github.com/wangzun/gogame/engine/texture"
 )
 
 // Renderer renders a 3D scene and/or a 2D GUI on the current window.
@@ -43,6 +44,9 @@ type Renderer struct {
 	rinfo        core.RenderInfo            // Preallocated Render info
 	specs        ShaderSpecs                // Preallocated Shader specs
 	sortObjects  bool                       // Flag indicating whether objects should be sorted before rendering
+	env          *Environment               // Scene background and fog
+	outlined     []*graphic.Graphic         // Outlined graphics rendered since their outlines were drawn
+	grmatsOutl   []*graphic.GraphicMaterial // Preallocated array of outline graphic materials
 
 	// --phone GUI TODO--
 	redrawGui bool // Flag indicating the gui must be redrawn completely
@@ -90,6 +94,7 @@ func NewRenderer(gs *gls.GLS) *Renderer {
 
 	r.frameBuffers = 2
 	r.sortObjects = true
+	r.env = NewEnvironment()
 	return r
 }
 
@@ -173,6 +178,18 @@ func (r *Renderer) ObjectSorting() bool {
 	return r.sortObjects
 }
 
+// SetEnvironment sets the scene background and fog environment.
+func (r *Renderer) SetEnvironment(env *Environment) {
+
+	r.env = env
+}
+
+// Environment returns the current scene background and fog environment.
+func (r *Renderer) Environment() *Environment {
+
+	return r.env
+}
+
 // Render renders the previously set Scene and Gui using the specified camera.
 // Returns an indication if anything was rendered and an error.
 func (r *Renderer) Render(icam camera.ICamera) (bool, error) {
@@ -204,6 +221,39 @@ func (r *Renderer) Render(icam camera.ICamera) (bool, error) {
 	return r.rendered, nil
 }
 
+// RenderTo renders the previously set Scene, without the Gui, into the specified render target
+// using the specified camera. It is normally called before Render to update textures used by
+// the scene, such as the reflection map of a water surface. It doesn't change the statistics.
+func (r *Renderer) RenderTo(target *texture.RenderTarget, icam camera.ICamera) error {
+
+	if r.scene == nil {
+		return nil
+	}
+	err := target.Bind(r.gs)
+	if err != nil {
+		return err
+	}
+
+	// Clears the whole render target, which is not confined to the 3D panel.
+	// The scene is rendered without the 3D panel, which would require redrawing the whole Gui,
+	// so the Gui redraw flag is also restored.
+	panel3D := r.panel3D
+	stats := r.stats
+	redrawGui := r.redrawGui
+	r.panel3D = nil
+	r.gs.Disable(gls.SCISSOR_TEST)
+	bg := r.env.BackgroundColor()
+	r.gs.ClearColor(bg.R, bg.G, bg.B, 1)
+	r.gs.Clear(gls.DEPTH_BUFFER_BIT | gls.STENCIL_BUFFER_BIT | gls.COLOR_BUFFER_BIT)
+
+	err = r.renderScene(r.scene, icam)
+	r.panel3D = panel3D
+	r.stats = stats
+	r.redrawGui = redrawGui
+	target.Unbind(r.gs)
+	return err
+}
+
 // renderScene renders the 3D scene using the specified camera.
 func (r *Renderer) renderScene(iscene core.INode, icam camera.ICamera) error {
 
@@ -323,14 +373,13 @@ func (r *Renderer) renderScene(iscene core.INode, icam camera.ICamera) error {
 		}
 	}
 
-	// TODO: If both GraphicMaterials belong to same Graphic we might want to keep their relative order...
-
 	// Z-sort graphic materials (opaque front-to-back and transparent back-to-front)
 	if r.sortObjects {
 		// Internal function to render a list of graphic materials
 		var zSortGraphicMaterials func(grmats []*graphic.GraphicMaterial, backToFront bool)
 		zSortGraphicMaterials = func(grmats []*graphic.GraphicMaterial, backToFront bool) {
-			sort.Slice(grmats, func(i, j int) bool {
+			// Stable sort keeps the relative order of graphic materials of the same graphic
+			sort.SliceStable(grmats, func(i, j int) bool {
 				gr1 := grmats[i].IGraphic().GetGraphic()
 				gr2 := grmats[j].IGraphic().GetGraphic()
 
@@ -371,8 +420,9 @@ func (r *Renderer) renderScene(iscene core.INode, icam camera.ICamera) error {
 	}
 
 	// If there is graphic material to render or there was in the previous frame
-	// it is necessary to clear the screen.
-	if len(r.grmatsOpaque) > 0 || len(r.grmatsTransp) > 0 || r.prevStats.Graphics > 0 {
+	// or there is a background gradient it is necessary to clear the screen.
+	bgGraphic := r.env.backgroundGraphic()
+	if len(r.grmatsOpaque) > 0 || len(r.grmatsTransp) > 0 || r.prevStats.Graphics > 0 || bgGraphic != nil {
 		// If the 3D scene to draw is to be confined to user specified panel
 		// sets scissor to avoid erasing gui elements outside of this panel
 
@@ -403,11 +453,15 @@ func (r *Renderer) renderScene(iscene core.INode, icam camera.ICamera) error {
 		}
 
 		// Clears the area inside the current scissor
+		bg := r.env.BackgroundColor()
+		r.gs.ClearColor(bg.R, bg.G, bg.B, 1)
 		r.gs.Clear(gls.DEPTH_BUFFER_BIT | gls.STENCIL_BUFFER_BIT | gls.COLOR_BUFFER_BIT)
 		r.rendered = true
 	}
 
 	err := error(nil)
+	outlinePass := false
+	r.outlined = r.outlined[:0]
 
 	// Internal function to render a list of graphic materials
 	var renderGraphicMaterials func(grmats []*graphic.GraphicMaterial)
@@ -419,11 +473,17 @@ func (r *Renderer) renderScene(iscene core.INode, icam camera.ICamera) error {
 			geom := grmat.IGraphic().GetGeometry()
 			gr := grmat.IGraphic().GetGraphic()
 
+			// Geometries whose defines depend on the OpenGL context, such as morph geometries, set them up
+			if sd, ok := gr.IGeometry().(interface{ SetupDefines(gs *gls.GLS) }); ok {
+				sd.SetupDefines(r.gs)
+			}
+
 			// Add defines from material and geometry
 			r.specs.Defines = *gls.NewShaderDefines()
 			r.specs.Defines.Add(&mat.ShaderDefines)
 			r.specs.Defines.Add(&geom.ShaderDefines)
 			r.specs.Defines.Add(&gr.ShaderDefines)
+			r.specs.Defines.Add(r.env.ShaderDefines())
 
 			// Sets the shader specs for this material and sets shader program
 			r.specs.Name = mat.Shader()
@@ -438,6 +498,9 @@ func (r *Renderer) renderScene(iscene core.INode, icam camera.ICamera) error {
 				return
 			}
 
+			// Transfer fog uniforms
+			r.env.RenderSetup(r.gs)
+
 			// Setup lights (transfer lights' uniforms)
 			for idx, l := range r.ambLights {
 				l.RenderSetup(r.gs, &r.rinfo, idx)
@@ -457,20 +520,81 @@ func (r *Renderer) renderScene(iscene core.INode, icam camera.ICamera) error {
 			}
 
 			// Render this graphic material
-			grmat.Render(r.gs, &r.rinfo)
+			if gr.Outlined() && !outlinePass {
+				// Marks the graphic pixels in the stencil buffer
+				r.gs.Enable(gls.STENCIL_TEST)
+				r.gs.StencilFunc(gls.ALWAYS, 1, 0xFF)
+				r.gs.StencilOp(gls.KEEP, gls.KEEP, gls.REPLACE)
+				r.gs.StencilMask(0xFF)
+				grmat.Render(r.gs, &r.rinfo)
+				r.gs.Disable(gls.STENCIL_TEST)
+				r.addOutlined(gr)
+			} else {
+				grmat.Render(r.gs, &r.rinfo)
+			}
 			r.stats.Graphics++
 		}
 	}
 
+	// Internal function to render the outlines of the outlined graphics rendered
+	// since the last call, where the stencil buffer was not marked by the graphics.
+	renderOutlines := func() {
+		if len(r.outlined) == 0 {
+			return
+		}
+		r.grmatsOutl = r.grmatsOutl[:0]
+		for _, gr := range r.outlined {
+			omats := gr.OutlineMaterials()
+			for i := range omats {
+				r.grmatsOutl = append(r.grmatsOutl, &omats[i])
+			}
+		}
+		r.gs.Enable(gls.STENCIL_TEST)
+		r.gs.StencilFunc(gls.NOTEQUAL, 1, 0xFF)
+		r.gs.StencilMask(0)
+		outlinePass = true
+		renderGraphicMaterials(r.grmatsOutl)
+		outlinePass = false
+		r.gs.StencilMask(0xFF)
+		r.gs.Disable(gls.STENCIL_TEST)
+		r.outlined = r.outlined[:0]
+	}
+
+	// Render background gradient before the scene objects
+	if bgGraphic != nil {
+		renderGraphicMaterials([]*graphic.GraphicMaterial{bgGraphic})
+		if err != nil {
+			return err
+		}
+	}
 	renderGraphicMaterials(r.grmatsOpaque) // Render opaque objects (front to back)
 	if err != nil {
 		return err
 	}
+	renderOutlines() // Render outlines of opaque objects
+	if err != nil {
+		return err
+	}
 	renderGraphicMaterials(r.grmatsTransp) // Render transparent objects (back to front)
+	if err != nil {
+		return err
+	}
+	renderOutlines() // Render outlines of transparent objects
 
 	return err
 }
 
+// addOutlined adds the specified graphic to the list of graphics whose outlines must be drawn.
+func (r *Renderer) addOutlined(gr *graphic.Graphic) {
+
+	for _, g := range r.outlined {
+		if g == gr {
+			return
+		}
+	}
+	r.outlined = append(r.outlined, gr)
+}
+
 // renderGui renders the Gui
 
 // ---phone GUI TODO----
@@ -586,17 +710,20 @@ func (r *Renderer) renderPanel(ipan gui.IPanel) error {
 	}
 	// If panel is renderable, renders it
 	if pan.Renderable() {
-		// Sets shader program for the panel's material
-		grmat := pan.GetGraphic().Materials()[0]
-		mat := grmat.IMaterial().GetMaterial()
-		r.specs.Name = mat.Shader()
-		r.specs.ShaderUnique = mat.ShaderUnique()
-		_, err := r.shaman.SetProgram(&r.specs)
-		if err != nil {
-			return err
+		// Panels such as labels may have additional materials after the panel material
+		grmats := pan.GetGraphic().Materials()
+		for i := range grmats {
+			// Sets shader program for the panel's material
+			mat := grmats[i].IMaterial().GetMaterial()
+			r.specs.Name = mat.Shader()
+			r.specs.ShaderUnique = mat.ShaderUnique()
+			_, err := r.shaman.SetProgram(&r.specs)
+			if err != nil {
+				return err
+			}
+			// Render this panel's graphic material
+			grmats[i].Render(r.gs, &r.rinfo)
 		}
-		// Render this panel's graphic material
-		grmat.Render(r.gs, &r.rinfo)
 		r.stats.Panels++
 	}
 	pan.SetChanged(false)
@@ -0,0 +1,17 @@
+//
+// Fragment shader for the scene background gradient
+//
+
+precision highp float;
+
+// Gradient colors
+uniform vec3 BackgroundTop;
+uniform vec3 BackgroundBottom;
+
+// Inputs from vertex shader
+varying float Height;
+
+void main() {
+
+    gl_FragColor = vec4(mix(BackgroundBottom, BackgroundTop, Height), 1.0);
+}
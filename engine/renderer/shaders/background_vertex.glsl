@@ -0,0 +1,17 @@
+//
+// Vertex shader for the scene background gradient
+//
+
+precision highp float;
+
+#include <attributes>
+
+// Outputs for fragment shader
+varying float Height;
+
+void main() {
+
+    // Vertex positions are already in clip coordinates
+    Height = VertexPosition.y * 0.5 + 0.5;
+    gl_Position = vec4(VertexPosition.xy, 1.0, 1.0);
+}
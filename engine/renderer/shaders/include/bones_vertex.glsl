@@ -1,5 +1,37 @@
 #ifdef BONE_INFLUENCERS
     #if BONE_INFLUENCERS > 0
+    #ifdef BONE_DQS
+
+        // Blends the dual quaternions in the hemisphere of the first one
+        int bone0 = 2 * int(matricesIndices[0]);
+        vec4 real0 = mBones[bone0];
+        vec4 blendReal = real0 * matricesWeights[0];
+        vec4 blendDual = mBones[bone0 + 1] * matricesWeights[0];
+        for (int i = 1; i < BONE_INFLUENCERS; i++) {
+            int bone = 2 * int(matricesIndices[i]);
+            vec4 real = mBones[bone];
+            float weight = matricesWeights[i];
+            if (dot(real, real0) < 0.0) {
+                weight = -weight;
+            }
+            blendReal += real * weight;
+            blendDual += mBones[bone + 1] * weight;
+        }
+        float len = length(blendReal);
+        blendReal /= len;
+        blendDual /= len;
+
+        // Converts the rotation and translation of the dual quaternion to a matrix
+        vec3 r = blendReal.xyz;
+        float w = blendReal.w;
+        vec3 t = 2.0 * (w * blendDual.xyz - blendDual.w * r + cross(r, blendDual.xyz));
+        mat4 influence = mat4(
+            1.0 - 2.0 * (r.y * r.y + r.z * r.z), 2.0 * (r.x * r.y + w * r.z), 2.0 * (r.x * r.z - w * r.y), 0.0,
+            2.0 * (r.x * r.y - w * r.z), 1.0 - 2.0 * (r.x * r.x + r.z * r.z), 2.0 * (r.y * r.z + w * r.x), 0.0,
+            2.0 * (r.x * r.z + w * r.y), 2.0 * (r.y * r.z - w * r.x), 1.0 - 2.0 * (r.x * r.x + r.y * r.y), 0.0,
+            t, 1.0);
+
+    #else
 
         mat4 influence = mBones[int(matricesIndices[0])] * matricesWeights[0];
         #if BONE_INFLUENCERS > 1
@@ -24,6 +56,7 @@
             #endif
         #endif
 
+    #endif
         finalWorld = finalWorld * influence;
 
     #endif
@@ -1,6 +1,11 @@
 #ifdef BONE_INFLUENCERS
     #if BONE_INFLUENCERS > 0
+    #ifdef BONE_DQS
+    // Real and dual parts of the dual quaternion of each bone
+	uniform vec4 mBones[2*TOTAL_BONES];
+    #else
 	uniform mat4 mBones[TOTAL_BONES];
+    #endif
     attribute vec4 matricesIndices;
     attribute vec4 matricesWeights;
 //    #if BONE_INFLUENCERS > 4
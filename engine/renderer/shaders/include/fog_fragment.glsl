@@ -0,0 +1,25 @@
+//
+// Fog fragment shader declarations
+//
+// FOG_FRAGMENT(color) mixes the specified fragment color with the fog color
+// according to the fragment depth and the fog mode.
+//
+#ifdef FOG
+    uniform vec3 FogColor;
+    uniform vec3 FogParams;
+    varying float FogDepth;
+    // Macros to access elements inside the FogParams uniform
+    #define FogNear     FogParams.x
+    #define FogFar      FogParams.y
+    #define FogDensity  FogParams.z
+    #if defined(FOG_EXP2)
+        #define FOG_FACTOR (1.0 - exp(-FogDensity * FogDensity * FogDepth * FogDepth))
+    #elif defined(FOG_EXP)
+        #define FOG_FACTOR (1.0 - exp(-FogDensity * FogDepth))
+    #else
+        #define FOG_FACTOR ((FogDepth - FogNear) / (FogFar - FogNear))
+    #endif
+    #define FOG_FRAGMENT(color) color.rgb = mix(color.rgb, FogColor, clamp(FOG_FACTOR, 0.0, 1.0));
+#else
+    #define FOG_FRAGMENT(color)
+#endif
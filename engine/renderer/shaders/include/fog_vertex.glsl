@@ -0,0 +1,13 @@
+//
+// Fog vertex shader declarations
+//
+// The renderer defines FOG and one of FOG_LINEAR, FOG_EXP or FOG_EXP2
+// when the scene environment fog is enabled.
+// FOG_VERTEX(viewPosition) must be called with the vertex position in camera coordinates.
+//
+#ifdef FOG
+    varying float FogDepth;
+    #define FOG_VERTEX(viewPosition) FogDepth = -(viewPosition).z;
+#else
+    #define FOG_VERTEX(viewPosition)
+#endif
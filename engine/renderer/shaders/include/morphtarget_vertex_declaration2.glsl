@@ -1,4 +1,4 @@
-	varying vec3 MorphPosition{i};
+	attribute vec3 MorphPosition{i};
   #ifdef MORPHTARGETS_NORMAL
-	varying vec3 MorphNormal{i};
+	attribute vec3 MorphNormal{i};
   #endif
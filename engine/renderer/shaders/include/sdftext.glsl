@@ -0,0 +1,46 @@
+//
+// Signed distance field text declarations
+//
+// SdfTextColor(texcoord) returns the color of the text fragment with the specified
+// atlas texture coordinates including the optional outline and drop shadow.
+//
+
+// Texture with the distance fields in the alpha channel
+uniform sampler2D MatTexture;
+
+// Text uniforms
+uniform vec4 TextColor;
+uniform vec4 TextOutlineColor;
+uniform vec4 TextShadowColor;
+uniform vec4 TextParams;
+#define Smoothing       TextParams.x      // edge smoothing in distance units
+#define OutlineWidth    TextParams.y      // outline width in distance units
+#define ShadowOffset    TextParams.zw     // shadow offset in texture coordinates
+
+vec4 SdfTextColor(vec2 texcoord) {
+
+    // Glyph fill
+    float dist = texture2D(MatTexture, texcoord).a;
+    float alpha = smoothstep(0.5 - Smoothing, 0.5 + Smoothing, dist);
+    vec4 color = vec4(TextColor.rgb, TextColor.a * alpha);
+
+    // Outline around the glyph fill
+    float edge = 0.5 - OutlineWidth;
+    if (OutlineWidth > 0.0) {
+        float oalpha = smoothstep(edge - Smoothing, edge + Smoothing, dist) * TextOutlineColor.a;
+        color.rgb = mix(TextOutlineColor.rgb, TextColor.rgb, alpha);
+        color.a = mix(oalpha, TextColor.a, alpha);
+    }
+
+    // Drop shadow under the glyph and its outline
+    if (TextShadowColor.a > 0.0) {
+        float sdist = texture2D(MatTexture, texcoord - ShadowOffset).a;
+        float salpha = smoothstep(edge - 2.0 * Smoothing, edge + 2.0 * Smoothing, sdist) * TextShadowColor.a;
+        float a = color.a + salpha * (1.0 - color.a);
+        if (a > 0.0) {
+            color.rgb = (color.rgb * color.a + TextShadowColor.rgb * salpha * (1.0 - color.a)) / a;
+        }
+        color.a = a;
+    }
+    return color;
+}
@@ -0,0 +1,12 @@
+//
+// Fragment shader for graphic outlines
+//
+
+precision highp float;
+
+uniform vec4 OutlineColor;
+
+void main() {
+
+    gl_FragColor = OutlineColor;
+}
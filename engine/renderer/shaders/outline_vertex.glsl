@@ -0,0 +1,35 @@
+//
+// Vertex shader for graphic outlines
+//
+
+precision highp float;
+#include <attributes>
+
+// Model uniforms
+uniform mat4 MVP;
+
+// Outline uniforms
+uniform float OutlineWidth;
+uniform vec2 OutlineViewport;
+
+#include <morphtarget_vertex_declaration>
+#include <bones_vertex_declaration>
+
+void main() {
+
+    vec3 vPosition = VertexPosition;
+    vec3 vNormal = VertexNormal;
+    mat4 finalWorld = mat4(1.0);
+    #include <morphtarget_vertex>
+    #include <bones_vertex>
+
+    // Extrudes the vertex along its normal projected in the screen
+    // so the outline has the same width in pixels at any distance.
+    vec4 position = MVP * finalWorld * vec4(vPosition, 1.0);
+    vec2 dir = (MVP * finalWorld * vec4(vNormal, 0.0)).xy;
+    if (length(dir) > 0.0) {
+        dir = normalize(dir);
+    }
+    position.xy += dir * OutlineWidth * 2.0 / OutlineViewport * position.w;
+    gl_Position = position;
+}
@@ -21,6 +21,7 @@ varying vec2 FragTexcoord;
 #include <lights>
 #include <material>
 #include <phong_model>
+#include <fog_fragment>
 
 // Final fragment color
 // out vec4 FragColor;
@@ -58,5 +59,6 @@ void main() {
     // Final fragment color
     // FragColor = min(vec4(Ambdiff + Spec, matDiffuse.a), vec4(1.0));
     gl_FragColor = min(vec4(Ambdiff + Spec, matDiffuse.a), vec4(1.0));
+    FOG_FRAGMENT(gl_FragColor)
 }
 
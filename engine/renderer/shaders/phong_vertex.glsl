@@ -15,6 +15,7 @@ uniform mat4 MVP;
 #include <material>
 #include <morphtarget_vertex_declaration>
 #include <bones_vertex_declaration>
+#include <fog_vertex>
 
 // Output variables for Fragment shader
 // out vec4 Position;
@@ -28,11 +29,18 @@ varying vec2 FragTexcoord;
 
 void main() {
 
+    // Applies the morph targets and the bones to the vertex position and normal
+    vec3 vPosition = VertexPosition;
+    vec3 vNormal = VertexNormal;
+    mat4 finalWorld = mat4(1.0);
+    #include <morphtarget_vertex>
+    #include <bones_vertex>
+
     // Transform this vertex position to camera coordinates.
-    Position = ModelViewMatrix * vec4(VertexPosition, 1.0);
+    Position = ModelViewMatrix * finalWorld * vec4(vPosition, 1.0);
 
     // Transform this vertex normal to camera coordinates.
-    Normal = normalize(NormalMatrix * VertexNormal);
+    Normal = normalize(NormalMatrix * vec3(finalWorld * vec4(vNormal, 0.0)));
     // Normal = normalize(VertexNormal);
     // Normal = VertexNormal;
     // Normal = normalize(VertexPosition);
@@ -49,11 +57,8 @@ void main() {
     }
 #endif
     FragTexcoord = texcoord;
-    vec3 vPosition = VertexPosition;
-    mat4 finalWorld = mat4(1.0);
-    #include <morphtarget_vertex>
-    #include <bones_vertex>
 
     gl_Position = MVP * finalWorld * vec4(vPosition, 1.0);
+    FOG_VERTEX(Position)
 }
 
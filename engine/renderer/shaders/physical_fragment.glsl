@@ -55,6 +55,7 @@ uniform vec4 Material[3];
 #define uRoughnessFactor    Material[2].y
 
 #include <lights>
+#include <fog_fragment>
 
 // Inputs from vertex shader
 varying vec3 Position;       // Vertex position in camera coordinates.
@@ -438,6 +439,7 @@ void main() {
     // gl_FragColor = vec4(vec3(baseColor1), 1.0);
     // gl_FragColor = uBaseColor;
     gl_FragColor = vec4(pow(color,vec3(1.0/2.2)), baseColor.a);
+    FOG_FRAGMENT(gl_FragColor)
 }
 
 
@@ -17,6 +17,7 @@ uniform mat4 MVP;
 
 #include <morphtarget_vertex_declaration>
 #include <bones_vertex_declaration>
+#include <fog_vertex>
 
 // Output variables for Fragment shader
 // out vec3 Position;
@@ -33,11 +34,19 @@ varying vec2 FragTexcoord;
 
 void main() {
 
+    // Applies the morph targets and the bones to the vertex position and normal
+    vec3 vPosition = VertexPosition;
+    vec3 vNormal = VertexNormal;
+    mat4 finalWorld = mat4(1.0);
+    #include <morphtarget_vertex>
+    #include <bones_vertex>
+
     // Transform this vertex position to camera coordinates.
-    Position = vec3(ModelViewMatrix * vec4(VertexPosition, 1.0));
+    vec4 viewPosition = ModelViewMatrix * finalWorld * vec4(vPosition, 1.0);
+    Position = vec3(viewPosition);
 
     // Transform this vertex normal to camera coordinates.
-    Normal = normalize(NormalMatrix * VertexNormal);
+    Normal = normalize(NormalMatrix * vec3(finalWorld * vec4(vNormal, 0.0)));
 
     // Calculate the direction vector from the vertex to the camera
     // The camera is at 0,0,0
@@ -52,12 +61,8 @@ void main() {
     // #endif
     FragTexcoord = texcoord;
 
-    vec3 vPosition = VertexPosition;
-    mat4 finalWorld = mat4(1.0);
-    #include <morphtarget_vertex>
-    #include <bones_vertex>
-
     gl_Position = MVP * finalWorld * vec4(vPosition, 1.0);
+    FOG_VERTEX(viewPosition)
     // gl_Position = MVP * vec4(vPosition, 1.0);
     // gl_Position = vec4(vPosition, 1.0);
 
@@ -1,6 +1,7 @@
 
 precision highp float;
 #include <material>
+#include <fog_fragment>
 
 // GLSL 3.30 does not allow indexing texture sampler with non constant values.
 // This macro is used to mix the texture with the specified index with the material color.
@@ -40,6 +41,7 @@ void main() {
 
     // Generates final color
     // FragColor = min(vec4(Color, MatOpacity) * texMixed, vec4(1));
-    gl_Position = min(vec4(Color, MatOpacity) * texMixed, vec4(1));
+    gl_FragColor = min(vec4(Color, MatOpacity) * texMixed, vec4(1));
+    FOG_FRAGMENT(gl_FragColor)
 }
 
@@ -3,10 +3,12 @@ precision highp float;
 #include <attributes>
 
 // Model uniforms
+uniform mat4 ModelViewMatrix;
 uniform mat4 MVP;
 
 // Material uniforms
 #include <material>
+#include <fog_vertex>
 
 // Outputs for fragment shader
 varying vec3 Color;
@@ -22,6 +24,7 @@ void main() {
     // Sets the vertex position
     vec4 pos = MVP * vec4(VertexPosition, 1.0);
     gl_Position = pos;
+    FOG_VERTEX(ModelViewMatrix * vec4(VertexPosition, 1.0))
 
     // Sets the size of the rasterized point decreasing with distance
     gl_PointSize = (1.0 - pos.z / pos.w) * MatPointSize;
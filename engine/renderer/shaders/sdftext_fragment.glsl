@@ -0,0 +1,28 @@
+//
+// Fragment shader for signed distance field text in panels
+//
+#ifdef GL_ES
+precision highp float;
+#endif
+
+#include <sdftext>
+
+// Panel uniform (only the bounds are used)
+uniform vec4 Panel[8];
+#define Bounds          Panel[0]
+
+// Inputs from vertex shader
+varying vec2 FragTexcoord;
+varying vec2 PanelTexcoord;
+
+void main() {
+
+    // Discard fragment outside of the panel bounds
+    if (PanelTexcoord.x <= Bounds[0] || PanelTexcoord.x >= Bounds[2]) {
+        discard;
+    }
+    if (PanelTexcoord.y <= Bounds[1] || PanelTexcoord.y >= Bounds[3]) {
+        discard;
+    }
+    gl_FragColor = SdfTextColor(FragTexcoord);
+}
@@ -0,0 +1,26 @@
+//
+// Vertex shader for signed distance field text in panels
+//
+#ifdef GL_ES
+precision highp float;
+#endif
+
+#include <attributes>
+
+// Model uniforms
+uniform mat4 ModelMatrix;
+
+// Outputs for fragment shader
+varying vec2 FragTexcoord;
+varying vec2 PanelTexcoord;
+
+void main() {
+
+    // Glyph texture coordinates are already in the atlas image space
+    FragTexcoord = VertexTexcoord;
+
+    // Position in the panel texture coordinates used to check the panel bounds
+    PanelTexcoord = vec2(VertexPosition.x, -VertexPosition.y);
+
+    gl_Position = ModelMatrix * vec4(VertexPosition.xyz, 1);
+}
@@ -122,7 +122,12 @@ void phongModel(vec4 position, vec3 normal, vec3 camDir, vec3 matAmbient, vec3 m
 
 const include_bones_vertex_declaration_source = `#ifdef BONE_INFLUENCERS
     #if BONE_INFLUENCERS > 0
+    #ifdef BONE_DQS
+    // Real and dual parts of the dual quaternion of each bone
+	uniform vec4 mBones[2*TOTAL_BONES];
+    #else
 	uniform mat4 mBones[TOTAL_BONES];
+    #endif
     attribute vec4 matricesIndices;
     attribute vec4 matricesWeights;
 //    #if BONE_INFLUENCERS > 4
@@ -140,14 +145,46 @@ const include_morphtarget_vertex_source = `#ifdef MORPHTARGETS
 #endif
 `
 
-const include_morphtarget_vertex_declaration2_source = `	varying vec3 MorphPosition{i};
+const include_morphtarget_vertex_declaration2_source = `	attribute vec3 MorphPosition{i};
   #ifdef MORPHTARGETS_NORMAL
-	varying vec3 MorphNormal{i};
+	attribute vec3 MorphNormal{i};
   #endif
 `
 
 const include_bones_vertex_source = `#ifdef BONE_INFLUENCERS
     #if BONE_INFLUENCERS > 0
+    #ifdef BONE_DQS
+
+        // Blends the dual quaternions in the hemisphere of the first one
+        int bone0 = 2 * int(matricesIndices[0]);
+        vec4 real0 = mBones[bone0];
+        vec4 blendReal = real0 * matricesWeights[0];
+        vec4 blendDual = mBones[bone0 + 1] * matricesWeights[0];
+        for (int i = 1; i < BONE_INFLUENCERS; i++) {
+            int bone = 2 * int(matricesIndices[i]);
+            vec4 real = mBones[bone];
+            float weight = matricesWeights[i];
+            if (dot(real, real0) < 0.0) {
+                weight = -weight;
+            }
+            blendReal += real * weight;
+            blendDual += mBones[bone + 1] * weight;
+        }
+        float len = length(blendReal);
+        blendReal /= len;
+        blendDual /= len;
+
+        // Converts the rotation and translation of the dual quaternion to a matrix
+        vec3 r = blendReal.xyz;
+        float w = blendReal.w;
+        vec3 t = 2.0 * (w * blendDual.xyz - blendDual.w * r + cross(r, blendDual.xyz));
+        mat4 influence = mat4(
+            1.0 - 2.0 * (r.y * r.y + r.z * r.z), 2.0 * (r.x * r.y + w * r.z), 2.0 * (r.x * r.z - w * r.y), 0.0,
+            2.0 * (r.x * r.y - w * r.z), 1.0 - 2.0 * (r.x * r.x + r.z * r.z), 2.0 * (r.y * r.z + w * r.x), 0.0,
+            2.0 * (r.x * r.z + w * r.y), 2.0 * (r.y * r.z - w * r.x), 1.0 - 2.0 * (r.x * r.x + r.y * r.y), 0.0,
+            t, 1.0);
+
+    #else
 
         mat4 influence = mBones[int(matricesIndices[0])] * matricesWeights[0];
         #if BONE_INFLUENCERS > 1
@@ -172,6 +209,7 @@ const include_bones_vertex_source = `#ifdef BONE_INFLUENCERS
             #endif
         #endif
 
+    #endif
         finalWorld = finalWorld * influence;
 
     #endif
@@ -305,6 +343,96 @@ const include_morphtarget_vertex2_source = `	vPosition += MorphPosition{i} * mor
 	vNormal += MorphNormal{i} * morphTargetInfluences[{i}];
   #endif`
 
+const include_fog_fragment_source = `//
+// Fog fragment shader declarations
+//
+// FOG_FRAGMENT(color) mixes the specified fragment color with the fog color
+// according to the fragment depth and the fog mode.
+//
+#ifdef FOG
+    uniform vec3 FogColor;
+    uniform vec3 FogParams;
+    varying float FogDepth;
+    // Macros to access elements inside the FogParams uniform
+    #define FogNear     FogParams.x
+    #define FogFar      FogParams.y
+    #define FogDensity  FogParams.z
+    #if defined(FOG_EXP2)
+        #define FOG_FACTOR (1.0 - exp(-FogDensity * FogDensity * FogDepth * FogDepth))
+    #elif defined(FOG_EXP)
+        #define FOG_FACTOR (1.0 - exp(-FogDensity * FogDepth))
+    #else
+        #define FOG_FACTOR ((FogDepth - FogNear) / (FogFar - FogNear))
+    #endif
+    #define FOG_FRAGMENT(color) color.rgb = mix(color.rgb, FogColor, clamp(FOG_FACTOR, 0.0, 1.0));
+#else
+    #define FOG_FRAGMENT(color)
+#endif
+`
+
+const include_fog_vertex_source = `//
+// Fog vertex shader declarations
+//
+// The renderer defines FOG and one of FOG_LINEAR, FOG_EXP or FOG_EXP2
+// when the scene environment fog is enabled.
+// FOG_VERTEX(viewPosition) must be called with the vertex position in camera coordinates.
+//
+#ifdef FOG
+    varying float FogDepth;
+    #define FOG_VERTEX(viewPosition) FogDepth = -(viewPosition).z;
+#else
+    #define FOG_VERTEX(viewPosition)
+#endif
+`
+
+const include_sdftext_source = `//
+// Signed distance field text declarations
+//
+// SdfTextColor(texcoord) returns the color of the text fragment with the specified
+// atlas texture coordinates including the optional outline and drop shadow.
+//
+
+// Texture with the distance fields in the alpha channel
+uniform sampler2D MatTexture;
+
+// Text uniforms
+uniform vec4 TextColor;
+uniform vec4 TextOutlineColor;
+uniform vec4 TextShadowColor;
+uniform vec4 TextParams;
+#define Smoothing       TextParams.x      // edge smoothing in distance units
+#define OutlineWidth    TextParams.y      // outline width in distance units
+#define ShadowOffset    TextParams.zw     // shadow offset in texture coordinates
+
+vec4 SdfTextColor(vec2 texcoord) {
+
+    // Glyph fill
+    float dist = texture2D(MatTexture, texcoord).a;
+    float alpha = smoothstep(0.5 - Smoothing, 0.5 + Smoothing, dist);
+    vec4 color = vec4(TextColor.rgb, TextColor.a * alpha);
+
+    // Outline around the glyph fill
+    float edge = 0.5 - OutlineWidth;
+    if (OutlineWidth > 0.0) {
+        float oalpha = smoothstep(edge - Smoothing, edge + Smoothing, dist) * TextOutlineColor.a;
+        color.rgb = mix(TextOutlineColor.rgb, TextColor.rgb, alpha);
+        color.a = mix(oalpha, TextColor.a, alpha);
+    }
+
+    // Drop shadow under the glyph and its outline
+    if (TextShadowColor.a > 0.0) {
+        float sdist = texture2D(MatTexture, texcoord - ShadowOffset).a;
+        float salpha = smoothstep(edge - 2.0 * Smoothing, edge + 2.0 * Smoothing, sdist) * TextShadowColor.a;
+        float a = color.a + salpha * (1.0 - color.a);
+        if (a > 0.0) {
+            color.rgb = (color.rgb * color.a + TextShadowColor.rgb * salpha * (1.0 - color.a)) / a;
+        }
+        color.a = a;
+    }
+    return color;
+}
+`
+
 const sprite_vertex_source = `//
 // Vertex shader for sprites
 //
@@ -314,9 +442,11 @@ precision highp float;
 #include <attributes>
 
 // Input uniforms
+uniform mat4 ModelViewMatrix;
 uniform mat4 MVP;
 
 #include <material>
+#include <fog_vertex>
 
 // Outputs for fragment shader
 varying vec3 Color;
@@ -326,6 +456,7 @@ void main() {
 
     // Applies transformation to vertex position
     gl_Position = MVP * vec4(VertexPosition, 1.0);
+    FOG_VERTEX(ModelViewMatrix * vec4(VertexPosition, 1.0))
 
     // Outputs color
     Color = MatDiffuseColor;
@@ -361,6 +492,7 @@ uniform mat4 MVP;
 
 #include <morphtarget_vertex_declaration>
 #include <bones_vertex_declaration>
+#include <fog_vertex>
 
 // Output variables for Fragment shader
 // out vec3 Position;
@@ -377,11 +509,19 @@ varying vec2 FragTexcoord;
 
 void main() {
 
+    // Applies the morph targets and the bones to the vertex position and normal
+    vec3 vPosition = VertexPosition;
+    vec3 vNormal = VertexNormal;
+    mat4 finalWorld = mat4(1.0);
+    #include <morphtarget_vertex>
+    #include <bones_vertex>
+
     // Transform this vertex position to camera coordinates.
-    Position = vec3(ModelViewMatrix * vec4(VertexPosition, 1.0));
+    vec4 viewPosition = ModelViewMatrix * finalWorld * vec4(vPosition, 1.0);
+    Position = vec3(viewPosition);
 
     // Transform this vertex normal to camera coordinates.
-    Normal = normalize(NormalMatrix * VertexNormal);
+    Normal = normalize(NormalMatrix * vec3(finalWorld * vec4(vNormal, 0.0)));
 
     // Calculate the direction vector from the vertex to the camera
     // The camera is at 0,0,0
@@ -396,12 +536,8 @@ void main() {
     // #endif
     FragTexcoord = texcoord;
 
-    vec3 vPosition = VertexPosition;
-    mat4 finalWorld = mat4(1.0);
-    #include <morphtarget_vertex>
-    #include <bones_vertex>
-
     gl_Position = MVP * finalWorld * vec4(vPosition, 1.0);
+    FOG_VERTEX(viewPosition)
     // gl_Position = MVP * vec4(vPosition, 1.0);
     // gl_Position = vec4(vPosition, 1.0);
 
@@ -427,6 +563,7 @@ uniform mat4 MVP;
 #include <material>
 #include <morphtarget_vertex_declaration>
 #include <bones_vertex_declaration>
+#include <fog_vertex>
 
 // Output variables for Fragment shader
 // out vec4 Position;
@@ -440,11 +577,18 @@ varying vec2 FragTexcoord;
 
 void main() {
 
+    // Applies the morph targets and the bones to the vertex position and normal
+    vec3 vPosition = VertexPosition;
+    vec3 vNormal = VertexNormal;
+    mat4 finalWorld = mat4(1.0);
+    #include <morphtarget_vertex>
+    #include <bones_vertex>
+
     // Transform this vertex position to camera coordinates.
-    Position = ModelViewMatrix * vec4(VertexPosition, 1.0);
+    Position = ModelViewMatrix * finalWorld * vec4(vPosition, 1.0);
 
     // Transform this vertex normal to camera coordinates.
-    Normal = normalize(NormalMatrix * VertexNormal);
+    Normal = normalize(NormalMatrix * vec3(finalWorld * vec4(vNormal, 0.0)));
     // Normal = normalize(VertexNormal);
     // Normal = VertexNormal;
     // Normal = normalize(VertexPosition);
@@ -461,12 +605,9 @@ void main() {
     }
 #endif
     FragTexcoord = texcoord;
-    vec3 vPosition = VertexPosition;
-    mat4 finalWorld = mat4(1.0);
-    #include <morphtarget_vertex>
-    #include <bones_vertex>
 
     gl_Position = MVP * finalWorld * vec4(vPosition, 1.0);
+    FOG_VERTEX(Position)
 }
 
 `
@@ -505,6 +646,7 @@ void main() {
 const point_fragment_source = `
 precision highp float;
 #include <material>
+#include <fog_fragment>
 
 // GLSL 3.30 does not allow indexing texture sampler with non constant values.
 // This macro is used to mix the texture with the specified index with the material color.
@@ -544,7 +686,8 @@ void main() {
 
     // Generates final color
     // FragColor = min(vec4(Color, MatOpacity) * texMixed, vec4(1));
-    gl_Position = min(vec4(Color, MatOpacity) * texMixed, vec4(1));
+    gl_FragColor = min(vec4(Color, MatOpacity) * texMixed, vec4(1));
+    FOG_FRAGMENT(gl_FragColor)
 }
 
 `
@@ -709,10 +852,12 @@ precision highp float;
 #include <attributes>
 
 // Model uniforms
+uniform mat4 ModelViewMatrix;
 uniform mat4 MVP;
 
 // Material uniforms
 #include <material>
+#include <fog_vertex>
 
 // Outputs for fragment shader
 varying vec3 Color;
@@ -728,6 +873,7 @@ void main() {
     // Sets the vertex position
     vec4 pos = MVP * vec4(VertexPosition, 1.0);
     gl_Position = pos;
+    FOG_VERTEX(ModelViewMatrix * vec4(VertexPosition, 1.0))
 
     // Sets the size of the rasterized point decreasing with distance
     gl_PointSize = (1.0 - pos.z / pos.w) * MatPointSize;
@@ -795,6 +941,7 @@ uniform vec4 Material[3];
 #define uRoughnessFactor    Material[2].y
 
 #include <lights>
+#include <fog_fragment>
 
 // Inputs from vertex shader
 varying vec3 Position;       // Vertex position in camera coordinates.
@@ -1178,6 +1325,7 @@ void main() {
     // gl_FragColor = vec4(vec3(baseColor1), 1.0);
     // gl_FragColor = uBaseColor;
     gl_FragColor = vec4(pow(color,vec3(1.0/2.2)), baseColor.a);
+    FOG_FRAGMENT(gl_FragColor)
 }
 
 
@@ -1216,6 +1364,7 @@ const sprite_fragment_source = `//
 precision highp float;
 
 #include <material>
+#include <fog_fragment>
 
 // Inputs from vertex shader
 varying vec3 Color;
@@ -1242,6 +1391,7 @@ void main() {
     // Combine material color with texture
     // FragColor = min(vec4(Color, MatOpacity) * texCombined, vec4(1));
     gl_FragColor = min(vec4(Color, MatOpacity) * texCombined, vec4(1));
+    FOG_FRAGMENT(gl_FragColor)
 }
 
 `
@@ -1264,6 +1414,7 @@ uniform mat4 MVP;
 #include <phong_model>
 #include <morphtarget_vertex_declaration>
 #include <bones_vertex_declaration>
+#include <fog_vertex>
 
 // Outputs for the fragment shader.
 varying vec3 ColorFrontAmbdiff;
@@ -1274,11 +1425,18 @@ varying vec2 FragTexcoord;
 
 void main() {
 
+    // Applies the morph targets and the bones to the vertex position and normal
+    vec3 vPosition = VertexPosition;
+    vec3 vNormal = VertexNormal;
+    mat4 finalWorld = mat4(1.0);
+    #include <morphtarget_vertex>
+    #include <bones_vertex>
+
     // Transform this vertex normal to camera coordinates.
-    vec3 Normal = normalize(NormalMatrix * VertexNormal);
+    vec3 Normal = normalize(NormalMatrix * vec3(finalWorld * vec4(vNormal, 0.0)));
 
     // Calculate this vertex position in camera coordinates
-    vec4 Position = ModelViewMatrix * vec4(VertexPosition, 1.0);
+    vec4 Position = ModelViewMatrix * finalWorld * vec4(vPosition, 1.0);
 
     // Calculate the direction vector from the vertex to the camera
     // The camera is at 0,0,0
@@ -1297,12 +1455,9 @@ void main() {
     }
 #endif
     FragTexcoord = texcoord;
-    vec3 vPosition = VertexPosition;
-    mat4 finalWorld = mat4(1.0);
-    #include <morphtarget_vertex>
-    #include <bones_vertex>
 
     gl_Position = MVP * finalWorld * vec4(vPosition, 1.0);
+    FOG_VERTEX(Position)
 }
 
 `
@@ -1313,6 +1468,7 @@ const standard_fragment_source = `//
 
 precision highp float;
 #include <material>
+#include <fog_fragment>
 
 // Inputs from Vertex shader
 varying vec3 ColorFrontAmbdiff;
@@ -1352,6 +1508,7 @@ void main() {
     }
     // FragColor = min(colorAmbDiff * texMixed + colorSpec, vec4(1));
     gl_FragColor = min(colorAmbDiff * texMixed + colorSpec, vec4(1));
+    FOG_FRAGMENT(gl_FragColor)
     // gl_FragColor = min(texMixed, vec4(1));
 }
 
@@ -1380,6 +1537,7 @@ varying vec2 FragTexcoord;
 #include <lights>
 #include <material>
 #include <phong_model>
+#include <fog_fragment>
 
 // Final fragment color
 // out vec4 FragColor;
@@ -1417,8 +1575,772 @@ void main() {
     // Final fragment color
     // FragColor = min(vec4(Ambdiff + Spec, matDiffuse.a), vec4(1.0));
     gl_FragColor = min(vec4(Ambdiff + Spec, matDiffuse.a), vec4(1.0));
+    FOG_FRAGMENT(gl_FragColor)
+}
+
+`
+
+const spritebatch_fragment_source = `//
+// Fragment shader for sprite batches
+//
+
+precision highp float;
+
+#include <material>
+
+// Inputs from vertex shader
+varying vec4 Tint;
+varying vec2 FragTexcoord;
+
+void main() {
+
+    vec4 texColor = vec4(1.0);
+#if MAT_TEXTURES>0
+    texColor = texture2D(MatTexture[0], FragTexcoord);
+#endif
+    gl_FragColor = Tint * texColor;
+}
+
+`
+
+const spritebatch_vertex_source = `//
+// Vertex shader for sprite batches
+//
+
+precision highp float;
+
+attribute vec3 VertexPosition;
+attribute vec2 VertexTexcoord;
+attribute vec4 VertexTint;
+
+// Input uniforms
+uniform mat4 MVP;
+#ifdef PIXEL_SNAP
+uniform vec2 Viewport;
+#endif
+
+#include <material>
+
+// Outputs for fragment shader
+varying vec4 Tint;
+varying vec2 FragTexcoord;
+
+void main() {
+
+    // Applies transformation to vertex position
+    vec4 pos = MVP * vec4(VertexPosition, 1.0);
+
+#ifdef PIXEL_SNAP
+    // Snaps the vertex to the nearest pixel center of the viewport
+    vec2 pixel = (pos.xy / pos.w * 0.5 + 0.5) * Viewport;
+    pixel = floor(pixel + 0.5);
+    pos.xy = ((pixel / Viewport) * 2.0 - 1.0) * pos.w;
+#endif
+    gl_Position = pos;
+
+    // Outputs tint color
+    Tint = VertexTint;
+
+    // Flips texture coordinate Y if requested.
+    vec2 texcoord = VertexTexcoord;
+#if MAT_TEXTURES>0
+    if (MatTexFlipY(0)) {
+        texcoord.y = 1.0 - texcoord.y;
+    }
+#endif
+    FragTexcoord = texcoord;
+}
+
+`
+
+const background_fragment_source = `//
+// Fragment shader for the scene background gradient
+//
+
+precision highp float;
+
+// Gradient colors
+uniform vec3 BackgroundTop;
+uniform vec3 BackgroundBottom;
+
+// Inputs from vertex shader
+varying float Height;
+
+void main() {
+
+    gl_FragColor = vec4(mix(BackgroundBottom, BackgroundTop, Height), 1.0);
+}
+`
+
+const background_vertex_source = `//
+// Vertex shader for the scene background gradient
+//
+
+precision highp float;
+
+#include <attributes>
+
+// Outputs for fragment shader
+varying float Height;
+
+void main() {
+
+    // Vertex positions are already in clip coordinates
+    Height = VertexPosition.y * 0.5 + 0.5;
+    gl_Position = vec4(VertexPosition.xy, 1.0, 1.0);
+}
+`
+
+const outline_fragment_source = `//
+// Fragment shader for graphic outlines
+//
+
+precision highp float;
+
+uniform vec4 OutlineColor;
+
+void main() {
+
+    gl_FragColor = OutlineColor;
+}
+`
+
+const outline_vertex_source = `//
+// Vertex shader for graphic outlines
+//
+
+precision highp float;
+#include <attributes>
+
+// Model uniforms
+uniform mat4 MVP;
+
+// Outline uniforms
+uniform float OutlineWidth;
+uniform vec2 OutlineViewport;
+
+#include <morphtarget_vertex_declaration>
+#include <bones_vertex_declaration>
+
+void main() {
+
+    vec3 vPosition = VertexPosition;
+    vec3 vNormal = VertexNormal;
+    mat4 finalWorld = mat4(1.0);
+    #include <morphtarget_vertex>
+    #include <bones_vertex>
+
+    // Extrudes the vertex along its normal projected in the screen
+    // so the outline has the same width in pixels at any distance.
+    vec4 position = MVP * finalWorld * vec4(vPosition, 1.0);
+    vec2 dir = (MVP * finalWorld * vec4(vNormal, 0.0)).xy;
+    if (length(dir) > 0.0) {
+        dir = normalize(dir);
+    }
+    position.xy += dir * OutlineWidth * 2.0 / OutlineViewport * position.w;
+    gl_Position = position;
+}
+`
+
+const sdftext_fragment_source = `//
+// Fragment shader for signed distance field text in panels
+//
+#ifdef GL_ES
+precision highp float;
+#endif
+
+#include <sdftext>
+
+// Panel uniform (only the bounds are used)
+uniform vec4 Panel[8];
+#define Bounds          Panel[0]
+
+// Inputs from vertex shader
+varying vec2 FragTexcoord;
+varying vec2 PanelTexcoord;
+
+void main() {
+
+    // Discard fragment outside of the panel bounds
+    if (PanelTexcoord.x <= Bounds[0] || PanelTexcoord.x >= Bounds[2]) {
+        discard;
+    }
+    if (PanelTexcoord.y <= Bounds[1] || PanelTexcoord.y >= Bounds[3]) {
+        discard;
+    }
+    gl_FragColor = SdfTextColor(FragTexcoord);
+}
+`
+
+const sdftext_vertex_source = `//
+// Vertex shader for signed distance field text in panels
+//
+#ifdef GL_ES
+precision highp float;
+#endif
+
+#include <attributes>
+
+// Model uniforms
+uniform mat4 ModelMatrix;
+
+// Outputs for fragment shader
+varying vec2 FragTexcoord;
+varying vec2 PanelTexcoord;
+
+void main() {
+
+    // Glyph texture coordinates are already in the atlas image space
+    FragTexcoord = VertexTexcoord;
+
+    // Position in the panel texture coordinates used to check the panel bounds
+    PanelTexcoord = vec2(VertexPosition.x, -VertexPosition.y);
+
+    gl_Position = ModelMatrix * vec4(VertexPosition.xyz, 1);
+}
+`
+
+const terrain_fragment_source = `//
+// Fragment shader for terrains
+//
+// The layer textures are repeated over the terrain and blended with the weights
+// from the splat map, which is the first texture when TERRAIN_SPLAT is defined.
+//
+#ifdef GL_ES
+precision highp float;
+#endif
+
+// Inputs from vertex shader
+varying vec4 Position;
+varying vec3 Normal;
+varying vec3 CamDir;
+varying vec2 FragTexcoord;
+
+#include <lights>
+#include <material>
+#include <phong_model>
+#include <fog_fragment>
+
+// Returns the color of the layer texture with the specified index
+#define LAYER(i) texture2D(MatTexture[i], FragTexcoord * MatTexRepeat(i) + MatTexOffset(i))
+
+void main() {
+
+    // Blends the layers with the weights of the splat map
+    vec4 texMixed = vec4(1.0);
+#if defined(TERRAIN_SPLAT) && MAT_TEXTURES > 1
+    vec4 weights = texture2D(MatTexture[0], FragTexcoord);
+    texMixed = weights.r * LAYER(1);
+    float total = weights.r;
+    #if MAT_TEXTURES > 2
+        texMixed += weights.g * LAYER(2);
+        total += weights.g;
+    #endif
+    #if MAT_TEXTURES > 3
+        texMixed += weights.b * LAYER(3);
+        total += weights.b;
+    #endif
+    #if MAT_TEXTURES > 4
+        texMixed += weights.a * LAYER(4);
+        total += weights.a;
+    #endif
+    texMixed /= max(total, 1e-4);
+#elif !defined(TERRAIN_SPLAT) && MAT_TEXTURES > 0
+    texMixed = LAYER(0);
+#endif
+
+    vec4 matDiffuse = vec4(MatDiffuseColor, MatOpacity) * texMixed;
+    vec4 matAmbient = vec4(MatAmbientColor, MatOpacity) * texMixed;
+
+    vec3 fragNormal = normalize(Normal);
+    if (!gl_FrontFacing) {
+        fragNormal = -fragNormal;
+    }
+
+    vec3 Ambdiff, Spec;
+    phongModel(Position, fragNormal, CamDir, vec3(matAmbient), vec3(matDiffuse), Ambdiff, Spec);
+    gl_FragColor = min(vec4(Ambdiff + Spec, matDiffuse.a), vec4(1.0));
+    FOG_FRAGMENT(gl_FragColor)
+}
+`
+
+const terrain_vertex_source = `//
+// Vertex shader for terrains
+//
+// The vertices of the terrain chunks which are removed by the next coarser level of
+// detail are morphed to the coarser surface as they approach the level switch distance.
+//
+#ifdef GL_ES
+precision highp float;
+#endif
+
+#include <attributes>
+
+// Terrain vertex attributes
+attribute vec2 TerrainMorph;    // level of detail where the vertex is removed and height offset to the coarser surface
+
+// Model uniforms
+uniform mat4 ModelViewMatrix;
+uniform mat3 NormalMatrix;
+uniform mat4 MVP;
+
+// Terrain chunk uniforms
+uniform vec3 TerrainLod;
+#define TerrainLevel        TerrainLod.x
+#define TerrainMorphStart   TerrainLod.y
+#define TerrainMorphEnd     TerrainLod.z
+
+#include <fog_vertex>
+
+// Outputs for fragment shader
+varying vec4 Position;
+varying vec3 Normal;
+varying vec3 CamDir;
+varying vec2 FragTexcoord;
+
+void main() {
+
+    vec3 vPosition = VertexPosition;
+    if (abs(TerrainMorph.x - TerrainLevel) < 0.5) {
+        float dist = length((ModelViewMatrix * vec4(vPosition, 1.0)).xyz);
+        float morph = clamp((dist - TerrainMorphStart) / max(TerrainMorphEnd - TerrainMorphStart, 1e-6), 0.0, 1.0);
+        vPosition.y += TerrainMorph.y * morph;
+    }
+
+    Position = ModelViewMatrix * vec4(vPosition, 1.0);
+    Normal = normalize(NormalMatrix * VertexNormal);
+    CamDir = normalize(-Position.xyz);
+    FragTexcoord = VertexTexcoord;
+
+    gl_Position = MVP * vec4(vPosition, 1.0);
+    FOG_VERTEX(Position)
+}
+`
+
+const text_fragment_source = `//
+// Fragment shader for signed distance field text in the scene
+//
+#ifdef GL_ES
+precision highp float;
+#endif
+
+#include <sdftext>
+#include <fog_fragment>
+
+// Inputs from vertex shader
+varying vec2 FragTexcoord;
+
+void main() {
+
+    vec4 color = SdfTextColor(FragTexcoord);
+    if (color.a <= 0.0) {
+        discard;
+    }
+    gl_FragColor = color;
+    FOG_FRAGMENT(gl_FragColor)
+}
+`
+
+const text_vertex_source = `//
+// Vertex shader for signed distance field text in the scene
+//
+#ifdef GL_ES
+precision highp float;
+#endif
+
+#include <attributes>
+
+// Input uniforms
+uniform mat4 ModelViewMatrix;
+uniform mat4 MVP;
+
+#include <fog_vertex>
+
+// Outputs for fragment shader
+varying vec2 FragTexcoord;
+
+void main() {
+
+    // Glyph texture coordinates are already in the atlas image space
+    FragTexcoord = VertexTexcoord;
+
+    gl_Position = MVP * vec4(VertexPosition, 1.0);
+    FOG_VERTEX(ModelViewMatrix * vec4(VertexPosition, 1.0))
+}
+`
+
+const thickline_fragment_source = `//
+// Fragment shader for thick lines
+//
+#ifdef GL_ES
+precision highp float;
+#endif
+
+// Dash length, gap length and offset in the model space of the lines
+uniform vec4 LineDash;
+
+#include <fog_fragment>
+
+// Inputs from vertex shader
+varying vec4 Color;
+varying float Distance;
+
+void main() {
+
+    // Discards the fragments in the gaps between dashes
+    if (LineDash.x > 0.0 && mod(Distance + LineDash.z, LineDash.x + LineDash.y) > LineDash.x) {
+        discard;
+    }
+    gl_FragColor = Color;
+    FOG_FRAGMENT(gl_FragColor)
+}
+`
+
+const thickline_vertex_source = `//
+// Vertex shader for thick lines
+//
+// Each vertex is expanded in screen space from its position on the polyline
+// according to the directions of the adjacent segments and its kind.
+//
+#ifdef GL_ES
+precision highp float;
+#endif
+
+#include <attributes>
+
+// Line vertex attributes
+attribute vec3 LinePrev;        // previous point of the polyline (or the vertex position at the start)
+attribute vec3 LineNext;        // next point of the polyline (or the vertex position at the end)
+attribute vec4 LineVertex;      // kind, side, fraction and distance along the polyline
+
+// Model uniforms
+uniform mat4 ModelViewMatrix;
+uniform mat4 MVP;
+uniform mat4 ProjMatrix;
+
+// Line uniforms
+uniform vec4 LineColor;
+uniform vec4 LineParams;
+uniform vec2 LineViewport;
+#define LineWidth       LineParams.x
+#define LineWorldUnits  LineParams.y
+#define LineMiterLimit  LineParams.z
+
+#include <fog_vertex>
+
+// Vertex kinds
+#define KIND_BODY       0.0     // segment side
+#define KIND_JOIN       1.0     // join edge on the outer side rotated by fraction from the incoming to the outgoing segment
+#define KIND_MITER      2.0     // miter join tip on the outer side
+#define KIND_CAP        3.0     // round cap edge rotated by fraction of half turn
+#define KIND_SQUARE     4.0     // square cap corner with the corner index in fraction
+#define KIND_CENTER     5.0     // point on the polyline
+
+// Outputs for fragment shader
+varying vec4 Color;
+varying float Distance;
+
+// Returns the position in pixels of the specified clip coordinates in front of the near plane
+vec2 toScreen(vec4 clip) {
+
+    return clip.xy / max(clip.w, 1e-6) * LineViewport * 0.5;
+}
+
+// Returns the specified clip coordinates moved along the segment to q onto
+// the near plane if they are behind it and q is in front of it
+vec4 clipNear(vec4 p, vec4 q) {
+
+    float dp = p.z + p.w;
+    float dq = q.z + q.w;
+    if (dp >= 0.0 || dq <= 0.0) {
+        return p;
+    }
+    return mix(p, q, dp / (dp - dq));
+}
+
+// Returns the normalized direction or the specified default direction if too short
+vec2 direction(vec2 d, vec2 def) {
+
+    float len = length(d);
+    if (len < 1e-5) {
+        return def;
+    }
+    return d / len;
+}
+
+void main() {
+
+    float kind = LineVertex.x;
+    vec4 clip = MVP * vec4(VertexPosition, 1.0);
+    vec4 prev = MVP * vec4(LinePrev, 1.0);
+    vec4 next = MVP * vec4(LineNext, 1.0);
+
+    // Clips the segments crossing the near plane before projecting them, so they are not mirrored.
+    // Body vertices are at one of the ends of their segment, which is moved onto the near plane
+    // if it is behind it. Join and cap vertices behind the near plane are clipped with their triangles.
+    if (kind < KIND_JOIN - 0.5) {
+        vec4 a = clipNear(prev, next);
+        vec4 b = clipNear(next, prev);
+        clip = distance(VertexPosition, LinePrev) <= distance(VertexPosition, LineNext) ? a : b;
+        prev = a;
+        next = b;
+    } else {
+        prev = clipNear(prev, clip);
+        next = clipNear(next, clip);
+    }
+    vec2 p = toScreen(clip);
+    vec2 a = toScreen(prev);
+    vec2 b = toScreen(next);
+
+    // Directions and normals of the incoming and outgoing segments in screen space
+    vec2 dout = direction(b - p, direction(p - a, vec2(1.0, 0.0)));
+    vec2 din = direction(p - a, dout);
+    vec2 nin = vec2(-din.y, din.x);
+    vec2 nout = vec2(-dout.y, dout.x);
+
+    // Half line width in pixels
+    float hw = LineWidth * 0.5;
+    if (LineWorldUnits > 0.5) {
+        hw *= ProjMatrix[1][1] * LineViewport.y * 0.5 / max(abs(clip.w), 1e-6);
+    }
+
+    float side = LineVertex.y;
+    float fraction = LineVertex.z;
+    // Joins are only drawn on the outer side of the turn, as the segment bodies overlap on the inner side
+    if (kind > KIND_JOIN - 0.5 && kind < KIND_CAP - 0.5) {
+        side = din.x * dout.y - din.y * dout.x > 0.0 ? -1.0 : 1.0;
+    }
+    vec2 offset = vec2(0.0);
+    if (kind < KIND_JOIN - 0.5) {
+        offset = side * nin;
+    } else if (kind < KIND_MITER - 0.5) {
+        float a0 = atan(side * nin.y, side * nin.x);
+        float a1 = atan(side * nout.y, side * nout.x);
+        float delta = a1 - a0;
+        if (delta > 3.14159265) {
+            delta -= 6.28318531;
+        } else if (delta < -3.14159265) {
+            delta += 6.28318531;
+        }
+        float angle = a0 + delta * fraction;
+        offset = vec2(cos(angle), sin(angle));
+    } else if (kind < KIND_CAP - 0.5) {
+        vec2 sum = nin + nout;
+        float len = length(sum);
+        offset = side * sum * 0.5;
+        if (len > 1e-5) {
+            vec2 miter = sum / len;
+            float mlen = 1.0 / max(dot(miter, nin), 1e-5);
+            if (mlen <= LineMiterLimit) {
+                offset = side * miter * mlen;
+            }
+        }
+    } else if (kind < KIND_SQUARE - 0.5) {
+        float angle = fraction * 3.14159265;
+        offset = cos(angle) * nin + sin(angle) * side * din;
+    } else if (kind < KIND_CENTER - 0.5) {
+        float n = fraction < 1.5 ? 1.0 : -1.0;
+        float ext = (fraction > 0.5 && fraction < 2.5) ? 1.0 : 0.0;
+        offset = n * nin + ext * side * din;
+    }
+
+    // Offsets the position in clip coordinates
+    clip.xy += offset * hw * 2.0 / LineViewport * clip.w;
+    gl_Position = clip;
+    FOG_VERTEX(ModelViewMatrix * vec4(VertexPosition, 1.0))
+
+    Color = LineColor * vec4(VertexColor, 1.0);
+    Distance = LineVertex.w;
+}
+`
+
+const water_fragment_source = `//
+// Fragment shader for water surfaces
+//
+// The wave normal is perturbed by two samples of the normal map scrolling in different
+// directions when WATER_NORMALMAP is defined. The lit water color is blended by the
+// fresnel factor with the sky colors or, when WATER_REFLECTION is defined, with the
+// reflection map, which is sampled in screen space distorted by the normal.
+//
+#ifdef GL_ES
+precision highp float;
+#endif
+
+// Inputs from vertex shader
+varying vec4 Position;
+varying vec3 WorldNormal;
+varying vec3 CamDir;
+varying vec2 WorldXZ;
+varying float Depth;
+
+// Model uniforms
+uniform mat3 NormalMatrix;
+
+// Water uniforms
+uniform vec4 WaterParams[5];
+#define WaterTime           WaterParams[0].x
+#define WaterNormalScale    WaterParams[0].y
+#define WaterNormalStrength WaterParams[0].z
+#define WaterFresnelBias    WaterParams[0].w
+#define WaterScroll1        WaterParams[1].xy
+#define WaterScroll2        WaterParams[1].zw
+#define WaterShallowColor   WaterParams[2].rgb
+#define WaterShoreDepth     WaterParams[2].w
+#define WaterHorizonColor   WaterParams[3].rgb
+#define WaterEdgeFade       WaterParams[3].w
+#define WaterZenithColor    WaterParams[4].rgb
+#define WaterDistortion     WaterParams[4].w
+uniform vec4 WaterViewport;     // viewport position and size
+
+#ifdef WATER_NORMALMAP
+uniform sampler2D WaterNormalSampler;
+#endif
+#ifdef WATER_REFLECTION
+uniform sampler2D WaterReflectionSampler;
+#endif
+
+#include <lights>
+#include <material>
+#include <phong_model>
+#include <fog_fragment>
+
+void main() {
+
+    // Perturbs the wave normal with the scrolling normal map samples
+    vec3 worldNormal = normalize(WorldNormal);
+    vec2 detail = vec2(0.0);
+#ifdef WATER_NORMALMAP
+    vec2 uv = WorldXZ / WaterNormalScale;
+    vec3 n1 = texture2D(WaterNormalSampler, uv + WaterScroll1 * WaterTime).rgb * 2.0 - 1.0;
+    vec3 n2 = texture2D(WaterNormalSampler, uv * 1.37 + WaterScroll2 * WaterTime).rgb * 2.0 - 1.0;
+    detail = (n1.xy + n2.xy) * WaterNormalStrength;
+    worldNormal = normalize(worldNormal * max(n1.z * n2.z, 1e-2) + vec3(detail.x, 0.0, detail.y));
+#endif
+    vec3 normal = normalize(NormalMatrix * worldNormal);
+    vec3 camDir = normalize(CamDir);
+    if (!gl_FrontFacing) {
+        normal = -normal;
+    }
+
+    // Tints the water from the shallow to the deep color and fades it at the edge
+    vec3 color = MatDiffuseColor;
+    float edge = 1.0;
+#ifdef WATER_DEPTH
+    color = mix(WaterShallowColor, MatDiffuseColor, clamp(Depth / max(WaterShoreDepth, 1e-4), 0.0, 1.0));
+    if (WaterEdgeFade > 0.0) {
+        edge = clamp(Depth / WaterEdgeFade, 0.0, 1.0);
+    }
+#endif
+
+    vec3 Ambdiff, Spec;
+    phongModel(Position, normal, camDir, color, color, Ambdiff, Spec);
+
+    // Reflected color from the sky colors or the reflection map
+    vec3 up = normalize(NormalMatrix * vec3(0.0, 1.0, 0.0));
+    vec3 reflected = reflect(-camDir, normal);
+    vec3 reflColor = mix(WaterHorizonColor, WaterZenithColor, clamp(dot(reflected, up), 0.0, 1.0));
+#ifdef WATER_REFLECTION
+    vec2 screen = (gl_FragCoord.xy - WaterViewport.xy) / WaterViewport.zw;
+    screen.y = 1.0 - screen.y;
+    screen += detail * WaterDistortion;
+    reflColor = texture2D(WaterReflectionSampler, clamp(screen, 0.001, 0.999)).rgb;
+#endif
+
+    // Schlick's approximation of the fresnel reflectance
+    float cosTheta = clamp(dot(normal, camDir), 0.0, 1.0);
+    float fresnel = WaterFresnelBias + (1.0 - WaterFresnelBias) * pow(1.0 - cosTheta, 5.0);
+
+    // The reflection makes the water more opaque at grazing angles
+    float alpha = mix(MatOpacity, 1.0, fresnel) * edge;
+    gl_FragColor = min(vec4(mix(Ambdiff, reflColor, fresnel) + Spec, alpha), vec4(1.0));
+    FOG_FRAGMENT(gl_FragColor)
 }
+`
+
+const water_vertex_source = `//
+// Vertex shader for water surfaces
+//
+// The vertices are displaced by the sum of up to four Gerstner waves evaluated at their
+// world positions, so adjacent water meshes match. The water mesh must not be rotated
+// or scaled. When WATER_DEPTH is defined the vertices have the depth of the water below
+// them, which attenuates the waves near the shore.
+//
+#ifdef GL_ES
+precision highp float;
+#endif
 
+#include <attributes>
+
+#ifdef WATER_DEPTH
+// Water vertex attributes
+attribute float WaterDepth;     // depth of the floor below the vertex
+#endif
+
+// Model uniforms
+uniform mat4 ModelMatrix;
+uniform mat4 ModelViewMatrix;
+uniform mat3 NormalMatrix;
+uniform mat4 MVP;
+
+// Water uniforms
+uniform vec4 WaterWaves[4];     // direction X and Z, steepness and wavelength of each wave
+uniform vec4 WaterParams[5];
+#define WaterTime           WaterParams[0].x
+#define WaterShoreDepth     WaterParams[2].w
+
+#include <fog_vertex>
+
+// Outputs for fragment shader
+varying vec4 Position;
+varying vec3 WorldNormal;
+varying vec3 CamDir;
+varying vec2 WorldXZ;
+varying float Depth;
+
+void main() {
+
+    vec3 world = (ModelMatrix * vec4(VertexPosition, 1.0)).xyz;
+
+    // Attenuates the waves in shallow water
+    float attenuation = 1.0;
+#ifdef WATER_DEPTH
+    Depth = WaterDepth;
+    attenuation = smoothstep(0.0, max(WaterShoreDepth, 1e-4), WaterDepth);
+#else
+    Depth = 1e4;
+#endif
+
+    // Sums the displacements and the tangent and binormal derivatives of the waves
+    vec3 offset = vec3(0.0);
+    vec3 tangent = vec3(1.0, 0.0, 0.0);
+    vec3 binormal = vec3(0.0, 0.0, 1.0);
+    for (int i = 0; i < 4; i++) {
+        vec4 wave = WaterWaves[i];
+        if (wave.w <= 0.0) {
+            continue;
+        }
+        vec2 d = wave.xy;
+        float k = 6.2831853 / wave.w;
+        float c = sqrt(9.8 / k);
+        float f = k * (dot(d, world.xz) - c * WaterTime);
+        float s = wave.z * attenuation;
+        float a = s / k;
+        float sinf = sin(f);
+        float cosf = cos(f);
+        offset += vec3(d.x * a * cosf, a * sinf, d.y * a * cosf);
+        tangent += vec3(-d.x * d.x * s * sinf, d.x * s * cosf, -d.x * d.y * s * sinf);
+        binormal += vec3(-d.x * d.y * s * sinf, d.y * s * cosf, -d.y * d.y * s * sinf);
+    }
+
+    vec3 vPosition = VertexPosition + offset;
+    Position = ModelViewMatrix * vec4(vPosition, 1.0);
+    WorldNormal = normalize(cross(binormal, tangent));
+    CamDir = normalize(-Position.xyz);
+    WorldXZ = world.xz + offset.xz;
+
+    gl_Position = MVP * vec4(vPosition, 1.0);
+    FOG_VERTEX(Position)
+}
 `
 
 // Maps include name with its source code
@@ -1434,35 +2356,62 @@ var includeMap = map[string]string{
 	"material":                        include_material_source,
 	"attributes":                      include_attributes_source,
 	"morphtarget_vertex2":             include_morphtarget_vertex2_source,
+	"fog_fragment":                    include_fog_fragment_source,
+	"fog_vertex":                      include_fog_vertex_source,
+	"sdftext":                         include_sdftext_source,
 }
 
 // Maps shader name with its source code
 var shaderMap = map[string]string{
 
-	"sprite_vertex":     sprite_vertex_source,
-	"physical_vertex":   physical_vertex_source,
-	"phong_vertex":      phong_vertex_source,
-	"panel_vertex":      panel_vertex_source,
-	"point_fragment":    point_fragment_source,
-	"panel_fragment":    panel_fragment_source,
-	"basic_fragment":    basic_fragment_source,
-	"point_vertex":      point_vertex_source,
-	"physical_fragment": physical_fragment_source,
-	"basic_vertex":      basic_vertex_source,
-	"sprite_fragment":   sprite_fragment_source,
-	"standard_vertex":   standard_vertex_source,
-	"standard_fragment": standard_fragment_source,
-	"phong_fragment":    phong_fragment_source,
+	"sprite_vertex":        sprite_vertex_source,
+	"physical_vertex":      physical_vertex_source,
+	"phong_vertex":         phong_vertex_source,
+	"panel_vertex":         panel_vertex_source,
+	"point_fragment":       point_fragment_source,
+	"panel_fragment":       panel_fragment_source,
+	"basic_fragment":       basic_fragment_source,
+	"point_vertex":         point_vertex_source,
+	"physical_fragment":    physical_fragment_source,
+	"basic_vertex":         basic_vertex_source,
+	"sprite_fragment":      sprite_fragment_source,
+	"standard_vertex":      standard_vertex_source,
+	"standard_fragment":    standard_fragment_source,
+	"phong_fragment":       phong_fragment_source,
+	"spritebatch_fragment": spritebatch_fragment_source,
+	"spritebatch_vertex":   spritebatch_vertex_source,
+	"background_fragment":  background_fragment_source,
+	"background_vertex":    background_vertex_source,
+	"outline_fragment":     outline_fragment_source,
+	"outline_vertex":       outline_vertex_source,
+	"sdftext_fragment":     sdftext_fragment_source,
+	"sdftext_vertex":       sdftext_vertex_source,
+	"text_fragment":        text_fragment_source,
+	"text_vertex":          text_vertex_source,
+	"thickline_fragment":   thickline_fragment_source,
+	"thickline_vertex":     thickline_vertex_source,
+	"terrain_fragment":     terrain_fragment_source,
+	"terrain_vertex":       terrain_vertex_source,
+	"water_fragment":       water_fragment_source,
+	"water_vertex":         water_vertex_source,
 }
 
 // Maps program name with Proginfo struct with shaders names
 var programMap = map[string]ProgramInfo{
 
-	"basic":    {"basic_vertex", "basic_fragment", ""},
-	"panel":    {"panel_vertex", "panel_fragment", ""},
-	"phong":    {"phong_vertex", "phong_fragment", ""},
-	"physical": {"physical_vertex", "physical_fragment", ""},
-	"point":    {"point_vertex", "point_fragment", ""},
-	"sprite":   {"sprite_vertex", "sprite_fragment", ""},
-	"standard": {"standard_vertex", "standard_fragment", ""},
+	"basic":       {"basic_vertex", "basic_fragment", ""},
+	"panel":       {"panel_vertex", "panel_fragment", ""},
+	"phong":       {"phong_vertex", "phong_fragment", ""},
+	"physical":    {"physical_vertex", "physical_fragment", ""},
+	"point":       {"point_vertex", "point_fragment", ""},
+	"sprite":      {"sprite_vertex", "sprite_fragment", ""},
+	"standard":    {"standard_vertex", "standard_fragment", ""},
+	"spritebatch": {"spritebatch_vertex", "spritebatch_fragment", ""},
+	"background":  {"background_vertex", "background_fragment", ""},
+	"outline":     {"outline_vertex", "outline_fragment", ""},
+	"sdftext":     {"sdftext_vertex", "sdftext_fragment", ""},
+	"text":        {"text_vertex", "text_fragment", ""},
+	"thickline":   {"thickline_vertex", "thickline_fragment", ""},
+	"terrain":     {"terrain_vertex", "terrain_fragment", ""},
+	"water":       {"water_vertex", "water_fragment", ""},
 }
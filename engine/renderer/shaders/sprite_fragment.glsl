@@ -5,6 +5,7 @@
 precision highp float;
 
 #include <material>
+#include <fog_fragment>
 
 // Inputs from vertex shader
 varying vec3 Color;
@@ -31,5 +32,6 @@ void main() {
     // Combine material color with texture
     // FragColor = min(vec4(Color, MatOpacity) * texCombined, vec4(1));
     gl_FragColor = min(vec4(Color, MatOpacity) * texCombined, vec4(1));
+    FOG_FRAGMENT(gl_FragColor)
 }
 
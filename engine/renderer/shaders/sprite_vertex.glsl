@@ -7,9 +7,11 @@ precision highp float;
 #include <attributes>
 
 // Input uniforms
+uniform mat4 ModelViewMatrix;
 uniform mat4 MVP;
 
 #include <material>
+#include <fog_vertex>
 
 // Outputs for fragment shader
 varying vec3 Color;
@@ -19,6 +21,7 @@ void main() {
 
     // Applies transformation to vertex position
     gl_Position = MVP * vec4(VertexPosition, 1.0);
+    FOG_VERTEX(ModelViewMatrix * vec4(VertexPosition, 1.0))
 
     // Outputs color
     Color = MatDiffuseColor;
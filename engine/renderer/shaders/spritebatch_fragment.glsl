@@ -0,0 +1,21 @@
+//
+// Fragment shader for sprite batches
+//
+
+precision highp float;
+
+#include <material>
+
+// Inputs from vertex shader
+varying vec4 Tint;
+varying vec2 FragTexcoord;
+
+void main() {
+
+    vec4 texColor = vec4(1.0);
+#if MAT_TEXTURES>0
+    texColor = texture2D(MatTexture[0], FragTexcoord);
+#endif
+    gl_FragColor = Tint * texColor;
+}
+
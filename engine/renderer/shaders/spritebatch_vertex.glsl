@@ -0,0 +1,48 @@
+//
+// Vertex shader for sprite batches
+//
+
+precision highp float;
+
+attribute vec3 VertexPosition;
+attribute vec2 VertexTexcoord;
+attribute vec4 VertexTint;
+
+// Input uniforms
+uniform mat4 MVP;
+#ifdef PIXEL_SNAP
+uniform vec2 Viewport;
+#endif
+
+#include <material>
+
+// Outputs for fragment shader
+varying vec4 Tint;
+varying vec2 FragTexcoord;
+
+void main() {
+
+    // Applies transformation to vertex position
+    vec4 pos = MVP * vec4(VertexPosition, 1.0);
+
+#ifdef PIXEL_SNAP
+    // Snaps the vertex to the nearest pixel center of the viewport
+    vec2 pixel = (pos.xy / pos.w * 0.5 + 0.5) * Viewport;
+    pixel = floor(pixel + 0.5);
+    pos.xy = ((pixel / Viewport) * 2.0 - 1.0) * pos.w;
+#endif
+    gl_Position = pos;
+
+    // Outputs tint color
+    Tint = VertexTint;
+
+    // Flips texture coordinate Y if requested.
+    vec2 texcoord = VertexTexcoord;
+#if MAT_TEXTURES>0
+    if (MatTexFlipY(0)) {
+        texcoord.y = 1.0 - texcoord.y;
+    }
+#endif
+    FragTexcoord = texcoord;
+}
+
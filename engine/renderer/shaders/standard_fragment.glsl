@@ -4,6 +4,7 @@
 
 precision highp float;
 #include <material>
+#include <fog_fragment>
 
 // Inputs from Vertex shader
 varying vec3 ColorFrontAmbdiff;
@@ -43,6 +44,7 @@ void main() {
     }
     // FragColor = min(colorAmbDiff * texMixed + colorSpec, vec4(1));
     gl_FragColor = min(colorAmbDiff * texMixed + colorSpec, vec4(1));
+    FOG_FRAGMENT(gl_FragColor)
     // gl_FragColor = min(texMixed, vec4(1));
 }
 
@@ -16,6 +16,7 @@ uniform mat4 MVP;
 #include <phong_model>
 #include <morphtarget_vertex_declaration>
 #include <bones_vertex_declaration>
+#include <fog_vertex>
 
 // Outputs for the fragment shader.
 varying vec3 ColorFrontAmbdiff;
@@ -26,11 +27,18 @@ varying vec2 FragTexcoord;
 
 void main() {
 
+    // Applies the morph targets and the bones to the vertex position and normal
+    vec3 vPosition = VertexPosition;
+    vec3 vNormal = VertexNormal;
+    mat4 finalWorld = mat4(1.0);
+    #include <morphtarget_vertex>
+    #include <bones_vertex>
+
     // Transform this vertex normal to camera coordinates.
-    vec3 Normal = normalize(NormalMatrix * VertexNormal);
+    vec3 Normal = normalize(NormalMatrix * vec3(finalWorld * vec4(vNormal, 0.0)));
 
     // Calculate this vertex position in camera coordinates
-    vec4 Position = ModelViewMatrix * vec4(VertexPosition, 1.0);
+    vec4 Position = ModelViewMatrix * finalWorld * vec4(vPosition, 1.0);
 
     // Calculate the direction vector from the vertex to the camera
     // The camera is at 0,0,0
@@ -49,11 +57,8 @@ void main() {
     }
 #endif
     FragTexcoord = texcoord;
-    vec3 vPosition = VertexPosition;
-    mat4 finalWorld = mat4(1.0);
-    #include <morphtarget_vertex>
-    #include <bones_vertex>
 
     gl_Position = MVP * finalWorld * vec4(vPosition, 1.0);
+    FOG_VERTEX(Position)
 }
 
@@ -0,0 +1,62 @@
+//
+// Fragment shader for terrains
+//
+// The layer textures are repeated over the terrain and blended with the weights
+// from the splat map, which is the first texture when TERRAIN_SPLAT is defined.
+//
+#ifdef GL_ES
+precision highp float;
+#endif
+
+// Inputs from vertex shader
+varying vec4 Position;
+varying vec3 Normal;
+varying vec3 CamDir;
+varying vec2 FragTexcoord;
+
+#include <lights>
+#include <material>
+#include <phong_model>
+#include <fog_fragment>
+
+// Returns the color of the layer texture with the specified index
+#define LAYER(i) texture2D(MatTexture[i], FragTexcoord * MatTexRepeat(i) + MatTexOffset(i))
+
+void main() {
+
+    // Blends the layers with the weights of the splat map
+    vec4 texMixed = vec4(1.0);
+#if defined(TERRAIN_SPLAT) && MAT_TEXTURES > 1
+    vec4 weights = texture2D(MatTexture[0], FragTexcoord);
+    texMixed = weights.r * LAYER(1);
+    float total = weights.r;
+    #if MAT_TEXTURES > 2
+        texMixed += weights.g * LAYER(2);
+        total += weights.g;
+    #endif
+    #if MAT_TEXTURES > 3
+        texMixed += weights.b * LAYER(3);
+        total += weights.b;
+    #endif
+    #if MAT_TEXTURES > 4
+        texMixed += weights.a * LAYER(4);
+        total += weights.a;
+    #endif
+    texMixed /= max(total, 1e-4);
+#elif !defined(TERRAIN_SPLAT) && MAT_TEXTURES > 0
+    texMixed = LAYER(0);
+#endif
+
+    vec4 matDiffuse = vec4(MatDiffuseColor, MatOpacity) * texMixed;
+    vec4 matAmbient = vec4(MatAmbientColor, MatOpacity) * texMixed;
+
+    vec3 fragNormal = normalize(Normal);
+    if (!gl_FrontFacing) {
+        fragNormal = -fragNormal;
+    }
+
+    vec3 Ambdiff, Spec;
+    phongModel(Position, fragNormal, CamDir, vec3(matAmbient), vec3(matDiffuse), Ambdiff, Spec);
+    gl_FragColor = min(vec4(Ambdiff + Spec, matDiffuse.a), vec4(1.0));
+    FOG_FRAGMENT(gl_FragColor)
+}
@@ -0,0 +1,51 @@
+//
+// Vertex shader for terrains
+//
+// The vertices of the terrain chunks which are removed by the next coarser level of
+// detail are morphed to the coarser surface as they approach the level switch distance.
+//
+#ifdef GL_ES
+precision highp float;
+#endif
+
+#include <attributes>
+
+// Terrain vertex attributes
+attribute vec2 TerrainMorph;    // level of detail where the vertex is removed and height offset to the coarser surface
+
+// Model uniforms
+uniform mat4 ModelViewMatrix;
+uniform mat3 NormalMatrix;
+uniform mat4 MVP;
+
+// Terrain chunk uniforms
+uniform vec3 TerrainLod;
+#define TerrainLevel        TerrainLod.x
+#define TerrainMorphStart   TerrainLod.y
+#define TerrainMorphEnd     TerrainLod.z
+
+#include <fog_vertex>
+
+// Outputs for fragment shader
+varying vec4 Position;
+varying vec3 Normal;
+varying vec3 CamDir;
+varying vec2 FragTexcoord;
+
+void main() {
+
+    vec3 vPosition = VertexPosition;
+    if (abs(TerrainMorph.x - TerrainLevel) < 0.5) {
+        float dist = length((ModelViewMatrix * vec4(vPosition, 1.0)).xyz);
+        float morph = clamp((dist - TerrainMorphStart) / max(TerrainMorphEnd - TerrainMorphStart, 1e-6), 0.0, 1.0);
+        vPosition.y += TerrainMorph.y * morph;
+    }
+
+    Position = ModelViewMatrix * vec4(vPosition, 1.0);
+    Normal = normalize(NormalMatrix * VertexNormal);
+    CamDir = normalize(-Position.xyz);
+    FragTexcoord = VertexTexcoord;
+
+    gl_Position = MVP * vec4(vPosition, 1.0);
+    FOG_VERTEX(Position)
+}
@@ -0,0 +1,22 @@
+//
+// Fragment shader for signed distance field text in the scene
+//
+#ifdef GL_ES
+precision highp float;
+#endif
+
+#include <sdftext>
+#include <fog_fragment>
+
+// Inputs from vertex shader
+varying vec2 FragTexcoord;
+
+void main() {
+
+    vec4 color = SdfTextColor(FragTexcoord);
+    if (color.a <= 0.0) {
+        discard;
+    }
+    gl_FragColor = color;
+    FOG_FRAGMENT(gl_FragColor)
+}
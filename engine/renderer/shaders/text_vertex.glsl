@@ -0,0 +1,26 @@
+//
+// Vertex shader for signed distance field text in the scene
+//
+#ifdef GL_ES
+precision highp float;
+#endif
+
+#include <attributes>
+
+// Input uniforms
+uniform mat4 ModelViewMatrix;
+uniform mat4 MVP;
+
+#include <fog_vertex>
+
+// Outputs for fragment shader
+varying vec2 FragTexcoord;
+
+void main() {
+
+    // Glyph texture coordinates are already in the atlas image space
+    FragTexcoord = VertexTexcoord;
+
+    gl_Position = MVP * vec4(VertexPosition, 1.0);
+    FOG_VERTEX(ModelViewMatrix * vec4(VertexPosition, 1.0))
+}
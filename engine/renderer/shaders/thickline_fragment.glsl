@@ -0,0 +1,25 @@
+//
+// Fragment shader for thick lines
+//
+#ifdef GL_ES
+precision highp float;
+#endif
+
+// Dash length, gap length and offset in the model space of the lines
+uniform vec4 LineDash;
+
+#include <fog_fragment>
+
+// Inputs from vertex shader
+varying vec4 Color;
+varying float Distance;
+
+void main() {
+
+    // Discards the fragments in the gaps between dashes
+    if (LineDash.x > 0.0 && mod(Distance + LineDash.z, LineDash.x + LineDash.y) > LineDash.x) {
+        discard;
+    }
+    gl_FragColor = Color;
+    FOG_FRAGMENT(gl_FragColor)
+}
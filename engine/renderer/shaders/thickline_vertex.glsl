@@ -0,0 +1,156 @@
+//
+// Vertex shader for thick lines
+//
+// Each vertex is expanded in screen space from its position on the polyline
+// according to the directions of the adjacent segments and its kind.
+//
+#ifdef GL_ES
+precision highp float;
+#endif
+
+#include <attributes>
+
+// Line vertex attributes
+attribute vec3 LinePrev;        // previous point of the polyline (or the vertex position at the start)
+attribute vec3 LineNext;        // next point of the polyline (or the vertex position at the end)
+attribute vec4 LineVertex;      // kind, side, fraction and distance along the polyline
+
+// Model uniforms
+uniform mat4 ModelViewMatrix;
+uniform mat4 MVP;
+uniform mat4 ProjMatrix;
+
+// Line uniforms
+uniform vec4 LineColor;
+uniform vec4 LineParams;
+uniform vec2 LineViewport;
+#define LineWidth       LineParams.x
+#define LineWorldUnits  LineParams.y
+#define LineMiterLimit  LineParams.z
+
+#include <fog_vertex>
+
+// Vertex kinds
+#define KIND_BODY       0.0     // segment side
+#define KIND_JOIN       1.0     // join edge on the outer side rotated by fraction from the incoming to the outgoing segment
+#define KIND_MITER      2.0     // miter join tip on the outer side
+#define KIND_CAP        3.0     // round cap edge rotated by fraction of half turn
+#define KIND_SQUARE     4.0     // square cap corner with the corner index in fraction
+#define KIND_CENTER     5.0     // point on the polyline
+
+// Outputs for fragment shader
+varying vec4 Color;
+varying float Distance;
+
+// Returns the position in pixels of the specified clip coordinates in front of the near plane
+vec2 toScreen(vec4 clip) {
+
+    return clip.xy / max(clip.w, 1e-6) * LineViewport * 0.5;
+}
+
+// Returns the specified clip coordinates moved along the segment to q onto
+// the near plane if they are behind it and q is in front of it
+vec4 clipNear(vec4 p, vec4 q) {
+
+    float dp = p.z + p.w;
+    float dq = q.z + q.w;
+    if (dp >= 0.0 || dq <= 0.0) {
+        return p;
+    }
+    return mix(p, q, dp / (dp - dq));
+}
+
+// Returns the normalized direction or the specified default direction if too short
+vec2 direction(vec2 d, vec2 def) {
+
+    float len = length(d);
+    if (len < 1e-5) {
+        return def;
+    }
+    return d / len;
+}
+
+void main() {
+
+    float kind = LineVertex.x;
+    vec4 clip = MVP * vec4(VertexPosition, 1.0);
+    vec4 prev = MVP * vec4(LinePrev, 1.0);
+    vec4 next = MVP * vec4(LineNext, 1.0);
+
+    // Clips the segments crossing the near plane before projecting them, so they are not mirrored.
+    // Body vertices are at one of the ends of their segment, which is moved onto the near plane
+    // if it is behind it. Join and cap vertices behind the near plane are clipped with their triangles.
+    if (kind < KIND_JOIN - 0.5) {
+        vec4 a = clipNear(prev, next);
+        vec4 b = clipNear(next, prev);
+        clip = distance(VertexPosition, LinePrev) <= distance(VertexPosition, LineNext) ? a : b;
+        prev = a;
+        next = b;
+    } else {
+        prev = clipNear(prev, clip);
+        next = clipNear(next, clip);
+    }
+    vec2 p = toScreen(clip);
+    vec2 a = toScreen(prev);
+    vec2 b = toScreen(next);
+
+    // Directions and normals of the incoming and outgoing segments in screen space
+    vec2 dout = direction(b - p, direction(p - a, vec2(1.0, 0.0)));
+    vec2 din = direction(p - a, dout);
+    vec2 nin = vec2(-din.y, din.x);
+    vec2 nout = vec2(-dout.y, dout.x);
+
+    // Half line width in pixels
+    float hw = LineWidth * 0.5;
+    if (LineWorldUnits > 0.5) {
+        hw *= ProjMatrix[1][1] * LineViewport.y * 0.5 / max(abs(clip.w), 1e-6);
+    }
+
+    float side = LineVertex.y;
+    float fraction = LineVertex.z;
+    // Joins are only drawn on the outer side of the turn, as the segment bodies overlap on the inner side
+    if (kind > KIND_JOIN - 0.5 && kind < KIND_CAP - 0.5) {
+        side = din.x * dout.y - din.y * dout.x > 0.0 ? -1.0 : 1.0;
+    }
+    vec2 offset = vec2(0.0);
+    if (kind < KIND_JOIN - 0.5) {
+        offset = side * nin;
+    } else if (kind < KIND_MITER - 0.5) {
+        float a0 = atan(side * nin.y, side * nin.x);
+        float a1 = atan(side * nout.y, side * nout.x);
+        float delta = a1 - a0;
+        if (delta > 3.14159265) {
+            delta -= 6.28318531;
+        } else if (delta < -3.14159265) {
+            delta += 6.28318531;
+        }
+        float angle = a0 + delta * fraction;
+        offset = vec2(cos(angle), sin(angle));
+    } else if (kind < KIND_CAP - 0.5) {
+        vec2 sum = nin + nout;
+        float len = length(sum);
+        offset = side * sum * 0.5;
+        if (len > 1e-5) {
+            vec2 miter = sum / len;
+            float mlen = 1.0 / max(dot(miter, nin), 1e-5);
+            if (mlen <= LineMiterLimit) {
+                offset = side * miter * mlen;
+            }
+        }
+    } else if (kind < KIND_SQUARE - 0.5) {
+        float angle = fraction * 3.14159265;
+        offset = cos(angle) * nin + sin(angle) * side * din;
+    } else if (kind < KIND_CENTER - 0.5) {
+        float n = fraction < 1.5 ? 1.0 : -1.0;
+        float ext = (fraction > 0.5 && fraction < 2.5) ? 1.0 : 0.0;
+        offset = n * nin + ext * side * din;
+    }
+
+    // Offsets the position in clip coordinates
+    clip.xy += offset * hw * 2.0 / LineViewport * clip.w;
+    gl_Position = clip;
+    FOG_VERTEX(ModelViewMatrix * vec4(VertexPosition, 1.0))
+
+    Color = LineColor * vec4(VertexColor, 1.0);
+    Distance = LineVertex.w;
+}
@@ -0,0 +1,101 @@
+//
+// Fragment shader for water surfaces
+//
+// The wave normal is perturbed by two samples of the normal map scrolling in different
+// directions when WATER_NORMALMAP is defined. The lit water color is blended by the
+// fresnel factor with the sky colors or, when WATER_REFLECTION is defined, with the
+// reflection map, which is sampled in screen space distorted by the normal.
+//
+#ifdef GL_ES
+precision highp float;
+#endif
+
+// Inputs from vertex shader
+varying vec4 Position;
+varying vec3 WorldNormal;
+varying vec3 CamDir;
+varying vec2 WorldXZ;
+varying float Depth;
+
+// Model uniforms
+uniform mat3 NormalMatrix;
+
+// Water uniforms
+uniform vec4 WaterParams[5];
+#define WaterTime           WaterParams[0].x
+#define WaterNormalScale    WaterParams[0].y
+#define WaterNormalStrength WaterParams[0].z
+#define WaterFresnelBias    WaterParams[0].w
+#define WaterScroll1        WaterParams[1].xy
+#define WaterScroll2        WaterParams[1].zw
+#define WaterShallowColor   WaterParams[2].rgb
+#define WaterShoreDepth     WaterParams[2].w
+#define WaterHorizonColor   WaterParams[3].rgb
+#define WaterEdgeFade       WaterParams[3].w
+#define WaterZenithColor    WaterParams[4].rgb
+#define WaterDistortion     WaterParams[4].w
+uniform vec4 WaterViewport;     // viewport position and size
+
+#ifdef WATER_NORMALMAP
+uniform sampler2D WaterNormalSampler;
+#endif
+#ifdef WATER_REFLECTION
+uniform sampler2D WaterReflectionSampler;
+#endif
+
+#include <lights>
+#include <material>
+#include <phong_model>
+#include <fog_fragment>
+
+void main() {
+
+    // Perturbs the wave normal with the scrolling normal map samples
+    vec3 worldNormal = normalize(WorldNormal);
+    vec2 detail = vec2(0.0);
+#ifdef WATER_NORMALMAP
+    vec2 uv = WorldXZ / WaterNormalScale;
+    vec3 n1 = texture2D(WaterNormalSampler, uv + WaterScroll1 * WaterTime).rgb * 2.0 - 1.0;
+    vec3 n2 = texture2D(WaterNormalSampler, uv * 1.37 + WaterScroll2 * WaterTime).rgb * 2.0 - 1.0;
+    detail = (n1.xy + n2.xy) * WaterNormalStrength;
+    worldNormal = normalize(worldNormal * max(n1.z * n2.z, 1e-2) + vec3(detail.x, 0.0, detail.y));
+#endif
+    vec3 normal = normalize(NormalMatrix * worldNormal);
+    vec3 camDir = normalize(CamDir);
+    if (!gl_FrontFacing) {
+        normal = -normal;
+    }
+
+    // Tints the water from the shallow to the deep color and fades it at the edge
+    vec3 color = MatDiffuseColor;
+    float edge = 1.0;
+#ifdef WATER_DEPTH
+    color = mix(WaterShallowColor, MatDiffuseColor, clamp(Depth / max(WaterShoreDepth, 1e-4), 0.0, 1.0));
+    if (WaterEdgeFade > 0.0) {
+        edge = clamp(Depth / WaterEdgeFade, 0.0, 1.0);
+    }
+#endif
+
+    vec3 Ambdiff, Spec;
+    phongModel(Position, normal, camDir, color, color, Ambdiff, Spec);
+
+    // Reflected color from the sky colors or the reflection map
+    vec3 up = normalize(NormalMatrix * vec3(0.0, 1.0, 0.0));
+    vec3 reflected = reflect(-camDir, normal);
+    vec3 reflColor = mix(WaterHorizonColor, WaterZenithColor, clamp(dot(reflected, up), 0.0, 1.0));
+#ifdef WATER_REFLECTION
+    vec2 screen = (gl_FragCoord.xy - WaterViewport.xy) / WaterViewport.zw;
+    screen.y = 1.0 - screen.y;
+    screen += detail * WaterDistortion;
+    reflColor = texture2D(WaterReflectionSampler, clamp(screen, 0.001, 0.999)).rgb;
+#endif
+
+    // Schlick's approximation of the fresnel reflectance
+    float cosTheta = clamp(dot(normal, camDir), 0.0, 1.0);
+    float fresnel = WaterFresnelBias + (1.0 - WaterFresnelBias) * pow(1.0 - cosTheta, 5.0);
+
+    // The reflection makes the water more opaque at grazing angles
+    float alpha = mix(MatOpacity, 1.0, fresnel) * edge;
+    gl_FragColor = min(vec4(mix(Ambdiff, reflColor, fresnel) + Spec, alpha), vec4(1.0));
+    FOG_FRAGMENT(gl_FragColor)
+}
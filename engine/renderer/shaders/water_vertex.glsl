@@ -0,0 +1,84 @@
+//
+// Vertex shader for water surfaces
+//
+// The vertices are displaced by the sum of up to four Gerstner waves evaluated at their
+// world positions, so adjacent water meshes match. The water mesh must not be rotated
+// or scaled. When WATER_DEPTH is defined the vertices have the depth of the water below
+// them, which attenuates the waves near the shore.
+//
+#ifdef GL_ES
+precision highp float;
+#endif
+
+#include <attributes>
+
+#ifdef WATER_DEPTH
+// Water vertex attributes
+attribute float WaterDepth;     // depth of the floor below the vertex
+#endif
+
+// Model uniforms
+uniform mat4 ModelMatrix;
+uniform mat4 ModelViewMatrix;
+uniform mat3 NormalMatrix;
+uniform mat4 MVP;
+
+// Water uniforms
+uniform vec4 WaterWaves[4];     // direction X and Z, steepness and wavelength of each wave
+uniform vec4 WaterParams[5];
+#define WaterTime           WaterParams[0].x
+#define WaterShoreDepth     WaterParams[2].w
+
+#include <fog_vertex>
+
+// Outputs for fragment shader
+varying vec4 Position;
+varying vec3 WorldNormal;
+varying vec3 CamDir;
+varying vec2 WorldXZ;
+varying float Depth;
+
+void main() {
+
+    vec3 world = (ModelMatrix * vec4(VertexPosition, 1.0)).xyz;
+
+    // Attenuates the waves in shallow water
+    float attenuation = 1.0;
+#ifdef WATER_DEPTH
+    Depth = WaterDepth;
+    attenuation = smoothstep(0.0, max(WaterShoreDepth, 1e-4), WaterDepth);
+#else
+    Depth = 1e4;
+#endif
+
+    // Sums the displacements and the tangent and binormal derivatives of the waves
+    vec3 offset = vec3(0.0);
+    vec3 tangent = vec3(1.0, 0.0, 0.0);
+    vec3 binormal = vec3(0.0, 0.0, 1.0);
+    for (int i = 0; i < 4; i++) {
+        vec4 wave = WaterWaves[i];
+        if (wave.w <= 0.0) {
+            continue;
+        }
+        vec2 d = wave.xy;
+        float k = 6.2831853 / wave.w;
+        float c = sqrt(9.8 / k);
+        float f = k * (dot(d, world.xz) - c * WaterTime);
+        float s = wave.z * attenuation;
+        float a = s / k;
+        float sinf = sin(f);
+        float cosf = cos(f);
+        offset += vec3(d.x * a * cosf, a * sinf, d.y * a * cosf);
+        tangent += vec3(-d.x * d.x * s * sinf, d.x * s * cosf, -d.x * d.y * s * sinf);
+        binormal += vec3(-d.x * d.y * s * sinf, d.y * s * cosf, -d.y * d.y * s * sinf);
+    }
+
+    vec3 vPosition = VertexPosition + offset;
+    Position = ModelViewMatrix * vec4(vPosition, 1.0);
+    WorldNormal = normalize(cross(binormal, tangent));
+    CamDir = normalize(-Position.xyz);
+    WorldXZ = world.xz + offset.xz;
+
+    gl_Position = MVP * vec4(vPosition, 1.0);
+    FOG_VERTEX(Position)
+}
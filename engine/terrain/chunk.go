@@ -0,0 +1,211 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package terrain
+
+import (
+	"github.com/wangzun/gogame/engine/core"
+	"github.com/wangzun/gogame/engine/geometry"
+	"github.com/wangzun/gogame/engine/gls"
+	"github.com/wangzun/gogame/engine/graphic"
+	"github.com/wangzun/gogame/engine/math32"
+	"golang.org/x/mobile/gl"
+)
+
+// chunk is a mesh with a square part of a terrain surface with the triangles of all its
+// levels of detail in its geometry, of which only the current level is drawn.
+type chunk struct {
+	graphic.Mesh                  // Embedded mesh
+	terrain      *Terrain         // Terrain which contains the chunk
+	box          math32.Box3      // Bounding box in the terrain node
+	level        int              // Current level of detail
+	starts       [chunkLevels]int // First index of each level of detail
+	counts       [chunkLevels]int // Number of indices of each level of detail
+	lod          math32.Vector3   // Current level and morph distances
+	uniLod       gls.Uniform      // Level of detail uniform location cache
+}
+
+// chunkNoMorph is the morph distance of the coarsest level, whose vertices never morph.
+const chunkNoMorph = 1e30
+
+// newChunk creates and returns a pointer to a new chunk of the specified terrain
+// with the specified indices along the X and Z axes.
+func newChunk(t *Terrain, ci, cj int) *chunk {
+
+	c := new(chunk)
+	c.terrain = t
+	c.level = -1
+	c.uniLod.Init("TerrainLod")
+
+	// Samples of the chunk vertices, clamped to the heightmap at the terrain edges
+	const n = ChunkCells
+	sx := make([]int, n+1)
+	sz := make([]int, n+1)
+	for k := 0; k <= n; k++ {
+		sx[k] = ci*n + k
+		if sx[k] > t.heightmap.width-1 {
+			sx[k] = t.heightmap.width - 1
+		}
+		sz[k] = cj*n + k
+		if sz[k] > t.heightmap.depth-1 {
+			sz[k] = t.heightmap.depth - 1
+		}
+	}
+
+	count := (n+1)*(n+1) + 4*(n+1)
+	positions := math32.NewArrayF32(0, count*3)
+	normals := math32.NewArrayF32(0, count*3)
+	uvs := math32.NewArrayF32(0, count*2)
+	morphs := math32.NewArrayF32(0, count*2)
+	heights := make([]float32, (n+1)*(n+1))
+	c.box.MakeEmpty()
+	for j := 0; j <= n; j++ {
+		for i := 0; i <= n; i++ {
+			p := t.samplePosition(sx[i], sz[j])
+			heights[j*(n+1)+i] = p.Y
+			c.box.ExpandByPoint(&p)
+		}
+	}
+	height := func(i, j int) float32 {
+		return heights[j*(n+1)+i]
+	}
+
+	// Adds a vertex with the morph to the coarser level where it is removed
+	vertex := func(i, j int, drop float32) {
+		p := t.samplePosition(sx[i], sz[j])
+		normal := t.sampleNormal(sx[i], sz[j])
+		positions.Append(p.X, p.Y-drop, p.Z)
+		normals.AppendVector3(&normal)
+		uvs.Append(float32(sx[i])/float32(t.heightmap.width-1), float32(sz[j])/float32(t.heightmap.depth-1))
+		level, dy := morphTarget(i, j, height)
+		morphs.Append(float32(level), dy)
+	}
+	for j := 0; j <= n; j++ {
+		for i := 0; i <= n; i++ {
+			vertex(i, j, 0)
+		}
+	}
+
+	// Skirts hanging from the edges, going around the chunk so they face outwards
+	skirtDepth := (c.box.Max.Y-c.box.Min.Y)/2 + t.cellSize
+	var skirts [4]uint32
+	for e := range skirts {
+		skirts[e] = uint32(positions.Len() / 3)
+		for k := 0; k <= n; k++ {
+			i, j := edgeVertex(e, k)
+			vertex(i, j, skirtDepth)
+		}
+	}
+	c.box.Min.Y -= skirtDepth
+
+	// Triangles of each level of detail with their skirts
+	indices := math32.NewArrayU32(0, 0)
+	for level := 0; level < chunkLevels; level++ {
+		s := 1 << uint(level)
+		c.starts[level] = indices.Len()
+		for j := 0; j < n; j += s {
+			for i := 0; i < n; i += s {
+				v00 := uint32(j*(n+1) + i)
+				v01 := uint32((j+s)*(n+1) + i)
+				v10 := v00 + uint32(s)
+				v11 := v01 + uint32(s)
+				indices.Append(v00, v01, v11, v00, v11, v10)
+			}
+		}
+		for e, skirt := range skirts {
+			for k := 0; k < n; k += s {
+				i0, j0 := edgeVertex(e, k)
+				i1, j1 := edgeVertex(e, k+s)
+				a := uint32(j0*(n+1) + i0)
+				b := uint32(j1*(n+1) + i1)
+				indices.Append(a, b, skirt+uint32(k+s), a, skirt+uint32(k+s), skirt+uint32(k))
+			}
+		}
+		c.counts[level] = indices.Len() - c.starts[level]
+	}
+
+	geom := geometry.NewGeometry()
+	geom.SetIndices(indices)
+	geom.AddVBO(gls.NewVBO(positions).AddAttrib(gls.VertexPosition))
+	geom.AddVBO(gls.NewVBO(normals).AddAttrib(gls.VertexNormal))
+	geom.AddVBO(gls.NewVBO(uvs).AddAttrib(gls.VertexTexcoord))
+	geom.AddVBO(gls.NewVBO(morphs).AddCustomAttrib("TerrainMorph", 2))
+	c.Mesh.Init(geom, nil)
+	c.setLevel(0, t.lodDistance*(1-t.morphRegion), t.lodDistance)
+	return c
+}
+
+// edgeVertex returns the indices of the specified vertex along the specified edge
+// of a chunk, going around the chunk with its outside on the left seen from above.
+func edgeVertex(edge, k int) (int, int) {
+
+	const n = ChunkCells
+	switch edge {
+	case 0:
+		return k, 0
+	case 1:
+		return n, k
+	case 2:
+		return n - k, n
+	default:
+		return 0, n - k
+	}
+}
+
+// morphTarget returns the level of detail which removes the specified chunk vertex, as the
+// coarser level doesn't have it, and the height offset to the surface of the coarser level.
+func morphTarget(i, j int, height func(i, j int) float32) (int, float32) {
+
+	level := 0
+	for level < chunkLevels-1 && i%(2<<uint(level)) == 0 && j%(2<<uint(level)) == 0 {
+		level++
+	}
+	if level == chunkLevels-1 {
+		return level, 0
+	}
+
+	// The coarser level interpolates the vertex between the vertices of the edge or of the
+	// cell diagonal the vertex is on, which are at the distance of the level step
+	s := 1 << uint(level)
+	var coarse float32
+	switch {
+	case i%(2*s) == 0:
+		coarse = (height(i, j-s) + height(i, j+s)) / 2
+	case j%(2*s) == 0:
+		coarse = (height(i-s, j) + height(i+s, j)) / 2
+	default:
+		coarse = (height(i-s, j-s) + height(i+s, j+s)) / 2
+	}
+	return level, coarse - height(i, j)
+}
+
+// setLevel sets the level of detail drawn by the chunk and the distances
+// where its vertices start and finish morphing to the next level.
+func (c *chunk) setLevel(level int, morphStart, morphEnd float32) {
+
+	if level == chunkLevels-1 {
+		morphStart = chunkNoMorph
+		morphEnd = chunkNoMorph
+	}
+	c.lod.Set(float32(level), morphStart, morphEnd)
+	if level == c.level {
+		return
+	}
+	c.level = level
+	c.ClearMaterials()
+	c.Graphic.AddMaterial(c, c.terrain.mat, c.starts[level], c.counts[level])
+}
+
+// RenderSetup is called by the engine before drawing the chunk
+// to transfer the matrices and the level of detail uniforms.
+func (c *chunk) RenderSetup(gs *gls.GLS, rinfo *core.RenderInfo) {
+
+	c.Mesh.RenderSetup(gs, rinfo)
+	location := c.uniLod.Location(gs)
+	gs.Uniform3f(gl.Uniform{Value: location}, c.lod.X, c.lod.Y, c.lod.Z)
+}
+
+// Raycast does nothing as the terrain checks the intersections with all its chunks.
+func (c *chunk) Raycast(rc *core.Raycaster, intersects *[]core.Intersect) {
+}
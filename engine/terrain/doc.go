@@ -0,0 +1,8 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package terrain implements large terrains built from heightmaps, split into
+// chunks with geomorphing levels of detail, which can be queried for the height
+// and normal at any point and raycast efficiently.
+package terrain
@@ -0,0 +1,134 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package terrain
+
+import (
+	"image"
+	"image/color"
+	_ "image/jpeg" // Registers the JPEG decoder
+	_ "image/png"  // Registers the PNG decoder
+	"os"
+)
+
+// Heightmap is a regular grid of height samples.
+type Heightmap struct {
+	width   int       // Number of samples along the X axis
+	depth   int       // Number of samples along the Z axis
+	heights []float32 // Heights by rows along the X axis
+}
+
+// NewHeightmap creates and returns a pointer to a new flat heightmap
+// with the specified number of samples along the X and Z axes.
+func NewHeightmap(width, depth int) *Heightmap {
+
+	hm := new(Heightmap)
+	hm.width = width
+	hm.depth = depth
+	hm.heights = make([]float32, width*depth)
+	return hm
+}
+
+// NewHeightmapFromFunc creates and returns a pointer to a new heightmap with the specified
+// number of samples along the X and Z axes and the heights returned by the specified function.
+func NewHeightmapFromFunc(width, depth int, height func(x, z int) float32) *Heightmap {
+
+	hm := NewHeightmap(width, depth)
+	for z := 0; z < depth; z++ {
+		for x := 0; x < width; x++ {
+			hm.heights[z*width+x] = height(x, z)
+		}
+	}
+	return hm
+}
+
+// NewHeightmapFromImage creates and returns a pointer to a new heightmap with one sample
+// for each pixel of the specified image, whose luminance from black to white is converted
+// to heights from 0 to 1. The rows of the image are along the X axis.
+// 16 bit grayscale images keep their full precision.
+func NewHeightmapFromImage(img image.Image) *Heightmap {
+
+	bounds := img.Bounds()
+	hm := NewHeightmap(bounds.Dx(), bounds.Dy())
+	for z := 0; z < hm.depth; z++ {
+		for x := 0; x < hm.width; x++ {
+			gray := color.Gray16Model.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+z)).(color.Gray16)
+			hm.heights[z*hm.width+x] = float32(gray.Y) / 0xFFFF
+		}
+	}
+	return hm
+}
+
+// NewHeightmapFromFile creates and returns a pointer to a new heightmap
+// from the PNG or JPEG image in the specified file.
+func NewHeightmapFromFile(filename string) (*Heightmap, error) {
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, err
+	}
+	return NewHeightmapFromImage(img), nil
+}
+
+// Width returns the number of samples along the X axis.
+func (hm *Heightmap) Width() int {
+
+	return hm.width
+}
+
+// Depth returns the number of samples along the Z axis.
+func (hm *Heightmap) Depth() int {
+
+	return hm.depth
+}
+
+// Height returns the height of the specified sample, which is
+// clamped to the heightmap bounds.
+func (hm *Heightmap) Height(x, z int) float32 {
+
+	if x < 0 {
+		x = 0
+	} else if x >= hm.width {
+		x = hm.width - 1
+	}
+	if z < 0 {
+		z = 0
+	} else if z >= hm.depth {
+		z = hm.depth - 1
+	}
+	return hm.heights[z*hm.width+x]
+}
+
+// SetHeight sets the height of the specified sample.
+// Terrains already built from the heightmap are not changed.
+func (hm *Heightmap) SetHeight(x, z int, height float32) {
+
+	hm.heights[z*hm.width+x] = height
+}
+
+// Smooth averages each height with its neighbors the specified number
+// of times, which removes the steps of 8 bit heightmap images.
+func (hm *Heightmap) Smooth(iterations int) {
+
+	smoothed := make([]float32, len(hm.heights))
+	for it := 0; it < iterations; it++ {
+		for z := 0; z < hm.depth; z++ {
+			for x := 0; x < hm.width; x++ {
+				var sum float32
+				for dz := -1; dz <= 1; dz++ {
+					for dx := -1; dx <= 1; dx++ {
+						sum += hm.Height(x+dx, z+dz)
+					}
+				}
+				smoothed[z*hm.width+x] = sum / 9
+			}
+		}
+		hm.heights, smoothed = smoothed, hm.heights
+	}
+}
@@ -0,0 +1,212 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package terrain
+
+import (
+	"github.com/wangzun/gogame/engine/core"
+	"github.com/wangzun/gogame/engine/math32"
+)
+
+// Raycast satisfies the INode interface and checks the intersection of the terrain surface with
+// the specified raycaster. Instead of testing all the triangles it walks the chunks crossed by the
+// ray, skipping the ones whose height range the ray doesn't cross, and then the cells of the chunks
+// crossed by the ray, testing only the two triangles of each cell, until the first intersection.
+// The intersection index is twice the index of the cell, plus one for its second triangle.
+func (t *Terrain) Raycast(rc *core.Raycaster, intersects *[]core.Intersect) {
+
+	// Copy ray and transform to model coordinates
+	matrixWorld := t.MatrixWorld()
+	var inverseMatrix math32.Matrix4
+	var ray math32.Ray
+	inverseMatrix.GetInverse(&matrixWorld)
+	ray.Copy(&rc.Ray).ApplyMatrix4(&inverseMatrix)
+
+	var point math32.Vector3
+	index, ok := t.intersect(&ray, &point)
+	if !ok {
+		return
+	}
+	point.ApplyMatrix4(&matrixWorld)
+	origin := rc.Ray.Origin()
+	distance := origin.DistanceTo(&point)
+	if distance < rc.Near || distance > rc.Far {
+		return
+	}
+	*intersects = append(*intersects, core.Intersect{
+		Distance: distance,
+		Point:    point,
+		Index:    index,
+		Object:   t,
+	})
+}
+
+// IntersectRay checks the intersection of the terrain surface with the specified ray in world
+// coordinates and returns whether the ray hits the surface, setting the specified point with the
+// first intersection in world coordinates.
+func (t *Terrain) IntersectRay(wray *math32.Ray, point *math32.Vector3) bool {
+
+	matrixWorld := t.MatrixWorld()
+	var inverseMatrix math32.Matrix4
+	var ray math32.Ray
+	inverseMatrix.GetInverse(&matrixWorld)
+	ray.Copy(wray).ApplyMatrix4(&inverseMatrix)
+	if _, ok := t.intersect(&ray, point); !ok {
+		return false
+	}
+	point.ApplyMatrix4(&matrixWorld)
+	return true
+}
+
+// intersect checks the intersection of the terrain surface with the specified ray in the node
+// coordinates and returns the index of the intersected triangle and whether there is one.
+func (t *Terrain) intersect(ray *math32.Ray, point *math32.Vector3) (uint32, bool) {
+
+	origin := ray.Origin()
+	dir := ray.Direction()
+	width, depth := t.Size()
+
+	// Clips the ray to the terrain bounds along X and Z
+	t0 := float32(0)
+	t1 := math32.Inf(1)
+	for _, axis := range [2]struct{ o, d, min, max float32 }{
+		{origin.X, dir.X, t.origin.X, t.origin.X + width},
+		{origin.Z, dir.Z, t.origin.Z, t.origin.Z + depth},
+	} {
+		if axis.d == 0 {
+			if axis.o < axis.min || axis.o > axis.max {
+				return 0, false
+			}
+			continue
+		}
+		ta := (axis.min - axis.o) / axis.d
+		tb := (axis.max - axis.o) / axis.d
+		if ta > tb {
+			ta, tb = tb, ta
+		}
+		t0 = math32.Max(t0, ta)
+		t1 = math32.Min(t1, tb)
+	}
+	if t0 > t1 {
+		return 0, false
+	}
+
+	ox := origin.X - t.origin.X
+	oz := origin.Z - t.origin.Z
+	chunkSize := ChunkCells * t.cellSize
+	var index uint32
+	hit := false
+	walkGrid(ox, oz, dir.X, dir.Z, t0, t1, chunkSize, t.chunksX, t.chunksZ, func(ci, cj int, tc0, tc1 float32) bool {
+		// Skips the chunk if the ray is above or below all its heights while crossing it
+		c := t.chunks[cj*t.chunksX+ci]
+		y0 := origin.Y + dir.Y*tc0
+		y1 := origin.Y + dir.Y*tc1
+		if (y0 > c.box.Max.Y && y1 > c.box.Max.Y) || (y0 < c.box.Min.Y && y1 < c.box.Min.Y) {
+			return false
+		}
+
+		// Walks the cells of the chunk
+		nx := t.heightmap.width - 1 - ci*ChunkCells
+		if nx > ChunkCells {
+			nx = ChunkCells
+		}
+		nz := t.heightmap.depth - 1 - cj*ChunkCells
+		if nz > ChunkCells {
+			nz = ChunkCells
+		}
+		cox := ox - float32(ci)*chunkSize
+		coz := oz - float32(cj)*chunkSize
+		walkGrid(cox, coz, dir.X, dir.Z, tc0, tc1, t.cellSize, nx, nz, func(i, j int, _, _ float32) bool {
+			x := ci*ChunkCells + i
+			z := cj*ChunkCells + j
+			a := t.samplePosition(x, z)
+			b := t.samplePosition(x, z+1)
+			c := t.samplePosition(x+1, z+1)
+			d := t.samplePosition(x+1, z)
+			var p1, p2 math32.Vector3
+			hit1 := ray.IntersectTriangle(&a, &b, &c, false, &p1)
+			hit2 := ray.IntersectTriangle(&a, &c, &d, false, &p2)
+			if !hit1 && !hit2 {
+				return false
+			}
+			cell := uint32(z*(t.heightmap.width-1) + x)
+			if hit1 && (!hit2 || origin.DistanceToSquared(&p1) <= origin.DistanceToSquared(&p2)) {
+				*point = p1
+				index = 2 * cell
+			} else {
+				*point = p2
+				index = 2*cell + 1
+			}
+			hit = true
+			return true
+		})
+		return hit
+	})
+	return index, hit
+}
+
+// walkGrid calls the specified function with the indices of the cells of a grid of nx by nz
+// cells of the specified size along the X and Z axes, starting at the origin, which are crossed
+// by the ray with the specified origin and direction on the XZ plane between the specified ray
+// parameters, in order, with the ray parameters where the ray enters and leaves each cell,
+// until the function returns true.
+func walkGrid(ox, oz, dx, dz, t0, t1, size float32, nx, nz int, f func(i, j int, tEnter, tExit float32) bool) {
+
+	if nx <= 0 || nz <= 0 || t0 > t1 {
+		return
+	}
+	// Starting cell
+	i := int(math32.Floor((ox + dx*t0) / size))
+	j := int(math32.Floor((oz + dz*t0) / size))
+	if i < 0 {
+		i = 0
+	} else if i >= nx {
+		i = nx - 1
+	}
+	if j < 0 {
+		j = 0
+	} else if j >= nz {
+		j = nz - 1
+	}
+
+	// Ray parameters of the next cell boundaries along each axis and their increments
+	stepI, stepJ := 1, 1
+	nextX, nextZ := math32.Inf(1), math32.Inf(1)
+	deltaX, deltaZ := math32.Inf(1), math32.Inf(1)
+	if dx > 0 {
+		nextX = (float32(i+1)*size - ox) / dx
+		deltaX = size / dx
+	} else if dx < 0 {
+		stepI = -1
+		nextX = (float32(i)*size - ox) / dx
+		deltaX = -size / dx
+	}
+	if dz > 0 {
+		nextZ = (float32(j+1)*size - oz) / dz
+		deltaZ = size / dz
+	} else if dz < 0 {
+		stepJ = -1
+		nextZ = (float32(j)*size - oz) / dz
+		deltaZ = -size / dz
+	}
+
+	enter := t0
+	for {
+		exit := math32.Min(math32.Min(nextX, nextZ), t1)
+		if f(i, j, enter, exit) || exit >= t1 {
+			return
+		}
+		enter = exit
+		if nextX < nextZ {
+			i += stepI
+			nextX += deltaX
+		} else {
+			j += stepJ
+			nextZ += deltaZ
+		}
+		if i < 0 || i >= nx || j < 0 || j >= nz {
+			return
+		}
+	}
+}
@@ -0,0 +1,291 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package terrain
+
+import (
+	"github.com/wangzun/gogame/engine/camera"
+	"github.com/wangzun/gogame/engine/core"
+	"github.com/wangzun/gogame/engine/material"
+	"github.com/wangzun/gogame/engine/math32"
+)
+
+// ChunkCells is the number of cells along each side of the terrain chunks.
+const ChunkCells = 32
+
+// chunkLevels is the number of levels of detail of the chunks, the coarsest
+// with a single cell and each finer level with twice the cells along each side.
+const chunkLevels = 6
+
+// Terrain is a node with the surface of a heightmap centered on its origin with the
+// heights along the Y axis. The surface is split into chunks of ChunkCells by ChunkCells
+// cells, which are culled individually, with levels of detail chosen by their distances
+// to the camera. The vertices removed by the next coarser level are smoothly morphed
+// to the coarser surface before the chunk switches levels (geomorphing) and the chunks
+// have skirts hanging from their edges to hide any cracks between different levels.
+// The chunks must be drawn with the Terrain material, which blends texture layers
+// with splat maps, and their levels are updated by calling Update every frame.
+// The terrain may be translated and scaled but not rotated.
+type Terrain struct {
+	core.Node                      // Embedded node
+	heightmap   *Heightmap         // Heightmap of the surface
+	cellSize    float32            // Size of the cells along X and Z
+	heightScale float32            // Scale of the heightmap heights
+	mat         material.IMaterial // Material of the chunks
+	origin      math32.Vector3     // Position of the first sample in the node
+	chunks      []*chunk           // Chunks by rows along the X axis
+	chunksX     int                // Number of chunks along the X axis
+	chunksZ     int                // Number of chunks along the Z axis
+	lodDistance float32            // Distance up to which the finest level of detail is used
+	morphRegion float32            // Fraction of the distance range of each level where vertices morph
+}
+
+// NewTerrain creates and returns a pointer to a new terrain with the specified heightmap,
+// size of its cells along X and Z, scale of its heights and material, which is normally a
+// material.Terrain. The texture coordinates go from 0 to 1 over the whole terrain.
+func NewTerrain(hm *Heightmap, cellSize, heightScale float32, mat material.IMaterial) *Terrain {
+
+	t := new(Terrain)
+	t.Node.Init()
+	t.heightmap = hm
+	t.cellSize = cellSize
+	t.heightScale = heightScale
+	t.mat = mat
+	t.origin = math32.Vector3{
+		X: -float32(hm.width-1) * cellSize / 2,
+		Z: -float32(hm.depth-1) * cellSize / 2,
+	}
+	t.lodDistance = 3 * ChunkCells * cellSize
+	t.morphRegion = 0.3
+
+	// Builds the chunks covering all the cells
+	t.chunksX = (hm.width - 2 + ChunkCells) / ChunkCells
+	t.chunksZ = (hm.depth - 2 + ChunkCells) / ChunkCells
+	for j := 0; j < t.chunksZ; j++ {
+		for i := 0; i < t.chunksX; i++ {
+			c := newChunk(t, i, j)
+			if len(t.chunks) > 0 {
+				mat.GetMaterial().Incref()
+			}
+			t.chunks = append(t.chunks, c)
+			t.Add(c)
+		}
+	}
+	return t
+}
+
+// Heightmap returns the heightmap of the terrain.
+func (t *Terrain) Heightmap() *Heightmap {
+
+	return t.heightmap
+}
+
+// CellSize returns the size of the terrain cells along the X and Z axes.
+func (t *Terrain) CellSize() float32 {
+
+	return t.cellSize
+}
+
+// HeightScale returns the scale of the heightmap heights.
+func (t *Terrain) HeightScale() float32 {
+
+	return t.heightScale
+}
+
+// Size returns the size of the terrain along the X and Z axes in the node coordinates.
+func (t *Terrain) Size() (float32, float32) {
+
+	return float32(t.heightmap.width-1) * t.cellSize, float32(t.heightmap.depth-1) * t.cellSize
+}
+
+// ChunkCount returns the number of chunks of the terrain.
+func (t *Terrain) ChunkCount() int {
+
+	return len(t.chunks)
+}
+
+// SetLodDistance sets the distance from the camera up to which the chunks use the finest level
+// of detail, which is doubled for each coarser level. Distances shorter than about twice the
+// size of the chunks make neighbor chunks differ by more than one level, which may show cracks.
+func (t *Terrain) SetLodDistance(dist float32) {
+
+	t.lodDistance = dist
+}
+
+// LodDistance returns the distance from the camera up to which the chunks use the finest level of detail.
+func (t *Terrain) LodDistance() float32 {
+
+	return t.lodDistance
+}
+
+// SetMorphRegion sets the fraction at the end of the distance range of each level of detail
+// where the vertices are morphed to the next coarser level (default = 0.3).
+func (t *Terrain) SetMorphRegion(fraction float32) {
+
+	t.morphRegion = fraction
+}
+
+// MorphRegion returns the fraction at the end of the distance range of each level of
+// detail where the vertices are morphed to the next coarser level.
+func (t *Terrain) MorphRegion() float32 {
+
+	return t.morphRegion
+}
+
+// Update selects the level of detail of each chunk from its distance to the specified camera.
+// It should be called every frame before rendering.
+func (t *Terrain) Update(icam camera.ICamera) {
+
+	var campos math32.Vector3
+	icam.GetCamera().WorldPosition(&campos)
+	t.UpdateMatrixWorld()
+	mw := t.MatrixWorld()
+	for _, c := range t.chunks {
+		box := c.box
+		box.ApplyMatrix4(&mw)
+		dist := box.DistanceToPoint(&campos)
+		level := 0
+		end := t.lodDistance
+		for level < chunkLevels-1 && dist >= end {
+			level++
+			end *= 2
+		}
+		c.setLevel(level, end*(1-t.morphRegion), end)
+	}
+}
+
+// Level returns the current level of detail of the specified chunk,
+// where 0 is the finest level.
+func (t *Terrain) Level(chunk int) int {
+
+	return t.chunks[chunk].level
+}
+
+// HeightAt returns the height of the terrain surface in world coordinates at the specified
+// world X and Z coordinates, which are clamped to the terrain bounds.
+func (t *Terrain) HeightAt(x, z float32) float32 {
+
+	mw := t.MatrixWorld()
+	lx, lz := t.toLocal(&mw, x, z)
+	return t.localHeight(lx, lz)*mw[5] + mw[13]
+}
+
+// NormalAt returns the normal of the terrain surface in world coordinates at the specified
+// world X and Z coordinates interpolated from the normals of the vertices around it.
+func (t *Terrain) NormalAt(x, z float32) math32.Vector3 {
+
+	mw := t.MatrixWorld()
+	lx, lz := t.toLocal(&mw, x, z)
+	i, j, fx, fz := t.cellAt(lx, lz)
+	na := t.sampleNormal(i, j)
+	nb := t.sampleNormal(i, j+1)
+	nc := t.sampleNormal(i+1, j+1)
+	nd := t.sampleNormal(i+1, j)
+
+	// Interpolates the normals of the vertices of the cell triangle under the point
+	var n math32.Vector3
+	if fz >= fx {
+		n.X = na.X + (nb.X-na.X)*fz + (nc.X-nb.X)*fx
+		n.Y = na.Y + (nb.Y-na.Y)*fz + (nc.Y-nb.Y)*fx
+		n.Z = na.Z + (nb.Z-na.Z)*fz + (nc.Z-nb.Z)*fx
+	} else {
+		n.X = na.X + (nd.X-na.X)*fx + (nc.X-nd.X)*fz
+		n.Y = na.Y + (nd.Y-na.Y)*fx + (nc.Y-nd.Y)*fz
+		n.Z = na.Z + (nd.Z-na.Z)*fx + (nc.Z-nd.Z)*fz
+	}
+	// Normals are scaled by the inverse of the node scale
+	n.Set(n.X/mw[0], n.Y/mw[5], n.Z/mw[10])
+	n.Normalize()
+	return n
+}
+
+// toLocal converts the specified world X and Z coordinates to the terrain node coordinates.
+func (t *Terrain) toLocal(mw *math32.Matrix4, x, z float32) (float32, float32) {
+
+	return (x - mw[12]) / mw[0], (z - mw[14]) / mw[10]
+}
+
+// cellAt returns the indices of the cell at the specified X and Z coordinates in the node
+// and the fractions of the coordinates inside the cell, clamped to the terrain bounds.
+func (t *Terrain) cellAt(x, z float32) (int, int, float32, float32) {
+
+	gx := math32.Clamp((x-t.origin.X)/t.cellSize, 0, float32(t.heightmap.width-1))
+	gz := math32.Clamp((z-t.origin.Z)/t.cellSize, 0, float32(t.heightmap.depth-1))
+	i := int(gx)
+	j := int(gz)
+	if i > t.heightmap.width-2 {
+		i = t.heightmap.width - 2
+	}
+	if j > t.heightmap.depth-2 {
+		j = t.heightmap.depth - 2
+	}
+	if i < 0 {
+		i = 0
+	}
+	if j < 0 {
+		j = 0
+	}
+	return i, j, gx - float32(i), gz - float32(j)
+}
+
+// localHeight returns the height of the surface at the specified X and Z coordinates
+// in the node, interpolated over the triangle of the cell under the point.
+func (t *Terrain) localHeight(x, z float32) float32 {
+
+	i, j, fx, fz := t.cellAt(x, z)
+	ha := t.sampleHeight(i, j)
+	hb := t.sampleHeight(i, j+1)
+	hc := t.sampleHeight(i+1, j+1)
+	hd := t.sampleHeight(i+1, j)
+	if fz >= fx {
+		return ha + (hb-ha)*fz + (hc-hb)*fx
+	}
+	return ha + (hd-ha)*fx + (hc-hd)*fz
+}
+
+// sampleHeight returns the height in the node of the specified heightmap sample.
+func (t *Terrain) sampleHeight(x, z int) float32 {
+
+	return t.heightmap.Height(x, z) * t.heightScale
+}
+
+// samplePosition returns the position in the node of the specified heightmap sample.
+func (t *Terrain) samplePosition(x, z int) math32.Vector3 {
+
+	return math32.Vector3{
+		X: t.origin.X + float32(x)*t.cellSize,
+		Y: t.sampleHeight(x, z),
+		Z: t.origin.Z + float32(z)*t.cellSize,
+	}
+}
+
+// sampleNormal returns the normal in the node of the specified heightmap
+// sample from the central differences of the heights around it.
+func (t *Terrain) sampleNormal(x, z int) math32.Vector3 {
+
+	x0, x1 := x-1, x+1
+	if x0 < 0 {
+		x0 = 0
+	}
+	if x1 > t.heightmap.width-1 {
+		x1 = t.heightmap.width - 1
+	}
+	z0, z1 := z-1, z+1
+	if z0 < 0 {
+		z0 = 0
+	}
+	if z1 > t.heightmap.depth-1 {
+		z1 = t.heightmap.depth - 1
+	}
+	var n math32.Vector3
+	if x1 > x0 {
+		n.X = -(t.sampleHeight(x1, z) - t.sampleHeight(x0, z)) / (float32(x1-x0) * t.cellSize)
+	}
+	if z1 > z0 {
+		n.Z = -(t.sampleHeight(x, z1) - t.sampleHeight(x, z0)) / (float32(z1-z0) * t.cellSize)
+	}
+	n.Y = 1
+	n.Normalize()
+	return n
+}
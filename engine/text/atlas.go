@@ -26,15 +26,23 @@ type CharInfo struct {
 	OffsetY float32
 	RepeatX float32
 	RepeatY float32
+	// Glyph metrics of signed distance field atlases in pixels
+	BearingX int     // Horizontal offset of the char image from the pen position
+	BearingY int     // Vertical offset of the char image top from the baseline (negative above)
+	Advance  float32 // Horizontal advance of the pen position
 }
 
 // Atlas represents an image containing characters and the information about their location in the image
 type Atlas struct {
 	Chars   []CharInfo
 	Image   *image.RGBA
-	Height  int // Recommended vertical space between two lines of text
-	Ascent  int // Distance from the top of a line to its base line
-	Descent int // Distance from the bottom of a line to its baseline
+	Height  int     // Recommended vertical space between two lines of text
+	Ascent  int     // Distance from the top of a line to its base line
+	Descent int     // Distance from the bottom of a line to its baseline
+	SDF     bool    // Whether the image alpha channel contains signed distance fields
+	Spread  int     // Maximum distance in pixels from the glyph outlines encoded in the distance fields
+	Size    float64 // Size in pixels of the glyphs of signed distance field atlases
+	sdf     *sdfState
 }
 
 // NewAtlas returns a pointer to a new Atlas object
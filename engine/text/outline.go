@@ -0,0 +1,137 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package text
+
+import (
+	"github.com/golang/freetype/truetype"
+	"github.com/wangzun/gogame/engine/math32"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// Contours returns the outlines of the glyphs of the specified text with the specified
+// font size in units as closed polygons, with the quadratic curves of the glyphs
+// approximated by the specified number of segments.
+// The coordinates have the Y axis up and the origin at the start of the baseline
+// of the first line. Outer contours and holes can be told apart by containment.
+func (f *Font) Contours(text string, size float32, curveSegments int) [][]math32.Vector2 {
+
+	if curveSegments < 1 {
+		curveSegments = 1
+	}
+	// Loads the glyphs with one 26.6 unit per font unit
+	upem := f.ttf.FUnitsPerEm()
+	fscale := fixed.Int26_6(upem << 6)
+	unit := size / float32(upem) / 64
+	bounds := f.ttf.Bounds(fscale)
+	lineHeight := float32(bounds.Max.Y-bounds.Min.Y) * unit
+	spacing := float32(f.attrib.LineSpacing)
+	if spacing <= 0 {
+		spacing = 1
+	}
+
+	contours := make([][]math32.Vector2, 0)
+	var gb truetype.GlyphBuf
+	var penX, penY float32
+	prev := truetype.Index(0)
+	hasPrev := false
+	for _, r := range text {
+		if r == '\n' {
+			penX = 0
+			penY -= lineHeight * spacing
+			hasPrev = false
+			continue
+		}
+		idx := f.ttf.Index(r)
+		if hasPrev {
+			penX += float32(f.ttf.Kern(fscale, prev, idx)) * unit
+		}
+		if err := gb.Load(f.ttf, fscale, idx, font.HintingNone); err == nil {
+			start := 0
+			for _, end := range gb.Ends {
+				c := flattenContour(gb.Points[start:end], curveSegments, unit, penX, penY)
+				if len(c) >= 3 {
+					contours = append(contours, c)
+				}
+				start = end
+			}
+		}
+		penX += float32(f.ttf.HMetric(fscale, idx).AdvanceWidth) * unit
+		prev = idx
+		hasPrev = true
+	}
+	return contours
+}
+
+// flattenContour converts the specified TrueType contour points, which are
+// on curve points or control points of quadratic curves, into a polygon.
+func flattenContour(points []truetype.Point, segments int, unit, dx, dy float32) []math32.Vector2 {
+
+	n := len(points)
+	if n == 0 {
+		return nil
+	}
+	pos := func(i int) math32.Vector2 {
+		p := points[(i+n)%n]
+		return math32.Vector2{float32(p.X)*unit + dx, float32(p.Y)*unit + dy}
+	}
+	onCurve := func(i int) bool {
+		return points[(i+n)%n].Flags&0x01 != 0
+	}
+
+	// Starts at the first on curve point or, when there is none,
+	// at the point implied between the first two control points
+	first := 0
+	var start math32.Vector2
+	for first < n && !onCurve(first) {
+		first++
+	}
+	if first == n {
+		first = 0
+		p0 := pos(0)
+		p1 := pos(1)
+		start = math32.Vector2{(p0.X + p1.X) / 2, (p0.Y + p1.Y) / 2}
+	} else {
+		start = pos(first)
+	}
+
+	poly := []math32.Vector2{start}
+	last := start
+	for k := 1; k <= n; k++ {
+		i := first + k
+		p := pos(i)
+		if onCurve(i) {
+			if p != last {
+				poly = append(poly, p)
+			}
+			last = p
+			continue
+		}
+		// Quadratic curve from the last point with control point p to the
+		// next on curve point or the point implied before the next control point
+		var end math32.Vector2
+		if onCurve(i + 1) {
+			end = pos(i + 1)
+			k++
+		} else {
+			next := pos(i + 1)
+			end = math32.Vector2{(p.X + next.X) / 2, (p.Y + next.Y) / 2}
+		}
+		for s := 1; s <= segments; s++ {
+			t := float32(s) / float32(segments)
+			mt := 1 - t
+			poly = append(poly, math32.Vector2{
+				mt*mt*last.X + 2*mt*t*p.X + t*t*end.X,
+				mt*mt*last.Y + 2*mt*t*p.Y + t*t*end.Y,
+			})
+		}
+		last = end
+	}
+	// Removes the closing point equal to the start
+	if len(poly) > 1 && poly[len(poly)-1] == poly[0] {
+		poly = poly[:len(poly)-1]
+	}
+	return poly
+}
@@ -0,0 +1,357 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package text
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"github.com/golang/freetype/truetype"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// Glyphs are rendered with this factor over the atlas size to calculate the distance fields
+const sdfUpscale = 4
+
+// Largest atlas image height
+const sdfMaxHeight = 4096
+
+// sdfState contains the state used to add glyphs to a signed distance field atlas
+type sdfState struct {
+	face    font.Face          // Face with the atlas size used for metrics
+	hiface  font.Face          // Face with the upscaled size used to render the glyphs
+	glyphs  map[rune]*CharInfo // Glyphs already in the atlas
+	penX    int                // Position of the next glyph in the current row
+	penY    int                // Top of the current row
+	rowH    int                // Height of the current row
+	version int                // Incremented when the image changes
+}
+
+// GlyphQuad describes the position of a glyph in a text layout
+// and its texture coordinates in the atlas image.
+type GlyphQuad struct {
+	X      float32 // Left position in pixels from the left of the text
+	Y      float32 // Top position in pixels from the top of the text
+	Width  float32 // Width in pixels
+	Height float32 // Height in pixels
+	U0     float32 // Left texture coordinate
+	V0     float32 // Top texture coordinate
+	U1     float32 // Right texture coordinate
+	V1     float32 // Bottom texture coordinate
+}
+
+// NewSDFAtlas creates and returns a pointer to a new atlas with the signed distance fields
+// of the glyphs of the specified font generated with the specified size in pixels.
+// The distance fields encode distances up to the specified spread in pixels from the glyph
+// outlines and are stored in the alpha channel of the image, with 0.5 at the outlines.
+// Glyphs are generated on demand by Glyph() and Layout(), so the image may grow and
+// its Version() is incremented when it changes.
+// Text rendered from the atlas remains crisp at any scale.
+func NewSDFAtlas(f *Font, size float64, spread int) *Atlas {
+
+	a := new(Atlas)
+	a.SDF = true
+	a.Size = size
+	a.Spread = spread
+	a.sdf = &sdfState{
+		face:   truetype.NewFace(f.ttf, &truetype.Options{Size: size, DPI: 72, Hinting: font.HintingNone}),
+		hiface: truetype.NewFace(f.ttf, &truetype.Options{Size: size * sdfUpscale, DPI: 72, Hinting: font.HintingNone}),
+		glyphs: make(map[rune]*CharInfo),
+		penX:   1,
+		penY:   1,
+	}
+
+	// Get font metrics
+	metrics := a.sdf.face.Metrics()
+	a.Height = metrics.Height.Ceil()
+	a.Ascent = metrics.Ascent.Ceil()
+	a.Descent = metrics.Descent.Ceil()
+
+	// The image width is enough for about 8 glyphs per row
+	cell := int(math.Ceil(size)) + 2*spread
+	width := 256
+	for width < cell*8 {
+		width *= 2
+	}
+	a.Image = image.NewRGBA(image.Rect(0, 0, width, width/2))
+	draw.Draw(a.Image, a.Image.Bounds(), image.NewUniform(color.RGBA{255, 255, 255, 0}), image.ZP, draw.Src)
+	return a
+}
+
+// Version returns a number which is incremented when glyphs are added to the atlas image.
+func (a *Atlas) Version() int {
+
+	if a.sdf == nil {
+		return 0
+	}
+	return a.sdf.version
+}
+
+// Glyph returns the information of the specified rune in a signed distance
+// field atlas, generating its distance field if necessary.
+// Glyphs which do not fit in the atlas image after it reached its largest height
+// have no image and are laid out as empty space with their advance.
+// It returns nil for atlases created by NewAtlas.
+func (a *Atlas) Glyph(r rune) *CharInfo {
+
+	if a.sdf == nil {
+		return nil
+	}
+	if ci, ok := a.sdf.glyphs[r]; ok {
+		return ci
+	}
+	return a.addGlyph(r)
+}
+
+// Layout appends to the specified slice the quads of the glyphs of the specified text
+// rendered from a signed distance field atlas with the specified size in pixels and
+// returns the updated slice and the width and height of the text in pixels.
+// The text can contain line breaks and the line spacing is in terms of the line height.
+func (a *Atlas) Layout(text string, size, lineSpacing float32, quads []GlyphQuad) ([]GlyphQuad, float32, float32) {
+
+	if a.sdf == nil {
+		return quads, 0, 0
+	}
+	scale := size / float32(a.Size)
+	lineHeight := float32(a.Ascent+a.Descent) * scale
+	baseline := float32(a.Ascent) * scale
+	var penX, width float32
+	height := lineHeight
+	prev := rune(-1)
+	for _, r := range text {
+		if r == '\n' {
+			baseline += lineHeight * lineSpacing
+			height += lineHeight * lineSpacing
+			penX = 0
+			prev = -1
+			continue
+		}
+		ci := a.Glyph(r)
+		if prev >= 0 {
+			penX += float32(a.sdf.face.Kern(prev, r)) / 64 * scale
+		}
+		if ci.Width > 0 {
+			quads = append(quads, GlyphQuad{
+				X:      penX + float32(ci.BearingX)*scale,
+				Y:      baseline + float32(ci.BearingY)*scale,
+				Width:  float32(ci.Width) * scale,
+				Height: float32(ci.Height) * scale,
+				U0:     ci.OffsetX,
+				V0:     ci.OffsetY,
+				U1:     ci.OffsetX + ci.RepeatX,
+				V1:     ci.OffsetY + ci.RepeatY,
+			})
+		}
+		penX += ci.Advance * scale
+		if penX > width {
+			width = penX
+		}
+		prev = r
+	}
+	return quads, width, height
+}
+
+// addGlyph generates the distance field of the specified rune and adds it to the atlas image.
+func (a *Atlas) addGlyph(r rune) *CharInfo {
+
+	s := a.sdf
+	ci := new(CharInfo)
+	s.glyphs[r] = ci
+	adv, _ := s.face.GlyphAdvance(r)
+	ci.Advance = float32(adv) / 64
+	dr, mask, maskp, _, ok := s.hiface.Glyph(fixed.Point26_6{}, r)
+	if !ok || dr.Empty() {
+		return ci
+	}
+
+	// Char image bounds in pixels relative to the pen position on the baseline
+	x0 := floorDiv(dr.Min.X, sdfUpscale) - a.Spread
+	y0 := floorDiv(dr.Min.Y, sdfUpscale) - a.Spread
+	x1 := -floorDiv(-dr.Max.X, sdfUpscale) + a.Spread
+	y1 := -floorDiv(-dr.Max.Y, sdfUpscale) + a.Spread
+	w := x1 - x0
+	h := y1 - y0
+
+	// Glyphs which do not fit in the full atlas image are skipped
+	x, y, ok := a.allocate(w, h)
+	if !ok {
+		return ci
+	}
+
+	// Builds the upscaled grids used to calculate the distances to the inside and outside pixels
+	gw := w * sdfUpscale
+	gh := h * sdfUpscale
+	toInside := make([]float64, gw*gh)
+	toOutside := make([]float64, gw*gh)
+	for gy := 0; gy < gh; gy++ {
+		py := y0*sdfUpscale + gy
+		for gx := 0; gx < gw; gx++ {
+			px := x0*sdfUpscale + gx
+			inside := false
+			if image.Pt(px, py).In(dr) {
+				_, _, _, alpha := mask.At(maskp.X+px-dr.Min.X, maskp.Y+py-dr.Min.Y).RGBA()
+				inside = alpha >= 0x8000
+			}
+			idx := gy*gw + gx
+			if inside {
+				toOutside[idx] = edtInf
+			} else {
+				toInside[idx] = edtInf
+			}
+		}
+	}
+	edt(toInside, gw, gh)
+	edt(toOutside, gw, gh)
+
+	// Samples the signed distances at the center of each atlas pixel
+	ci.X, ci.Y = x, y
+	ci.Width = w
+	ci.Height = h
+	ci.BearingX = x0
+	ci.BearingY = y0
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			idx := (y*sdfUpscale+sdfUpscale/2)*gw + x*sdfUpscale + sdfUpscale/2
+			din := math.Max(math.Sqrt(toInside[idx])-0.5, 0)
+			dout := math.Max(math.Sqrt(toOutside[idx])-0.5, 0)
+			dist := (din - dout) / sdfUpscale
+			v := 0.5 - dist/float64(2*a.Spread)
+			v = math.Min(math.Max(v, 0), 1)
+			a.Image.SetRGBA(ci.X+x, ci.Y+y, color.RGBA{255, 255, 255, uint8(v*255 + 0.5)})
+		}
+	}
+	a.normalize(ci)
+	s.version++
+	return ci
+}
+
+// allocate returns the position in the atlas image for a char image with the
+// specified size, growing the image if necessary, or false if the image is full.
+func (a *Atlas) allocate(w, h int) (int, int, bool) {
+
+	s := a.sdf
+	if w+2 > a.Image.Rect.Dx() {
+		return 0, 0, false
+	}
+	penX, penY, rowH := s.penX, s.penY, s.rowH
+	if penX+w+1 > a.Image.Rect.Dx() {
+		penX = 1
+		penY += rowH + 1
+		rowH = 0
+	}
+	if penY+h+1 > sdfMaxHeight {
+		return 0, 0, false
+	}
+	for penY+h+1 > a.Image.Rect.Dy() {
+		a.grow()
+	}
+	s.penX, s.penY, s.rowH = penX, penY, rowH
+	x, y := s.penX, s.penY
+	s.penX += w + 1
+	if h > s.rowH {
+		s.rowH = h
+	}
+	return x, y, true
+}
+
+// grow doubles the height of the atlas image and updates the normalized positions of its chars.
+func (a *Atlas) grow() {
+
+	old := a.Image
+	a.Image = image.NewRGBA(image.Rect(0, 0, old.Rect.Dx(), old.Rect.Dy()*2))
+	draw.Draw(a.Image, a.Image.Bounds(), image.NewUniform(color.RGBA{255, 255, 255, 0}), image.ZP, draw.Src)
+	copy(a.Image.Pix, old.Pix)
+	for _, ci := range a.sdf.glyphs {
+		a.normalize(ci)
+	}
+}
+
+// normalize updates the normalized position of the specified char in the atlas image.
+func (a *Atlas) normalize(ci *CharInfo) {
+
+	fWidth := float32(a.Image.Rect.Dx())
+	fHeight := float32(a.Image.Rect.Dy())
+	ci.OffsetX = float32(ci.X) / fWidth
+	ci.OffsetY = float32(ci.Y) / fHeight
+	ci.RepeatX = float32(ci.Width) / fWidth
+	ci.RepeatY = float32(ci.Height) / fHeight
+}
+
+// floorDiv returns the floor of the integer division of a by b (b > 0).
+func floorDiv(a, b int) int {
+
+	q := a / b
+	if a%b != 0 && a < 0 {
+		q--
+	}
+	return q
+}
+
+// Value used as infinity by the distance transform
+const edtInf = 1e20
+
+// edt calculates in place the squared euclidean distance transform of the
+// specified grid, where the feature pixels are 0 and the others are edtInf,
+// using the algorithm from Felzenszwalb and Huttenlocher.
+func edt(grid []float64, width, height int) {
+
+	n := width
+	if height > n {
+		n = height
+	}
+	f := make([]float64, n)
+	d := make([]float64, n)
+	v := make([]int, n)
+	z := make([]float64, n+1)
+
+	// Transforms columns
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			f[y] = grid[y*width+x]
+		}
+		edt1d(f, d, v, z, height)
+		for y := 0; y < height; y++ {
+			grid[y*width+x] = d[y]
+		}
+	}
+	// Transforms rows
+	for y := 0; y < height; y++ {
+		copy(f, grid[y*width:(y+1)*width])
+		edt1d(f, d, v, z, width)
+		copy(grid[y*width:(y+1)*width], d[:width])
+	}
+}
+
+// edt1d calculates the one dimensional squared distance transform of the first n elements of f into d.
+func edt1d(f, d []float64, v []int, z []float64, n int) {
+
+	k := 0
+	v[0] = 0
+	z[0] = -edtInf
+	z[1] = edtInf
+	for q := 1; q < n; q++ {
+		fq := f[q] + float64(q*q)
+		s := (fq - (f[v[k]] + float64(v[k]*v[k]))) / float64(2*q-2*v[k])
+		for s <= z[k] {
+			k--
+			s = (fq - (f[v[k]] + float64(v[k]*v[k]))) / float64(2*q-2*v[k])
+		}
+		k++
+		v[k] = q
+		z[k] = s
+		z[k+1] = edtInf
+	}
+	k = 0
+	for q := 0; q < n; q++ {
+		for z[k+1] < float64(q) {
+			k++
+		}
+		dq := float64(q - v[k])
+		d[q] = dq*dq + f[v[k]]
+	}
+}
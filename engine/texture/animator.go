@@ -5,53 +5,187 @@
 package texture
 
 import (
+	"fmt"
 	"time"
 
+	"github.com/wangzun/gogame/engine/core"
 	"github.com/wangzun/gogame/engine/gls"
 )
 
-// Animator can generate a texture animation based on a texture sheet
+// Animator events dispatched with a *ClipEvent parameter
+const (
+	OnClipStart    = "texture.OnClipStart"    // Clip started playing from its first frame
+	OnClipLoop     = "texture.OnClipLoop"     // Clip looped
+	OnClipFinished = "texture.OnClipFinished" // Clip finished playing its number of repeats
+	OnClipFrame    = "texture.OnClipFrame"    // Frame with an event was shown
+)
+
+// Animator can generate a texture animation based on a texture sheet.
+// The frames of the animation are rectangles of the texture, which can be the
+// tiles of a grid or arbitrary regions of an atlas page, and can have their own
+// display times. The animator plays all its frames in order or the frames of one
+// of its named clips, and dispatches events when clips start, loop and finish and
+// when the frames with events are shown.
+// The texture can be used by a graphic.Sprite material or, if it is a texture view
+// with the size of the frames, by a gui.Image.
 type Animator struct {
-	tex       *Texture2D    // pointer to texture being displayed
-	dispTime  time.Duration // disply duration of each tile (default = 1.0/30.0)
-	maxCycles int           // maximum number of cycles (default = 0 - continuous)
-	cycles    int           // current number of complete cycles
-	columns   int           // number of columns
-	rows      int           // number of rows
-	row       int           // current row
-	col       int           // current column
-	tileTime  time.Time     // time when tile started to be displayed
-}
-
-// NewAnimator creates and returns a texture sheet animator for the specified texture.
+	core.Dispatcher                  // Embedded event dispatcher
+	tex             *Texture2D       // pointer to texture being displayed
+	dispTime        time.Duration    // disply duration of each tile (default = 1.0/30.0)
+	maxCycles       int              // maximum number of cycles (default = 0 - continuous)
+	cycles          int              // current number of complete cycles
+	frames          []Frame          // animation frames
+	clips           map[string]*Clip // clips by name
+	clipNames       []string         // clip names in insertion order
+	clip            *Clip            // current clip or nil to play all frames
+	seq             []int            // positions of the frames shown in one cycle
+	idx             int              // current index in the sequence
+	elapsed         time.Duration    // time the current frame has been displayed
+	speed           float32          // playing speed multiplier
+	paused          bool             // whether the animation is paused
+	finished        bool             // whether the current clip finished
+	tileTime        time.Time        // time of the last update
+}
+
+// Frame is a rectangle of the animated texture shown by an animator.
+type Frame struct {
+	OffsetX  float32       // Normalized position X of the frame from the left of the texture
+	OffsetY  float32       // Normalized position Y of the frame from the top of the texture
+	RepeatX  float32       // Normalized width of the frame
+	RepeatY  float32       // Normalized height of the frame
+	Duration time.Duration // Display time of the frame or 0 to use the animator display time
+}
+
+// ClipEvent is the parameter of the events dispatched by an animator.
+type ClipEvent struct {
+	Animator *Animator // Animator which dispatched the event
+	Clip     *Clip     // Clip being played or nil when playing all the frames
+	Name     string    // Event name for OnClipFrame events
+	Pos      int       // Position of the frame shown in the clip
+	Frame    int       // Index of the frame shown in the animator
+}
+
+// NewAnimator creates and returns a texture sheet animator for the specified texture
+// whose frames are the tiles of a grid with the specified number of columns and rows,
+// from left to right and from top to bottom.
 func NewAnimator(tex *Texture2D, htiles, vtiles int) *Animator {
 
-	a := new(Animator)
-	a.tex = tex
-	a.columns = htiles
-	a.rows = vtiles
-	a.dispTime = time.Millisecond * 16
-	a.maxCycles = 0
+	a := NewFrameAnimator(tex)
 
 	// Sets texture properties
 	tex.SetWrapS(gls.REPEAT)
 	tex.SetWrapT(gls.REPEAT)
-	tex.SetRepeat(1/float32(a.columns), 1/float32(a.rows))
 
-	// Initial state
+	a.AddGrid(htiles, vtiles)
 	a.Restart()
 	return a
 }
 
+// NewFrameAnimator creates and returns an animator for the specified texture without frames.
+// The frames are added with AddFrame() or AddGrid() and are shown after calling Restart() or Play().
+func NewFrameAnimator(tex *Texture2D) *Animator {
+
+	a := new(Animator)
+	a.Dispatcher.Initialize()
+	a.tex = tex
+	a.dispTime = time.Millisecond * 16
+	a.maxCycles = 0
+	a.clips = make(map[string]*Clip)
+	a.speed = 1
+	return a
+}
+
+// Texture returns the animated texture.
+func (a *Animator) Texture() *Texture2D {
+
+	return a.tex
+}
+
+// AddFrame adds the specified frame to the animator and returns its index.
+func (a *Animator) AddFrame(f Frame) int {
+
+	a.frames = append(a.frames, f)
+	return len(a.frames) - 1
+}
+
+// AddGrid adds the tiles of a grid over the whole texture with the specified number of
+// columns and rows as frames, from left to right and from top to bottom.
+func (a *Animator) AddGrid(columns, rows int) {
+
+	for row := 0; row < rows; row++ {
+		for col := 0; col < columns; col++ {
+			a.AddFrame(Frame{
+				OffsetX: float32(col) / float32(columns),
+				OffsetY: float32(row) / float32(rows),
+				RepeatX: 1 / float32(columns),
+				RepeatY: 1 / float32(rows),
+			})
+		}
+	}
+}
+
+// Frames returns the frames of the animator.
+func (a *Animator) Frames() []Frame {
+
+	return a.frames
+}
+
+// AddClip adds the specified clip to the animator.
+// A previous clip with the same name is replaced.
+func (a *Animator) AddClip(c *Clip) {
+
+	if _, ok := a.clips[c.name]; !ok {
+		a.clipNames = append(a.clipNames, c.name)
+	}
+	a.clips[c.name] = c
+}
+
+// Clip returns the clip with the specified name or nil if not found.
+func (a *Animator) Clip(name string) *Clip {
+
+	return a.clips[name]
+}
+
+// ClipNames returns the names of the clips of the animator in insertion order.
+func (a *Animator) ClipNames() []string {
+
+	return a.clipNames
+}
+
+// CurrentClip returns the clip being played or nil when playing all the frames.
+func (a *Animator) CurrentClip() *Clip {
+
+	return a.clip
+}
+
+// Play starts playing the clip with the specified name from its first frame.
+// If the clip is already playing and has not finished it continues playing,
+// so it can be called on every frame with the clip corresponding to the state of a character.
+func (a *Animator) Play(name string) error {
+
+	c := a.clips[name]
+	if c == nil {
+		return fmt.Errorf("clip %q not found", name)
+	}
+	if c == a.clip && !a.finished {
+		return nil
+	}
+	a.clip = c
+	a.Restart()
+	return nil
+}
+
 // SetDispTime sets the display time of each tile in milliseconds.
 // The default value is: 1.0/30.0 = 16.6.ms
+// It is used for frames without their own display time.
 func (a *Animator) SetDispTime(dtime time.Duration) {
 
 	a.dispTime = dtime
 }
 
-// SetMaxCycles sets the number of complete cycles to display.
+// SetMaxCycles sets the number of complete cycles to display when playing all the frames.
 // The default value is: 0 (display continuously)
+// Clips have their own number of repeats.
 func (a *Animator) SetMaxCycles(maxCycles int) {
 
 	a.maxCycles = maxCycles
@@ -63,42 +197,161 @@ func (a *Animator) Cycles() int {
 	return a.cycles
 }
 
-// Restart restart the animator
+// SetSpeed sets the playing speed multiplier (default = 1).
+func (a *Animator) SetSpeed(speed float32) {
+
+	a.speed = speed
+}
+
+// Speed returns the playing speed multiplier.
+func (a *Animator) Speed() float32 {
+
+	return a.speed
+}
+
+// SetPaused sets the paused state of the animator.
+func (a *Animator) SetPaused(state bool) {
+
+	a.paused = state
+}
+
+// Paused returns whether the animator is paused.
+func (a *Animator) Paused() bool {
+
+	return a.paused
+}
+
+// Finished returns whether the current clip, or all the frames, finished playing.
+func (a *Animator) Finished() bool {
+
+	return a.finished
+}
+
+// Frame returns the index of the frame being shown or -1 if there are no frames.
+func (a *Animator) Frame() int {
+
+	if len(a.seq) == 0 {
+		return -1
+	}
+	return a.frameIndex(a.seq[a.idx])
+}
+
+// Restart restarts the animator from the first frame of the current clip,
+// or of all the frames if no clip was played.
 func (a *Animator) Restart() {
 
 	// Time of the currently displayed image
 	a.tileTime = time.Now()
-	// Position of current tile to display
-	a.row = 0
-	a.col = 0
+	// Sequence of frames to display
+	if a.clip != nil {
+		a.seq = a.clip.sequence(len(a.clip.frames))
+	} else {
+		c := Clip{}
+		a.seq = c.sequence(len(a.frames))
+	}
+	a.idx = 0
+	a.elapsed = 0
+	a.finished = false
 	// Number of cycles displayed
 	a.cycles = 0
+
+	if len(a.seq) == 0 {
+		return
+	}
+	a.dispatch(OnClipStart, "")
+	a.show()
 }
 
 // Update prepares the next tile to be rendered.
 // Must be called with the current time
 func (a *Animator) Update(now time.Time) {
 
-	// Checks maximum number of cycles
-	if a.maxCycles > 0 && a.cycles >= a.maxCycles {
+	delta := now.Sub(a.tileTime)
+	a.tileTime = now
+	a.Advance(delta)
+}
+
+// Advance advances the animation by the specified time, showing the next frames
+// and dispatching the events of the frames shown and of the clip.
+// It can be used instead of Update() with the frame time of the application.
+func (a *Animator) Advance(delta time.Duration) {
+
+	if a.paused || a.finished || len(a.seq) == 0 {
 		return
 	}
+	a.elapsed += time.Duration(float64(delta) * float64(a.speed))
+	for !a.finished {
+		dtime := a.frames[a.Frame()].Duration
+		if dtime <= 0 {
+			dtime = a.dispTime
+		}
+		if dtime <= 0 || a.elapsed < dtime {
+			break
+		}
+		a.elapsed -= dtime
+		a.next()
+	}
+}
 
-	// If current tile time not reached, do nothing
-	if now.Sub(a.tileTime) < a.dispTime {
+// next shows the next frame of the sequence, looping or finishing the clip at its end.
+func (a *Animator) next() {
+
+	a.idx++
+	if a.idx < len(a.seq) {
+		a.show()
 		return
 	}
-	a.tileTime = now
+	a.cycles++
+	repeat := a.maxCycles
+	pingpong := false
+	if a.clip != nil {
+		repeat = a.clip.repeat
+		pingpong = a.clip.mode == ClipPingPong || a.clip.mode == ClipPingPongReverse
+	}
+	if repeat > 0 && a.cycles >= repeat {
+		// Ping-pong clips end back at their first frame and other clips at their last frame
+		a.finished = true
+		a.elapsed = 0
+		if pingpong && len(a.seq) > 1 {
+			a.idx = 0
+			a.show()
+		} else {
+			a.idx = len(a.seq) - 1
+		}
+		a.dispatch(OnClipFinished, "")
+		return
+	}
+	a.idx = 0
+	a.dispatch(OnClipLoop, "")
+	a.show()
+}
+
+// show sets the texture offset and repeat of the current frame
+// and dispatches the events of the frame.
+func (a *Animator) show() {
 
-	// Sets the position of the next tile to show
-	a.tex.SetOffset(float32(a.col)/float32(a.columns), float32(a.row)/float32(a.rows))
-	a.col += 1
-	if a.col >= a.columns {
-		a.col = 0
-		a.row += 1
-		if a.row >= a.rows {
-			a.row = 0
-			a.cycles += 1
+	f := a.frames[a.Frame()]
+	a.tex.SetOffset(f.OffsetX, f.OffsetY)
+	a.tex.SetRepeat(f.RepeatX, f.RepeatY)
+	if a.clip != nil {
+		for _, name := range a.clip.events[a.seq[a.idx]] {
+			a.dispatch(OnClipFrame, name)
 		}
 	}
 }
+
+// frameIndex returns the index of the animator frame at the specified position of the current clip.
+func (a *Animator) frameIndex(pos int) int {
+
+	if a.clip != nil {
+		return a.clip.frames[pos]
+	}
+	return pos
+}
+
+// dispatch dispatches the specified event for the current frame.
+func (a *Animator) dispatch(evname, name string) {
+
+	ev := ClipEvent{Animator: a, Clip: a.clip, Name: name, Pos: a.seq[a.idx], Frame: a.Frame()}
+	a.Dispatch(evname, &ev)
+}
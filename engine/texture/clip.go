@@ -0,0 +1,133 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package texture
+
+// ClipMode specifies the order in which the frames of a clip are played.
+type ClipMode int
+
+// Clip play modes
+const (
+	ClipForward         ClipMode = iota // From the first to the last frame
+	ClipReverse                         // From the last to the first frame
+	ClipPingPong                        // From the first to the last frame and back
+	ClipPingPongReverse                 // From the last to the first frame and back
+)
+
+// Clip is a named sequence of frames of an Animator, such as the "idle", "run" or
+// "attack" animation of a 2D character, which can have named events on its frames,
+// such as the frame in which an attack hits.
+type Clip struct {
+	name   string           // Clip name
+	frames []int            // Indices of the animator frames
+	mode   ClipMode         // Play mode
+	repeat int              // Number of times the clip is played or 0 to loop continuously
+	events map[int][]string // Event names by position of the frame in the clip
+}
+
+// NewClip creates and returns a pointer to a new clip with the specified name
+// which plays the animator frames with the specified indices.
+// By default the clip is played forward continuously.
+func NewClip(name string, frames ...int) *Clip {
+
+	c := new(Clip)
+	c.name = name
+	c.frames = frames
+	c.events = make(map[int][]string)
+	return c
+}
+
+// NewClipRange creates and returns a pointer to a new clip with the specified name
+// which plays the animator frames from the first to the last specified indices, inclusive.
+func NewClipRange(name string, first, last int) *Clip {
+
+	frames := make([]int, 0)
+	if first <= last {
+		for i := first; i <= last; i++ {
+			frames = append(frames, i)
+		}
+	} else {
+		for i := first; i >= last; i-- {
+			frames = append(frames, i)
+		}
+	}
+	return NewClip(name, frames...)
+}
+
+// Name returns the name of the clip.
+func (c *Clip) Name() string {
+
+	return c.name
+}
+
+// Frames returns the indices of the animator frames played by the clip.
+func (c *Clip) Frames() []int {
+
+	return c.frames
+}
+
+// SetMode sets the order in which the frames are played (default = ClipForward).
+func (c *Clip) SetMode(mode ClipMode) {
+
+	c.mode = mode
+}
+
+// Mode returns the order in which the frames are played.
+func (c *Clip) Mode() ClipMode {
+
+	return c.mode
+}
+
+// SetRepeat sets the number of times the clip is played before finishing,
+// such as 1 to play it once, or 0 to loop continuously (default).
+// A ping-pong clip plays its frames forward and back each time.
+func (c *Clip) SetRepeat(repeat int) {
+
+	c.repeat = repeat
+}
+
+// Repeat returns the number of times the clip is played or 0 if it loops continuously.
+func (c *Clip) Repeat() int {
+
+	return c.repeat
+}
+
+// AddEvent adds an event with the specified name to the frame at the specified
+// position in the clip, which is dispatched by the animator when the frame is shown.
+func (c *Clip) AddEvent(pos int, name string) {
+
+	c.events[pos] = append(c.events[pos], name)
+}
+
+// Events returns the names of the events of the frame at the specified position in the clip.
+func (c *Clip) Events(pos int) []string {
+
+	return c.events[pos]
+}
+
+// ClearEvents removes all the events of the clip.
+func (c *Clip) ClearEvents() {
+
+	c.events = make(map[int][]string)
+}
+
+// sequence returns the positions of the clip frames shown in one cycle of the clip.
+func (c *Clip) sequence(count int) []int {
+
+	seq := make([]int, 0, 2*count)
+	forward := c.mode == ClipForward || c.mode == ClipPingPong
+	for i := 0; i < count; i++ {
+		if forward {
+			seq = append(seq, i)
+		} else {
+			seq = append(seq, count-1-i)
+		}
+	}
+	if c.mode == ClipPingPong || c.mode == ClipPingPongReverse {
+		for i := count - 2; i > 0; i-- {
+			seq = append(seq, seq[i])
+		}
+	}
+	return seq
+}
@@ -0,0 +1,308 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package texture
+
+import (
+	"fmt"
+
+	"github.com/wangzun/gogame/engine/gls"
+)
+
+// ETC1 and ETC2 modifier tables
+var etcModifiers = [8][4]int{
+	{2, 8, -2, -8},
+	{5, 17, -5, -17},
+	{9, 29, -9, -29},
+	{13, 42, -13, -42},
+	{18, 60, -18, -60},
+	{24, 80, -24, -80},
+	{33, 106, -33, -106},
+	{47, 183, -47, -183},
+}
+
+// ETC2 T and H modes distance table
+var etcDistances = [8]int{3, 6, 11, 16, 23, 32, 41, 64}
+
+// EAC alpha modifier tables
+var eacModifiers = [16][8]int{
+	{-3, -6, -9, -15, 2, 5, 8, 14},
+	{-3, -7, -10, -13, 2, 6, 9, 12},
+	{-2, -5, -8, -13, 1, 4, 7, 12},
+	{-2, -4, -6, -13, 1, 3, 5, 12},
+	{-3, -6, -8, -12, 2, 5, 7, 11},
+	{-3, -7, -9, -11, 2, 6, 8, 10},
+	{-4, -7, -8, -11, 3, 6, 7, 10},
+	{-3, -5, -8, -11, 2, 4, 7, 10},
+	{-2, -6, -8, -10, 1, 5, 7, 9},
+	{-2, -5, -8, -10, 1, 4, 7, 9},
+	{-2, -4, -8, -10, 1, 3, 7, 9},
+	{-2, -5, -7, -10, 1, 4, 6, 9},
+	{-3, -4, -7, -10, 2, 3, 6, 9},
+	{-1, -2, -3, -10, 0, 1, 2, 9},
+	{-4, -6, -8, -9, 3, 5, 7, 8},
+	{-3, -5, -7, -9, 2, 4, 6, 8},
+}
+
+// canDecodeETC returns whether the specified compressed format can be decoded by DecodeETC.
+func canDecodeETC(format uint32) bool {
+
+	switch format {
+	case gls.ETC1_RGB8_OES,
+		gls.COMPRESSED_RGB8_ETC2, gls.COMPRESSED_SRGB8_ETC2,
+		gls.COMPRESSED_RGB8_PUNCHTHROUGH_ALPHA1_ETC2, gls.COMPRESSED_SRGB8_PUNCHTHROUGH_ALPHA1_ETC2,
+		gls.COMPRESSED_RGBA8_ETC2_EAC, gls.COMPRESSED_SRGB8_ALPHA8_ETC2_EAC:
+		return true
+	}
+	return false
+}
+
+// DecodeETC decodes an image level in the ETC1, ETC2 RGB, ETC2 RGB with punchthrough alpha
+// or ETC2 RGBA with EAC alpha formats into RGBA8 pixels.
+// It is used when the GPU does not support these formats.
+func DecodeETC(format uint32, width, height int, data []byte) ([]byte, error) {
+
+	if !canDecodeETC(format) {
+		return nil, fmt.Errorf("format:0x%X cannot be decoded", format)
+	}
+	blockSize := 8
+	if format == gls.COMPRESSED_RGBA8_ETC2_EAC || format == gls.COMPRESSED_SRGB8_ALPHA8_ETC2_EAC {
+		blockSize = 16
+	}
+	punch := format == gls.COMPRESSED_RGB8_PUNCHTHROUGH_ALPHA1_ETC2 || format == gls.COMPRESSED_SRGB8_PUNCHTHROUGH_ALPHA1_ETC2
+	bw := (width + 3) / 4
+	bh := (height + 3) / 4
+	if len(data) < bw*bh*blockSize {
+		return nil, fmt.Errorf("ETC data size:%d smaller than expected:%d", len(data), bw*bh*blockSize)
+	}
+
+	rgba := make([]byte, width*height*4)
+	var block [16][4]uint8
+	pos := 0
+	for by := 0; by < bh; by++ {
+		for bx := 0; bx < bw; bx++ {
+			if blockSize == 16 {
+				decodeETC2Block(data[pos+8:pos+16], false, &block)
+				decodeEACAlpha(data[pos:pos+8], &block)
+			} else {
+				decodeETC2Block(data[pos:pos+8], punch, &block)
+			}
+			pos += blockSize
+			// Copies block pixels (indexed by x*4+y) to the image
+			for x := 0; x < 4; x++ {
+				px := bx*4 + x
+				if px >= width {
+					break
+				}
+				for y := 0; y < 4; y++ {
+					py := by*4 + y
+					if py >= height {
+						break
+					}
+					copy(rgba[(py*width+px)*4:], block[x*4+y][:])
+				}
+			}
+		}
+	}
+	return rgba, nil
+}
+
+// decodeETC2Block decodes a 64 bits ETC1/ETC2 RGB block into 16 RGBA pixels indexed by x*4+y.
+func decodeETC2Block(src []byte, punch bool, out *[16][4]uint8) {
+
+	msb := uint32(src[4])<<8 | uint32(src[5])
+	lsb := uint32(src[6])<<8 | uint32(src[7])
+	index := func(i int) int {
+		return int((msb>>uint(i))&1)<<1 | int((lsb>>uint(i))&1)
+	}
+	diff := src[3]&0x02 != 0
+	flip := src[3]&0x01 != 0
+	// In punchthrough mode the diff bit is the opaque bit and the individual mode does not exist
+	opaque := true
+	if punch {
+		opaque = diff
+		diff = true
+	}
+
+	if diff {
+		r := int(src[0] >> 3)
+		g := int(src[1] >> 3)
+		b := int(src[2] >> 3)
+		dr := signExtend3(int(src[0] & 7))
+		dg := signExtend3(int(src[1] & 7))
+		db := signExtend3(int(src[2] & 7))
+
+		// T mode
+		if r+dr < 0 || r+dr > 31 {
+			var paint [4][3]int
+			c1 := [3]int{
+				extend4(int((src[0]&0x18)>>1 | src[0]&0x03)),
+				extend4(int(src[1] >> 4)),
+				extend4(int(src[1] & 0x0F)),
+			}
+			c2 := [3]int{extend4(int(src[2] >> 4)), extend4(int(src[2] & 0x0F)), extend4(int(src[3] >> 4))}
+			d := etcDistances[int((src[3]&0x0C)>>1|src[3]&0x01)]
+			for c := 0; c < 3; c++ {
+				paint[0][c] = c1[c]
+				paint[1][c] = c2[c] + d
+				paint[2][c] = c2[c]
+				paint[3][c] = c2[c] - d
+			}
+			etcPaint(&paint, index, punch && !opaque, out)
+			return
+		}
+
+		// H mode
+		if g+dg < 0 || g+dg > 31 {
+			var paint [4][3]int
+			c1 := [3]int{
+				extend4(int((src[0] & 0x78) >> 3)),
+				extend4(int((src[0]&0x07)<<1 | (src[1]&0x10)>>4)),
+				extend4(int(src[1]&0x08 | (src[1]&0x03)<<1 | (src[2]&0x80)>>7)),
+			}
+			c2 := [3]int{
+				extend4(int((src[2] & 0x78) >> 3)),
+				extend4(int((src[2]&0x07)<<1 | (src[3]&0x80)>>7)),
+				extend4(int((src[3] & 0x78) >> 3)),
+			}
+			didx := int(src[3]&0x04 | (src[3]&0x01)<<1)
+			if c1[0]<<16|c1[1]<<8|c1[2] >= c2[0]<<16|c2[1]<<8|c2[2] {
+				didx |= 1
+			}
+			d := etcDistances[didx]
+			for c := 0; c < 3; c++ {
+				paint[0][c] = c1[c] + d
+				paint[1][c] = c1[c] - d
+				paint[2][c] = c2[c] + d
+				paint[3][c] = c2[c] - d
+			}
+			etcPaint(&paint, index, punch && !opaque, out)
+			return
+		}
+
+		// Planar mode
+		if b+db < 0 || b+db > 31 {
+			o := [3]int{
+				extend6(int((src[0] & 0x7E) >> 1)),
+				extend7(int((src[0]&0x01)<<6 | (src[1]&0x7E)>>1)),
+				extend6(int((src[1]&0x01)<<5 | src[2]&0x18 | (src[2]&0x03)<<1 | (src[3]&0x80)>>7)),
+			}
+			h := [3]int{
+				extend6(int((src[3]&0x7C)>>1 | src[3]&0x01)),
+				extend7(int((src[4] & 0xFE) >> 1)),
+				extend6(int((src[4]&0x01)<<5 | (src[5]&0xF8)>>3)),
+			}
+			v := [3]int{
+				extend6(int((src[5]&0x07)<<3 | (src[6]&0xE0)>>5)),
+				extend7(int((src[6]&0x1F)<<2 | (src[7]&0xC0)>>6)),
+				extend6(int(src[7] & 0x3F)),
+			}
+			for x := 0; x < 4; x++ {
+				for y := 0; y < 4; y++ {
+					p := &out[x*4+y]
+					for c := 0; c < 3; c++ {
+						p[c] = clamp255((x*(h[c]-o[c]) + y*(v[c]-o[c]) + 4*o[c] + 2) >> 2)
+					}
+					p[3] = 255
+				}
+			}
+			return
+		}
+	}
+
+	// Individual or differential mode with two sub blocks
+	var base [2][3]int
+	if diff {
+		for c := 0; c < 3; c++ {
+			v := int(src[c] >> 3)
+			base[0][c] = extend5(v)
+			base[1][c] = extend5(v + signExtend3(int(src[c]&7)))
+		}
+	} else {
+		for c := 0; c < 3; c++ {
+			base[0][c] = extend4(int(src[c] >> 4))
+			base[1][c] = extend4(int(src[c] & 0x0F))
+		}
+	}
+	tables := [2]int{int(src[3] >> 5), int((src[3] >> 2) & 7)}
+	for x := 0; x < 4; x++ {
+		for y := 0; y < 4; y++ {
+			sub := 0
+			if (flip && y >= 2) || (!flip && x >= 2) {
+				sub = 1
+			}
+			i := x*4 + y
+			idx := index(i)
+			p := &out[i]
+			if punch && !opaque && idx == 2 {
+				*p = [4]uint8{0, 0, 0, 0}
+				continue
+			}
+			mod := etcModifiers[tables[sub]][idx]
+			if punch && !opaque && idx == 0 {
+				mod = 0
+			}
+			for c := 0; c < 3; c++ {
+				p[c] = clamp255(base[sub][c] + mod)
+			}
+			p[3] = 255
+		}
+	}
+}
+
+// etcPaint sets the pixels of a T or H mode block from its paint colors.
+func etcPaint(paint *[4][3]int, index func(int) int, transparent bool, out *[16][4]uint8) {
+
+	for i := 0; i < 16; i++ {
+		idx := index(i)
+		if transparent && idx == 2 {
+			out[i] = [4]uint8{0, 0, 0, 0}
+			continue
+		}
+		out[i] = [4]uint8{clamp255(paint[idx][0]), clamp255(paint[idx][1]), clamp255(paint[idx][2]), 255}
+	}
+}
+
+// decodeEACAlpha decodes a 64 bits EAC alpha block into the alpha of 16 pixels indexed by x*4+y.
+func decodeEACAlpha(src []byte, out *[16][4]uint8) {
+
+	base := int(src[0])
+	mul := int(src[1] >> 4)
+	table := int(src[1] & 0x0F)
+	var bits uint64
+	for i := 2; i < 8; i++ {
+		bits = bits<<8 | uint64(src[i])
+	}
+	for i := 0; i < 16; i++ {
+		idx := int((bits >> uint(45-3*i)) & 7)
+		out[i][3] = clamp255(base + eacModifiers[table][idx]*mul)
+	}
+}
+
+func signExtend3(v int) int {
+
+	if v >= 4 {
+		return v - 8
+	}
+	return v
+}
+
+func extend4(v int) int { return v<<4 | v }
+
+func extend5(v int) int { return v<<3 | v>>2 }
+
+func extend6(v int) int { return v<<2 | v>>4 }
+
+func extend7(v int) int { return v<<1 | v>>6 }
+
+func clamp255(v int) uint8 {
+
+	if v < 0 {
+		return 0
+	}
+	if v > 255 {
+		return 255
+	}
+	return uint8(v)
+}
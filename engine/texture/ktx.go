@@ -0,0 +1,214 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package texture
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/wangzun/gogame/engine/gls"
+)
+
+// CompressedImage contains the data of a texture image in a GPU
+// compressed format with all its mipmap levels.
+type CompressedImage struct {
+	Format uint32   // OpenGL compressed internal format
+	Width  int      // Width of the base level in pixels
+	Height int      // Height of the base level in pixels
+	Levels [][]byte // Data of each mipmap level starting from the base level
+}
+
+// KTX file identifiers
+var (
+	ktx1Identifier = []byte{0xAB, 'K', 'T', 'X', ' ', '1', '1', 0xBB, '\r', '\n', 0x1A, '\n'}
+	ktx2Identifier = []byte{0xAB, 'K', 'T', 'X', ' ', '2', '0', 0xBB, '\r', '\n', 0x1A, '\n'}
+)
+
+// ktx1Header is the header of a KTX 1.1 file after its identifier
+type ktx1Header struct {
+	Endianness            uint32
+	GlType                uint32
+	GlTypeSize            uint32
+	GlFormat              uint32
+	GlInternalFormat      uint32
+	GlBaseInternalFormat  uint32
+	PixelWidth            uint32
+	PixelHeight           uint32
+	PixelDepth            uint32
+	NumberOfArrayElements uint32
+	NumberOfFaces         uint32
+	NumberOfMipmapLevels  uint32
+	BytesOfKeyValueData   uint32
+}
+
+// ktx2Header is the header of a KTX 2.0 file after its identifier, including the index
+type ktx2Header struct {
+	VkFormat               uint32
+	TypeSize               uint32
+	PixelWidth             uint32
+	PixelHeight            uint32
+	PixelDepth             uint32
+	LayerCount             uint32
+	FaceCount              uint32
+	LevelCount             uint32
+	SupercompressionScheme uint32
+	DfdByteOffset          uint32
+	DfdByteLength          uint32
+	KvdByteOffset          uint32
+	KvdByteLength          uint32
+	SgdByteOffset          uint64
+	SgdByteLength          uint64
+}
+
+// ktx2Level is an entry of the KTX 2.0 levels index
+type ktx2Level struct {
+	ByteOffset             uint64
+	ByteLength             uint64
+	UncompressedByteLength uint64
+}
+
+// Maps Vulkan formats used by KTX 2.0 files to OpenGL compressed internal formats
+var ktx2Formats = map[uint32]uint32{
+	147:        gls.COMPRESSED_RGB8_ETC2,
+	148:        gls.COMPRESSED_SRGB8_ETC2,
+	149:        gls.COMPRESSED_RGB8_PUNCHTHROUGH_ALPHA1_ETC2,
+	150:        gls.COMPRESSED_SRGB8_PUNCHTHROUGH_ALPHA1_ETC2,
+	151:        gls.COMPRESSED_RGBA8_ETC2_EAC,
+	152:        gls.COMPRESSED_SRGB8_ALPHA8_ETC2_EAC,
+	153:        gls.COMPRESSED_R11_EAC,
+	154:        gls.COMPRESSED_SIGNED_R11_EAC,
+	155:        gls.COMPRESSED_RG11_EAC,
+	156:        gls.COMPRESSED_SIGNED_RG11_EAC,
+	1000054000: gls.COMPRESSED_RGBA_PVRTC_2BPPV1_IMG,
+	1000054001: gls.COMPRESSED_RGBA_PVRTC_4BPPV1_IMG,
+}
+
+func init() {
+
+	// ASTC formats are in the same order in Vulkan (interleaved UNORM/SRGB) and OpenGL
+	for i := uint32(0); i < 14; i++ {
+		ktx2Formats[157+2*i] = gls.COMPRESSED_RGBA_ASTC_4x4_KHR + i
+		ktx2Formats[158+2*i] = gls.COMPRESSED_SRGB8_ALPHA8_ASTC_4x4_KHR + i
+	}
+}
+
+// IsKTX returns whether the specified data starts with a KTX 1.1 or KTX 2.0 file identifier.
+func IsKTX(data []byte) bool {
+
+	return bytes.HasPrefix(data, ktx1Identifier) || bytes.HasPrefix(data, ktx2Identifier)
+}
+
+// LoadKTX reads and decodes the specified KTX 1.1 or KTX 2.0 file.
+func LoadKTX(fpath string) (*CompressedImage, error) {
+
+	data, err := ioutil.ReadFile(fpath)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeKTX(data)
+}
+
+// DecodeKTX decodes the specified KTX 1.1 or KTX 2.0 file data
+// with a 2D compressed texture and its mipmap levels.
+// Array, cube map and 3D textures and KTX 2.0 supercompressed
+// (Basis Universal) data are not supported.
+func DecodeKTX(data []byte) (*CompressedImage, error) {
+
+	if bytes.HasPrefix(data, ktx1Identifier) {
+		return decodeKTX1(data[len(ktx1Identifier):])
+	}
+	if bytes.HasPrefix(data, ktx2Identifier) {
+		return decodeKTX2(data)
+	}
+	return nil, fmt.Errorf("invalid KTX identifier")
+}
+
+// decodeKTX1 decodes the KTX 1.1 data after its identifier.
+func decodeKTX1(data []byte) (*CompressedImage, error) {
+
+	// Checks endianness
+	var h ktx1Header
+	var order binary.ByteOrder = binary.LittleEndian
+	if len(data) < binary.Size(&h) {
+		return nil, fmt.Errorf("KTX header too short")
+	}
+	if binary.LittleEndian.Uint32(data) != 0x04030201 {
+		order = binary.BigEndian
+	}
+	binary.Read(bytes.NewReader(data), order, &h)
+
+	if h.GlType != 0 || h.GlFormat != 0 {
+		return nil, fmt.Errorf("KTX uncompressed textures are not supported")
+	}
+	if h.PixelDepth > 1 || h.NumberOfArrayElements > 0 || h.NumberOfFaces > 1 {
+		return nil, fmt.Errorf("only KTX 2D textures are supported")
+	}
+	levels := int(h.NumberOfMipmapLevels)
+	if levels == 0 {
+		levels = 1
+	}
+
+	ci := &CompressedImage{Format: h.GlInternalFormat, Width: int(h.PixelWidth), Height: int(h.PixelHeight)}
+	pos := binary.Size(&h) + int(h.BytesOfKeyValueData)
+	for i := 0; i < levels; i++ {
+		if pos+4 > len(data) {
+			return nil, fmt.Errorf("KTX level:%d truncated", i)
+		}
+		size := int(order.Uint32(data[pos:]))
+		pos += 4
+		if pos+size > len(data) {
+			return nil, fmt.Errorf("KTX level:%d truncated", i)
+		}
+		ci.Levels = append(ci.Levels, data[pos:pos+size])
+		// Level data is padded to 4 bytes
+		pos += (size + 3) &^ 3
+	}
+	return ci, nil
+}
+
+// decodeKTX2 decodes the KTX 2.0 data including its identifier.
+func decodeKTX2(data []byte) (*CompressedImage, error) {
+
+	var h ktx2Header
+	pos := len(ktx2Identifier)
+	if len(data) < pos+binary.Size(&h) {
+		return nil, fmt.Errorf("KTX2 header too short")
+	}
+	binary.Read(bytes.NewReader(data[pos:]), binary.LittleEndian, &h)
+	pos += binary.Size(&h)
+
+	if h.SupercompressionScheme != 0 {
+		return nil, fmt.Errorf("KTX2 supercompression scheme:%d not supported", h.SupercompressionScheme)
+	}
+	if h.PixelDepth > 1 || h.LayerCount > 1 || h.FaceCount > 1 {
+		return nil, fmt.Errorf("only KTX2 2D textures are supported")
+	}
+	format, ok := ktx2Formats[h.VkFormat]
+	if !ok {
+		return nil, fmt.Errorf("KTX2 format:%d not supported", h.VkFormat)
+	}
+	levels := int(h.LevelCount)
+	if levels == 0 {
+		levels = 1
+	}
+
+	ci := &CompressedImage{Format: format, Width: int(h.PixelWidth), Height: int(h.PixelHeight)}
+	var level ktx2Level
+	lsize := binary.Size(&level)
+	if pos+levels*lsize > len(data) {
+		return nil, fmt.Errorf("KTX2 levels index truncated")
+	}
+	for i := 0; i < levels; i++ {
+		binary.Read(bytes.NewReader(data[pos+i*lsize:]), binary.LittleEndian, &level)
+		size := uint64(len(data))
+		if level.ByteOffset > size || level.ByteLength > size-level.ByteOffset {
+			return nil, fmt.Errorf("KTX2 level:%d truncated", i)
+		}
+		ci.Levels = append(ci.Levels, data[level.ByteOffset:level.ByteOffset+level.ByteLength])
+	}
+	return ci, nil
+}
@@ -0,0 +1,287 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package texture
+
+import (
+	"errors"
+	"image"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/wangzun/gogame/engine/gls"
+	"golang.org/x/mobile/gl"
+)
+
+// Loader loads textures asynchronously. Images are decoded by worker goroutines and
+// the decoded data is transferred to OpenGL by Update(), which must be called from the
+// render goroutine once per frame, uploading at most the configured number of bytes
+// per frame. Large images are uploaded in strips of rows during several frames.
+// Textures returned by the loader show a placeholder color until their data is uploaded.
+type Loader struct {
+	budget      int        // Maximum number of bytes uploaded per frame
+	placeholder [4]uint8   // Placeholder color RGBA
+	mutex       sync.Mutex // Protects the fields below
+	cond        *sync.Cond // Signals the workers new jobs or closing
+	queue       []*loadJob // Jobs waiting to be decoded
+	decoded     []*loadJob // Jobs waiting to be uploaded
+	pending     int        // Number of jobs not finished
+	closed      bool       // Loader closed flag
+	current     *loadJob   // Job being uploaded (render goroutine only)
+}
+
+// errClosed is the error passed to the ready callbacks of textures not loaded because the loader was closed
+var errClosed = errors.New("texture loader closed")
+
+// loadJob describes a texture being loaded
+type loadJob struct {
+	tex     *Texture2D                      // Texture returned to the user
+	imgfile string                          // Image file path
+	mipmaps bool                            // Generate mipmaps flag
+	ready   func(tex *Texture2D, err error) // Ready callback
+	rgba    *image.RGBA                     // Decoded image
+	ci      *CompressedImage                // Decoded compressed image
+	err     error                           // Decoding error
+	texname gl.Texture                      // OpenGL texture being uploaded
+	row     int                             // Next row to upload
+}
+
+// NewLoader creates and returns a pointer to a new texture loader with
+// the specified number of decoding worker goroutines.
+// The default upload budget is 1MB per frame.
+func NewLoader(workers int) *Loader {
+
+	l := new(Loader)
+	l.budget = 1 << 20
+	l.placeholder = [4]uint8{128, 128, 128, 255}
+	l.cond = sync.NewCond(&l.mutex)
+	if workers < 1 {
+		workers = 1
+	}
+	for i := 0; i < workers; i++ {
+		go l.worker()
+	}
+	return l
+}
+
+// SetBudget sets the maximum number of bytes of texture data uploaded per frame.
+// At least one row of an image is always uploaded per frame.
+func (l *Loader) SetBudget(bytes int) {
+
+	l.budget = bytes
+}
+
+// Budget returns the maximum number of bytes of texture data uploaded per frame.
+func (l *Loader) Budget() int {
+
+	return l.budget
+}
+
+// SetPlaceholderColor sets the color of the placeholder of the textures loaded after this call.
+func (l *Loader) SetPlaceholderColor(r, g, b, a uint8) {
+
+	l.placeholder = [4]uint8{r, g, b, a}
+}
+
+// Load starts loading the specified image file and returns immediately a texture which
+// shows the placeholder color until the image is decoded and uploaded.
+// PNG, JPEG, GIF and KTX files are supported.
+// If mipmaps is true, mipmaps are generated after the upload.
+// The optional ready callback is called from Update() when the texture data
+// has been uploaded or if an error occurred. If the loader was closed the texture is not
+// loaded and the callback is called immediately with an error.
+func (l *Loader) Load(imgfile string, mipmaps bool, ready func(tex *Texture2D, err error)) *Texture2D {
+
+	tex := newTexture2D()
+	pixel := l.placeholder
+	tex.SetData(1, 1, gls.RGBA, gls.UNSIGNED_BYTE, gls.RGBA8, pixel[:])
+	if !mipmaps {
+		tex.genMipmap = false
+		tex.SetMinFilter(gls.LINEAR)
+	}
+
+	l.mutex.Lock()
+	if l.closed {
+		l.mutex.Unlock()
+		if ready != nil {
+			ready(tex, errClosed)
+		}
+		return tex
+	}
+	l.queue = append(l.queue, &loadJob{tex: tex, imgfile: imgfile, mipmaps: mipmaps, ready: ready})
+	l.pending++
+	l.mutex.Unlock()
+	l.cond.Signal()
+	return tex
+}
+
+// Pending returns the number of textures which are still being decoded or uploaded.
+func (l *Loader) Pending() int {
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.pending
+}
+
+// Close stops the loader workers. Textures whose decoding has not started are not
+// loaded and their ready callbacks are called with an error.
+func (l *Loader) Close() {
+
+	l.mutex.Lock()
+	l.closed = true
+	l.pending -= len(l.queue)
+	dropped := l.queue
+	l.queue = nil
+	l.mutex.Unlock()
+	l.cond.Broadcast()
+	for _, job := range dropped {
+		if job.ready != nil {
+			job.ready(job.tex, errClosed)
+		}
+	}
+}
+
+// worker decodes the queued images until the loader is closed.
+func (l *Loader) worker() {
+
+	for {
+		l.mutex.Lock()
+		for len(l.queue) == 0 && !l.closed {
+			l.cond.Wait()
+		}
+		if l.closed {
+			l.mutex.Unlock()
+			return
+		}
+		job := l.queue[0]
+		l.queue = l.queue[1:]
+		l.mutex.Unlock()
+
+		ext := strings.ToLower(filepath.Ext(job.imgfile))
+		if ext == ".ktx" || ext == ".ktx2" {
+			job.ci, job.err = LoadKTX(job.imgfile)
+		} else {
+			job.rgba, job.err = DecodeImage(job.imgfile)
+		}
+
+		l.mutex.Lock()
+		l.decoded = append(l.decoded, job)
+		l.mutex.Unlock()
+	}
+}
+
+// Update uploads the decoded images data to OpenGL within the per frame budget and
+// calls the ready callbacks of the finished textures.
+// It must be called from the render goroutine once per frame before rendering.
+func (l *Loader) Update(gs *gls.GLS) {
+
+	budget := l.budget
+	for {
+		// Gets the next decoded job
+		if l.current == nil {
+			l.mutex.Lock()
+			if len(l.decoded) == 0 {
+				l.mutex.Unlock()
+				return
+			}
+			job := l.decoded[0]
+			l.decoded = l.decoded[1:]
+			l.mutex.Unlock()
+
+			if job.err != nil {
+				log.Error("loading texture:%s: %v", job.imgfile, job.err)
+				l.finish(job)
+				continue
+			}
+			// Compressed images are uploaded at once with all their levels
+			if job.ci != nil {
+				if budget <= 0 {
+					l.mutex.Lock()
+					l.decoded = append([]*loadJob{job}, l.decoded...)
+					l.mutex.Unlock()
+					return
+				}
+				job.tex.SetCompressed(job.ci)
+				job.tex.bind(gs, 0)
+				for _, level := range job.ci.Levels {
+					budget -= len(level)
+				}
+				l.finish(job)
+				continue
+			}
+			// Allocates the OpenGL texture storage
+			job.texname = gs.GenTexture()
+			gs.ActiveTexture(gls.TEXTURE0)
+			gs.BindTexture(gls.TEXTURE_2D, job.texname)
+			size := job.rgba.Rect.Size()
+			gs.TexImage2D(gls.TEXTURE_2D, 0, gls.RGBA8, int32(size.X), int32(size.Y), 0, gls.RGBA, gls.UNSIGNED_BYTE, nil)
+			l.current = job
+		}
+		if budget <= 0 {
+			return
+		}
+
+		// Uploads the next strip of rows of the current job
+		job := l.current
+		size := job.rgba.Rect.Size()
+		rowBytes := size.X * 4
+		rows := 1
+		if rowBytes > 0 && budget/rowBytes > 1 {
+			rows = budget / rowBytes
+		}
+		if job.row+rows > size.Y {
+			rows = size.Y - job.row
+		}
+		gs.ActiveTexture(gls.TEXTURE0)
+		gs.BindTexture(gls.TEXTURE_2D, job.texname)
+		if rows > 0 {
+			start := job.row * job.rgba.Stride
+			gs.TexSubImage2D(gls.TEXTURE_2D, 0, 0, int32(job.row), int32(size.X), int32(rows), gls.RGBA, gls.UNSIGNED_BYTE, job.rgba.Pix[start:start+rows*job.rgba.Stride])
+		}
+		job.row += rows
+		budget -= rows * rowBytes
+		if job.row < size.Y {
+			return
+		}
+
+		// Image completely uploaded
+		if job.mipmaps {
+			gs.GenerateMipmap(gls.TEXTURE_2D)
+		}
+		job.tex.replaceTexture(gs, job.texname, size.X, size.Y)
+		l.current = nil
+		l.finish(job)
+	}
+}
+
+// finish calls the ready callback of the specified job and updates the pending count.
+func (l *Loader) finish(job *loadJob) {
+
+	l.mutex.Lock()
+	l.pending--
+	l.mutex.Unlock()
+	if job.ready != nil {
+		job.ready(job.tex, job.err)
+	}
+	job.rgba = nil
+	job.ci = nil
+}
+
+// replaceTexture replaces the OpenGL texture of this texture by the specified
+// already uploaded texture with the specified size, deleting the previous one.
+func (t *Texture2D) replaceTexture(gs *gls.GLS, texname gl.Texture, width, height int) {
+
+	if t.gs != nil {
+		t.gs.DeleteTextures(t.texname)
+	}
+	t.gs = gs
+	t.texname = texname
+	t.width = int32(width)
+	t.height = int32(height)
+	t.data = nil
+	t.compressed = nil
+	t.updateData = false
+	t.updateParams = true
+}
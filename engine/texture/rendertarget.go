@@ -0,0 +1,122 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package texture
+
+import (
+	"fmt"
+
+	"github.com/wangzun/gogame/engine/gls"
+	"golang.org/x/mobile/gl"
+)
+
+// RenderTarget is an offscreen framebuffer with a color texture, which can be used by
+// materials after rendering into it, and a depth renderbuffer. Its OpenGL objects
+// are created when it is first bound.
+type RenderTarget struct {
+	gs         *gls.GLS        // Pointer to OpenGL state
+	tex        *Texture2D      // Color texture
+	fb         gl.Framebuffer  // Framebuffer handle
+	depth      gl.Renderbuffer // Depth renderbuffer handle
+	width      int             // Width in pixels
+	height     int             // Height in pixels
+	prevFb     gl.Framebuffer  // Framebuffer bound before binding this render target
+	prevView   [4]int32        // Viewport before binding this render target
+	updateSize bool            // Storage of the attachments needs to be reallocated
+}
+
+// NewRenderTarget creates and returns a pointer to a new render target
+// with the specified size in pixels.
+func NewRenderTarget(width, height int) *RenderTarget {
+
+	rt := new(RenderTarget)
+	rt.width = width
+	rt.height = height
+	rt.tex = NewTexture2DFromData(width, height, gls.RGBA, gls.UNSIGNED_BYTE, gls.RGBA, nil)
+	rt.tex.SetMinFilter(gls.LINEAR)
+	rt.tex.SetFlipY(false)
+	rt.tex.genMipmap = false
+	return rt
+}
+
+// Texture returns the color texture of the render target.
+// Materials using it must not be drawn while the render target is bound.
+func (rt *RenderTarget) Texture() *Texture2D {
+
+	return rt.tex
+}
+
+// Size returns the width and height of the render target in pixels.
+func (rt *RenderTarget) Size() (int, int) {
+
+	return rt.width, rt.height
+}
+
+// SetSize sets the width and height of the render target in pixels,
+// normally after the size of the window changes.
+func (rt *RenderTarget) SetSize(width, height int) {
+
+	if width == rt.width && height == rt.height {
+		return
+	}
+	rt.width = width
+	rt.height = height
+	rt.tex.SetData(width, height, gls.RGBA, gls.UNSIGNED_BYTE, gls.RGBA, nil)
+	rt.updateSize = true
+}
+
+// Bind binds the framebuffer of the render target, so the following draw calls render into
+// it, and sets the viewport to its size. The previous framebuffer and viewport are restored
+// by Unbind. Returns an error if the framebuffer is not complete.
+func (rt *RenderTarget) Bind(gs *gls.GLS) error {
+
+	// Saves the current framebuffer and viewport
+	rt.prevFb = gl.Framebuffer{Value: uint32(gs.GetInteger(gls.FRAMEBUFFER_BINDING))}
+	x, y, w, h := gs.GetViewport()
+	rt.prevView = [4]int32{x, y, w, h}
+
+	// One time initialization
+	if rt.gs == nil {
+		rt.gs = gs
+		rt.fb = gs.GenFramebuffer()
+		rt.depth = gs.GenRenderbuffer()
+		rt.updateSize = true
+	}
+
+	// Transfers the texture storage and attaches it with the depth renderbuffer if necessary
+	rt.tex.bind(gs, 0)
+	gs.BindFramebuffer(gls.FRAMEBUFFER, rt.fb)
+	if rt.updateSize {
+		gs.BindRenderbuffer(gls.RENDERBUFFER, rt.depth)
+		gs.RenderbufferStorage(gls.RENDERBUFFER, gls.DEPTH_COMPONENT16, rt.width, rt.height)
+		gs.FramebufferTexture2D(gls.FRAMEBUFFER, gls.COLOR_ATTACHMENT0, gls.TEXTURE_2D, rt.tex.texname, 0)
+		gs.FramebufferRenderbuffer(gls.FRAMEBUFFER, gls.DEPTH_ATTACHMENT, gls.RENDERBUFFER, rt.depth)
+		status := gs.CheckFramebufferStatus(gls.FRAMEBUFFER)
+		if status != gls.FRAMEBUFFER_COMPLETE {
+			gs.BindFramebuffer(gls.FRAMEBUFFER, rt.prevFb)
+			return fmt.Errorf("incomplete framebuffer: 0x%X", uint32(status))
+		}
+		rt.updateSize = false
+	}
+	gs.Viewport(0, 0, int32(rt.width), int32(rt.height))
+	return nil
+}
+
+// Unbind restores the framebuffer and the viewport which were current when the render target was bound.
+func (rt *RenderTarget) Unbind(gs *gls.GLS) {
+
+	gs.BindFramebuffer(gls.FRAMEBUFFER, rt.prevFb)
+	gs.Viewport(rt.prevView[0], rt.prevView[1], rt.prevView[2], rt.prevView[3])
+}
+
+// Dispose releases the OpenGL objects of the render target and its texture.
+func (rt *RenderTarget) Dispose() {
+
+	if rt.gs != nil {
+		rt.gs.DeleteFramebuffer(rt.fb)
+		rt.gs.DeleteRenderbuffer(rt.depth)
+		rt.gs = nil
+	}
+	rt.tex.Dispose()
+}
@@ -12,6 +12,8 @@ import (
 	_ "image/jpeg"
 	_ "image/png"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/wangzun/gogame/engine/gls"
 
@@ -20,25 +22,28 @@ import (
 
 // Texture2D represents a texture
 type Texture2D struct {
-	gs           *gls.GLS    // Pointer to OpenGL state
-	refcount     int         // Current number of references
-	texname      gl.Texture  // Texture handle
-	magFilter    uint32      // magnification filter
-	minFilter    uint32      // minification filter
-	wrapS        uint32      // wrap mode for s coordinate
-	wrapT        uint32      // wrap mode for t coordinate
-	iformat      int32       // internal format
-	width        int32       // texture width in pixels
-	height       int32       // texture height in pixels
-	format       uint32      // format of the pixel data
-	formatType   uint32      // type of the pixel data
-	updateData   bool        // texture data needs to be sent
-	updateParams bool        // texture parameters needs to be sent
-	genMipmap    bool        // generate mipmaps flag
-	data         []uint8     // array with texture data
-	uniUnit      gls.Uniform // Texture unit uniform location cache
-	uniInfo      gls.Uniform // Texture info uniform location cache
-	udata        struct {    // Combined uniform data in 3 vec2:
+	gs           *gls.GLS                    // Pointer to OpenGL state
+	refcount     int                         // Current number of references
+	texname      gl.Texture                  // Texture handle
+	magFilter    uint32                      // magnification filter
+	minFilter    uint32                      // minification filter
+	wrapS        uint32                      // wrap mode for s coordinate
+	wrapT        uint32                      // wrap mode for t coordinate
+	iformat      int32                       // internal format
+	width        int32                       // texture width in pixels
+	height       int32                       // texture height in pixels
+	format       uint32                      // format of the pixel data
+	formatType   uint32                      // type of the pixel data
+	updateData   bool                        // texture data needs to be sent
+	updateParams bool                        // texture parameters needs to be sent
+	genMipmap    bool                        // generate mipmaps flag
+	data         []uint8                     // array with texture data
+	source       *Texture2D                  // source texture for texture views
+	compressed   *CompressedImage            // compressed image data
+	fallback     func() (*image.RGBA, error) // loads the image used when the compressed format is not supported
+	uniUnit      gls.Uniform                 // Texture unit uniform location cache
+	uniInfo      gls.Uniform                 // Texture info uniform location cache
+	udata        struct {                    // Combined uniform data in 3 vec2:
 		offsetX float32
 		offsetY float32
 		repeatX float32
@@ -104,6 +109,58 @@ func NewTexture2DFromData(width, height int, format int, formatType, iformat int
 	return t
 }
 
+// NewTexture2DFromKTX creates and returns a pointer to a new Texture2D
+// using the specified KTX 1.1 or KTX 2.0 compressed texture file as data.
+// If a PNG or JPEG file with the same name exists in the same directory it is
+// used as a fallback when the GPU does not support the compressed format.
+func NewTexture2DFromKTX(ktxfile string) (*Texture2D, error) {
+
+	ci, err := LoadKTX(ktxfile)
+	if err != nil {
+		return nil, err
+	}
+	t := NewTexture2DFromCompressed(ci)
+	base := strings.TrimSuffix(ktxfile, filepath.Ext(ktxfile))
+	for _, ext := range []string{".png", ".jpg", ".jpeg"} {
+		imgfile := base + ext
+		if _, err := os.Stat(imgfile); err == nil {
+			t.SetFallback(func() (*image.RGBA, error) { return DecodeImage(imgfile) })
+			break
+		}
+	}
+	return t, nil
+}
+
+// NewTexture2DFromCompressed creates and returns a pointer to a new Texture2D
+// using the specified compressed image as data.
+func NewTexture2DFromCompressed(ci *CompressedImage) *Texture2D {
+
+	t := newTexture2D()
+	t.SetCompressed(ci)
+	return t
+}
+
+// NewTexture2DView creates and returns a pointer to a new texture which shares
+// the image data and the OpenGL texture of the specified source texture but has
+// its own offset, repeat, flip and visibility parameters and the specified size in pixels.
+// It is normally used to show a region of a texture atlas page.
+// The view keeps a reference to the source texture which is released when the view is disposed.
+func NewTexture2DView(src *Texture2D, width, height int) *Texture2D {
+
+	t := newTexture2D()
+	t.source = src.Incref()
+	t.width = int32(width)
+	t.height = int32(height)
+	t.updateParams = false
+	return t
+}
+
+// Source returns the source texture of this texture view or nil if this texture is not a view.
+func (t *Texture2D) Source() *Texture2D {
+
+	return t.source
+}
+
 // Incref increments the reference count for this texture
 // and returns a pointer to the geometry.
 // It should be used when this texture is shared by another
@@ -123,6 +180,11 @@ func (t *Texture2D) Dispose() {
 		t.refcount--
 		return
 	}
+	if t.source != nil {
+		t.source.Dispose()
+		t.source = nil
+		return
+	}
 	if t.gs != nil {
 		t.gs.DeleteTextures(t.texname)
 		t.gs = nil
@@ -170,6 +232,7 @@ func (t *Texture2D) SetFromRGBA(rgba *image.RGBA) {
 // SetData sets the texture data
 func (t *Texture2D) SetData(width, height int, format int, formatType, iformat int, data []uint8) {
 
+	t.compressed = nil
 	t.width = int32(width)
 	t.height = int32(height)
 	t.format = uint32(format)
@@ -179,6 +242,35 @@ func (t *Texture2D) SetData(width, height int, format int, formatType, iformat i
 	t.updateData = true
 }
 
+// SetCompressed sets the texture data from the specified compressed image.
+// The compressed mipmap levels are uploaded as they are, so if the image does not
+// contain a complete mipmap chain the minification filter is set to gls.LINEAR.
+func (t *Texture2D) SetCompressed(ci *CompressedImage) {
+
+	t.compressed = ci
+	t.width = int32(ci.Width)
+	t.height = int32(ci.Height)
+	t.data = nil
+	if len(ci.Levels) < mipmapLevels(ci.Width, ci.Height) {
+		t.SetMinFilter(gls.LINEAR)
+	}
+	t.updateData = true
+}
+
+// Compressed returns the compressed image of this texture or nil if the texture is not compressed.
+func (t *Texture2D) Compressed() *CompressedImage {
+
+	return t.compressed
+}
+
+// SetFallback sets the function used to load the image which is uploaded
+// instead of the compressed data when the GPU does not support its format
+// and the format cannot be decoded by the engine.
+func (t *Texture2D) SetFallback(fallback func() (*image.RGBA, error)) {
+
+	t.fallback = fallback
+}
+
 // SetVisible sets the visibility state of the texture
 func (t *Texture2D) SetVisible(state bool) {
 
@@ -308,6 +400,19 @@ func DecodeImage(imgfile string) (*image.RGBA, error) {
 // RenderSetup is called by the material render setup
 func (t *Texture2D) RenderSetup(gs *gls.GLS, slotIdx, uniIdx int) { // Could have as input - TEXTURE0 (slot) and uni location
 
+	// Texture views bind the texture of their source
+	if t.source != nil {
+		t.source.bind(gs, slotIdx)
+	} else {
+		t.bind(gs, slotIdx)
+	}
+	t.transferUniforms(gs, slotIdx, uniIdx)
+}
+
+// bind binds this texture to the specified texture unit,
+// transferring its data and parameters to OpenGL if necessary.
+func (t *Texture2D) bind(gs *gls.GLS, slotIdx int) {
+
 	// One time initialization
 	if t.gs == nil {
 		t.texname = gs.GenTexture()
@@ -321,20 +426,10 @@ func (t *Texture2D) RenderSetup(gs *gls.GLS, slotIdx, uniIdx int) { // Could hav
 
 	// Transfer texture data to OpenGL if necessary
 	if t.updateData {
-		gs.TexImage2D(
-			gls.TEXTURE_2D, // texture type
-			0,              // level of detail
-			t.iformat,      // internal format
-			t.width,        // width in texels
-			t.height,       // height in texels
-			0,              // border must be 0
-			t.format,       // format of supplied texture data
-			t.formatType,   // type of external format color component
-			t.data,         // image data
-		)
-		// Generates mipmaps if requested
-		if t.genMipmap {
-			gs.GenerateMipmap(gls.TEXTURE_2D)
+		if t.compressed != nil {
+			t.transferCompressed(gs)
+		} else {
+			t.transferData(gs)
 		}
 		// No data to send
 		t.updateData = false
@@ -348,6 +443,102 @@ func (t *Texture2D) RenderSetup(gs *gls.GLS, slotIdx, uniIdx int) { // Could hav
 		gs.TexParameteri(gls.TEXTURE_2D, gls.TEXTURE_WRAP_T, int32(t.wrapT))
 		t.updateParams = false
 	}
+}
+
+// transferData transfers the uncompressed texture data to OpenGL.
+func (t *Texture2D) transferData(gs *gls.GLS) {
+
+	gs.TexImage2D(
+		gls.TEXTURE_2D, // texture type
+		0,              // level of detail
+		t.iformat,      // internal format
+		t.width,        // width in texels
+		t.height,       // height in texels
+		0,              // border must be 0
+		t.format,       // format of supplied texture data
+		t.formatType,   // type of external format color component
+		t.data,         // image data
+	)
+	// Generates mipmaps if requested
+	if t.genMipmap {
+		gs.GenerateMipmap(gls.TEXTURE_2D)
+	}
+}
+
+// transferCompressed transfers the compressed texture data with all its mipmap levels
+// to OpenGL if its format is supported by the GPU. Otherwise the levels are decoded
+// into RGBA8 if possible or the fallback image is loaded and transferred.
+func (t *Texture2D) transferCompressed(gs *gls.GLS) {
+
+	ci := t.compressed
+	width := ci.Width
+	height := ci.Height
+	if gs.SupportsCompressedFormat(ci.Format) {
+		for level, data := range ci.Levels {
+			gs.CompressedTexImage2D(gls.TEXTURE_2D, int32(level), ci.Format, int32(width), int32(height), 0, data)
+			width = maxInt(width/2, 1)
+			height = maxInt(height/2, 1)
+		}
+		return
+	}
+
+	// Decodes the levels in the CPU
+	if canDecodeETC(ci.Format) {
+		for level, data := range ci.Levels {
+			rgba, err := DecodeETC(ci.Format, width, height, data)
+			if err != nil {
+				log.Error("decoding compressed texture level:%d: %v", level, err)
+				return
+			}
+			gs.TexImage2D(gls.TEXTURE_2D, int32(level), gls.RGBA8, int32(width), int32(height), 0, gls.RGBA, gls.UNSIGNED_BYTE, rgba)
+			width = maxInt(width/2, 1)
+			height = maxInt(height/2, 1)
+		}
+		return
+	}
+
+	// Uses the fallback image
+	if t.fallback == nil {
+		log.Error("compressed texture format:0x%X not supported and no fallback image", ci.Format)
+		return
+	}
+	rgba, err := t.fallback()
+	if err != nil {
+		log.Error("loading fallback image for compressed texture: %v", err)
+		return
+	}
+	t.width = int32(rgba.Rect.Dx())
+	t.height = int32(rgba.Rect.Dy())
+	t.format = gls.RGBA
+	t.formatType = gls.UNSIGNED_BYTE
+	t.iformat = gls.RGBA8
+	t.data = rgba.Pix
+	t.transferData(gs)
+	t.data = nil
+}
+
+// mipmapLevels returns the number of levels of a complete mipmap chain for the specified size.
+func mipmapLevels(width, height int) int {
+
+	levels := 1
+	for width > 1 || height > 1 {
+		width /= 2
+		height /= 2
+		levels++
+	}
+	return levels
+}
+
+func maxInt(a, b int) int {
+
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// transferUniforms transfers the texture unit and texture info uniforms.
+func (t *Texture2D) transferUniforms(gs *gls.GLS, slotIdx, uniIdx int) {
 
 	// Transfer texture unit uniform
 
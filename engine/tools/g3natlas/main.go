@@ -0,0 +1,130 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// g3natlas packs all the images of a folder into a texture atlas saved
+// as PNG images and TexturePacker JSON files. It is normally used at build time:
+//
+//	//go:generate g3natlas -in=icons -out=assets/icons.json
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/wangzun/gogame/engine/atlas"
+	"github.com/wangzun/gogame/engine/texture"
+)
+
+// Program name and version
+const (
+	progName = "g3natlas"
+	vMajor   = 0
+	vMinor   = 1
+)
+
+// Command line options
+var (
+	oVersion = flag.Bool("version", false, "Show version and exits")
+	oIn      = flag.String("in", ".", "Input folder with the images to pack")
+	oOut     = flag.String("out", "atlas.json", "Output TexturePacker JSON file")
+	oWidth   = flag.Int("width", 2048, "Maximum page width in pixels")
+	oHeight  = flag.Int("height", 2048, "Maximum page height in pixels")
+	oPadding = flag.Int("padding", 2, "Number of transparent pixels between regions")
+	oExtrude = flag.Int("extrude", 1, "Number of pixels the regions borders are repeated")
+	oPot     = flag.Bool("pot", true, "Round pages sizes up to powers of two")
+	oVerbose = flag.Bool("v", false, "Show the packed images")
+)
+
+// Supported image files extensions
+var imageExts = map[string]bool{".png": true, ".jpg": true, ".jpeg": true, ".gif": true}
+
+func main() {
+
+	flag.Usage = usage
+	flag.Parse()
+	if *oVersion {
+		fmt.Fprintf(os.Stderr, "%s v%d.%d\n", progName, vMajor, vMinor)
+		return
+	}
+
+	// Collects the images files of the input folder and its sub folders
+	files := make([]string, 0)
+	err := filepath.Walk(*oIn, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && imageExts[strings.ToLower(filepath.Ext(path))] {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		fatal(err)
+	}
+	if len(files) == 0 {
+		fatal(fmt.Errorf("no images found in:%s", *oIn))
+	}
+	sort.Strings(files)
+
+	// Region names are the images paths relative to the input folder without extension
+	outBase := filepath.Base(*oOut)
+	packer := atlas.NewPacker(*oWidth, *oHeight)
+	packer.SetPadding(*oPadding)
+	packer.SetExtrude(*oExtrude)
+	packer.SetPowerOfTwo(*oPot)
+	packer.SetName(strings.TrimSuffix(outBase, filepath.Ext(outBase)))
+	for _, fpath := range files {
+		rgba, err := texture.DecodeImage(fpath)
+		if err != nil {
+			fatal(fmt.Errorf("image:%s: %v", fpath, err))
+		}
+		rel, err := filepath.Rel(*oIn, fpath)
+		if err != nil {
+			fatal(err)
+		}
+		name := filepath.ToSlash(strings.TrimSuffix(rel, filepath.Ext(rel)))
+		packer.Add(name, rgba)
+		if *oVerbose {
+			fmt.Printf("%s: %dx%d\n", name, rgba.Rect.Dx(), rgba.Rect.Dy())
+		}
+	}
+
+	a, err := packer.Pack()
+	if err != nil {
+		fatal(err)
+	}
+	err = os.MkdirAll(filepath.Dir(*oOut), 0755)
+	if err != nil {
+		fatal(err)
+	}
+	err = a.Save(*oOut)
+	if err != nil {
+		fatal(err)
+	}
+	if *oVerbose {
+		for _, p := range a.Pages {
+			fmt.Printf("page:%s size:%dx%d regions:%d\n", p.Name, p.Width, p.Height, len(a.Regions(p)))
+		}
+	}
+}
+
+// fatal prints the specified error and exits
+func fatal(err error) {
+
+	fmt.Fprintf(os.Stderr, "%s: %v\n", progName, err)
+	os.Exit(1)
+}
+
+// usage shows the application usage
+func usage() {
+
+	fmt.Fprintf(os.Stderr, "%s v%d.%d\n", progName, vMajor, vMinor)
+	fmt.Fprintf(os.Stderr, "usage: %s [options]\n", strings.ToLower(progName))
+	flag.PrintDefaults()
+	os.Exit(2)
+}
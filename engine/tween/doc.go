@@ -0,0 +1,11 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package tween implements tweens which interpolate values over time with
+// easing functions, such as the transforms of nodes, the position, size and
+// color of gui panels and the colors and opacity of materials. Tweens can be
+// delayed, repeated and played back and forth, combined in sequences and
+// parallel groups, and are played by a Manager which is normally the one
+// updated with the frame time by the application.
+package tween
@@ -0,0 +1,273 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tween
+
+import (
+	"github.com/wangzun/gogame/engine/math32"
+)
+
+// Easing is the type of the functions which map the linear progress of a tween from 0 to 1
+// to the eased progress, which starts at 0 and ends at 1 but may go beyond them in between.
+// These are Robert Penner's easing functions.
+type Easing func(t float32) float32
+
+// Constants of the back and elastic easing functions
+const (
+	backOvershoot   = 1.70158
+	backOvershootIO = backOvershoot * 1.525
+	elasticPeriod   = 0.3
+)
+
+// Linear is the easing function without easing.
+func Linear(t float32) float32 {
+
+	return t
+}
+
+// QuadIn accelerates from zero velocity.
+func QuadIn(t float32) float32 {
+
+	return t * t
+}
+
+// QuadOut decelerates to zero velocity.
+func QuadOut(t float32) float32 {
+
+	return t * (2 - t)
+}
+
+// QuadInOut accelerates until halfway and then decelerates.
+func QuadInOut(t float32) float32 {
+
+	if t < 0.5 {
+		return 2 * t * t
+	}
+	return -1 + (4-2*t)*t
+}
+
+// CubicIn accelerates from zero velocity.
+func CubicIn(t float32) float32 {
+
+	return t * t * t
+}
+
+// CubicOut decelerates to zero velocity.
+func CubicOut(t float32) float32 {
+
+	t--
+	return t*t*t + 1
+}
+
+// CubicInOut accelerates until halfway and then decelerates.
+func CubicInOut(t float32) float32 {
+
+	if t < 0.5 {
+		return 4 * t * t * t
+	}
+	t = 2*t - 2
+	return 0.5*t*t*t + 1
+}
+
+// QuartIn accelerates from zero velocity.
+func QuartIn(t float32) float32 {
+
+	return t * t * t * t
+}
+
+// QuartOut decelerates to zero velocity.
+func QuartOut(t float32) float32 {
+
+	t--
+	return 1 - t*t*t*t
+}
+
+// QuartInOut accelerates until halfway and then decelerates.
+func QuartInOut(t float32) float32 {
+
+	if t < 0.5 {
+		return 8 * t * t * t * t
+	}
+	t--
+	return 1 - 8*t*t*t*t
+}
+
+// QuintIn accelerates from zero velocity.
+func QuintIn(t float32) float32 {
+
+	return t * t * t * t * t
+}
+
+// QuintOut decelerates to zero velocity.
+func QuintOut(t float32) float32 {
+
+	t--
+	return t*t*t*t*t + 1
+}
+
+// QuintInOut accelerates until halfway and then decelerates.
+func QuintInOut(t float32) float32 {
+
+	if t < 0.5 {
+		return 16 * t * t * t * t * t
+	}
+	t = 2*t - 2
+	return 0.5*t*t*t*t*t + 1
+}
+
+// SineIn accelerates from zero velocity along a sine curve.
+func SineIn(t float32) float32 {
+
+	return 1 - math32.Cos(t*math32.Pi/2)
+}
+
+// SineOut decelerates to zero velocity along a sine curve.
+func SineOut(t float32) float32 {
+
+	return math32.Sin(t * math32.Pi / 2)
+}
+
+// SineInOut accelerates until halfway and then decelerates along a sine curve.
+func SineInOut(t float32) float32 {
+
+	return -0.5 * (math32.Cos(math32.Pi*t) - 1)
+}
+
+// ExpoIn accelerates exponentially from zero velocity.
+func ExpoIn(t float32) float32 {
+
+	if t == 0 {
+		return 0
+	}
+	return math32.Pow(2, 10*(t-1))
+}
+
+// ExpoOut decelerates exponentially to zero velocity.
+func ExpoOut(t float32) float32 {
+
+	if t == 1 {
+		return 1
+	}
+	return 1 - math32.Pow(2, -10*t)
+}
+
+// ExpoInOut accelerates exponentially until halfway and then decelerates.
+func ExpoInOut(t float32) float32 {
+
+	if t == 0 || t == 1 {
+		return t
+	}
+	if t < 0.5 {
+		return 0.5 * math32.Pow(2, 20*t-10)
+	}
+	return 1 - 0.5*math32.Pow(2, -20*t+10)
+}
+
+// CircIn accelerates from zero velocity along a circle.
+func CircIn(t float32) float32 {
+
+	return 1 - math32.Sqrt(1-t*t)
+}
+
+// CircOut decelerates to zero velocity along a circle.
+func CircOut(t float32) float32 {
+
+	t--
+	return math32.Sqrt(1 - t*t)
+}
+
+// CircInOut accelerates until halfway and then decelerates along circles.
+func CircInOut(t float32) float32 {
+
+	if t < 0.5 {
+		return 0.5 * (1 - math32.Sqrt(1-4*t*t))
+	}
+	t = 2*t - 2
+	return 0.5 * (math32.Sqrt(1-t*t) + 1)
+}
+
+// ElasticIn starts with oscillations of increasing amplitude.
+func ElasticIn(t float32) float32 {
+
+	if t == 0 || t == 1 {
+		return t
+	}
+	t--
+	return -math32.Pow(2, 10*t) * math32.Sin((t-elasticPeriod/4)*2*math32.Pi/elasticPeriod)
+}
+
+// ElasticOut ends with oscillations of decreasing amplitude around the end.
+func ElasticOut(t float32) float32 {
+
+	if t == 0 || t == 1 {
+		return t
+	}
+	return math32.Pow(2, -10*t)*math32.Sin((t-elasticPeriod/4)*2*math32.Pi/elasticPeriod) + 1
+}
+
+// ElasticInOut oscillates at the start and at the end.
+func ElasticInOut(t float32) float32 {
+
+	if t < 0.5 {
+		return 0.5 * ElasticIn(2*t)
+	}
+	return 0.5*ElasticOut(2*t-1) + 0.5
+}
+
+// BackIn moves slightly backwards before starting.
+func BackIn(t float32) float32 {
+
+	return t * t * ((backOvershoot+1)*t - backOvershoot)
+}
+
+// BackOut overshoots the end and comes back.
+func BackOut(t float32) float32 {
+
+	t--
+	return t*t*((backOvershoot+1)*t+backOvershoot) + 1
+}
+
+// BackInOut moves slightly backwards at the start and overshoots the end.
+func BackInOut(t float32) float32 {
+
+	t *= 2
+	if t < 1 {
+		return 0.5 * (t * t * ((backOvershootIO+1)*t - backOvershootIO))
+	}
+	t -= 2
+	return 0.5 * (t*t*((backOvershootIO+1)*t+backOvershootIO) + 2)
+}
+
+// BounceIn starts with bounces of increasing height.
+func BounceIn(t float32) float32 {
+
+	return 1 - BounceOut(1-t)
+}
+
+// BounceOut ends with bounces of decreasing height like a dropped ball.
+func BounceOut(t float32) float32 {
+
+	switch {
+	case t < 1/2.75:
+		return 7.5625 * t * t
+	case t < 2/2.75:
+		t -= 1.5 / 2.75
+		return 7.5625*t*t + 0.75
+	case t < 2.5/2.75:
+		t -= 2.25 / 2.75
+		return 7.5625*t*t + 0.9375
+	default:
+		t -= 2.625 / 2.75
+		return 7.5625*t*t + 0.984375
+	}
+}
+
+// BounceInOut bounces at the start and at the end.
+func BounceInOut(t float32) float32 {
+
+	if t < 0.5 {
+		return 0.5 * BounceIn(2*t)
+	}
+	return 0.5*BounceOut(2*t-1) + 0.5
+}
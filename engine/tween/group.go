@@ -0,0 +1,134 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tween
+
+// Group plays tweens in parallel and completes when all of them completed.
+type Group struct {
+	tweens     []ITween // Tweens played together
+	done       []bool   // Whether each tween completed
+	repeat     int      // Number of repetitions, -1 to repeat forever
+	onRepeat   func()   // Optional callback when repeated
+	onComplete func()   // Optional callback when completed
+	iteration  int      // Current iteration
+}
+
+// NewGroup creates and returns a pointer to a new group of the specified tweens.
+func NewGroup(tweens ...ITween) *Group {
+
+	g := new(Group)
+	g.tweens = tweens
+	g.done = make([]bool, len(tweens))
+	return g
+}
+
+// Add adds the specified tween to the group.
+// Returns pointer to this updated group.
+func (g *Group) Add(t ITween) *Group {
+
+	g.tweens = append(g.tweens, t)
+	g.done = append(g.done, false)
+	return g
+}
+
+// Len returns the number of tweens of the group.
+func (g *Group) Len() int {
+
+	return len(g.tweens)
+}
+
+// SetRepeat sets the number of times the group is repeated after the first time,
+// or -1 to repeat it until cancelled. The default is 0.
+// Returns pointer to this updated group.
+func (g *Group) SetRepeat(count int) *Group {
+
+	g.repeat = count
+	return g
+}
+
+// OnRepeat sets the function called when the group is repeated.
+// Returns pointer to this updated group.
+func (g *Group) OnRepeat(cb func()) *Group {
+
+	g.onRepeat = cb
+	return g
+}
+
+// OnComplete sets the function called when all the tweens of the last repetition completed.
+// Returns pointer to this updated group.
+func (g *Group) OnComplete(cb func()) *Group {
+
+	g.onComplete = cb
+	return g
+}
+
+// start restarts the group and its tweens.
+func (g *Group) start() {
+
+	g.iteration = 0
+	for i, t := range g.tweens {
+		t.start()
+		g.done[i] = false
+	}
+}
+
+// rewind restarts the group and its tweens keeping their captured start values.
+func (g *Group) rewind() {
+
+	g.iteration = 0
+	g.rewindTweens()
+}
+
+// rewindTweens restarts the tweens of the group for a repetition.
+func (g *Group) rewindTweens() {
+
+	for i, t := range g.tweens {
+		t.rewind()
+		g.done[i] = false
+	}
+}
+
+// update advances the tweens by the specified time in seconds and returns
+// the time left after the group completed and whether it completed.
+func (g *Group) update(delta float32) (float32, bool) {
+
+	for {
+		// The time left is the one of the tween which completed last
+		finished := true
+		left := delta
+		for i, t := range g.tweens {
+			if g.done[i] {
+				continue
+			}
+			tleft, done := t.update(delta)
+			if !done {
+				finished = false
+				continue
+			}
+			g.done[i] = true
+			if tleft < left {
+				left = tleft
+			}
+		}
+		if !finished {
+			return 0, false
+		}
+		if g.repeat >= 0 && g.iteration >= g.repeat {
+			if g.onComplete != nil {
+				g.onComplete()
+			}
+			return left, true
+		}
+		g.iteration++
+		g.rewindTweens()
+		if g.onRepeat != nil {
+			g.onRepeat()
+		}
+		// A group without duration repeated forever is repeated once per update
+		if left <= 0 || left >= delta {
+			return 0, false
+		}
+		delta = left
+	}
+}
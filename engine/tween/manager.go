@@ -0,0 +1,133 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tween
+
+// Manager plays tweens, sequences and groups, which are identified by the handles
+// returned when started and can be paused, resumed and cancelled by them.
+type Manager struct {
+	nextID int      // Next handle
+	active []active // Tweens being played
+}
+
+// active is a tween being played by a manager.
+type active struct {
+	id     int    // Handle
+	tween  ITween // Tween, sequence or group
+	paused bool   // Playback paused
+}
+
+// NewManager creates and returns a pointer to a new tween manager.
+func NewManager() *Manager {
+
+	m := new(Manager)
+	m.nextID = 1
+	return m
+}
+
+// Start starts playing the specified tween, sequence or group from the beginning,
+// capturing the start values again, and returns its handle.
+func (m *Manager) Start(t ITween) int {
+
+	t.start()
+	id := m.nextID
+	m.nextID++
+	m.active = append(m.active, active{id: id, tween: t})
+	return id
+}
+
+// Pause pauses the tween with the specified handle.
+// Returns true if the tween is found.
+func (m *Manager) Pause(id int) bool {
+
+	return m.setPaused(id, true)
+}
+
+// Resume resumes the paused tween with the specified handle.
+// Returns true if the tween is found.
+func (m *Manager) Resume(id int) bool {
+
+	return m.setPaused(id, false)
+}
+
+// Paused returns whether the tween with the specified handle is paused.
+func (m *Manager) Paused(id int) bool {
+
+	pos := m.find(id)
+	return pos >= 0 && m.active[pos].paused
+}
+
+// Playing returns whether the tween with the specified handle has not completed and was not cancelled.
+func (m *Manager) Playing(id int) bool {
+
+	return m.find(id) >= 0
+}
+
+// Cancel stops the tween with the specified handle, leaving the values as they are,
+// without calling its completion callback.
+// Returns true if the tween is found.
+func (m *Manager) Cancel(id int) bool {
+
+	pos := m.find(id)
+	if pos < 0 {
+		return false
+	}
+	copy(m.active[pos:], m.active[pos+1:])
+	m.active[len(m.active)-1] = active{}
+	m.active = m.active[:len(m.active)-1]
+	return true
+}
+
+// CancelAll stops all the tweens of the manager.
+func (m *Manager) CancelAll() {
+
+	m.active = nil
+}
+
+// Count returns the number of tweens being played.
+func (m *Manager) Count() int {
+
+	return len(m.active)
+}
+
+// Update advances the tweens which are not paused by the specified time in seconds
+// and removes the completed ones. The callbacks of the tweens may start and cancel tweens.
+func (m *Manager) Update(delta float32) {
+
+	// Updates a copy of the list as the callbacks may change it
+	current := make([]active, len(m.active))
+	copy(current, m.active)
+	for _, a := range current {
+		pos := m.find(a.id)
+		if pos < 0 || m.active[pos].paused {
+			continue
+		}
+		_, done := a.tween.update(delta)
+		if done {
+			m.Cancel(a.id)
+		}
+	}
+}
+
+// find returns the position of the tween with the specified handle or -1 if not found.
+func (m *Manager) find(id int) int {
+
+	for pos := range m.active {
+		if m.active[pos].id == id {
+			return pos
+		}
+	}
+	return -1
+}
+
+// setPaused sets the paused state of the tween with the specified handle.
+func (m *Manager) setPaused(id int, state bool) bool {
+
+	pos := m.find(id)
+	if pos < 0 {
+		return false
+	}
+	m.active[pos].paused = state
+	return true
+}
@@ -0,0 +1,135 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tween
+
+// Sequence plays tweens one after the other.
+type Sequence struct {
+	tweens     []ITween // Tweens in playback order
+	repeat     int      // Number of repetitions, -1 to repeat forever
+	onRepeat   func()   // Optional callback when repeated
+	onComplete func()   // Optional callback when completed
+	current    int      // Index of the current tween
+	iteration  int      // Current iteration
+}
+
+// NewSequence creates and returns a pointer to a new sequence of the specified tweens.
+func NewSequence(tweens ...ITween) *Sequence {
+
+	s := new(Sequence)
+	s.tweens = tweens
+	return s
+}
+
+// Append appends the specified tween to the sequence.
+// Returns pointer to this updated sequence.
+func (s *Sequence) Append(t ITween) *Sequence {
+
+	s.tweens = append(s.tweens, t)
+	return s
+}
+
+// Delay appends a delay with the specified duration in seconds to the sequence.
+// Returns pointer to this updated sequence.
+func (s *Sequence) Delay(duration float32) *Sequence {
+
+	return s.Append(NewTween(duration, nil))
+}
+
+// Call appends a call to the specified function to the sequence.
+// Returns pointer to this updated sequence.
+func (s *Sequence) Call(cb func()) *Sequence {
+
+	return s.Append(NewTween(0, nil).OnComplete(cb))
+}
+
+// Len returns the number of tweens of the sequence.
+func (s *Sequence) Len() int {
+
+	return len(s.tweens)
+}
+
+// SetRepeat sets the number of times the sequence is repeated after the first time,
+// or -1 to repeat it until cancelled. The default is 0.
+// Returns pointer to this updated sequence.
+func (s *Sequence) SetRepeat(count int) *Sequence {
+
+	s.repeat = count
+	return s
+}
+
+// OnRepeat sets the function called when the sequence is repeated.
+// Returns pointer to this updated sequence.
+func (s *Sequence) OnRepeat(cb func()) *Sequence {
+
+	s.onRepeat = cb
+	return s
+}
+
+// OnComplete sets the function called when the sequence completes its last repetition.
+// Returns pointer to this updated sequence.
+func (s *Sequence) OnComplete(cb func()) *Sequence {
+
+	s.onComplete = cb
+	return s
+}
+
+// start restarts the sequence and its tweens.
+func (s *Sequence) start() {
+
+	s.current = 0
+	s.iteration = 0
+	for _, t := range s.tweens {
+		t.start()
+	}
+}
+
+// rewind restarts the sequence and its tweens keeping their captured start values.
+func (s *Sequence) rewind() {
+
+	s.current = 0
+	s.iteration = 0
+	s.rewindTweens()
+}
+
+// rewindTweens restarts the tweens of the sequence for a repetition.
+func (s *Sequence) rewindTweens() {
+
+	for _, t := range s.tweens {
+		t.rewind()
+	}
+}
+
+// update advances the current tweens by the specified time in seconds and returns
+// the time left after the sequence completed and whether it completed.
+func (s *Sequence) update(delta float32) (float32, bool) {
+
+	for {
+		begin := delta
+		for s.current < len(s.tweens) {
+			left, done := s.tweens[s.current].update(delta)
+			if !done {
+				return 0, false
+			}
+			s.current++
+			delta = left
+		}
+		if s.repeat >= 0 && s.iteration >= s.repeat {
+			if s.onComplete != nil {
+				s.onComplete()
+			}
+			return delta, true
+		}
+		s.iteration++
+		s.current = 0
+		s.rewindTweens()
+		if s.onRepeat != nil {
+			s.onRepeat()
+		}
+		// A sequence without duration repeated forever is repeated once per update
+		if delta <= 0 || delta >= begin {
+			return 0, false
+		}
+	}
+}
@@ -0,0 +1,185 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tween
+
+import (
+	"github.com/wangzun/gogame/engine/core"
+	"github.com/wangzun/gogame/engine/gui"
+	"github.com/wangzun/gogame/engine/material"
+	"github.com/wangzun/gogame/engine/math32"
+)
+
+// NodePosition creates and returns a pointer to a new tween which moves
+// the specified node from its current position to the specified position.
+func NodePosition(inode core.INode, to math32.Vector3, duration float32) *Tween {
+
+	node := inode.GetNode()
+	var from math32.Vector3
+	t := NewTween(duration, func(k float32) {
+		pos := from
+		pos.Lerp(&to, k)
+		node.SetPositionVec(&pos)
+	})
+	t.SetBegin(func() { from = node.Position() })
+	return t
+}
+
+// NodeRotation creates and returns a pointer to a new tween which rotates the specified node
+// from its current rotation to the specified Euler angles in radians along the shortest path.
+func NodeRotation(inode core.INode, to math32.Vector3, duration float32) *Tween {
+
+	var qto math32.Quaternion
+	qto.SetFromEuler(&to)
+	return NodeQuaternion(inode, qto, duration)
+}
+
+// NodeQuaternion creates and returns a pointer to a new tween which rotates the specified
+// node from its current rotation to the specified quaternion along the shortest path.
+func NodeQuaternion(inode core.INode, to math32.Quaternion, duration float32) *Tween {
+
+	node := inode.GetNode()
+	var from math32.Quaternion
+	t := NewTween(duration, func(k float32) {
+		q := from
+		q.Slerp(&to, k)
+		node.SetQuaternionQuat(&q)
+	})
+	t.SetBegin(func() { from = node.Quaternion() })
+	return t
+}
+
+// NodeScale creates and returns a pointer to a new tween which scales
+// the specified node from its current scale to the specified scale.
+func NodeScale(inode core.INode, to math32.Vector3, duration float32) *Tween {
+
+	node := inode.GetNode()
+	var from math32.Vector3
+	t := NewTween(duration, func(k float32) {
+		scale := from
+		scale.Lerp(&to, k)
+		node.SetScaleVec(&scale)
+	})
+	t.SetBegin(func() { from = node.Scale() })
+	return t
+}
+
+// PanelPosition creates and returns a pointer to a new tween which moves
+// the specified panel from its current position to the specified position.
+func PanelPosition(ipan gui.IPanel, x, y float32, duration float32) *Tween {
+
+	panel := ipan.GetPanel()
+	var from math32.Vector3
+	t := NewTween(duration, func(k float32) {
+		panel.SetPosition(from.X+(x-from.X)*k, from.Y+(y-from.Y)*k)
+	})
+	t.SetBegin(func() { from = panel.Position() })
+	return t
+}
+
+// PanelSize creates and returns a pointer to a new tween which resizes
+// the specified panel from its current size to the specified size.
+func PanelSize(ipan gui.IPanel, width, height float32, duration float32) *Tween {
+
+	panel := ipan.GetPanel()
+	var fromW, fromH float32
+	t := NewTween(duration, func(k float32) {
+		panel.SetSize(fromW+(width-fromW)*k, fromH+(height-fromH)*k)
+	})
+	t.SetBegin(func() { fromW, fromH = panel.Size() })
+	return t
+}
+
+// PanelColor creates and returns a pointer to a new tween which changes the color
+// of the specified panel from its current color to the specified color.
+func PanelColor(ipan gui.IPanel, to math32.Color4, duration float32) *Tween {
+
+	panel := ipan.GetPanel()
+	var from math32.Color4
+	t := NewTween(duration, func(k float32) {
+		color := lerpColor4(&from, &to, k)
+		panel.SetColor4(&color)
+	})
+	t.SetBegin(func() { from = panel.Color4() })
+	return t
+}
+
+// StandardColor creates and returns a pointer to a new tween which changes the diffuse
+// and ambient color of the specified material from its current color to the specified color.
+func StandardColor(mat *material.Standard, to math32.Color, duration float32) *Tween {
+
+	var from math32.Color
+	t := NewTween(duration, func(k float32) {
+		color := from
+		color.Lerp(&to, k)
+		mat.SetColor(&color)
+	})
+	t.SetBegin(func() { from = mat.Color() })
+	return t
+}
+
+// StandardEmissive creates and returns a pointer to a new tween which changes the emissive
+// color of the specified material from its current emissive color to the specified color.
+func StandardEmissive(mat *material.Standard, to math32.Color, duration float32) *Tween {
+
+	var from math32.Color
+	t := NewTween(duration, func(k float32) {
+		color := from
+		color.Lerp(&to, k)
+		mat.SetEmissiveColor(&color)
+	})
+	t.SetBegin(func() { from = mat.EmissiveColor() })
+	return t
+}
+
+// StandardOpacity creates and returns a pointer to a new tween which changes the opacity
+// of the specified material from its current opacity to the specified opacity.
+// The material should be transparent for opacities below 1.
+func StandardOpacity(mat *material.Standard, to float32, duration float32) *Tween {
+
+	var from float32
+	t := NewTween(duration, func(k float32) {
+		mat.SetOpacity(from + (to-from)*k)
+	})
+	t.SetBegin(func() { from = mat.Opacity() })
+	return t
+}
+
+// PhysicalColor creates and returns a pointer to a new tween which changes the base color,
+// including the opacity, of the specified material from its current base color to the specified color.
+func PhysicalColor(mat *material.Physical, to math32.Color4, duration float32) *Tween {
+
+	var from math32.Color4
+	t := NewTween(duration, func(k float32) {
+		color := lerpColor4(&from, &to, k)
+		mat.SetBaseColorFactor(&color)
+	})
+	t.SetBegin(func() { from = mat.BaseColorFactor() })
+	return t
+}
+
+// PhysicalEmissive creates and returns a pointer to a new tween which changes the emissive
+// color of the specified material from its current emissive color to the specified color.
+func PhysicalEmissive(mat *material.Physical, to math32.Color, duration float32) *Tween {
+
+	var from math32.Color
+	t := NewTween(duration, func(k float32) {
+		color := from
+		color.Lerp(&to, k)
+		mat.SetEmissiveFactor(&color)
+	})
+	t.SetBegin(func() { from = mat.EmissiveFactor() })
+	return t
+}
+
+// lerpColor4 returns the linear interpolation of the specified colors.
+func lerpColor4(from, to *math32.Color4, k float32) math32.Color4 {
+
+	return math32.Color4{
+		R: from.R + (to.R-from.R)*k,
+		G: from.G + (to.G-from.G)*k,
+		B: from.B + (to.B-from.B)*k,
+		A: from.A + (to.A-from.A)*k,
+	}
+}
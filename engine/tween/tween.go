@@ -0,0 +1,249 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tween
+
+// ITween is the interface of tweens, sequences and groups which can be played by a manager
+// or be part of a sequence or group.
+type ITween interface {
+	start()                               // Restarts playback and captures the start values again
+	rewind()                              // Restarts playback for a repetition of a parent
+	update(delta float32) (float32, bool) // Advances playback and returns the unused time and whether finished
+}
+
+// Tween interpolates values over a duration with an easing function.
+// The values are set by a function called with the eased progress, from 0 at the start
+// to 1 at the end, and the start values are normally captured by a begin function when
+// the tween first starts after its delay, so the tween continues from the current values.
+type Tween struct {
+	duration   float32         // Duration of each iteration in seconds
+	delay      float32         // Delay before starting in seconds
+	easing     Easing          // Easing function
+	repeat     int             // Number of repetitions, -1 to repeat forever
+	yoyo       bool            // Plays the odd repetitions backwards
+	begin      func()          // Optional function to capture the start values
+	apply      func(k float32) // Function which sets the values for the eased progress
+	onStart    func()          // Optional callback when started after the delay
+	onUpdate   func()          // Optional callback after the values are set
+	onRepeat   func()          // Optional callback when repeated
+	onComplete func()          // Optional callback when completed
+	waited     float32         // Elapsed delay
+	elapsed    float32         // Elapsed time of the current iteration
+	iteration  int             // Current iteration
+	started    bool            // Started after the delay
+	captured   bool            // Start values captured
+}
+
+// NewTween creates and returns a pointer to a new tween with the specified duration
+// in seconds which calls the specified function with the eased progress.
+// The function may be nil, for example for a delay in a sequence.
+func NewTween(duration float32, apply func(k float32)) *Tween {
+
+	t := new(Tween)
+	t.duration = duration
+	t.easing = Linear
+	t.apply = apply
+	return t
+}
+
+// SetBegin sets the function called to capture the start values the first time the tween starts.
+// Returns pointer to this updated tween.
+func (t *Tween) SetBegin(begin func()) *Tween {
+
+	t.begin = begin
+	return t
+}
+
+// SetDuration sets the duration in seconds of each iteration of the tween.
+// Returns pointer to this updated tween.
+func (t *Tween) SetDuration(duration float32) *Tween {
+
+	t.duration = duration
+	return t
+}
+
+// Duration returns the duration in seconds of each iteration of the tween.
+func (t *Tween) Duration() float32 {
+
+	return t.duration
+}
+
+// SetDelay sets the delay in seconds before the tween starts. The default is 0.
+// Returns pointer to this updated tween.
+func (t *Tween) SetDelay(delay float32) *Tween {
+
+	t.delay = delay
+	return t
+}
+
+// Delay returns the delay in seconds before the tween starts.
+func (t *Tween) Delay() float32 {
+
+	return t.delay
+}
+
+// SetEasing sets the easing function of the tween. The default is Linear.
+// Returns pointer to this updated tween.
+func (t *Tween) SetEasing(easing Easing) *Tween {
+
+	t.easing = easing
+	return t
+}
+
+// SetRepeat sets the number of times the tween is repeated after the first time,
+// or -1 to repeat it until cancelled. The default is 0.
+// Returns pointer to this updated tween.
+func (t *Tween) SetRepeat(count int) *Tween {
+
+	t.repeat = count
+	return t
+}
+
+// Repeat returns the number of times the tween is repeated after the first time.
+func (t *Tween) Repeat() int {
+
+	return t.repeat
+}
+
+// SetYoyo sets whether the repetitions alternate between playing forwards and backwards.
+// Returns pointer to this updated tween.
+func (t *Tween) SetYoyo(state bool) *Tween {
+
+	t.yoyo = state
+	return t
+}
+
+// Yoyo returns whether the repetitions alternate between playing forwards and backwards.
+func (t *Tween) Yoyo() bool {
+
+	return t.yoyo
+}
+
+// OnStart sets the function called when the tween starts after its delay.
+// Returns pointer to this updated tween.
+func (t *Tween) OnStart(cb func()) *Tween {
+
+	t.onStart = cb
+	return t
+}
+
+// OnUpdate sets the function called after the values are set on each update.
+// Returns pointer to this updated tween.
+func (t *Tween) OnUpdate(cb func()) *Tween {
+
+	t.onUpdate = cb
+	return t
+}
+
+// OnRepeat sets the function called when the tween is repeated.
+// Returns pointer to this updated tween.
+func (t *Tween) OnRepeat(cb func()) *Tween {
+
+	t.onRepeat = cb
+	return t
+}
+
+// OnComplete sets the function called when the tween completes its last repetition.
+// Returns pointer to this updated tween.
+func (t *Tween) OnComplete(cb func()) *Tween {
+
+	t.onComplete = cb
+	return t
+}
+
+// Progress returns the linear progress from 0 to 1 of the current iteration of the tween.
+func (t *Tween) Progress() float32 {
+
+	if t.duration <= 0 {
+		return 1
+	}
+	return t.elapsed / t.duration
+}
+
+// Iteration returns the index of the current repetition of the tween.
+func (t *Tween) Iteration() int {
+
+	return t.iteration
+}
+
+// start restarts the tween and captures the start values again.
+func (t *Tween) start() {
+
+	t.rewind()
+	t.captured = false
+}
+
+// rewind restarts the tween keeping the captured start values.
+func (t *Tween) rewind() {
+
+	t.waited = 0
+	t.elapsed = 0
+	t.iteration = 0
+	t.started = false
+}
+
+// set sets the values for the specified linear progress of the current iteration.
+func (t *Tween) set(p float32) {
+
+	if t.yoyo && t.iteration%2 == 1 {
+		p = 1 - p
+	}
+	if t.apply != nil {
+		t.apply(t.easing(p))
+	}
+	if t.onUpdate != nil {
+		t.onUpdate()
+	}
+}
+
+// update advances the tween by the specified time in seconds and returns
+// the time left after it completed and whether it completed.
+func (t *Tween) update(delta float32) (float32, bool) {
+
+	// Waits for the delay
+	if !t.started {
+		t.waited += delta
+		if t.waited < t.delay {
+			return 0, false
+		}
+		delta = t.waited - t.delay
+		t.started = true
+		if !t.captured {
+			t.captured = true
+			if t.begin != nil {
+				t.begin()
+			}
+		}
+		if t.onStart != nil {
+			t.onStart()
+		}
+	}
+
+	t.elapsed += delta
+	for {
+		if t.elapsed < t.duration {
+			t.set(t.elapsed / t.duration)
+			return 0, false
+		}
+		left := t.elapsed - t.duration
+		t.set(1)
+		if t.repeat >= 0 && t.iteration >= t.repeat {
+			t.elapsed = t.duration
+			if t.onComplete != nil {
+				t.onComplete()
+			}
+			return left, true
+		}
+		t.iteration++
+		t.elapsed = left
+		if t.onRepeat != nil {
+			t.onRepeat()
+		}
+		// A tween without duration repeated forever is repeated once per update
+		if t.duration <= 0 {
+			t.elapsed = 0
+			return 0, false
+		}
+	}
+}
@@ -18,6 +18,8 @@ import (
 	"github.com/wangzun/gogame/engine/math32"
 	"github.com/wangzun/gogame/engine/moblie"
 	"github.com/wangzun/gogame/engine/renderer"
+	"github.com/wangzun/gogame/engine/texture"
+	"github.com/wangzun/gogame/engine/tween"
 	"github.com/wangzun/gogame/engine/util/logger"
 )
 
@@ -46,6 +48,8 @@ type Application struct {
 	execTrace         *string               // File to write execution trace data to
 	moblie            *moblie.Moblie
 	control           bool
+	texLoader         *texture.Loader // Asynchronous texture loader created on demand
+	tweens            *tween.Manager  // Tween manager created on demand
 }
 
 // Options defines initial options passed to the application creation function
@@ -359,9 +363,19 @@ func (app *Application) Loop() error {
 	// Process application timers
 	app.ProcessTimers()
 
+	// Advances the tweens with the frame time
+	if app.tweens != nil {
+		app.tweens.Update(app.FrameDeltaSeconds())
+	}
+
 	// Dispatch before render event
 	app.Dispatch(OnBeforeRender, nil)
 
+	// Uploads asynchronously loaded textures within the frame budget
+	if app.texLoader != nil && app.show {
+		app.texLoader.Update(app.gl)
+	}
+
 	// Renders the current scene and/or gui
 	if app.show {
 		isRender, err := app.renderer.Render(app.camera)
@@ -385,10 +399,33 @@ func (app *Application) Loop() error {
 	return nil
 }
 
+// TextureLoader returns the application asynchronous texture loader, creating it on the first call.
+// The loaded textures are uploaded by the application before rendering each frame.
+func (app *Application) TextureLoader() *texture.Loader {
+
+	if app.texLoader == nil {
+		app.texLoader = texture.NewLoader(2)
+	}
+	return app.texLoader
+}
+
+// Tweens returns the application tween manager, creating it on the first call.
+// The tweens are advanced by the application with the frame time before rendering each frame.
+func (app *Application) Tweens() *tween.Manager {
+
+	if app.tweens == nil {
+		app.tweens = tween.NewManager()
+	}
+	return app.tweens
+}
+
+// ClearUI clears the screen with the background color of the renderer environment.
 func (app *Application) ClearUI() {
-	cc := math32.NewColor("gray")
+	cc := renderer.DefaultBackgroundColor
+	if app.renderer != nil {
+		cc = app.renderer.Environment().BackgroundColor()
+	}
 	app.gl.ClearColor(cc.R, cc.G, cc.B, 1)
-	// app.gl.ClearColor(0, 0, 0, 1)
 	app.gl.Clear(gl.DEPTH_BUFFER_BIT | gl.STENCIL_BUFFER_BIT | gl.COLOR_BUFFER_BIT)
 }
 
@@ -420,10 +457,6 @@ func (app *Application) InitGls(glctx gl.Context) {
 	glVersion := app.Gl().GetString(gl.VERSION)
 	app.log.Info("OpenGL version: %s", glVersion)
 
-	// // Clears the screen
-	// app.gl.Viewport(0, 0, int32(app.moblie.WidthPx), int32(app.moblie.HeightPx))
-	app.ClearUI()
-
 	// Creates orbit camera control
 	// It is important to do this after the root panel subscription
 	// to avoid GUI events being propagated to the orbit control.
@@ -443,4 +476,6 @@ func (app *Application) InitGls(glctx gl.Context) {
 	app.renderer.SetScene(app.scene)
 	app.renderer.SetGui(app.guiroot)
 
+	// Clears the screen with the environment background color
+	app.ClearUI()
 }